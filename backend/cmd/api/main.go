@@ -69,6 +69,17 @@ func main() {
 		log.Println("✅ Database migrations applied")
 	}
 
+	// Tenant secrets are encrypted at rest; without a key they can be neither saved nor used
+	if cfg.Secrets.EncryptionKey == "" {
+		log.Println("⚠️  SECRETS_ENCRYPTION_KEY not set, tenant secrets are disabled")
+	} else if err := storage.SetSecretKey(cfg.Secrets.EncryptionKey); err != nil {
+		log.Fatalf("❌ Invalid SECRETS_ENCRYPTION_KEY: %v", err)
+	} else if n, err := storage.EncryptTenantSecrets(context.Background()); err != nil {
+		log.Printf("⚠️  Failed to encrypt stored tenant secrets: %v", err)
+	} else if n > 0 {
+		log.Printf("✅ Encrypted %d plaintext tenant secret(s)", n)
+	}
+
 	// Connect to Redis
 	var redisClient *cache.RedisClient
 	redisClient, err = cache.New(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password)
@@ -95,6 +106,8 @@ func main() {
 		redisAddr := fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port)
 		asynqServer = workers.StartServer(redisAddr, graphWalker)
 		defer asynqServer.Stop()
+		scheduler := workers.StartScheduler(redisAddr)
+		defer scheduler.Shutdown()
 	}
 
 	addr := fmt.Sprintf(":%s", cfg.AppPort)
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicBaseURL    = "https://api.anthropic.com/v1"
+	anthropicVersion    = "2023-06-01"
+	anthropicMaxTokens  = 1024
+	anthropicJSONTokens = 8192 // generated workflow graphs are long
+	anthropicJSONTool   = "workflow_graph"
+)
+
+// AnthropicClient implements the LLMClient interface for Anthropic's Claude models through the
+// Messages API. Anthropic has no embeddings API; configure another provider for embeddings.
+type AnthropicClient struct {
+	apiKey     string
+	model      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAnthropicClient initializes a new Anthropic provider
+func NewAnthropicClient(apiKey string, model string) *AnthropicClient {
+	if model == "" {
+		model = "claude-3-5-haiku-latest" // Default recommended for speed/cost
+	}
+	return &AnthropicClient{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    anthropicBaseURL,
+		httpClient: defaultHTTPClient,
+	}
+}
+
+// WithBaseURL points the client at another endpoint, such as a proxy or a test server
+func (c *AnthropicClient) WithBaseURL(baseURL string) *AnthropicClient {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+	return c
+}
+
+// WithModel returns a client for another model with the same credentials
+func (c *AnthropicClient) WithModel(model string) LLMClient {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature float32            `json:"temperature,omitempty"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice  map[string]string  `json:"tool_choice,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (c *AnthropicClient) send(ctx context.Context, req anthropicRequest) (*anthropicResponse, error) {
+	var resp anthropicResponse
+	headers := map[string]string{"x-api-key": c.apiKey, "anthropic-version": anthropicVersion}
+	if err := postJSON(ctx, c.httpClient, ProviderAnthropic, c.baseURL+"/messages", headers, req, &resp); err != nil {
+		return nil, err
+	}
+	reportUsage(ctx, Usage{Provider: ProviderAnthropic, Model: req.Model, PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens})
+	return &resp, nil
+}
+
+// GenerateText sends a simple prompt to the Messages API
+func (c *AnthropicClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return c.Chat(ctx, ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: prompt}}})
+}
+
+// GenerateEmbedding is not available: Anthropic does not offer embeddings
+func (c *AnthropicClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, ErrEmbeddingsUnsupported
+}
+
+// GenerateStructuredJSON forces a reply matching `schema` by making the model call a tool whose
+// input schema it is, and returns the tool input
+func (c *AnthropicClient) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schema: %v", err)
+	}
+
+	resp, err := c.send(ctx, anthropicRequest{
+		Model:      c.model,
+		MaxTokens:  anthropicJSONTokens,
+		Messages:   []anthropicMessage{{Role: RoleUser, Content: prompt}},
+		Tools:      []anthropicTool{{Name: anthropicJSONTool, Description: "Record the answer.", InputSchema: schemaBytes}},
+		ToolChoice: map[string]string{"type": "tool", "name": anthropicJSONTool},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == anthropicJSONTool {
+			return string(block.Input), nil
+		}
+	}
+	return "", fmt.Errorf("anthropic error: no structured answer returned")
+}
+
+// Chat sends a system prompt and the conversation to the Messages API
+func (c *AnthropicClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicMaxTokens
+	}
+	messages := make([]anthropicMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		role := RoleUser
+		if m.Role == RoleAssistant {
+			role = RoleAssistant
+		}
+		messages = append(messages, anthropicMessage{Role: role, Content: m.Content})
+	}
+	// The Messages API wants the conversation to open with a user turn
+	if len(messages) == 0 || messages[0].Role != RoleUser {
+		messages = append([]anthropicMessage{{Role: RoleUser, Content: "(conversation continues)"}}, messages...)
+	}
+
+	resp, err := c.send(ctx, anthropicRequest{
+		Model:       c.model,
+		MaxTokens:   maxTokens,
+		System:      req.System,
+		Messages:    messages,
+		Temperature: req.Temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("anthropic error: no text returned")
+	}
+	return text.String(), nil
+}
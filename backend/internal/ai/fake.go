@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Methods of LLMClient, as recorded in FakeCall and fixture files
+const (
+	MethodGenerateText   = "generate_text"
+	MethodEmbedding      = "embedding"
+	MethodStructuredJSON = "structured_json"
+	MethodChat           = "chat"
+)
+
+// FakeCall is one request received by a FakeClient
+type FakeCall struct {
+	Method string
+	Prompt string       // the prompt, or for Chat the system prompt and the conversation as text
+	Chat   *ChatRequest // set for Chat
+	Schema any          // set for GenerateStructuredJSON
+}
+
+type fakeRule struct {
+	method    string // empty matches every method
+	pattern   *regexp.Regexp
+	responses []string
+	err       error
+}
+
+// FakeClient is a deterministic LLMClient for tests. Text answers come from rules matching the
+// prompt; embeddings are derived from the words of the text, so texts sharing words are similar.
+// Every call is recorded, and metered with estimated token counts.
+type FakeClient struct {
+	mu    sync.Mutex
+	rules []*fakeRule
+	calls []FakeCall
+}
+
+// NewFakeClient returns a FakeClient without rules: every text request fails until one is added
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+// Respond answers requests of method (empty for any) whose prompt matches the regular expression
+// pattern. Several responses are returned one per call, the last one repeating. Rules are tried
+// in the order they were added.
+func (f *FakeClient) Respond(method, pattern string, responses ...string) *FakeClient {
+	if len(responses) == 0 {
+		responses = []string{""}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &fakeRule{method: method, pattern: regexp.MustCompile(pattern), responses: responses})
+	return f
+}
+
+// Fail makes requests of method (empty for any) whose prompt matches pattern return err
+func (f *FakeClient) Fail(method, pattern string, err error) *FakeClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &fakeRule{method: method, pattern: regexp.MustCompile(pattern), err: err})
+	return f
+}
+
+// Calls returns the requests received so far, oldest first
+func (f *FakeClient) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeCall(nil), f.calls...)
+}
+
+// CallsTo returns the requests of one method
+func (f *FakeClient) CallsTo(method string) []FakeCall {
+	var out []FakeCall
+	for _, c := range f.Calls() {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (f *FakeClient) answer(ctx context.Context, call FakeCall) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+	for _, r := range f.rules {
+		if (r.method != "" && r.method != call.Method) || !r.pattern.MatchString(call.Prompt) {
+			continue
+		}
+		if r.err != nil {
+			return "", r.err
+		}
+		out := r.responses[0]
+		if len(r.responses) > 1 {
+			r.responses = r.responses[1:]
+		}
+		reportUsage(ctx, Usage{Provider: "fake", Model: "fake", PromptTokens: estimateTokens(call.Prompt), CompletionTokens: estimateTokens(out)})
+		return out, nil
+	}
+	return "", fmt.Errorf("fake LLM: no response for %s prompt %q", call.Method, truncate(call.Prompt, 120))
+}
+
+// GenerateText answers from the matching rule
+func (f *FakeClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return f.answer(ctx, FakeCall{Method: MethodGenerateText, Prompt: prompt})
+}
+
+// GenerateStructuredJSON answers from the matching rule; the schema is recorded, not enforced
+func (f *FakeClient) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return f.answer(ctx, FakeCall{Method: MethodStructuredJSON, Prompt: prompt, Schema: schema})
+}
+
+// Chat answers from the rule matching the system prompt and conversation
+func (f *FakeClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	return f.answer(ctx, FakeCall{Method: MethodChat, Prompt: ChatTranscript(req), Chat: &req})
+}
+
+// GenerateEmbedding returns a unit vector hashing every word of text into one of the
+// embeddingDimensions, the same for the same text. Failures scripted with Fail apply.
+func (f *FakeClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, FakeCall{Method: MethodEmbedding, Prompt: text})
+	for _, r := range f.rules {
+		if r.err != nil && r.method == MethodEmbedding && r.pattern.MatchString(text) {
+			f.mu.Unlock()
+			return nil, r.err
+		}
+	}
+	f.mu.Unlock()
+	reportUsage(ctx, Usage{Provider: "fake", Model: "fake-embedding", PromptTokens: estimateTokens(text)})
+	return HashEmbedding(text), nil
+}
+
+// HashEmbedding is the embedding of FakeClient: the normalised count of words hashed into
+// embeddingDimensions buckets
+func HashEmbedding(text string) []float32 {
+	vec := make([]float32, embeddingDimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		vec[0] = 1
+		return vec
+	}
+	for _, w := range words {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		vec[h.Sum32()%embeddingDimensions]++
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v * v)
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] = float32(float64(vec[i]) / norm)
+	}
+	return vec
+}
+
+// ChatTranscript renders a chat request as text, the system prompt first and then one
+// "role: content" line per message
+func ChatTranscript(req ChatRequest) string {
+	var b strings.Builder
+	if req.System != "" {
+		b.WriteString("system: " + req.System + "\n")
+	}
+	for _, m := range req.Messages {
+		b.WriteString(m.Role + ": " + m.Content + "\n")
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
+
+var _ LLMClient = (*FakeClient)(nil)
@@ -0,0 +1,95 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+)
+
+func TestFakeClientRules(t *testing.T) {
+	ctx := context.Background()
+	f := ai.NewFakeClient().
+		Respond(ai.MethodStructuredJSON, `graph`, `{"v":1}`, `{"v":2}`).
+		Fail("", `outage`, errors.New("provider down")).
+		Respond("", `.`, "fallback")
+
+	for _, want := range []string{`{"v":1}`, `{"v":2}`, `{"v":2}`} {
+		if got, _ := f.GenerateStructuredJSON(ctx, "build a graph", nil); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+	if got, _ := f.GenerateText(ctx, "build a graph"); got != "fallback" {
+		t.Errorf("structured rule answered a text request: %q", got)
+	}
+	if _, err := f.Chat(ctx, ai.ChatRequest{System: "outage"}); err == nil || err.Error() != "provider down" {
+		t.Errorf("expected the scripted failure, got %v", err)
+	}
+	if _, err := ai.NewFakeClient().GenerateText(ctx, "hi"); err == nil {
+		t.Error("an unscripted prompt must fail")
+	}
+	if calls := f.CallsTo(ai.MethodStructuredJSON); len(calls) != 3 || calls[0].Prompt != "build a graph" {
+		t.Errorf("unexpected call log %+v", calls)
+	}
+}
+
+func TestHashEmbedding(t *testing.T) {
+	cosine := func(a, b []float32) float64 {
+		var dot float64
+		for i := range a {
+			dot += float64(a[i] * b[i])
+		}
+		return dot
+	}
+	f := ai.NewFakeClient()
+	a, _ := f.GenerateEmbedding(context.Background(), "2BHK flats in Baner")
+	b, _ := f.GenerateEmbedding(context.Background(), "2BHK flats in Baner")
+	c, _ := f.GenerateEmbedding(context.Background(), "Flats in Baner, 2BHK!")
+	d, _ := f.GenerateEmbedding(context.Background(), "office space for rent")
+
+	if len(a) != 1536 || math.Abs(cosine(a, a)-1) > 1e-5 {
+		t.Fatalf("expected a 1536-dimension unit vector")
+	}
+	if cosine(a, b) != cosine(a, a) || math.Abs(cosine(a, c)-1) > 1e-5 {
+		t.Error("the same words must give the same embedding")
+	}
+	if cosine(a, d) > 0.5 {
+		t.Errorf("unrelated texts too similar: %v", cosine(a, d))
+	}
+}
+
+func TestFixtureClientRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	req := ai.ChatRequest{System: "Be brief.", Messages: []ai.ChatMessage{{Role: ai.RoleUser, Content: "price?"}}}
+
+	if _, err := ai.NewFixtureClient(dir, ai.FixtureReplay, nil).Chat(ctx, req); !errors.Is(err, ai.ErrFixtureMissing) {
+		t.Fatalf("expected ErrFixtureMissing before recording, got %v", err)
+	}
+
+	live := ai.NewFakeClient().Respond(ai.MethodChat, `price`, "78 lakh onwards")
+	recorder := ai.NewFixtureClient(dir, ai.FixtureRecord, live)
+	if got, err := recorder.Chat(ctx, req); err != nil || got != "78 lakh onwards" {
+		t.Fatalf("record: %q, %v", got, err)
+	}
+	if _, err := recorder.GenerateEmbedding(ctx, "Baner"); err != nil {
+		t.Fatal(err)
+	}
+
+	replay := ai.NewFixtureClient(dir, ai.FixtureReplay, nil)
+	if got, err := replay.Chat(ctx, req); err != nil || got != "78 lakh onwards" {
+		t.Errorf("replay: %q, %v", got, err)
+	}
+	if vec, err := replay.GenerateEmbedding(ctx, "Baner"); err != nil || len(vec) != 1536 {
+		t.Errorf("embedding replay: %d values, %v", len(vec), err)
+	}
+	req.Messages[0].Content = "price of the 3BHK?"
+	if _, err := replay.Chat(ctx, req); !errors.Is(err, ai.ErrFixtureMissing) {
+		t.Errorf("a changed conversation must not match the recording, got %v", err)
+	}
+	if len(live.Calls()) != 2 {
+		t.Errorf("replay called the live client")
+	}
+}
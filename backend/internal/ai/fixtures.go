@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FixtureMode selects whether a FixtureClient replays recorded answers or records new ones
+type FixtureMode string
+
+const (
+	FixtureReplay FixtureMode = "replay"
+	FixtureRecord FixtureMode = "record"
+)
+
+// FixtureModeFromEnv returns FixtureRecord when AI_FIXTURES=record, FixtureReplay otherwise
+func FixtureModeFromEnv() FixtureMode {
+	if os.Getenv("AI_FIXTURES") == string(FixtureRecord) {
+		return FixtureRecord
+	}
+	return FixtureReplay
+}
+
+// ErrFixtureMissing is returned in replay mode for a request that was never recorded
+var ErrFixtureMissing = errors.New("no recorded LLM response")
+
+// FixtureClient records the answers of a live provider to fixture files, one per request, and
+// replays them offline. A fixture is keyed by the whole request (prompt, schema or conversation),
+// so a changed prompt needs recording again.
+type FixtureClient struct {
+	dir  string
+	mode FixtureMode
+	live LLMClient // only used when recording
+}
+
+// NewFixtureClient returns a client reading fixtures from dir, or in FixtureRecord mode calling
+// live and writing its answers to dir
+func NewFixtureClient(dir string, mode FixtureMode, live LLMClient) *FixtureClient {
+	return &FixtureClient{dir: dir, mode: mode, live: live}
+}
+
+// fixture is the file format; the request is stored for readers of the fixture, not for matching
+type fixture struct {
+	Method    string       `json:"method"`
+	Prompt    string       `json:"prompt,omitempty"`
+	Chat      *ChatRequest `json:"chat,omitempty"`
+	Response  string       `json:"response,omitempty"`
+	Embedding []float32    `json:"embedding,omitempty"`
+}
+
+// path returns the fixture file of a request: the method and a hash of everything sent
+func (c *FixtureClient) path(method string, request ...any) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(method))
+	for _, part := range request {
+		data, err := json.Marshal(part)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal fixture key: %v", err)
+		}
+		h.Write([]byte{0})
+		h.Write(data)
+	}
+	return filepath.Join(c.dir, method+"-"+hex.EncodeToString(h.Sum(nil))[:16]+".json"), nil
+}
+
+// do replays the fixture at path, or records the answer of call into it
+func (c *FixtureClient) do(path string, f fixture, call func() (fixture, error)) (*fixture, error) {
+	if c.mode != FixtureRecord {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for %s (%s): record it with AI_FIXTURES=record", ErrFixtureMissing, f.Method, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var out fixture
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+		}
+		return &out, nil
+	}
+
+	if c.live == nil {
+		return nil, errors.New("recording LLM fixtures needs a live client")
+	}
+	answer, err := call()
+	if err != nil {
+		return nil, err
+	}
+	f.Response, f.Embedding = answer.Response, answer.Embedding
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GenerateText replays or records a text answer
+func (c *FixtureClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	path, err := c.path(MethodGenerateText, prompt)
+	if err != nil {
+		return "", err
+	}
+	f, err := c.do(path, fixture{Method: MethodGenerateText, Prompt: prompt}, func() (fixture, error) {
+		out, err := c.live.GenerateText(ctx, prompt)
+		return fixture{Response: out}, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return f.Response, nil
+}
+
+// GenerateEmbedding replays or records an embedding
+func (c *FixtureClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	path, err := c.path(MethodEmbedding, text)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.do(path, fixture{Method: MethodEmbedding, Prompt: text}, func() (fixture, error) {
+		out, err := c.live.GenerateEmbedding(ctx, text)
+		return fixture{Embedding: out}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.Embedding, nil
+}
+
+// GenerateStructuredJSON replays or records a structured answer; the schema is part of the key
+func (c *FixtureClient) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	path, err := c.path(MethodStructuredJSON, prompt, schema)
+	if err != nil {
+		return "", err
+	}
+	f, err := c.do(path, fixture{Method: MethodStructuredJSON, Prompt: prompt}, func() (fixture, error) {
+		out, err := c.live.GenerateStructuredJSON(ctx, prompt, schema)
+		return fixture{Response: out}, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return f.Response, nil
+}
+
+// Chat replays or records a chat answer
+func (c *FixtureClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	path, err := c.path(MethodChat, req)
+	if err != nil {
+		return "", err
+	}
+	f, err := c.do(path, fixture{Method: MethodChat, Chat: &req}, func() (fixture, error) {
+		out, err := c.live.Chat(ctx, req)
+		return fixture{Response: out}, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return f.Response, nil
+}
+
+var _ LLMClient = (*FixtureClient)(nil)
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	geminiBaseURL        = "https://generativelanguage.googleapis.com/v1beta"
+	geminiEmbeddingModel = "gemini-embedding-001"
+	embeddingDimensions  = 1536 // size of the knowledge base vectors
+)
+
+// GeminiClient implements the LLMClient interface for Google's Gemini models through the
+// Generative Language API
+type GeminiClient struct {
+	apiKey         string
+	model          string
+	embeddingModel string
+	baseURL        string
+	httpClient     *http.Client
+}
+
+// NewGeminiClient initializes a new Gemini provider
+func NewGeminiClient(apiKey string, model string) *GeminiClient {
+	if model == "" {
+		model = "gemini-2.0-flash" // Default recommended for speed/cost
+	}
+	return &GeminiClient{
+		apiKey:         apiKey,
+		model:          model,
+		embeddingModel: geminiEmbeddingModel,
+		baseURL:        geminiBaseURL,
+		httpClient:     defaultHTTPClient,
+	}
+}
+
+// WithBaseURL points the client at another endpoint, such as a proxy or a test server
+func (c *GeminiClient) WithBaseURL(baseURL string) *GeminiClient {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+	return c
+}
+
+// WithModel returns a client for another model with the same credentials
+func (c *GeminiClient) WithModel(model string) LLMClient {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature        float32         `json:"temperature,omitempty"`
+	MaxOutputTokens    int             `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+func (c *GeminiClient) generate(ctx context.Context, req geminiRequest) (string, error) {
+	var resp geminiResponse
+	url := fmt.Sprintf("%s/models/%s:generateContent", c.baseURL, c.model)
+	if err := postJSON(ctx, c.httpClient, ProviderGemini, url, map[string]string{"x-goog-api-key": c.apiKey}, req, &resp); err != nil {
+		return "", err
+	}
+	reportUsage(ctx, Usage{Provider: ProviderGemini, Model: c.model,
+		PromptTokens: resp.UsageMetadata.PromptTokenCount, CompletionTokens: resp.UsageMetadata.CandidatesTokenCount})
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("gemini error: no candidates returned")
+	}
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("gemini error: empty answer (finish reason %s)", resp.Candidates[0].FinishReason)
+	}
+	return text.String(), nil
+}
+
+// GenerateText sends a simple prompt to generateContent
+func (c *GeminiClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return c.Chat(ctx, ChatRequest{Messages: []ChatMessage{{Role: RoleUser, Content: prompt}}})
+}
+
+// GenerateEmbedding calls embedContent, asking for vectors the size of OpenAI's so they fit the
+// knowledge base
+func (c *GeminiClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	req := map[string]any{
+		"content":              geminiContent{Parts: []geminiPart{{Text: text}}},
+		"outputDimensionality": embeddingDimensions,
+	}
+	var resp struct {
+		Embedding struct {
+			Values []float32 `json:"values"`
+		} `json:"embedding"`
+	}
+	url := fmt.Sprintf("%s/models/%s:embedContent", c.baseURL, c.embeddingModel)
+	if err := postJSON(ctx, c.httpClient, ProviderGemini, url, map[string]string{"x-goog-api-key": c.apiKey}, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embedding.Values) == 0 {
+		return nil, fmt.Errorf("gemini embedding error: no values returned")
+	}
+	// embedContent reports no usage
+	reportUsage(ctx, Usage{Provider: ProviderGemini, Model: c.embeddingModel, PromptTokens: estimateTokens(text)})
+	return resp.Embedding.Values, nil
+}
+
+// GenerateStructuredJSON forces the LLM to reply with a JSON structure matching the provided `schema`
+func (c *GeminiClient) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schema: %v", err)
+	}
+	return c.generate(ctx, geminiRequest{
+		Contents: []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+		GenerationConfig: geminiGenerationConfig{
+			ResponseMimeType:   "application/json",
+			ResponseJSONSchema: schemaBytes,
+		},
+	})
+}
+
+// Chat sends a system prompt and the conversation to generateContent
+func (c *GeminiClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	contents := make([]geminiContent, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model"
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+
+	greq := geminiRequest{
+		Contents:         contents,
+		GenerationConfig: geminiGenerationConfig{Temperature: req.Temperature, MaxOutputTokens: req.MaxTokens},
+	}
+	if req.System != "" {
+		greq.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.System}}}
+	}
+	return c.generate(ctx, greq)
+}
@@ -19,4 +19,28 @@ type LLMClient interface {
 	// a specific JSON schema.
 	// Useful for the "Magic Prompt" visual flow builder
 	GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error)
+
+	// Chat sends a system prompt and a conversation and returns the assistant's next turn
+	// Useful for AI replies that need the conversation so far
+	Chat(ctx context.Context, req ChatRequest) (string, error)
+}
+
+// Roles of a ChatMessage
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatMessage is one turn of a conversation
+type ChatMessage struct {
+	Role    string `json:"role"` // RoleUser or RoleAssistant
+	Content string `json:"content"`
+}
+
+// ChatRequest is a multi-message completion request
+type ChatRequest struct {
+	System      string        `json:"system,omitempty"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature float32       `json:"temperature,omitempty"` // 0 uses the provider default
+	MaxTokens   int           `json:"max_tokens,omitempty"`  // 0 uses the provider default
 }
@@ -0,0 +1,223 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// ErrBudgetExceeded is returned instead of calling a provider once a tenant has spent its monthly
+// LLM budget. AI nodes take their fallback path on it.
+var ErrBudgetExceeded = errors.New("monthly AI budget exceeded")
+
+// Budget states of a tenant (BudgetStatus.State)
+const (
+	BudgetOK       = "ok"
+	BudgetWarning  = "warning"  // past the warning threshold, calls still go through
+	BudgetExceeded = "exceeded" // calls are refused
+)
+
+// UsageStore persists metered calls and answers what a tenant has spent. store.Store implements it.
+type UsageStore interface {
+	GetUserByID(ctx context.Context, id int64) (*models.User, error)
+	CreateLLMUsage(ctx context.Context, u *models.LLMUsage) error
+	GetLLMSpend(ctx context.Context, userID int64, since time.Time) (float64, error)
+}
+
+// Budgets are monthly LLM spending limits in USD per plan (User.Plan). Plans without a budget
+// are not limited.
+type Budgets struct {
+	PerPlan     map[string]float64
+	WarnPercent float64 // share of the budget at which tenants are warned, e.g. 80
+}
+
+// BudgetStatus is a tenant's spend this calendar month (UTC) against its plan's budget
+type BudgetStatus struct {
+	Plan        string    `json:"plan"`
+	BudgetUSD   float64   `json:"budget_usd"` // 0 when the plan is not limited
+	SpentUSD    float64   `json:"spent_usd"`
+	State       string    `json:"state"`
+	PeriodStart time.Time `json:"period_start"`
+}
+
+// MonthStart returns the start of t's calendar month in UTC, when budgets reset
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MeteredClient records the tokens, model, latency and cost of every call of the wrapped client,
+// attributed with WithAttribution, and refuses calls of tenants over budget. It keeps the
+// wrapped client's tenant and node selection.
+type MeteredClient struct {
+	inner   LLMClient
+	store   UsageStore
+	budgets Budgets
+	now     func() time.Time
+
+	mu     sync.Mutex
+	warned map[int64]time.Time // tenant -> month start of the last warning logged
+}
+
+// NewMeteredClient wraps inner with metering and budgets
+func NewMeteredClient(inner LLMClient, store UsageStore, budgets Budgets) *MeteredClient {
+	return &MeteredClient{inner: inner, store: store, budgets: budgets, now: time.Now, warned: map[int64]time.Time{}}
+}
+
+// Select keeps the metering around the client selected for a tenant and node
+func (m *MeteredClient) Select(userID int64, sel Selection) LLMClient {
+	return &selectedMeter{MeteredClient: m, inner: Select(m.inner, userID, sel)}
+}
+
+// selectedMeter meters a client chosen by Select
+type selectedMeter struct {
+	*MeteredClient
+	inner LLMClient
+}
+
+func (s *selectedMeter) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return meterText(ctx, s.MeteredClient, MethodGenerateText, func(ctx context.Context) (string, error) { return s.inner.GenerateText(ctx, prompt) })
+}
+func (s *selectedMeter) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return s.MeteredClient.embedding(ctx, s.inner, text)
+}
+func (s *selectedMeter) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return meterText(ctx, s.MeteredClient, MethodStructuredJSON, func(ctx context.Context) (string, error) {
+		return s.inner.GenerateStructuredJSON(ctx, prompt, schema)
+	})
+}
+func (s *selectedMeter) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	return meterText(ctx, s.MeteredClient, MethodChat, func(ctx context.Context) (string, error) { return s.inner.Chat(ctx, req) })
+}
+
+// GenerateText meters the wrapped client's GenerateText
+func (m *MeteredClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return meterText(ctx, m, MethodGenerateText, func(ctx context.Context) (string, error) { return m.inner.GenerateText(ctx, prompt) })
+}
+
+// GenerateEmbedding meters the wrapped client's GenerateEmbedding
+func (m *MeteredClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return m.embedding(ctx, m.inner, text)
+}
+
+// GenerateStructuredJSON meters the wrapped client's GenerateStructuredJSON
+func (m *MeteredClient) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return meterText(ctx, m, MethodStructuredJSON, func(ctx context.Context) (string, error) {
+		return m.inner.GenerateStructuredJSON(ctx, prompt, schema)
+	})
+}
+
+// Chat meters the wrapped client's Chat
+func (m *MeteredClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	return meterText(ctx, m, MethodChat, func(ctx context.Context) (string, error) { return m.inner.Chat(ctx, req) })
+}
+
+func (m *MeteredClient) embedding(ctx context.Context, inner LLMClient, text string) ([]float32, error) {
+	var vec []float32
+	err := m.meter(ctx, MethodEmbedding, func(ctx context.Context) (err error) {
+		vec, err = inner.GenerateEmbedding(ctx, text)
+		return err
+	})
+	return vec, err
+}
+
+func meterText(ctx context.Context, m *MeteredClient, method string, call func(context.Context) (string, error)) (string, error) {
+	var out string
+	err := m.meter(ctx, method, func(ctx context.Context) (err error) {
+		out, err = call(ctx)
+		return err
+	})
+	return out, err
+}
+
+// meter checks the budget, makes the call and records what the providers reported
+func (m *MeteredClient) meter(ctx context.Context, method string, call func(context.Context) error) error {
+	a := AttributionFrom(ctx)
+	if a.UserID != 0 && len(m.budgets.PerPlan) > 0 {
+		status, err := m.Status(ctx, a.UserID)
+		if err != nil {
+			// Metering must not take AI down with the database
+			log.Printf("[AI] Failed to check the budget of tenant %d: %v", a.UserID, err)
+		} else if status.State == BudgetExceeded {
+			return fmt.Errorf("%w: spent $%.2f of $%.2f", ErrBudgetExceeded, status.SpentUSD, status.BudgetUSD)
+		}
+	}
+
+	callCtx, collector := withUsageCollector(ctx)
+	start := m.now()
+	err := call(callCtx)
+	latency := m.now().Sub(start)
+
+	u := &models.LLMUsage{
+		UserID: a.UserID, WorkflowID: a.WorkflowID, NodeID: a.NodeID, Feature: a.Feature,
+		Method: method, LatencyMs: latency.Milliseconds(), Success: err == nil,
+	}
+	// Providers that failed before the one that answered may have reported too; all of it is billed
+	for _, r := range collector.reported() {
+		u.Provider, u.Model = r.Provider, r.Model
+		u.PromptTokens += r.PromptTokens
+		u.CompletionTokens += r.CompletionTokens
+		u.CostUSD += Cost(r)
+	}
+	// Store the record without the caller's deadline, which may be what just expired
+	if recErr := m.store.CreateLLMUsage(context.WithoutCancel(ctx), u); recErr != nil {
+		log.Printf("[AI] Failed to record usage of tenant %d: %v", a.UserID, recErr)
+	}
+	if a.UserID != 0 && u.CostUSD > 0 && len(m.budgets.PerPlan) > 0 {
+		m.warnIfNeeded(ctx, a.UserID)
+	}
+	return err
+}
+
+// Status returns a tenant's spend this month against its plan's budget
+func (m *MeteredClient) Status(ctx context.Context, userID int64) (*BudgetStatus, error) {
+	user, err := m.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+	status := &BudgetStatus{Plan: user.Plan, BudgetUSD: m.budgets.PerPlan[user.Plan], PeriodStart: MonthStart(m.now()), State: BudgetOK}
+	if status.SpentUSD, err = m.store.GetLLMSpend(ctx, userID, status.PeriodStart); err != nil {
+		return nil, err
+	}
+	switch {
+	case status.BudgetUSD <= 0:
+	case status.SpentUSD >= status.BudgetUSD:
+		status.State = BudgetExceeded
+	case m.budgets.WarnPercent > 0 && status.SpentUSD >= status.BudgetUSD*m.budgets.WarnPercent/100:
+		status.State = BudgetWarning
+	}
+	return status, nil
+}
+
+// warnIfNeeded logs once a month when a tenant crosses the warning threshold or the budget
+func (m *MeteredClient) warnIfNeeded(ctx context.Context, userID int64) {
+	status, err := m.Status(ctx, userID)
+	if err != nil || status.State == BudgetOK {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userID
+	if status.State == BudgetExceeded {
+		key = -userID // a second warning when the cutoff is reached
+	}
+	if m.warned[key].Equal(status.PeriodStart) {
+		return
+	}
+	m.warned[key] = status.PeriodStart
+	log.Printf("[AI] Tenant %d (%s plan) has spent $%.2f of its $%.2f monthly AI budget: %s",
+		userID, status.Plan, status.SpentUSD, status.BudgetUSD, status.State)
+}
+
+var (
+	_ LLMClient = (*MeteredClient)(nil)
+	_ Selector  = (*MeteredClient)(nil)
+)
@@ -0,0 +1,96 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// usageStore keeps metered calls in memory
+type usageStore struct {
+	plan  string
+	usage []models.LLMUsage
+}
+
+func (s *usageStore) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return &models.User{ID: id, Plan: s.plan}, nil
+}
+
+func (s *usageStore) CreateLLMUsage(ctx context.Context, u *models.LLMUsage) error {
+	s.usage = append(s.usage, *u)
+	return nil
+}
+
+func (s *usageStore) GetLLMSpend(ctx context.Context, userID int64, since time.Time) (float64, error) {
+	spend := 0.0
+	for _, u := range s.usage {
+		if u.UserID == userID {
+			spend += u.CostUSD
+		}
+	}
+	return spend, nil
+}
+
+func TestMeteredClientRecordsUsage(t *testing.T) {
+	srv, _, _ := apiServer(t, http.StatusOK,
+		`{"content":[{"type":"text","text":"Hi!"}],"usage":{"input_tokens":1000,"output_tokens":200}}`)
+	s := &usageStore{plan: "starter"}
+	m := ai.NewMeteredClient(ai.NewAnthropicClient("key", "claude-3-5-haiku-latest").WithBaseURL(srv.URL), s, ai.Budgets{})
+
+	ctx := ai.WithAttribution(context.Background(), ai.Attribution{UserID: 7, WorkflowID: 3, NodeID: "reply", Feature: "action_ai_reply"})
+	if _, err := ai.Select(m, 7, ai.Selection{}).Chat(ctx, ai.ChatRequest{Messages: []ai.ChatMessage{{Role: ai.RoleUser, Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.usage) != 1 {
+		t.Fatalf("expected 1 usage record, got %d", len(s.usage))
+	}
+	u := s.usage[0]
+	if u.UserID != 7 || u.WorkflowID != 3 || u.NodeID != "reply" || u.Feature != "action_ai_reply" || u.Method != ai.MethodChat {
+		t.Errorf("call not attributed: %+v", u)
+	}
+	if u.Provider != ai.ProviderAnthropic || u.Model != "claude-3-5-haiku-latest" || u.PromptTokens != 1000 || u.CompletionTokens != 200 || !u.Success {
+		t.Errorf("usage not recorded: %+v", u)
+	}
+	// 1000 × $0.80/M + 200 × $4/M
+	if u.CostUSD < 0.0015999 || u.CostUSD > 0.0016001 {
+		t.Errorf("expected a cost of $0.0016, got %v", u.CostUSD)
+	}
+}
+
+func TestMeteredClientBudget(t *testing.T) {
+	ctx := ai.WithAttribution(context.Background(), ai.Attribution{UserID: 7})
+	s := &usageStore{plan: "starter", usage: []models.LLMUsage{{UserID: 7, CostUSD: 8.5}}}
+	live := ai.NewFakeClient().Respond("", ".", "ok")
+	m := ai.NewMeteredClient(live, s, ai.Budgets{PerPlan: map[string]float64{"starter": 10}, WarnPercent: 80})
+
+	status, err := m.Status(ctx, 7)
+	if err != nil || status.State != ai.BudgetWarning || status.BudgetUSD != 10 {
+		t.Fatalf("expected a warning at $8.50 of $10, got %+v %v", status, err)
+	}
+	if _, err := m.GenerateText(ctx, "hi"); err != nil {
+		t.Fatalf("a warning must not block calls: %v", err)
+	}
+
+	s.usage = append(s.usage, models.LLMUsage{UserID: 7, CostUSD: 2})
+	if _, err := m.GenerateText(ctx, "hi"); !errors.Is(err, ai.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if len(live.Calls()) != 1 {
+		t.Error("the provider was called over budget")
+	}
+
+	// Other plans and calls outside a tenant are not limited
+	if _, err := m.GenerateText(context.Background(), "hi"); err != nil {
+		t.Errorf("unattributed call refused: %v", err)
+	}
+	s.plan = "enterprise"
+	if _, err := m.GenerateText(ctx, "hi"); err != nil {
+		t.Errorf("unlimited plan refused: %v", err)
+	}
+}
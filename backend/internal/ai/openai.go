@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 )
 
 // OpenAIClient implements the LLMClient interface for OpenAI's models
 type OpenAIClient struct {
-	client *openai.Client
-	model  string
+	client         *openai.Client
+	provider       string
+	model          string
+	embeddingModel openai.EmbeddingModel
 }
 
 // NewOpenAIClient initializes a new OpenAI provider
@@ -20,11 +23,35 @@ func NewOpenAIClient(apiKey string, model string) *OpenAIClient {
 		model = openai.GPT4oMini // Default recommended for speed/cost
 	}
 	return &OpenAIClient{
-		client: openai.NewClient(apiKey),
-		model:  model,
+		client:         openai.NewClient(apiKey),
+		provider:       ProviderOpenAI,
+		model:          model,
+		embeddingModel: openai.SmallEmbedding3, // 1536 dimensions
 	}
 }
 
+// NewOpenAICompatibleClient initializes a provider for any server speaking the OpenAI API,
+// such as Ollama (http://localhost:11434/v1) or vLLM. The model is required; embeddingModel
+// may be empty when the server is only used for chat. Embeddings must have 1536 dimensions
+// to fit the knowledge base.
+func NewOpenAICompatibleClient(baseURL, apiKey, model, embeddingModel string) *OpenAIClient {
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = strings.TrimRight(baseURL, "/")
+	return &OpenAIClient{
+		client:         openai.NewClientWithConfig(cfg),
+		provider:       ProviderOpenAICompatible,
+		model:          model,
+		embeddingModel: openai.EmbeddingModel(embeddingModel),
+	}
+}
+
+// WithModel returns a client for another model with the same credentials
+func (c *OpenAIClient) WithModel(model string) LLMClient {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
 // GenerateText sends a simple prompt to the OpenAI Chat Completion API
 func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string, error) {
 	resp, err := c.client.CreateChatCompletion(
@@ -42,6 +69,7 @@ func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string,
 	if err != nil {
 		return "", fmt.Errorf("openai error: %v", err)
 	}
+	c.report(ctx, c.model, resp.Usage)
 	return resp.Choices[0].Message.Content, nil
 }
 
@@ -49,13 +77,20 @@ func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string,
 func (c *OpenAIClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	req := openai.EmbeddingRequest{
 		Input: []string{text},
-		Model: openai.SmallEmbedding3, // 1536 dimensions
+		Model: c.embeddingModel,
+	}
+	if req.Model == "" {
+		return nil, ErrEmbeddingsUnsupported
 	}
 
 	resp, err := c.client.CreateEmbeddings(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("openai embedding error: %v", err)
 	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("openai embedding error: no data returned")
+	}
+	c.report(ctx, string(c.embeddingModel), resp.Usage)
 
 	return resp.Data[0].Embedding, nil
 }
@@ -94,6 +129,45 @@ func (c *OpenAIClient) GenerateStructuredJSON(ctx context.Context, prompt string
 	if err != nil {
 		return "", fmt.Errorf("openai structured generation error: %v", err)
 	}
+	c.report(ctx, c.model, resp.Usage)
 
 	return resp.Choices[0].Message.Content, nil
 }
+
+// Chat sends a system prompt and the conversation to the OpenAI Chat Completion API
+func (c *OpenAIClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: req.System})
+	}
+	for _, m := range req.Messages {
+		role := openai.ChatMessageRoleUser
+		if m.Role == RoleAssistant {
+			role = openai.ChatMessageRoleAssistant
+		}
+		messages = append(messages, openai.ChatCompletionMessage{Role: role, Content: m.Content})
+	}
+
+	resp, err := c.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model:       c.model,
+			Messages:    messages,
+			Temperature: req.Temperature,
+			MaxTokens:   req.MaxTokens,
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("openai error: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai error: no choices returned")
+	}
+	c.report(ctx, c.model, resp.Usage)
+	return resp.Choices[0].Message.Content, nil
+}
+
+// report passes the token usage of a call to the meter, if the call is metered
+func (c *OpenAIClient) report(ctx context.Context, model string, u openai.Usage) {
+	reportUsage(ctx, Usage{Provider: c.provider, Model: model, PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens})
+}
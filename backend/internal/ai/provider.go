@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider names used in configuration and in the provider field of AI nodes
+const (
+	ProviderOpenAI           = "openai"
+	ProviderAnthropic        = "anthropic"
+	ProviderGemini           = "gemini"
+	ProviderOpenAICompatible = "openai_compatible" // Ollama, vLLM or any server speaking the OpenAI API
+)
+
+// ProviderNames lists every provider this package can talk to
+var ProviderNames = []string{ProviderOpenAI, ProviderAnthropic, ProviderGemini, ProviderOpenAICompatible}
+
+// KnownProvider reports whether name is one of ProviderNames
+func KnownProvider(name string) bool {
+	for _, p := range ProviderNames {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrEmbeddingsUnsupported is returned by providers (or configurations) without an embeddings API
+var ErrEmbeddingsUnsupported = errors.New("embeddings are not supported by this provider")
+
+// modelSwitcher is implemented by providers that can serve another model with the same credentials
+type modelSwitcher interface {
+	WithModel(model string) LLMClient
+}
+
+// APIError is a non-2xx answer of a provider's HTTP API
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s error: status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// defaultHTTPClient is shared by the providers that call their APIs directly
+var defaultHTTPClient = &http.Client{Timeout: 2 * time.Minute}
+
+// postJSON sends body as JSON and decodes a 2xx answer into out
+func postJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("%s error: failed to marshal request: %v", provider, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%s error: %v", provider, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s error: %v", provider, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("%s error: failed to read response: %v", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: apiErrorMessage(data)}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s error: invalid response: %v", provider, err)
+	}
+	return nil
+}
+
+// apiErrorMessage extracts {"error":{"message":...}}, the shape used by Anthropic and Gemini,
+// falling back to the raw body
+func apiErrorMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+		return body.Error.Message
+	}
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > 300 {
+		msg = msg[:300]
+	}
+	return msg
+}
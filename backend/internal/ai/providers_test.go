@@ -0,0 +1,97 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+)
+
+// apiServer answers every request with status and body and records the last request
+func apiServer(t *testing.T, status int, body string) (*httptest.Server, *http.Request, *map[string]any) {
+	t.Helper()
+	var last http.Request
+	payload := map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = *r
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &payload)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &last, &payload
+}
+
+func TestAnthropicStructuredJSON(t *testing.T) {
+	srv, req, payload := apiServer(t, http.StatusOK,
+		`{"content":[{"type":"tool_use","name":"workflow_graph","input":{"nodes":[]}}]}`)
+	c := ai.NewAnthropicClient("key", "claude-test").WithBaseURL(srv.URL)
+
+	got, err := c.GenerateStructuredJSON(context.Background(), "build it", map[string]any{"type": "object"})
+	if err != nil || got != `{"nodes":[]}` {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if req.URL.Path != "/messages" || req.Header.Get("x-api-key") != "key" || req.Header.Get("anthropic-version") == "" {
+		t.Errorf("unexpected request %s %v", req.URL.Path, req.Header)
+	}
+	if choice, _ := (*payload)["tool_choice"].(map[string]any); choice["name"] != "workflow_graph" || (*payload)["model"] != "claude-test" {
+		t.Errorf("tool not forced: %v", *payload)
+	}
+}
+
+func TestAnthropicChatOpensWithUserTurn(t *testing.T) {
+	srv, _, payload := apiServer(t, http.StatusOK, `{"content":[{"type":"text","text":"Hello!"}]}`)
+	c := ai.NewAnthropicClient("key", "").WithBaseURL(srv.URL)
+
+	got, err := c.Chat(context.Background(), ai.ChatRequest{
+		System:   "Be brief.",
+		Messages: []ai.ChatMessage{{Role: ai.RoleAssistant, Content: "Hi, how can I help?"}, {Role: ai.RoleUser, Content: "price?"}},
+	})
+	if err != nil || got != "Hello!" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	msgs, _ := (*payload)["messages"].([]any)
+	if len(msgs) != 3 || msgs[0].(map[string]any)["role"] != "user" || (*payload)["system"] != "Be brief." {
+		t.Errorf("unexpected messages %v", *payload)
+	}
+	if _, err := c.GenerateEmbedding(context.Background(), "x"); !errors.Is(err, ai.ErrEmbeddingsUnsupported) {
+		t.Errorf("expected ErrEmbeddingsUnsupported, got %v", err)
+	}
+}
+
+func TestGeminiChat(t *testing.T) {
+	srv, req, payload := apiServer(t, http.StatusOK, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Sure"}]}}]}`)
+	c := ai.NewGeminiClient("key", "gemini-test").WithBaseURL(srv.URL)
+
+	got, err := c.Chat(context.Background(), ai.ChatRequest{
+		System:      "Be brief.",
+		Messages:    []ai.ChatMessage{{Role: ai.RoleUser, Content: "hi"}, {Role: ai.RoleAssistant, Content: "hello"}, {Role: ai.RoleUser, Content: "price?"}},
+		Temperature: 0.5,
+	})
+	if err != nil || got != "Sure" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if req.URL.Path != "/models/gemini-test:generateContent" || req.Header.Get("x-goog-api-key") != "key" {
+		t.Errorf("unexpected request %s", req.URL.Path)
+	}
+	contents, _ := (*payload)["contents"].([]any)
+	if len(contents) != 3 || contents[1].(map[string]any)["role"] != "model" || (*payload)["systemInstruction"] == nil {
+		t.Errorf("unexpected payload %v", *payload)
+	}
+}
+
+func TestProviderAPIError(t *testing.T) {
+	srv, _, _ := apiServer(t, http.StatusTooManyRequests, `{"error":{"message":"quota exceeded"}}`)
+	_, err := ai.NewGeminiClient("key", "").WithBaseURL(srv.URL).GenerateText(context.Background(), "hi")
+
+	var apiErr *ai.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "quota exceeded" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Selection picks a provider and model for a request. Empty fields fall back to the tenant's
+// selection and then to the configured default.
+type Selection struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// ParseSelection parses "provider" or "provider:model"
+func ParseSelection(s string) Selection {
+	provider, model, _ := strings.Cut(strings.TrimSpace(s), ":")
+	return Selection{Provider: strings.TrimSpace(provider), Model: strings.TrimSpace(model)}
+}
+
+// Selector is implemented by clients that route each request to a provider chosen per tenant and per node
+type Selector interface {
+	Select(userID int64, sel Selection) LLMClient
+}
+
+// Select returns the client to use for a tenant and a node's selection. Clients that do not
+// route (a single provider, test fakes) are returned as they are.
+func Select(llm LLMClient, userID int64, sel Selection) LLMClient {
+	if s, ok := llm.(Selector); ok {
+		return s.Select(userID, sel)
+	}
+	return llm
+}
+
+// RouterConfig configures a Router
+type RouterConfig struct {
+	Providers          map[string]LLMClient // by provider name
+	DefaultProvider    string
+	FallbackProviders  []string // tried in order when the selected provider fails
+	EmbeddingsProvider string   // may differ from the chat provider
+	Tenants            map[int64]Selection
+}
+
+// Router implements LLMClient over several providers. Chat, text and structured requests go to
+// the provider selected for the tenant and node and fall back to the configured fallback providers
+// on errors. Embeddings always come from one provider: vectors of different models cannot be
+// compared, so they never fall back.
+type Router struct {
+	providers       map[string]LLMClient
+	defaultProvider string
+	fallbacks       []string
+	embeddings      LLMClient
+	tenants         map[int64]Selection
+}
+
+// NewRouter checks that every provider the configuration refers to is available
+func NewRouter(cfg RouterConfig) (*Router, error) {
+	if _, ok := cfg.Providers[cfg.DefaultProvider]; !ok {
+		return nil, fmt.Errorf("default LLM provider %q is not configured", cfg.DefaultProvider)
+	}
+	for _, name := range cfg.FallbackProviders {
+		if _, ok := cfg.Providers[name]; !ok {
+			return nil, fmt.Errorf("fallback LLM provider %q is not configured", name)
+		}
+	}
+	r := &Router{
+		providers:       cfg.Providers,
+		defaultProvider: cfg.DefaultProvider,
+		fallbacks:       cfg.FallbackProviders,
+		tenants:         cfg.Tenants,
+	}
+	if cfg.EmbeddingsProvider != "" {
+		embeddings, ok := cfg.Providers[cfg.EmbeddingsProvider]
+		if !ok {
+			return nil, fmt.Errorf("embeddings provider %q is not configured", cfg.EmbeddingsProvider)
+		}
+		r.embeddings = embeddings
+	}
+	for userID, sel := range cfg.Tenants {
+		if _, ok := cfg.Providers[sel.Provider]; sel.Provider != "" && !ok {
+			return nil, fmt.Errorf("LLM provider %q of tenant %d is not configured", sel.Provider, userID)
+		}
+	}
+	return r, nil
+}
+
+// Select resolves the provider and model for a tenant and a node: the node's choice wins over the
+// tenant's, which wins over the default. A node that only names a model keeps the tenant's provider.
+// A provider that is not configured is logged and replaced by the default.
+func (r *Router) Select(userID int64, sel Selection) LLMClient {
+	chosen := r.tenants[userID]
+	if chosen.Provider == "" {
+		chosen.Provider = r.defaultProvider
+	}
+	if sel.Provider != "" && sel.Provider != chosen.Provider {
+		chosen = Selection{Provider: sel.Provider}
+	}
+	if sel.Model != "" {
+		chosen.Model = sel.Model
+	}
+	if _, ok := r.providers[chosen.Provider]; !ok {
+		log.Printf("[AI] Provider %q is not configured, using %q", chosen.Provider, r.defaultProvider)
+		chosen = Selection{Provider: r.defaultProvider}
+	}
+
+	chain := []namedClient{{name: chosen.Provider, client: withModel(r.providers[chosen.Provider], chosen.Model)}}
+	for _, name := range r.fallbacks {
+		if name != chosen.Provider {
+			chain = append(chain, namedClient{name: name, client: r.providers[name]})
+		}
+	}
+	return &fallbackClient{chain: chain, embeddings: r.embeddings}
+}
+
+func withModel(c LLMClient, model string) LLMClient {
+	if m, ok := c.(modelSwitcher); ok && model != "" {
+		return m.WithModel(model)
+	}
+	return c
+}
+
+// GenerateText uses the default provider
+func (r *Router) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return r.Select(0, Selection{}).GenerateText(ctx, prompt)
+}
+
+// GenerateEmbedding uses the embeddings provider
+func (r *Router) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return r.Select(0, Selection{}).GenerateEmbedding(ctx, text)
+}
+
+// GenerateStructuredJSON uses the default provider
+func (r *Router) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return r.Select(0, Selection{}).GenerateStructuredJSON(ctx, prompt, schema)
+}
+
+// Chat uses the default provider
+func (r *Router) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	return r.Select(0, Selection{}).Chat(ctx, req)
+}
+
+type namedClient struct {
+	name   string
+	client LLMClient
+}
+
+// fallbackClient tries each provider of its chain in turn until one answers
+type fallbackClient struct {
+	chain      []namedClient
+	embeddings LLMClient
+}
+
+func (f *fallbackClient) try(ctx context.Context, call func(LLMClient) (string, error)) (string, error) {
+	var err error
+	for i, c := range f.chain {
+		var out string
+		if out, err = call(c.client); err == nil {
+			return out, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		if i < len(f.chain)-1 {
+			log.Printf("[AI] Provider %s failed, falling back to %s: %v", c.name, f.chain[i+1].name, err)
+		}
+	}
+	return "", err
+}
+
+func (f *fallbackClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return f.try(ctx, func(c LLMClient) (string, error) { return c.GenerateText(ctx, prompt) })
+}
+
+func (f *fallbackClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if f.embeddings == nil {
+		return nil, ErrEmbeddingsUnsupported
+	}
+	return f.embeddings.GenerateEmbedding(ctx, text)
+}
+
+func (f *fallbackClient) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return f.try(ctx, func(c LLMClient) (string, error) { return c.GenerateStructuredJSON(ctx, prompt, schema) })
+}
+
+func (f *fallbackClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	return f.try(ctx, func(c LLMClient) (string, error) { return c.Chat(ctx, req) })
+}
@@ -0,0 +1,138 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+)
+
+// stubProvider answers with its name and model, or fails
+type stubProvider struct {
+	name  string
+	model string
+	fail  bool
+	calls *[]string
+}
+
+func (p stubProvider) answer() (string, error) {
+	*p.calls = append(*p.calls, p.name+":"+p.model)
+	if p.fail {
+		return "", errors.New(p.name + " is down")
+	}
+	return p.name + ":" + p.model, nil
+}
+
+func (p stubProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return p.answer()
+}
+func (p stubProvider) Chat(ctx context.Context, req ai.ChatRequest) (string, error) {
+	return p.answer()
+}
+func (p stubProvider) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return p.answer()
+}
+func (p stubProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	*p.calls = append(*p.calls, "embed:"+p.name)
+	return []float32{1}, nil
+}
+func (p stubProvider) WithModel(model string) ai.LLMClient {
+	p.model = model
+	return p
+}
+
+func newTestRouter(t *testing.T, calls *[]string, down ...string) *ai.Router {
+	t.Helper()
+	providers := map[string]ai.LLMClient{}
+	for _, name := range []string{ai.ProviderOpenAI, ai.ProviderAnthropic, ai.ProviderGemini} {
+		p := stubProvider{name: name, model: "default", calls: calls}
+		for _, d := range down {
+			p.fail = p.fail || d == name
+		}
+		providers[name] = p
+	}
+	r, err := ai.NewRouter(ai.RouterConfig{
+		Providers:          providers,
+		DefaultProvider:    ai.ProviderOpenAI,
+		FallbackProviders:  []string{ai.ProviderGemini, ai.ProviderOpenAI},
+		EmbeddingsProvider: ai.ProviderGemini,
+		Tenants:            map[int64]ai.Selection{7: {Provider: ai.ProviderAnthropic, Model: "claude-tenant"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRouterSelection(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	r := newTestRouter(t, &calls)
+
+	cases := []struct {
+		name   string
+		userID int64
+		sel    ai.Selection
+		want   string
+	}{
+		{"default", 1, ai.Selection{}, "openai:default"},
+		{"tenant", 7, ai.Selection{}, "anthropic:claude-tenant"},
+		{"node model keeps the tenant's provider", 7, ai.Selection{Model: "claude-node"}, "anthropic:claude-node"},
+		{"node provider drops the tenant's model", 7, ai.Selection{Provider: ai.ProviderGemini}, "gemini:default"},
+		{"unconfigured provider uses the default", 1, ai.Selection{Provider: ai.ProviderOpenAICompatible}, "openai:default"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ai.Select(r, tc.userID, tc.sel).Chat(ctx, ai.ChatRequest{})
+			if err != nil || got != tc.want {
+				t.Errorf("got %q, %v; want %q", got, err, tc.want)
+			}
+		})
+	}
+
+	if _, err := ai.Select(r, 7, ai.Selection{}).GenerateEmbedding(ctx, "hi"); err != nil || calls[len(calls)-1] != "embed:gemini" {
+		t.Errorf("embeddings must come from the embeddings provider, calls %v", calls)
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	var calls []string
+	r := newTestRouter(t, &calls, ai.ProviderAnthropic, ai.ProviderGemini)
+
+	got, err := ai.Select(r, 7, ai.Selection{}).GenerateText(context.Background(), "hi")
+	if err != nil || got != "openai:default" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	want := []string{"anthropic:claude-tenant", "gemini:default", "openai:default"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls %v, want %v", calls, want)
+			break
+		}
+	}
+
+	calls = nil
+	r = newTestRouter(t, &calls, ai.ProviderOpenAI, ai.ProviderGemini)
+	if _, err := r.Chat(context.Background(), ai.ChatRequest{}); err == nil || err.Error() != "gemini is down" {
+		t.Errorf("expected the last provider's error, got %v", err)
+	}
+}
+
+func TestNewRouterRejectsUnknownProviders(t *testing.T) {
+	var calls []string
+	providers := map[string]ai.LLMClient{ai.ProviderOpenAI: stubProvider{name: "openai", calls: &calls}}
+	for _, cfg := range []ai.RouterConfig{
+		{Providers: providers, DefaultProvider: ai.ProviderAnthropic},
+		{Providers: providers, DefaultProvider: ai.ProviderOpenAI, FallbackProviders: []string{ai.ProviderGemini}},
+		{Providers: providers, DefaultProvider: ai.ProviderOpenAI, EmbeddingsProvider: ai.ProviderGemini},
+		{Providers: providers, DefaultProvider: ai.ProviderOpenAI, Tenants: map[int64]ai.Selection{1: {Provider: ai.ProviderGemini}}},
+	} {
+		if _, err := ai.NewRouter(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Usage is the token count of one provider call
+type Usage struct {
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Attribution says on whose behalf an LLM call is made, for metering and budgets
+type Attribution struct {
+	UserID     int64
+	WorkflowID int64
+	NodeID     string
+	Feature    string // node type, or the API feature such as generate_workflow
+}
+
+type attributionKey struct{}
+type usageKey struct{}
+
+// WithAttribution marks the LLM calls made with ctx as made for a tenant, workflow and node
+func WithAttribution(ctx context.Context, a Attribution) context.Context {
+	return context.WithValue(ctx, attributionKey{}, a)
+}
+
+// AttributionFrom returns the attribution set on ctx, if any
+func AttributionFrom(ctx context.Context) Attribution {
+	a, _ := ctx.Value(attributionKey{}).(Attribution)
+	return a
+}
+
+// usageCollector gathers the usage providers report during one metered call. With fallbacks
+// several providers may report.
+type usageCollector struct {
+	mu    sync.Mutex
+	usage []Usage
+}
+
+func withUsageCollector(ctx context.Context) (context.Context, *usageCollector) {
+	c := &usageCollector{}
+	return context.WithValue(ctx, usageKey{}, c), c
+}
+
+// reportUsage is called by providers after every successful call
+func reportUsage(ctx context.Context, u Usage) {
+	if c, ok := ctx.Value(usageKey{}).(*usageCollector); ok {
+		c.mu.Lock()
+		c.usage = append(c.usage, u)
+		c.mu.Unlock()
+	}
+}
+
+func (c *usageCollector) reported() []Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Usage(nil), c.usage...)
+}
+
+// estimateTokens approximates a token count for providers that do not report one
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// modelPrices are USD per million prompt and completion tokens. Models are matched by the longest
+// prefix, so dated versions share the price of their family.
+var modelPrices = map[string][2]float64{
+	"gpt-4o-mini":            {0.15, 0.60},
+	"gpt-4o":                 {2.50, 10.00},
+	"gpt-4.1-mini":           {0.40, 1.60},
+	"gpt-4.1-nano":           {0.10, 0.40},
+	"gpt-4.1":                {2.00, 8.00},
+	"text-embedding-3-small": {0.02, 0},
+	"text-embedding-3-large": {0.13, 0},
+	"claude-3-5-haiku":       {0.80, 4.00},
+	"claude-haiku-4":         {1.00, 5.00},
+	"claude-sonnet-4":        {3.00, 15.00},
+	"claude-3-7-sonnet":      {3.00, 15.00},
+	"gemini-2.0-flash":       {0.10, 0.40},
+	"gemini-2.5-flash":       {0.30, 2.50},
+	"gemini-2.5-pro":         {1.25, 10.00},
+	"gemini-embedding-001":   {0.15, 0},
+}
+
+// Cost returns the price of a call in USD. Models without a known price, such as self-hosted
+// ones, cost nothing.
+func Cost(u Usage) float64 {
+	best := ""
+	for prefix := range modelPrices {
+		if strings.HasPrefix(u.Model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" || u.Provider == ProviderOpenAICompatible {
+		return 0
+	}
+	price := modelPrices[best]
+	return (float64(u.PromptTokens)*price[0] + float64(u.CompletionTokens)*price[1]) / 1e6
+}
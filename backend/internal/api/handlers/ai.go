@@ -1,84 +1,42 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
 )
 
 type AIHandler struct {
 	LLMClient ai.LLMClient
+	Meter     *ai.MeteredClient // budget status for the usage report; nil when usage is not metered
+	Store     store.Store
 }
 
 type GenerateWorkflowRequest struct {
 	Prompt string `json:"prompt" binding:"required"`
+	Save   bool   `json:"save"` // store the result as a draft workflow
+	Name   string `json:"name"` // name of the saved draft, defaults to the start of the prompt
 }
 
-// schema struct exactly matching OpenAI JSON Schema requirements
-var workflowSchema = map[string]interface{}{
-	"type": "object",
-	"properties": map[string]interface{}{
-		"nodes": map[string]interface{}{
-			"type": "array",
-			"items": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"id":   map[string]interface{}{"type": "string"},
-					"type": map[string]interface{}{
-						"type": "string",
-						"enum": []string{
-							"trigger_meta_dm", "trigger_keyword",
-							"action_send_message", "action_delay",
-							"action_ai_reply", "logic_ai_router",
-						},
-					},
-					"position": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"x": map[string]interface{}{"type": "number"},
-							"y": map[string]interface{}{"type": "number"},
-						},
-						"required": []string{"x", "y"},
-						"additionalProperties": false,
-					},
-					"data": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"label":       map[string]interface{}{"type": "string"},
-							"description": map[string]interface{}{"type": "string"},
-							"message":     map[string]interface{}{"type": "string"},
-							"prompt":      map[string]interface{}{"type": "string"},
-							"isRouter":    map[string]interface{}{"type": "boolean"},
-						},
-						"additionalProperties": false,
-					},
-				},
-				"required": []string{"id", "type", "position", "data"},
-				"additionalProperties": false,
-			},
-		},
-		"edges": map[string]interface{}{
-			"type": "array",
-			"items": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"id":           map[string]interface{}{"type": "string"},
-					"source":       map[string]interface{}{"type": "string"},
-					"target":       map[string]interface{}{"type": "string"},
-					"sourceHandle": map[string]interface{}{"type": "string"},
-				},
-				"required": []string{"id", "source", "target"},
-				"additionalProperties": false,
-			},
-		},
-	},
-	"required": []string{"nodes", "edges"},
-	"additionalProperties": false,
+// GenerateWorkflowResponse is the generated graph with its validation report, and the draft
+// it was saved as when requested
+type GenerateWorkflowResponse struct {
+	*engine.GenerationResult
+	Workflow *models.Workflow `json:"workflow,omitempty"`
 }
 
+// GenerateWorkflow turns a natural language description into a workflow graph. The graph is
+// validated and sent back to the model for repair while it has errors (see
+// engine.GenerateWorkflow); the final report is part of the response. An invalid graph is still
+// returned, and saved when asked, since it is only a draft the builder can fix.
 func (h *AIHandler) GenerateWorkflow(c *gin.Context) {
 	var req GenerateWorkflowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -86,38 +44,212 @@ func (h *AIHandler) GenerateWorkflow(c *gin.Context) {
 		return
 	}
 
-	systemPrompt := `You are an expert AI architect generating automation workflows for a Social Media Lead SaaS app.
-The user will provide a desired behavior (e.g., "reply to DM pricing inquiries, wait a day, follow up").
-Your goal is to output a strictly formatted Directed Acyclic Graph containing nodes and edges.
+	ctx := ai.WithAttribution(c.Request.Context(), ai.Attribution{UserID: c.GetInt64("user_id"), Feature: "generate_workflow"})
 
-Valid Node Types:
-- trigger_meta_dm: A new inbound Instagram/Messenger DM arrives.
-- trigger_keyword: Fires if the message contains specific words.
-- action_send_message: Sends a static text reply (put in data.message).
-- action_delay: Pauses the workflow.
-- action_ai_reply: Uses the Knowledge Base to answer a question (put instructions in data.prompt).
-- logic_ai_router: Branches based on intent (Outputs: sourceHandle="hot" or "cold").
+	result, err := engine.GenerateWorkflow(ctx, h.llm(c), req.Prompt)
+	if errors.Is(err, ai.ErrBudgetExceeded) {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate workflow via AI: " + err.Error()})
+		return
+	}
+	resp := GenerateWorkflowResponse{GenerationResult: result}
+
+	if req.Save {
+		w := &models.Workflow{
+			UserID:        c.GetInt64("user_id"),
+			Name:          draftName(req.Name, req.Prompt),
+			TriggerType:   engine.GraphTriggerType(result.Nodes, string(models.NodeTypeTriggerDM)),
+			Status:        "draft",
+			Prompt:        req.Prompt,
+			Nodes:         []byte(result.Nodes),
+			Edges:         []byte(result.Edges),
+			ReentryPolicy: engine.ReentrySkip,
+		}
+		if err := h.Store.CreateWorkflow(ctx, w); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save generated workflow"})
+			return
+		}
+		resp.Workflow = w
+	}
 
-Requirements:
-- Always start with exactly 1 trigger node (ID: "1", positioned at x: 250, y: 50).
-- Sequence all subsequent nodes cleanly, spacing them vertically (y + 150 each).
-- Ensure edges connect source node IDs to target node IDs sequentially.
-- Use visually descriptive text for data.label and data.description.
+	c.JSON(http.StatusOK, resp)
+}
+
+// draftName is the name for a generated draft: the given name, else the prompt cut to a title
+func draftName(name, prompt string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	name = strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(name); len(runes) > 60 {
+		name = strings.TrimSpace(string(runes[:60])) + "…"
+	}
+	return name
+}
 
-User Prompt: ` + req.Prompt
+// EditWorkflowRequest is the body of POST /workflows/:id/ai-edit. Without confirm the model is
+// asked for a patch and nothing is saved; with confirm the given patch (usually the previewed
+// one) is saved as a draft version.
+type EditWorkflowRequest struct {
+	Instruction string             `json:"instruction"`
+	Patch       *engine.GraphPatch `json:"patch"`
+	Confirm     bool               `json:"confirm"`
+}
 
-	rawJSON, err := h.LLMClient.GenerateStructuredJSON(context.Background(), systemPrompt, workflowSchema)
+// EditWorkflow changes an existing workflow from a natural language instruction in two steps:
+// a preview returns the model's patch, its validation report and a diff against the current
+// graph; a confirmation applies the patch to the published graph and stores the result as a
+// draft version. The workflow itself is left alone until the draft is published through
+// POST /workflows/:id/versions/:version/rollback.
+func (h *AIHandler) EditWorkflow(c *gin.Context) {
+	var req EditWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Confirm && req.Patch == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patch is required to confirm an edit"})
+		return
+	}
+	if !req.Confirm && strings.TrimSpace(req.Instruction) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instruction is required"})
+		return
+	}
+
+	workflowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate workflow via AI: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
 		return
 	}
 
-	// Just proxy the raw JSON string back to the client, it is guaranteed to match the layout schema
-	var finalResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(rawJSON), &finalResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse AI response"})
+	ctx := c.Request.Context()
+
+	w, err := h.Store.GetWorkflowByID(ctx, workflowID)
+	if err != nil || w.UserID != c.GetInt64("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
+	if _, err := models.ParseWorkflowGraph(w.Nodes, w.Edges); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse workflow graph"})
 		return
 	}
 
-	c.JSON(http.StatusOK, finalResponse)
+	if !req.Confirm {
+		aiCtx := ai.WithAttribution(ctx, ai.Attribution{UserID: w.UserID, WorkflowID: w.ID, Feature: "edit_workflow"})
+		edit, err := engine.EditWorkflow(aiCtx, h.llm(c), w.Nodes, w.Edges, req.Instruction)
+		if errors.Is(err, ai.ErrBudgetExceeded) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit workflow via AI: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, edit)
+		return
+	}
+
+	edit, err := engine.PreviewGraphPatch(w.Nodes, w.Edges, req.Patch)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Patch no longer applies to the workflow: " + err.Error()})
+		return
+	}
+	if edit.Diff.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Patch does not change the workflow"})
+		return
+	}
+	// Same rule as the preview: errors the workflow already had do not block the patch
+	if len(edit.NewErrors()) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Patched workflow is not valid", "validation": edit.Validation})
+		return
+	}
+
+	draft := &models.WorkflowVersion{WorkflowID: w.ID, Nodes: []byte(edit.Nodes), Edges: []byte(edit.Edges), Draft: true}
+	if err := h.Store.CreateWorkflowVersion(ctx, draft); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"draft":      draft,
+		"validation": edit.Validation,
+		"diff":       edit.Diff,
+	})
+}
+
+// llm returns the model client selected for the requesting tenant
+func (h *AIHandler) llm(c *gin.Context) ai.LLMClient {
+	return ai.Select(h.LLMClient, c.GetInt64("user_id"), ai.Selection{})
+}
+
+// GetUsage reports the tenant's LLM calls, tokens and cost between from and to (YYYY-MM-DD,
+// defaulting to the current month), grouped by group_by: workflow, node, model, feature or day.
+// The current month's spend against the plan's budget is included.
+func (h *AIHandler) GetUsage(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	groupBy := c.DefaultQuery("group_by", store.UsageByWorkflow)
+
+	switch groupBy {
+	case store.UsageByWorkflow, store.UsageByNode, store.UsageByModel, store.UsageByFeature, store.UsageByDay:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be workflow, node, model, feature or day"})
+		return
+	}
+
+	// Whole UTC days, to inclusive
+	from := ai.MonthStart(time.Now())
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	for param, day := range map[string]*time.Time{"from": &from, "to": &to} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse("2006-01-02", raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ": use YYYY-MM-DD"})
+				return
+			}
+			*day = t
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	usage, err := h.Store.GetLLMUsageReport(ctx, userID, from, to.AddDate(0, 0, 1), groupBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load AI usage"})
+		return
+	}
+	if usage == nil {
+		usage = []models.LLMUsageSummary{}
+	}
+	total := models.LLMUsageSummary{Key: "total"}
+	for _, u := range usage {
+		total.Calls += u.Calls
+		total.Failures += u.Failures
+		total.PromptTokens += u.PromptTokens
+		total.CompletionTokens += u.CompletionTokens
+		total.CostUSD += u.CostUSD
+	}
+
+	resp := gin.H{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"group_by": groupBy,
+		"usage":    usage,
+		"count":    len(usage),
+		"total":    total,
+	}
+	if h.Meter != nil {
+		budget, err := h.Meter.Status(ctx, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load AI budget"})
+			return
+		}
+		resp["budget"] = budget
+	}
+	c.JSON(http.StatusOK, resp)
 }
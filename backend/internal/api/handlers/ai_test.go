@@ -0,0 +1,220 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// graphLLM always answers with the same generated graph
+type graphLLM struct{ graph string }
+
+func (l graphLLM) GenerateText(ctx context.Context, prompt string) (string, error) { return "", nil }
+func (l graphLLM) Chat(ctx context.Context, req ai.ChatRequest) (string, error)    { return "", nil }
+func (l graphLLM) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, nil
+}
+func (l graphLLM) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return l.graph, nil
+}
+
+func TestGenerateWorkflowSavesDraft(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	handler := &handlers.AIHandler{Store: mockStore, LLMClient: graphLLM{graph: `{"nodes":[` +
+		`{"id":"1","type":"trigger_keyword","position":{"x":250,"y":50},"data":{"label":"Price","keywords":["price"],"tag":null}},` +
+		`{"id":"2","type":"action_add_tag","position":{"x":250,"y":200},"data":{"label":"Tag","keywords":null,"tag":"pricing"}}],` +
+		`"edges":[{"id":"e1","source":"1","target":"2","sourceHandle":null}]}`}}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.POST("/api/v1/workflows/generate", handler.GenerateWorkflow)
+
+	body, _ := json.Marshal(map[string]interface{}{"prompt": "Tag everyone who asks about the price", "save": true})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows/generate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Nodes      []map[string]interface{} `json:"nodes"`
+		Validation struct {
+			Errors []interface{} `json:"errors"`
+		} `json:"validation"`
+		Workflow *struct {
+			ID int64 `json:"id"`
+		} `json:"workflow"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Nodes) != 2 || len(resp.Validation.Errors) != 0 {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+	if resp.Workflow == nil {
+		t.Fatal("workflow was not saved")
+	}
+
+	saved := mockStore.Workflows[resp.Workflow.ID]
+	if saved.Status != "draft" || saved.Prompt != "Tag everyone who asks about the price" || saved.TriggerType != "trigger_keyword" {
+		t.Errorf("unexpected draft %+v", saved)
+	}
+}
+
+func TestEditWorkflowPreviewAndConfirm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	edges, _ := json.Marshal(minimalEdges)
+	mockStore.Workflows[1] = &models.Workflow{
+		ID: 1, UserID: 1, Name: "Brochure", TriggerType: "trigger_meta_dm", Status: "published",
+		Nodes: json.RawMessage(`[{"id":"1","type":"trigger_meta_dm","data":{}},{"id":"2","type":"action_send_message","data":{"message":"Here is the brochure"}}]`),
+		Edges: edges,
+	}
+	handler := &handlers.AIHandler{Store: mockStore, LLMClient: graphLLM{graph: `{"summary":"Tag brochure requests",` +
+		`"add_nodes":[{"id":"3","type":"action_add_tag","position":{"x":250,"y":350},"data":{"label":"Tag","tag":"brochure","message":null}}],` +
+		`"update_nodes":[],"remove_nodes":[],"add_edges":[{"id":"e3","source":"2","target":"3","sourceHandle":null}],"remove_edges":[]}`}}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.POST("/api/v1/workflows/:id/ai-edit", handler.EditWorkflow)
+
+	send := func(payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows/1/ai-edit", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(map[string]interface{}{"instruction": "tag everyone who gets the brochure"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("preview: %d %s", w.Code, w.Body.String())
+	}
+	var preview struct {
+		Patch json.RawMessage `json:"patch"`
+		Diff  struct {
+			AddedNodes []string `json:"added_nodes"`
+		} `json:"diff"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &preview)
+	if len(preview.Diff.AddedNodes) != 1 {
+		t.Errorf("unexpected preview %s", w.Body.String())
+	}
+	if strings.Contains(string(mockStore.Workflows[1].Nodes), "action_add_tag") {
+		t.Fatal("preview must not change the workflow")
+	}
+
+	published := string(mockStore.Workflows[1].Nodes)
+	w = send(map[string]interface{}{"confirm": true, "patch": preview.Patch})
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", w.Code, w.Body.String())
+	}
+	if string(mockStore.Workflows[1].Nodes) != published || mockStore.Workflows[1].Status != "published" {
+		t.Error("confirming an AI edit must leave the published workflow alone")
+	}
+	if len(mockStore.Versions) != 1 || !mockStore.Versions[0].Draft || !strings.Contains(string(mockStore.Versions[0].Nodes), "action_add_tag") {
+		t.Fatalf("expected the patched graph as a draft version, got %+v", mockStore.Versions)
+	}
+	if _, err := mockStore.GetLatestWorkflowVersion(context.Background(), 1); err == nil {
+		t.Error("a draft must not count as the published version")
+	}
+
+	// A patch that breaks the workflow is refused
+	broken := map[string]interface{}{"update_nodes": []interface{}{map[string]interface{}{"id": "2", "data": map[string]interface{}{"message": "Hi {{nope.name}}"}}}}
+	if w := send(map[string]interface{}{"confirm": true, "patch": broken}); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an invalid patch, got %d %s", w.Code, w.Body.String())
+	}
+	if len(mockStore.Versions) != 1 {
+		t.Error("an invalid patch must not be saved")
+	}
+
+	// Errors the workflow already had do not block a patch, as in the preview
+	mockStore.Workflows[1].Nodes = json.RawMessage(`[{"id":"1","type":"trigger_meta_dm","data":{}},{"id":"2","type":"action_send_message","data":{"message":"Here is the brochure"}},{"id":"9","type":"action_send_message","data":{}}]`)
+	if w := send(map[string]interface{}{"confirm": true, "patch": preview.Patch}); w.Code != http.StatusOK || len(mockStore.Versions) != 2 {
+		t.Errorf("expected a draft despite the existing error, got %d %s", w.Code, w.Body.String())
+	}
+
+	// The patch no longer fits once node 3 exists in the workflow
+	mockStore.Workflows[1].Nodes = json.RawMessage(`[{"id":"1","type":"trigger_meta_dm","data":{}},{"id":"2","type":"action_send_message","data":{"message":"Here is the brochure"}},{"id":"3","type":"action_delay","data":{}}]`)
+	if w := send(map[string]interface{}{"confirm": true, "patch": preview.Patch}); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale patch, got %d", w.Code)
+	}
+}
+
+func TestAIUsageAndBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	mockStore.Users[1] = &models.User{ID: 1, Plan: "starter"}
+	mockStore.Usage = []models.LLMUsage{
+		{UserID: 1, Feature: "action_ai_reply", PromptTokens: 900, CompletionTokens: 100, CostUSD: 3},
+		{UserID: 1, Feature: "generate_workflow", PromptTokens: 4000, CompletionTokens: 1000, CostUSD: 2},
+		{UserID: 2, Feature: "action_ai_reply", CostUSD: 50},
+	}
+	meter := ai.NewMeteredClient(graphLLM{graph: `{"nodes":[],"edges":[]}`}, mockStore, ai.Budgets{PerPlan: map[string]float64{"starter": 5}})
+	handler := &handlers.AIHandler{Store: mockStore, LLMClient: meter, Meter: meter}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.GET("/api/v1/ai/usage", handler.GetUsage)
+	r.POST("/api/v1/workflows/generate", handler.GenerateWorkflow)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ai/usage?group_by=feature", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Count int `json:"count"`
+		Total struct {
+			Calls   int     `json:"calls"`
+			CostUSD float64 `json:"cost_usd"`
+		} `json:"total"`
+		Budget struct {
+			State    string  `json:"state"`
+			SpentUSD float64 `json:"spent_usd"`
+		} `json:"budget"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Count != 2 || resp.Total.Calls != 2 || resp.Total.CostUSD != 5 {
+		t.Errorf("unexpected report %s", w.Body.String())
+	}
+	if resp.Budget.State != ai.BudgetExceeded || resp.Budget.SpentUSD != 5 {
+		t.Errorf("unexpected budget %+v", resp.Budget)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"prompt": "Tag everyone who asks about the price"})
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/workflows/generate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPaymentRequired {
+		t.Errorf("expected 402 over budget, got %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/ai/usage?group_by=tenant", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown grouping, got %d", w.Code)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
 	"github.com/social-media-lead/backend/internal/models"
 	"github.com/social-media-lead/backend/internal/store"
 )
@@ -22,6 +23,7 @@ type CreateAutomationRequest struct {
 	ReplyText   string   `json:"reply_text" binding:"required"`
 	ReplyMedia  string   `json:"reply_media"`
 	DelayMs     int      `json:"delay_ms"`
+	ActiveHours string   `json:"active_hours"` // "always" (default), "open" or "closed"
 }
 
 // ListAutomations returns all active automations for the current user.
@@ -50,6 +52,19 @@ func (h *AutomationHandler) CreateAutomation(c *gin.Context) {
 		return
 	}
 
+	if err := engine.ValidateTemplate(req.ReplyText); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reply template: " + err.Error()})
+		return
+	}
+	switch req.ActiveHours {
+	case "":
+		req.ActiveHours = "always"
+	case "always", "open", "closed":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "active_hours must be always, open or closed"})
+		return
+	}
+
 	automation := &models.Automation{
 		UserID:      userID.(int64),
 		Name:        req.Name,
@@ -58,6 +73,7 @@ func (h *AutomationHandler) CreateAutomation(c *gin.Context) {
 		ReplyText:   req.ReplyText,
 		ReplyMedia:  req.ReplyMedia,
 		DelayMs:     req.DelayMs,
+		ActiveHours: req.ActiveHours,
 		IsActive:    true,
 	}
 
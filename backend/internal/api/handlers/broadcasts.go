@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/social-media-lead/backend/internal/cache"
+	"github.com/social-media-lead/backend/internal/engine"
 	"github.com/social-media-lead/backend/internal/meta"
 	"github.com/social-media-lead/backend/internal/models"
 	"github.com/social-media-lead/backend/internal/store"
@@ -39,6 +40,11 @@ func (h *BroadcastHandler) CreateBroadcast(c *gin.Context) {
 		return
 	}
 
+	if err := engine.ValidateTemplate(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid content template: " + err.Error()})
+		return
+	}
+
 	broadcast := &models.Broadcast{
 		UserID:      userID.(int64),
 		Name:        req.Name,
@@ -136,6 +142,13 @@ func (h *BroadcastHandler) executeBroadcast(broadcast *models.Broadcast) {
 		channelMap[channels[i].ID] = &channels[i]
 	}
 
+	// Content may contain {{contact.*}} / {{project.*}} placeholders rendered per recipient
+	tmpl, err := engine.ParseTemplate(broadcast.Content)
+	if err != nil {
+		log.Printf("[Broadcast] Invalid content template, sending raw text: %v", err)
+	}
+	project, _ := h.Store.GetPropertyVisitConfig(ctx, broadcast.UserID)
+
 	// Set broadcast dedup expiry (24 hours)
 	if h.Redis != nil {
 		_ = h.Redis.ExpireBroadcastSet(ctx, broadcast.ID, 24*time.Hour)
@@ -169,11 +182,16 @@ func (h *BroadcastHandler) executeBroadcast(broadcast *models.Broadcast) {
 			continue
 		}
 
+		content := broadcast.Content
+		if tmpl != nil {
+			content = tmpl.Render(&engine.Variables{Contact: &contact, Project: project})
+		}
+
 		result, err := h.MetaClient.SendMessage(
 			contact.Platform,
 			channel.AccountID,
 			contact.PlatformUserID,
-			broadcast.Content,
+			content,
 			channel.AccessToken,
 		)
 
@@ -196,7 +214,7 @@ func (h *BroadcastHandler) executeBroadcast(broadcast *models.Broadcast) {
 			ContactID:     contact.ID,
 			Platform:      contact.Platform,
 			Direction:     "outbound",
-			Content:       broadcast.Content,
+			Content:       content,
 			MessageType:   "text",
 			PlatformMsgID: result.MessageID,
 			Status:        "sent",
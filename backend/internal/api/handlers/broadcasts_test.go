@@ -55,6 +55,21 @@ func TestBroadcastHandlers(t *testing.T) {
 		}
 	})
 
+	t.Run("Create Broadcast Unknown Variable", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"name":    "Promo",
+			"content": "Hi {{contact.nickname}}, 50% off!",
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/broadcasts", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %v", w.Code)
+		}
+	})
+
 	t.Run("List Broadcasts", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/broadcasts", nil)
 		w := httptest.NewRecorder()
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/calendar"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// BusinessHoursHandler manages the tenant's timezone, weekly hours and holidays used by time
+// window nodes, automations and visit slot offering.
+type BusinessHoursHandler struct {
+	Store store.Store
+}
+
+// PutBusinessHoursRequest is the expected body for saving business hours.
+type PutBusinessHoursRequest struct {
+	Timezone    string                            `json:"timezone" binding:"required"`
+	WeeklyHours map[string][]models.BusinessHours `json:"weekly_hours" binding:"required"`
+	Holidays    []string                          `json:"holidays"`
+}
+
+// GetBusinessHours returns the current user's calendar and whether they are open right now.
+// Tenants that have not set one up get an always-open UTC calendar.
+func (h *BusinessHoursHandler) GetBusinessHours(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	cfg, err := h.Store.GetBusinessCalendar(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch business hours"})
+		return
+	}
+	if cfg == nil {
+		cfg = &models.BusinessCalendar{UserID: userID.(int64), Timezone: "UTC", WeeklyHours: map[string][]models.BusinessHours{}, Holidays: []string{}}
+	}
+
+	c.JSON(http.StatusOK, businessHoursResponse(cfg, engine.TenantCalendar(c.Request.Context(), h.Store, userID.(int64))))
+}
+
+// PutBusinessHours creates or replaces the current user's calendar.
+func (h *BusinessHoursHandler) PutBusinessHours(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req PutBusinessHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg := &models.BusinessCalendar{
+		UserID:      userID.(int64),
+		Timezone:    req.Timezone,
+		WeeklyHours: req.WeeklyHours,
+		Holidays:    req.Holidays,
+	}
+	cal, err := calendar.New(cfg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid business hours: " + err.Error()})
+		return
+	}
+
+	if err := h.Store.UpsertBusinessCalendar(c.Request.Context(), cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save business hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, businessHoursResponse(cfg, cal))
+}
+
+func businessHoursResponse(cfg *models.BusinessCalendar, cal *calendar.Calendar) gin.H {
+	now := time.Now()
+	resp := gin.H{
+		"calendar": cfg,
+		"open_now": cal.IsOpen(now),
+	}
+	if next, ok := cal.NextOpen(now); ok {
+		resp["next_open"] = next.In(cal.Location())
+	}
+	return resp
+}
@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+)
+
+func TestBusinessHoursHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	handler := &handlers.BusinessHoursHandler{Store: mockStore}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.GET("/business-hours", handler.GetBusinessHours)
+	r.PUT("/business-hours", handler.PutBusinessHours)
+
+	t.Run("Unconfigured Tenant Is Always Open", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/business-hours", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			OpenNow bool `json:"open_now"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || !resp.OpenNow {
+			t.Errorf("expected 200 and open, got %v %s", w.Code, w.Body.String())
+		}
+	})
+
+	tests := []struct {
+		name           string
+		body           map[string]interface{}
+		expectedStatus int
+	}{
+		{"Valid Calendar", map[string]interface{}{
+			"timezone":     "Asia/Kolkata",
+			"weekly_hours": map[string]interface{}{"mon": []interface{}{map[string]interface{}{"open": "09:00", "close": "18:00"}}},
+			"holidays":     []string{"2026-12-25"},
+		}, http.StatusOK},
+		{"Unknown Timezone", map[string]interface{}{
+			"timezone":     "Nowhere/Special",
+			"weekly_hours": map[string]interface{}{},
+		}, http.StatusBadRequest},
+		{"Closes Before Opening", map[string]interface{}{
+			"timezone":     "UTC",
+			"weekly_hours": map[string]interface{}{"mon": []interface{}{map[string]interface{}{"open": "18:00", "close": "09:00"}}},
+		}, http.StatusBadRequest},
+		{"Missing Timezone", map[string]interface{}{
+			"weekly_hours": map[string]interface{}{},
+		}, http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			req := httptest.NewRequest(http.MethodPut, "/business-hours", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.expectedStatus {
+				t.Errorf("expected status %v, got %v: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if cal := mockStore.Calendars[1]; cal == nil || cal.Timezone != "Asia/Kolkata" {
+		t.Errorf("expected the valid calendar to be saved, got %+v", cal)
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// ExecutionHandler exposes workflow executions and their step timelines for debugging, and lets
+// tenants cancel, pause and resume them.
+type ExecutionHandler struct {
+	Store       store.Store
+	GraphWalker *engine.GraphWalker
+}
+
+// BulkExecutionRequest is the body of POST /executions/bulk. At least one of WorkflowID and
+// ContactID is required so a typo can never stop every lead of the tenant.
+type BulkExecutionRequest struct {
+	Action     string `json:"action" binding:"required"` // cancel, pause or resume
+	WorkflowID int64  `json:"workflow_id"`
+	ContactID  int64  `json:"contact_id"`
+}
+
+// parseExecutionFilter reads ?workflow_id, contact_id, parent_execution_id, status, since, until
+// (RFC 3339), limit and offset.
+func parseExecutionFilter(c *gin.Context) (store.ExecutionFilter, error) {
+	var f store.ExecutionFilter
+	var err error
+
+	if raw := c.Query("workflow_id"); raw != "" {
+		if f.WorkflowID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return f, fmt.Errorf("invalid workflow_id")
+		}
+	}
+	if raw := c.Query("contact_id"); raw != "" {
+		if f.ContactID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return f, fmt.Errorf("invalid contact_id")
+		}
+	}
+	if raw := c.Query("parent_execution_id"); raw != "" {
+		if f.ParentExecutionID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return f, fmt.Errorf("invalid parent_execution_id")
+		}
+	}
+	f.Status = c.Query("status")
+	if raw := c.Query("since"); raw != "" {
+		if f.Since, err = time.Parse(time.RFC3339, raw); err != nil {
+			return f, fmt.Errorf("since must be an RFC 3339 timestamp")
+		}
+	}
+	if raw := c.Query("until"); raw != "" {
+		if f.Until, err = time.Parse(time.RFC3339, raw); err != nil {
+			return f, fmt.Errorf("until must be an RFC 3339 timestamp")
+		}
+	}
+	f.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	f.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	return f, nil
+}
+
+// ListExecutions returns the current user's executions, optionally filtered by workflow,
+// contact, status and creation time.
+func (h *ExecutionHandler) ListExecutions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	filter, err := parseExecutionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Nested route: GET /workflows/:id/executions
+	if raw := c.Param("id"); raw != "" {
+		if filter.WorkflowID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+			return
+		}
+	}
+
+	executions, err := h.Store.ListWorkflowExecutions(c.Request.Context(), userID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch executions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": executions,
+		"count":      len(executions),
+	})
+}
+
+// ownedExecution loads an execution of the current user, writing a 404 otherwise
+func (h *ExecutionHandler) ownedExecution(c *gin.Context, userID, executionID int64) (*models.WorkflowExecution, *models.Workflow, bool) {
+	exec, err := h.Store.GetWorkflowExecutionByID(c.Request.Context(), executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+		return nil, nil, false
+	}
+	workflow, err := h.Store.GetWorkflowByID(c.Request.Context(), exec.WorkflowID)
+	if err != nil || workflow.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+		return nil, nil, false
+	}
+	return exec, workflow, true
+}
+
+// GetExecution returns one execution with its full step timeline.
+func (h *ExecutionHandler) GetExecution(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	executionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid execution ID"})
+		return
+	}
+
+	exec, workflow, ok := h.ownedExecution(c, userID, executionID)
+	if !ok {
+		return
+	}
+
+	steps, err := h.Store.GetWorkflowExecutionSteps(c.Request.Context(), exec.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch execution steps"})
+		return
+	}
+
+	// Executions started by this one's action_call_workflow nodes; the parent is on exec itself
+	children, err := h.Store.ListWorkflowExecutions(c.Request.Context(), userID, store.ExecutionFilter{ParentExecutionID: exec.ID, Limit: 200})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch called executions"})
+		return
+	}
+	if children == nil {
+		children = []models.WorkflowExecution{}
+	}
+
+	state := json.RawMessage(exec.StateData)
+	if len(state) == 0 {
+		state = json.RawMessage("{}")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"execution":     exec,
+		"workflow_name": workflow.Name,
+		"state":         state,
+		"steps":         steps,
+		"children":      children,
+	})
+}
+
+// CancelExecution stops an execution for good
+func (h *ExecutionHandler) CancelExecution(c *gin.Context) {
+	h.controlExecution(c, engine.ActionCancel)
+}
+
+// PauseExecution holds an execution where it is until it is resumed
+func (h *ExecutionHandler) PauseExecution(c *gin.Context) {
+	h.controlExecution(c, engine.ActionPause)
+}
+
+// ResumeExecution continues a paused execution
+func (h *ExecutionHandler) ResumeExecution(c *gin.Context) {
+	h.controlExecution(c, engine.ActionResume)
+}
+
+func (h *ExecutionHandler) controlExecution(c *gin.Context, action string) {
+	userID := c.GetInt64("user_id")
+	executionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid execution ID"})
+		return
+	}
+
+	exec, _, ok := h.ownedExecution(c, userID, executionID)
+	if !ok {
+		return
+	}
+
+	if err := h.GraphWalker.ControlExecution(c.Request.Context(), exec, action); err != nil {
+		if errors.Is(err, engine.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Execution is %s and cannot be %s", exec.Status, pastTense(action))})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " execution"})
+		return
+	}
+
+	updated, err := h.Store.GetWorkflowExecutionByID(c.Request.Context(), executionID)
+	if err != nil {
+		updated = exec
+	}
+	c.JSON(http.StatusOK, gin.H{"execution": updated})
+}
+
+// BulkControlExecutions cancels, pauses or resumes every matching execution of a workflow
+// and/or contact.
+func (h *ExecutionHandler) BulkControlExecutions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	var req BulkExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.Action {
+	case engine.ActionCancel, engine.ActionPause, engine.ActionResume:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be cancel, pause or resume"})
+		return
+	}
+	if req.WorkflowID == 0 && req.ContactID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workflow_id or contact_id is required"})
+		return
+	}
+
+	filter := store.ExecutionFilter{WorkflowID: req.WorkflowID, ContactID: req.ContactID}
+	affected, err := h.GraphWalker.ControlExecutions(c.Request.Context(), userID, filter, req.Action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + req.Action + " executions", "affected": affected})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "affected": affected})
+}
+
+func pastTense(action string) string {
+	switch action {
+	case engine.ActionCancel:
+		return "cancelled"
+	case engine.ActionPause:
+		return "paused"
+	}
+	return "resumed"
+}
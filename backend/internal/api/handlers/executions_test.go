@@ -0,0 +1,164 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestExecutionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	mockStore.Workflows[1] = &models.Workflow{ID: 1, UserID: 1, Name: "Mine"}
+	mockStore.Workflows[2] = &models.Workflow{ID: 2, UserID: 2, Name: "Someone else's"}
+	mockStore.Executions[10] = &models.WorkflowExecution{ID: 10, WorkflowID: 1, ContactID: 5, Status: "completed", StateData: []byte(`{"answer":"yes"}`)}
+	mockStore.Executions[11] = &models.WorkflowExecution{ID: 11, WorkflowID: 1, ContactID: 6, Status: "failed"}
+	mockStore.Executions[12] = &models.WorkflowExecution{ID: 12, WorkflowID: 2, ContactID: 5, Status: "completed"}
+	mockStore.Steps[10] = []models.WorkflowExecutionStep{
+		{ID: 1, ExecutionID: 10, NodeID: "1", NodeType: "trigger_meta_dm", Input: json.RawMessage(`{}`), Output: json.RawMessage(`{}`)},
+		{ID: 2, ExecutionID: 10, NodeID: "2", NodeType: "logic_condition", Branch: "true", Input: json.RawMessage(`{}`), Output: json.RawMessage(`{}`)},
+	}
+
+	handler := &handlers.ExecutionHandler{Store: mockStore}
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.GET("/api/v1/executions", handler.ListExecutions)
+	r.GET("/api/v1/executions/:id", handler.GetExecution)
+	r.GET("/api/v1/workflows/:id/executions", handler.ListExecutions)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List By Contact", func(t *testing.T) {
+		w := get("/api/v1/executions?contact_id=5")
+		var resp struct {
+			Count int `json:"count"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Count != 1 {
+			t.Errorf("expected only the user's execution for contact 5, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("List By Workflow And Status", func(t *testing.T) {
+		w := get("/api/v1/workflows/1/executions?status=failed")
+		var resp struct {
+			Executions []models.WorkflowExecution `json:"executions"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if len(resp.Executions) != 1 || resp.Executions[0].ID != 11 {
+			t.Errorf("unexpected executions %s", w.Body.String())
+		}
+	})
+
+	t.Run("Invalid Since", func(t *testing.T) {
+		if w := get("/api/v1/executions?since=yesterday"); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Timeline", func(t *testing.T) {
+		w := get("/api/v1/executions/10")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var resp struct {
+			State map[string]interface{}         `json:"state"`
+			Steps []models.WorkflowExecutionStep `json:"steps"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if len(resp.Steps) != 2 || resp.Steps[1].Branch != "true" || resp.State["answer"] != "yes" {
+			t.Errorf("unexpected timeline %s", w.Body.String())
+		}
+	})
+
+	t.Run("Other Tenant's Execution", func(t *testing.T) {
+		if w := get("/api/v1/executions/12"); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestExecutionControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	mockStore.Workflows[1] = &models.Workflow{ID: 1, UserID: 1, Name: "Mine"}
+	mockStore.Workflows[2] = &models.Workflow{ID: 2, UserID: 2, Name: "Someone else's"}
+	mockStore.Executions[10] = &models.WorkflowExecution{ID: 10, WorkflowID: 1, ContactID: 5, Status: "waiting", WaitingFor: "reply"}
+	mockStore.Executions[11] = &models.WorkflowExecution{ID: 11, WorkflowID: 1, ContactID: 6, Status: "running"}
+	mockStore.Executions[12] = &models.WorkflowExecution{ID: 12, WorkflowID: 1, ContactID: 6, Status: "completed"}
+	mockStore.Executions[13] = &models.WorkflowExecution{ID: 13, WorkflowID: 2, ContactID: 7, Status: "running"}
+
+	handler := &handlers.ExecutionHandler{Store: mockStore, GraphWalker: engine.NewGraphWalker(mockStore, nil, nil, nil)}
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.POST("/api/v1/executions/:id/cancel", handler.CancelExecution)
+	r.POST("/api/v1/executions/:id/pause", handler.PauseExecution)
+	r.POST("/api/v1/executions/bulk", handler.BulkControlExecutions)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Pause", func(t *testing.T) {
+		w := post("/api/v1/executions/10/pause", nil)
+		if w.Code != http.StatusOK || mockStore.Executions[10].Status != engine.StatusPaused {
+			t.Fatalf("expected execution 10 paused, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Cancel Finished Execution", func(t *testing.T) {
+		if w := post("/api/v1/executions/12/cancel", nil); w.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d", w.Code)
+		}
+	})
+
+	t.Run("Other Tenant's Execution", func(t *testing.T) {
+		if w := post("/api/v1/executions/13/cancel", nil); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+		if mockStore.Executions[13].Status != "running" {
+			t.Error("another tenant's execution was cancelled")
+		}
+	})
+
+	t.Run("Bulk Needs A Scope", func(t *testing.T) {
+		if w := post("/api/v1/executions/bulk", map[string]interface{}{"action": "cancel"}); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Bulk Cancel Workflow", func(t *testing.T) {
+		w := post("/api/v1/executions/bulk", map[string]interface{}{"action": "cancel", "workflow_id": 1})
+		var resp struct {
+			Affected int `json:"affected"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Affected != 2 {
+			t.Fatalf("expected the paused and running executions cancelled, got %d %s", w.Code, w.Body.String())
+		}
+		if mockStore.Executions[12].Status != "completed" || mockStore.Executions[13].Status != "running" {
+			t.Error("bulk cancel touched executions outside its scope")
+		}
+	})
+}
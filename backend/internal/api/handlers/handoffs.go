@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// AgentHandler manages the human agents workflows hand conversations off to.
+type AgentHandler struct {
+	Store store.Store
+}
+
+// AgentRequest is the body for creating or updating an agent.
+type AgentRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email"`
+	Team     string `json:"team"`
+	IsActive *bool  `json:"is_active"` // defaults to true
+}
+
+func (r *AgentRequest) apply(a *models.Agent) {
+	a.Name = strings.TrimSpace(r.Name)
+	a.Email = strings.TrimSpace(r.Email)
+	a.Team = strings.TrimSpace(r.Team)
+	a.IsActive = r.IsActive == nil || *r.IsActive
+}
+
+// ListAgents returns the current user's agents.
+func (h *AgentHandler) ListAgents(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	agents, err := h.Store.ListAgents(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch agents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agents": agents,
+		"count":  len(agents),
+	})
+}
+
+// CreateAgent adds an agent.
+func (h *AgentHandler) CreateAgent(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req AgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agent := &models.Agent{UserID: userID.(int64)}
+	req.apply(agent)
+	if err := h.Store.CreateAgent(c.Request.Context(), agent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agent"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, agent)
+}
+
+// UpdateAgent changes an agent's details, team or availability.
+func (h *AgentHandler) UpdateAgent(c *gin.Context) {
+	agent, ok := h.ownedAgent(c)
+	if !ok {
+		return
+	}
+
+	var req AgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.apply(agent)
+	if err := h.Store.UpdateAgent(c.Request.Context(), agent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, agent)
+}
+
+// DeleteAgent removes an agent. Their open handoffs stay queued for the team.
+func (h *AgentHandler) DeleteAgent(c *gin.Context) {
+	agent, ok := h.ownedAgent(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Store.DeleteAgent(c.Request.Context(), agent.ID, agent.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete agent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Agent deleted"})
+}
+
+// ownedAgent loads the agent in the URL and checks it belongs to the current user
+func (h *AgentHandler) ownedAgent(c *gin.Context) (*models.Agent, bool) {
+	userID, _ := c.Get("user_id")
+
+	agentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return nil, false
+	}
+	agent, err := h.Store.GetAgentByID(c.Request.Context(), agentID)
+	if err != nil || agent.UserID != userID.(int64) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return nil, false
+	}
+	return agent, true
+}
+
+// HandoffHandler lists the conversations workflows handed to agents.
+type HandoffHandler struct {
+	Store store.Store
+}
+
+// ListHandoffs returns the current user's handoffs, newest first. ?status=open|resolved filters.
+func (h *HandoffHandler) ListHandoffs(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	status := c.Query("status")
+	if status != "" && status != engine.HandoffOpen && status != engine.HandoffResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open or resolved"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	handoffs, err := h.Store.ListHandoffs(c.Request.Context(), userID.(int64), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch handoffs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"handoffs": handoffs,
+		"count":    len(handoffs),
+	})
+}
+
+// ResolveHandoff closes a handoff and stops its SLA timer. The bot stays paused; hand the
+// conversation back with PUT /inbox/contacts/:contact_id/bot.
+func (h *HandoffHandler) ResolveHandoff(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	handoffID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid handoff ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	handoff, err := h.Store.GetHandoffByID(ctx, handoffID)
+	if err != nil || handoff.UserID != userID.(int64) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Handoff not found"})
+		return
+	}
+	if handoff.Status != engine.HandoffOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Handoff is already resolved"})
+		return
+	}
+
+	if err := h.Store.ResolveHandoffs(ctx, handoff.ContactID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve handoff"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": handoff.ID, "status": engine.HandoffResolved})
+}
@@ -4,8 +4,10 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
 	"github.com/social-media-lead/backend/internal/meta"
 	"github.com/social-media-lead/backend/internal/models"
 	"github.com/social-media-lead/backend/internal/store"
@@ -13,8 +15,9 @@ import (
 
 // InboxHandler handles unified inbox endpoints.
 type InboxHandler struct {
-	Store      store.Store
-	MetaClient *meta.Client
+	Store       store.Store
+	MetaClient  *meta.Client
+	GraphWalker *engine.GraphWalker
 }
 
 // GetConversations returns the last message per contact for the current user (inbox list).
@@ -163,6 +166,11 @@ func (h *InboxHandler) SendMessage(c *gin.Context) {
 		log.Printf("[Inbox] Message sent but failed to save in DB: %v", err)
 	}
 
+	// A human answered: the SLA timer of an open handoff stops here
+	if err := h.Store.MarkHandoffResponded(ctx, contact.ID); err != nil {
+		log.Printf("[Inbox] Failed to record handoff response for contact #%d: %v", contact.ID, err)
+	}
+
 	// Agent Escape Hatch: Pause automation since the agent replied manually
 	if !contact.BotPaused {
 		if err := h.Store.UpdateContactState(ctx, contact.ID, contact.BookingState, true); err != nil {
@@ -183,3 +191,245 @@ func (h *InboxHandler) SendMessage(c *gin.Context) {
 		},
 	})
 }
+
+// SetBotPausedRequest is the body of PUT /inbox/contacts/:contact_id/bot
+type SetBotPausedRequest struct {
+	Paused *bool `json:"paused" binding:"required"`
+}
+
+// SetBotPaused lets an agent take over a conversation or hand it back to the bot. Handing it
+// back resumes the workflow executions that were holding messages for the contact.
+func (h *InboxHandler) SetBotPaused(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	contactID, err := strconv.ParseInt(c.Param("contact_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	var req SetBotPausedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	contact, err := h.Store.GetContactByID(ctx, contactID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+	if contact.UserID != userID.(int64) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if err := h.Store.UpdateContactState(ctx, contact.ID, contact.BookingState, *req.Paused); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+
+	if !*req.Paused {
+		// Handing the conversation back to the bot closes the handoff
+		if err := h.Store.ResolveHandoffs(ctx, contact.ID); err != nil {
+			log.Printf("[Inbox] Failed to resolve handoff for contact #%d: %v", contact.ID, err)
+		}
+	}
+
+	if !*req.Paused && contact.BotPaused && h.GraphWalker != nil {
+		h.GraphWalker.ResumeAgentWaits(ctx, contact.ID)
+		h.GraphWalker.EmitContactEvent(ctx, engine.ContactEvent{Type: engine.ContactEventBotResumed, UserID: contact.UserID, ContactID: contact.ID})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contact_id": contact.ID, "bot_paused": *req.Paused})
+}
+
+// UpdateContactRequest is the body of PATCH /inbox/contacts/:contact_id. Omitted fields are left
+// as they are.
+type UpdateContactRequest struct {
+	Phone             *string `json:"phone"`
+	Budget            *string `json:"budget"`
+	PreferredLocation *string `json:"preferred_location"`
+	PurchaseTimeline  *string `json:"purchase_timeline"`
+	IsHotLead         *bool   `json:"is_hot_lead"`
+}
+
+// UpdateContact lets an agent edit a lead's qualification fields. Every changed field fires the
+// field_changed contact event, and every field sent is marked as the agent's.
+func (h *InboxHandler) UpdateContact(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	contactID, err := strconv.ParseInt(c.Param("contact_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	var req UpdateContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	contact, err := h.Store.GetContactByID(ctx, contactID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+	if contact.UserID != userID.(int64) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	before := *contact
+	var agentFields []string
+	if req.Phone != nil {
+		contact.Phone = strings.TrimSpace(*req.Phone)
+		agentFields = append(agentFields, "phone")
+	}
+	if req.Budget != nil {
+		contact.Budget = strings.TrimSpace(*req.Budget)
+		agentFields = append(agentFields, "budget")
+	}
+	if req.PreferredLocation != nil {
+		contact.PreferredLocation = strings.TrimSpace(*req.PreferredLocation)
+		agentFields = append(agentFields, "preferred_location")
+	}
+	if req.PurchaseTimeline != nil {
+		contact.PurchaseTimeline = strings.TrimSpace(*req.PurchaseTimeline)
+		agentFields = append(agentFields, "purchase_timeline")
+	}
+	if req.IsHotLead != nil {
+		contact.IsHotLead = *req.IsHotLead
+		agentFields = append(agentFields, "is_hot_lead")
+	}
+
+	// Only the fields sent are written, each marked as the agent's so lead extraction will not
+	// overwrite it; both happen together so an extraction running meanwhile cannot slip in
+	values := map[string]interface{}{
+		"phone":              contact.Phone,
+		"budget":             contact.Budget,
+		"preferred_location": contact.PreferredLocation,
+		"purchase_timeline":  contact.PurchaseTimeline,
+		"is_hot_lead":        contact.IsHotLead,
+	}
+	sources := make([]models.ContactFieldSource, len(agentFields))
+	for i, field := range agentFields {
+		sources[i] = models.ContactFieldSource{ContactID: contact.ID, Field: field, Source: models.FieldSourceAgent, Confidence: 1}
+	}
+	if _, err := h.Store.SaveContactLeadFields(ctx, contact.ID, values, sources); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
+		return
+	}
+
+	if h.GraphWalker != nil {
+		// Field goals may be met now, and field_changed workflows may start
+		h.GraphWalker.CheckGoals(ctx, contact.ID)
+		h.GraphWalker.EmitFieldChanges(ctx, &before, contact)
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+// GetFieldSources returns who set each of a contact's lead fields: an agent, or the AI with its
+// confidence and the lead's words.
+func (h *InboxHandler) GetFieldSources(c *gin.Context) {
+	contact, ok := h.ownedContact(c)
+	if !ok {
+		return
+	}
+
+	sources, err := h.Store.GetContactFieldSources(c.Request.Context(), contact.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch field sources"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sources": sources,
+		"count":   len(sources),
+	})
+}
+
+// AddNoteRequest is the body of POST /inbox/contacts/:contact_id/notes
+type AddNoteRequest struct {
+	Body   string `json:"body" binding:"required"`
+	Author string `json:"author"` // defaults to the current user's name
+}
+
+// GetNotes returns the internal notes on a contact, newest first.
+func (h *InboxHandler) GetNotes(c *gin.Context) {
+	contact, ok := h.ownedContact(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	notes, err := h.Store.GetContactNotes(c.Request.Context(), contact.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes": notes,
+		"count": len(notes),
+	})
+}
+
+// AddNote posts an internal note on a contact. Notes are never sent to the lead.
+func (h *InboxHandler) AddNote(c *gin.Context) {
+	contact, ok := h.ownedContact(c)
+	if !ok {
+		return
+	}
+
+	var req AddNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	author := strings.TrimSpace(req.Author)
+	if author == "" {
+		if user, err := h.Store.GetUserByID(ctx, contact.UserID); err == nil {
+			author = user.FullName
+		}
+	}
+
+	note := &models.ContactNote{UserID: contact.UserID, ContactID: contact.ID, Author: author, Body: strings.TrimSpace(req.Body)}
+	if err := h.Store.CreateContactNote(ctx, note); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// ownedContact loads the contact in the URL and checks it belongs to the current user
+func (h *InboxHandler) ownedContact(c *gin.Context) (*models.Contact, bool) {
+	userID, _ := c.Get("user_id")
+
+	contactID, err := strconv.ParseInt(c.Param("contact_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return nil, false
+	}
+	contact, err := h.Store.GetContactByID(c.Request.Context(), contactID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return nil, false
+	}
+	if contact.UserID != userID.(int64) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return contact, true
+}
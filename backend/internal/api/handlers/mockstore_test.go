@@ -3,9 +3,11 @@ package handlers_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
 )
 
 // MockStore is a mock implementation of the store.Store interface for testing.
@@ -13,7 +15,25 @@ type MockStore struct {
 	Users          map[int64]*models.User
 	UsersByEmail   map[string]*models.User
 	Workflows      map[int64]*models.Workflow
+	Versions       []*models.WorkflowVersion
+	Executions     map[int64]*models.WorkflowExecution
+	Steps          map[int64][]models.WorkflowExecutionStep
+	Calendars      map[int64]*models.BusinessCalendar
+	SplitCounts    []store.SplitBranchCount
+	Usage          []models.LLMUsage
+	AwaitingReply  []models.WorkflowExecution
+	Automations    []models.Automation
 	CreateUserFunc func(ctx context.Context, user *models.User) error
+
+	mu       sync.Mutex
+	messages []models.Message
+}
+
+// SentMessages returns the messages stored so far, which webhooks store from goroutines
+func (m *MockStore) SentMessages() []models.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.Message(nil), m.messages...)
 }
 
 func NewMockStore() *MockStore {
@@ -21,6 +41,9 @@ func NewMockStore() *MockStore {
 		Users:        make(map[int64]*models.User),
 		UsersByEmail: make(map[string]*models.User),
 		Workflows:    make(map[int64]*models.Workflow),
+		Executions:   make(map[int64]*models.WorkflowExecution),
+		Steps:        make(map[int64][]models.WorkflowExecutionStep),
+		Calendars:    make(map[int64]*models.BusinessCalendar),
 	}
 }
 
@@ -56,18 +79,33 @@ func (m *MockStore) UpdateUserPassword(ctx context.Context, userID int64, passwo
 func (m *MockStore) UpdateChannelToken(ctx context.Context, channelID int64, accessToken string, expiry time.Time) error { return nil }
 func (m *MockStore) Close() {}
 func (m *MockStore) RunMigrations() error { return nil }
-func (m *MockStore) CreateMessage(ctx context.Context, msg *models.Message) error { msg.ID = 1; return nil }
+func (m *MockStore) CreateMessage(ctx context.Context, msg *models.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msg.ID = int64(len(m.messages) + 1)
+	m.messages = append(m.messages, *msg)
+	return nil
+}
 func (m *MockStore) GetMessagesByContact(ctx context.Context, contactID int64, limit, offset int) ([]models.Message, error) { return nil, nil }
+func (m *MockStore) GetRecentMessagesByContact(ctx context.Context, contactID int64, limit int) ([]models.Message, error) { return nil, nil }
+func (m *MockStore) GetMessagesByContactAfter(ctx context.Context, contactID, afterID int64, limit int) ([]models.Message, error) { return nil, nil }
 func (m *MockStore) GetConversations(ctx context.Context, userID int64, limit, offset int) ([]models.Message, error) { return nil, nil }
+func (m *MockStore) GetConversationSummary(ctx context.Context, contactID int64) (*models.ConversationSummary, error) { return nil, nil }
+func (m *MockStore) SaveConversationSummary(ctx context.Context, cs *models.ConversationSummary) error { return nil }
 func (m *MockStore) CreateContact(ctx context.Context, c *models.Contact) error { return nil }
 func (m *MockStore) GetOrCreateContact(ctx context.Context, c *models.Contact) error { c.ID = 1; return nil }
 func (m *MockStore) GetContactsByUser(ctx context.Context, userID int64, limit, offset int) ([]models.Contact, error) { return nil, nil }
 func (m *MockStore) UpdateContactLead(ctx context.Context, contactID int64, budget, location, timeline, phone string, isHot bool) error { return nil }
 func (m *MockStore) GetContactByID(ctx context.Context, contactID int64) (*models.Contact, error) { return nil, nil }
+func (m *MockStore) ListTriggerContacts(ctx context.Context, userID int64, filter store.TriggerContactFilter) ([]models.Contact, error) { return nil, nil }
+func (m *MockStore) GetContactFieldSources(ctx context.Context, contactID int64) ([]models.ContactFieldSource, error) { return nil, nil }
+func (m *MockStore) SaveContactFieldSources(ctx context.Context, sources []models.ContactFieldSource) error { return nil }
+func (m *MockStore) SaveContactLeadFields(ctx context.Context, contactID int64, values map[string]interface{}, sources []models.ContactFieldSource) ([]string, error) { return nil, nil }
 func (m *MockStore) UpdateContactState(ctx context.Context, contactID int64, bookingState string, botPaused bool) error { return nil }
 func (m *MockStore) CreateVisit(ctx context.Context, v *models.Visit) error { return nil }
 func (m *MockStore) GetVisitsByUser(ctx context.Context, userID int64, limit, offset int) ([]models.Visit, error) { return nil, nil }
 func (m *MockStore) GetVisitByContact(ctx context.Context, contactID int64) (*models.Visit, error) { return nil, nil }
+func (m *MockStore) GetVisitByID(ctx context.Context, visitID int64) (*models.Visit, error) { return nil, errors.New("visit not found") }
 func (m *MockStore) UpdateVisitStatus(ctx context.Context, visitID int64, status string) error { return nil }
 func (m *MockStore) UpsertPropertyVisitConfig(ctx context.Context, cfg *models.PropertyVisitConfig) error { return nil }
 func (m *MockStore) GetPropertyVisitConfig(ctx context.Context, userID int64) (*models.PropertyVisitConfig, error) {
@@ -84,10 +122,36 @@ func (m *MockStore) CreateBroadcast(ctx context.Context, b *models.Broadcast) er
 func (m *MockStore) GetBroadcastsByUser(ctx context.Context, userID int64, limit, offset int) ([]models.Broadcast, error) { return nil, nil }
 func (m *MockStore) GetBroadcastByID(ctx context.Context, broadcastID int64) (*models.Broadcast, error) { return nil, nil }
 func (m *MockStore) UpdateBroadcastStatus(ctx context.Context, broadcastID int64, status string, totalSent, totalFailed int) error { return nil }
+func (m *MockStore) GetBusinessCalendar(ctx context.Context, userID int64) (*models.BusinessCalendar, error) {
+	return m.Calendars[userID], nil
+}
+func (m *MockStore) UpsertBusinessCalendar(ctx context.Context, cal *models.BusinessCalendar) error {
+	m.Calendars[cal.UserID] = cal
+	return nil
+}
+func (m *MockStore) CreateAgent(ctx context.Context, a *models.Agent) error { return nil }
+func (m *MockStore) ListAgents(ctx context.Context, userID int64) ([]models.Agent, error) { return nil, nil }
+func (m *MockStore) GetAgentByID(ctx context.Context, agentID int64) (*models.Agent, error) { return nil, errors.New("agent not found") }
+func (m *MockStore) UpdateAgent(ctx context.Context, a *models.Agent) error { return nil }
+func (m *MockStore) DeleteAgent(ctx context.Context, agentID, userID int64) error { return nil }
+func (m *MockStore) PickAgent(ctx context.Context, userID int64, team, strategy string, excludeAgentID int64) (*models.Agent, error) { return nil, nil }
+func (m *MockStore) CreateHandoff(ctx context.Context, h *models.Handoff) error { return nil }
+func (m *MockStore) GetHandoffByID(ctx context.Context, handoffID int64) (*models.Handoff, error) { return nil, errors.New("handoff not found") }
+func (m *MockStore) GetOpenHandoffByContact(ctx context.Context, contactID int64) (*models.Handoff, error) { return nil, nil }
+func (m *MockStore) ListHandoffs(ctx context.Context, userID int64, status string, limit, offset int) ([]models.Handoff, error) { return nil, nil }
+func (m *MockStore) UpdateHandoffAssignment(ctx context.Context, h *models.Handoff) error { return nil }
+func (m *MockStore) MarkHandoffResponded(ctx context.Context, contactID int64) error { return nil }
+func (m *MockStore) ResolveHandoffs(ctx context.Context, contactID int64) error { return nil }
+func (m *MockStore) CreateContactNote(ctx context.Context, n *models.ContactNote) error { return nil }
+func (m *MockStore) GetContactNotes(ctx context.Context, contactID int64, limit int) ([]models.ContactNote, error) { return nil, nil }
 func (m *MockStore) CreateAutomation(ctx context.Context, a *models.Automation) error { return nil }
-func (m *MockStore) GetAutomationsByUser(ctx context.Context, userID int64) ([]models.Automation, error) { return nil, nil }
+func (m *MockStore) GetAutomationsByUser(ctx context.Context, userID int64) ([]models.Automation, error) { return m.Automations, nil }
 func (m *MockStore) UpdateAutomation(ctx context.Context, a *models.Automation) error { return nil }
 func (m *MockStore) DeleteAutomation(ctx context.Context, automationID, userID int64) error { return nil }
+func (m *MockStore) UpsertTenantSecret(ctx context.Context, secret *models.TenantSecret) error { return nil }
+func (m *MockStore) GetTenantSecrets(ctx context.Context, userID int64) (map[string]string, error) { return nil, nil }
+func (m *MockStore) ListTenantSecrets(ctx context.Context, userID int64) ([]models.TenantSecret, error) { return nil, nil }
+func (m *MockStore) DeleteTenantSecret(ctx context.Context, userID int64, name string) error { return nil }
 
 func (m *MockStore) CreateKnowledgeBaseEntry(ctx context.Context, entry *models.KnowledgeBase, embedding []float32) error { return nil }
 func (m *MockStore) GetKnowledgeBaseEntriesByUser(ctx context.Context, userID int64) ([]models.KnowledgeBase, error) { return nil, nil }
@@ -114,6 +178,9 @@ func (m *MockStore) GetWorkflowsByUser(ctx context.Context, userID int64) ([]mod
 	return result, nil
 }
 func (m *MockStore) GetActiveWorkflowsByTrigger(ctx context.Context, userID int64, triggerType string) ([]models.Workflow, error) { return nil, nil }
+func (m *MockStore) GetPublishedWorkflowsByTrigger(ctx context.Context, triggerType string) ([]models.Workflow, error) { return nil, nil }
+func (m *MockStore) GetTriggerLastRun(ctx context.Context, workflowID int64, nodeID string) (time.Time, error) { return time.Time{}, nil }
+func (m *MockStore) AdvanceTriggerRun(ctx context.Context, workflowID int64, nodeID string, prev, next time.Time) (bool, error) { return true, nil }
 func (m *MockStore) UpdateWorkflow(ctx context.Context, w *models.Workflow) error {
 	if _, exists := m.Workflows[w.ID]; exists {
 		m.Workflows[w.ID] = w
@@ -128,6 +195,169 @@ func (m *MockStore) DeleteWorkflow(ctx context.Context, workflowID, userID int64
 	}
 	return errors.New("workflow not found or unauthorized")
 }
+func (m *MockStore) CreateWorkflowVersion(ctx context.Context, v *models.WorkflowVersion) error {
+	v.ID = int64(len(m.Versions) + 1)
+	v.Version = 1
+	for _, existing := range m.Versions {
+		if existing.WorkflowID == v.WorkflowID && existing.Version >= v.Version {
+			v.Version = existing.Version + 1
+		}
+	}
+	cp := *v
+	m.Versions = append(m.Versions, &cp)
+	return nil
+}
+func (m *MockStore) PublishWorkflowVersion(ctx context.Context, w *models.Workflow, v *models.WorkflowVersion) error {
+	if err := m.UpdateWorkflow(ctx, w); err != nil {
+		return err
+	}
+	v.WorkflowID = w.ID
+	return m.CreateWorkflowVersion(ctx, v)
+}
+func (m *MockStore) GetWorkflowVersionByID(ctx context.Context, versionID int64) (*models.WorkflowVersion, error) {
+	for _, v := range m.Versions {
+		if v.ID == versionID {
+			return v, nil
+		}
+	}
+	return nil, errors.New("version not found")
+}
+func (m *MockStore) GetWorkflowVersion(ctx context.Context, workflowID int64, version int) (*models.WorkflowVersion, error) {
+	for _, v := range m.Versions {
+		if v.WorkflowID == workflowID && v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, errors.New("version not found")
+}
+func (m *MockStore) GetLatestWorkflowVersion(ctx context.Context, workflowID int64) (*models.WorkflowVersion, error) {
+	var latest *models.WorkflowVersion
+	for _, v := range m.Versions {
+		if v.WorkflowID == workflowID && !v.Draft && (latest == nil || v.Version > latest.Version) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return nil, errors.New("version not found")
+	}
+	return latest, nil
+}
+func (m *MockStore) ListWorkflowVersions(ctx context.Context, workflowID int64) ([]models.WorkflowVersion, error) {
+	var result []models.WorkflowVersion
+	for i := len(m.Versions) - 1; i >= 0; i-- {
+		if m.Versions[i].WorkflowID == workflowID {
+			result = append(result, *m.Versions[i])
+		}
+	}
+	return result, nil
+}
+
 func (m *MockStore) CreateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error { return nil }
-func (m *MockStore) GetWorkflowExecutionByID(ctx context.Context, executionID int64) (*models.WorkflowExecution, error) { return nil, nil }
+func (m *MockStore) GetWorkflowExecutionByID(ctx context.Context, executionID int64) (*models.WorkflowExecution, error) {
+	if e, exists := m.Executions[executionID]; exists {
+		return e, nil
+	}
+	return nil, errors.New("execution not found")
+}
 func (m *MockStore) UpdateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error { return nil }
+func (m *MockStore) GetExecutionsAwaitingReply(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) { return m.AwaitingReply, nil }
+func (m *MockStore) ClaimWaitingExecution(ctx context.Context, executionID int64, waitingFor string) (bool, error) { return false, nil }
+func (m *MockStore) ListWorkflowExecutions(ctx context.Context, userID int64, filter store.ExecutionFilter) ([]models.WorkflowExecution, error) {
+	var result []models.WorkflowExecution
+	for _, e := range m.Executions {
+		w, ok := m.Workflows[e.WorkflowID]
+		if !ok || w.UserID != userID {
+			continue
+		}
+		if (filter.WorkflowID != 0 && e.WorkflowID != filter.WorkflowID) || (filter.ContactID != 0 && e.ContactID != filter.ContactID) {
+			continue
+		}
+		if filter.Status != "" && e.Status != filter.Status {
+			continue
+		}
+		if filter.ParentExecutionID != 0 && (e.ParentExecutionID == nil || *e.ParentExecutionID != filter.ParentExecutionID) {
+			continue
+		}
+		result = append(result, *e)
+	}
+	return result, nil
+}
+func (m *MockStore) GetActiveExecutionsByContact(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) {
+	var result []models.WorkflowExecution
+	for _, e := range m.Executions {
+		if e.ContactID == contactID && (e.Status == "running" || e.Status == "waiting" || e.Status == "paused") {
+			result = append(result, *e)
+		}
+	}
+	return result, nil
+}
+func (m *MockStore) SetWorkflowExecutionStatus(ctx context.Context, executionID int64, from []string, status string) (bool, error) {
+	e, exists := m.Executions[executionID]
+	if !exists {
+		return false, nil
+	}
+	for _, f := range from {
+		if e.Status == f {
+			e.Status = status
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *MockStore) SetExecutionSplitBranch(ctx context.Context, executionID int64, nodeID, branch string) error { return nil }
+func (m *MockStore) GetSplitReport(ctx context.Context, workflowID int64, goal store.SplitGoal, since time.Time) ([]store.SplitBranchCount, error) {
+	return m.SplitCounts, nil
+}
+func (m *MockStore) MarkExecutionGoalReached(ctx context.Context, executionID int64, goal string) (bool, error) {
+	return m.SetWorkflowExecutionStatus(ctx, executionID, []string{"running", "waiting", "paused"}, "goal_reached")
+}
+func (m *MockStore) GetLastExecutionStart(ctx context.Context, workflowID, contactID int64) (time.Time, error) {
+	return time.Time{}, nil
+}
+func (m *MockStore) ClaimQueuedExecution(ctx context.Context, workflowID, contactID int64) (int64, error) {
+	return 0, nil
+}
+func (m *MockStore) CreateWorkflowExecutionStep(ctx context.Context, step *models.WorkflowExecutionStep) error {
+	m.Steps[step.ExecutionID] = append(m.Steps[step.ExecutionID], *step)
+	return nil
+}
+func (m *MockStore) GetWorkflowExecutionSteps(ctx context.Context, executionID int64) ([]models.WorkflowExecutionStep, error) {
+	return m.Steps[executionID], nil
+}
+func (m *MockStore) AddContactTag(ctx context.Context, contactID int64, tag string) (bool, error) {
+	return true, nil
+}
+func (m *MockStore) CreateLLMUsage(ctx context.Context, u *models.LLMUsage) error {
+	u.ID = int64(len(m.Usage) + 1)
+	m.Usage = append(m.Usage, *u)
+	return nil
+}
+func (m *MockStore) GetLLMSpend(ctx context.Context, userID int64, since time.Time) (float64, error) {
+	spend := 0.0
+	for _, u := range m.Usage {
+		if u.UserID == userID {
+			spend += u.CostUSD
+		}
+	}
+	return spend, nil
+}
+func (m *MockStore) GetLLMUsageReport(ctx context.Context, userID int64, from, to time.Time, groupBy string) ([]models.LLMUsageSummary, error) {
+	byKey := map[string]int{}
+	var out []models.LLMUsageSummary
+	for _, u := range m.Usage {
+		if u.UserID != userID {
+			continue
+		}
+		i, ok := byKey[u.Feature]
+		if !ok {
+			i = len(out)
+			byKey[u.Feature] = i
+			out = append(out, models.LLMUsageSummary{Key: u.Feature})
+		}
+		out[i].Calls++
+		out[i].PromptTokens += int64(u.PromptTokens)
+		out[i].CompletionTokens += int64(u.CompletionTokens)
+		out[i].CostUSD += u.CostUSD
+	}
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// SecretHandler manages tenant secrets used by workflow HTTP request nodes.
+// Values are write-only: they are never returned by the API.
+type SecretHandler struct {
+	Store store.Store
+}
+
+// PutSecretRequest is the expected body for creating or replacing a secret.
+type PutSecretRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+// secretNamePattern keeps names usable inside {{secret.NAME}} placeholders
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// ListSecrets returns the names of the current user's secrets.
+func (h *SecretHandler) ListSecrets(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	secrets, err := h.Store.ListTenantSecrets(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch secrets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secrets": secrets,
+		"count":   len(secrets),
+	})
+}
+
+// PutSecret creates or replaces a named secret.
+func (h *SecretHandler) PutSecret(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	name := c.Param("name")
+	if !secretNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Secret names may only contain letters, digits and underscores"})
+		return
+	}
+
+	var req PutSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := &models.TenantSecret{UserID: userID.(int64), Name: name, Value: req.Value}
+	if err := h.Store.UpsertTenantSecret(c.Request.Context(), secret); errors.Is(err, store.ErrNoSecretKey) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Secrets are not enabled on this server"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, secret)
+}
+
+// DeleteSecret removes a named secret.
+func (h *SecretHandler) DeleteSecret(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.Store.DeleteTenantSecret(c.Request.Context(), userID.(int64), c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Secret deleted"})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// SimulateWorkflowRequest is the body of POST /workflows/:id/simulate. Nodes/Edges may carry
+// unsaved edits from the builder; otherwise the stored graph is simulated.
+type SimulateWorkflowRequest struct {
+	Message      string                 `json:"message"`
+	Contact      models.Contact         `json:"contact"`
+	Replies      []string               `json:"replies"`
+	State        map[string]interface{} `json:"state"`
+	LLM          string                 `json:"llm"` // "real" (default) or "canned"
+	LLMResponses []string               `json:"llm_responses"`
+	Nodes        json.RawMessage        `json:"nodes"`
+	Edges        json.RawMessage        `json:"edges"`
+}
+
+// SimulateWorkflow dry-runs a workflow against a synthetic contact without sending anything.
+func (h *WorkflowHandler) SimulateWorkflow(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req SimulateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.LLM != "" && req.LLM != "real" && req.LLM != "canned" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "llm must be \"real\" or \"canned\""})
+		return
+	}
+
+	draft := *w
+	if len(req.Nodes) > 0 || len(req.Edges) > 0 {
+		draft.Nodes = []byte(req.Nodes)
+		draft.Edges = []byte(req.Edges)
+	}
+
+	report, err := engine.ValidateWorkflow(draft.Nodes, draft.Edges)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.GraphWalker.Simulate(c.Request.Context(), &draft, engine.SimulationOptions{
+		Contact:      req.Contact,
+		Message:      req.Message,
+		Replies:      req.Replies,
+		State:        req.State,
+		CannedLLM:    req.LLM == "canned" || len(req.LLMResponses) > 0,
+		LLMResponses: req.LLMResponses,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Simulation failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"simulation": result,
+		"validation": report,
+	})
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestSimulateWorkflow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	nodes, _ := json.Marshal(minimalNodes)
+	edges, _ := json.Marshal(minimalEdges)
+	mockStore := NewMockStore()
+	mockStore.Workflows[1] = &models.Workflow{ID: 1, UserID: 1, Name: "Mine", Nodes: nodes, Edges: edges}
+	mockStore.Workflows[2] = &models.Workflow{ID: 2, UserID: 2, Name: "Someone else's", Nodes: nodes, Edges: edges}
+
+	handler := &handlers.WorkflowHandler{Store: mockStore, GraphWalker: engine.NewGraphWalker(mockStore, nil, nil, nil)}
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.POST("/api/v1/workflows/:id/simulate", handler.SimulateWorkflow)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Unsaved Graph", func(t *testing.T) {
+		w := post("/api/v1/workflows/1/simulate", map[string]interface{}{
+			"message": "hi",
+			"contact": map[string]interface{}{"name": "Asha"},
+			"nodes": []map[string]interface{}{
+				{"id": "1", "type": "trigger_meta_dm"},
+				{"id": "2", "type": "action_send_message", "data": map[string]interface{}{"message": "Hello {{contact.name}}"}},
+			},
+			"edges": []map[string]interface{}{{"id": "e1", "source": "1", "target": "2"}},
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Simulation engine.SimulationResult `json:"simulation"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Simulation.Status != "completed" || len(resp.Simulation.Messages) != 1 || resp.Simulation.Messages[0] != "Hello Asha" {
+			t.Errorf("unexpected simulation %s", w.Body.String())
+		}
+		if len(mockStore.Executions) != 0 {
+			t.Error("simulation created a real execution")
+		}
+	})
+
+	t.Run("Bad LLM Mode", func(t *testing.T) {
+		if w := post("/api/v1/workflows/1/simulate", map[string]interface{}{"llm": "cheap"}); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Other Tenant", func(t *testing.T) {
+		if w := post("/api/v1/workflows/2/simulate", map[string]interface{}{}); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// SplitReport returns per-branch contacts and conversions of a workflow's logic_split nodes:
+// GET /workflows/:id/split-report?goal=tag:hot&since=2026-01-01
+func (h *WorkflowHandler) SplitReport(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	goal, err := engine.ParseSplitGoal(c.Query("goal"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		if since, err = time.Parse(time.RFC3339, raw); err != nil {
+			if since, err = time.Parse("2006-01-02", raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: use RFC 3339 or YYYY-MM-DD"})
+				return
+			}
+		}
+	}
+
+	graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse workflow graph"})
+		return
+	}
+
+	counts, err := h.Store.GetSplitReport(c.Request.Context(), w.ID, goal, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build split report"})
+		return
+	}
+
+	goalName := goal.Kind
+	if goal.Value != "" {
+		goalName += ":" + goal.Value
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"workflow_id": w.ID,
+		"goal":        goalName,
+		"splits":      engine.BuildSplitReports(graph, counts),
+	})
+}
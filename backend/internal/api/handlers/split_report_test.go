@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+func TestSplitReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	mockStore.Workflows[1] = &models.Workflow{
+		ID: 1, UserID: 1,
+		Nodes: json.RawMessage(`[{"id":"1","type":"trigger_meta_dm","data":{}},
+			{"id":"s","type":"logic_split","data":{"branches":[{"handle":"a","weight":50},{"handle":"b","weight":50}]}}]`),
+		Edges: json.RawMessage(`[{"id":"e1","source":"1","target":"s"}]`),
+	}
+	mockStore.Workflows[2] = &models.Workflow{ID: 2, UserID: 2, Nodes: json.RawMessage(`[]`), Edges: json.RawMessage(`[]`)}
+	mockStore.SplitCounts = []store.SplitBranchCount{
+		{NodeID: "s", Branch: "a", Contacts: 400, Conversions: 40},
+		{NodeID: "s", Branch: "b", Contacts: 400, Conversions: 80},
+	}
+	handler := &handlers.WorkflowHandler{Store: mockStore}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.GET("/api/v1/workflows/:id/split-report", handler.SplitReport)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/v1/workflows/1/split-report?goal=tag:hot&since=2026-01-01")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Goal   string `json:"goal"`
+		Splits []struct {
+			NodeID   string `json:"node_id"`
+			Branches []struct {
+				Handle      string `json:"handle"`
+				Significant bool   `json:"significant"`
+			} `json:"branches"`
+		} `json:"splits"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Goal != "tag:hot" || len(resp.Splits) != 1 || len(resp.Splits[0].Branches) != 2 || !resp.Splits[0].Branches[1].Significant {
+		t.Errorf("unexpected report %s", w.Body.String())
+	}
+
+	if w := get("/api/v1/workflows/1/split-report?goal=clicked"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown goal, got %d", w.Code)
+	}
+	if w := get("/api/v1/workflows/2/split-report"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another user's workflow, got %d", w.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// VisitHandler lists booked property visits and lets agents move them through their lifecycle.
+type VisitHandler struct {
+	Store       store.Store
+	GraphWalker *engine.GraphWalker
+}
+
+// visitStatuses are the states a visit can be in
+var visitStatuses = map[string]bool{"confirmed": true, "rescheduled": true, "completed": true, "cancelled": true}
+
+// UpdateVisitStatusRequest is the body of PUT /visits/:id/status
+type UpdateVisitStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// ListVisits returns the current user's visits, soonest first.
+func (h *VisitHandler) ListVisits(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	visits, err := h.Store.GetVisitsByUser(c.Request.Context(), userID.(int64), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch visits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"visits": visits,
+		"count":  len(visits),
+	})
+}
+
+// UpdateVisitStatus changes a visit's status and fires the visit_status_changed contact event.
+func (h *VisitHandler) UpdateVisitStatus(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	visitID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visit ID"})
+		return
+	}
+
+	var req UpdateVisitStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !visitStatuses[req.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: must be confirmed, rescheduled, completed or cancelled"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	visit, err := h.Store.GetVisitByID(ctx, visitID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Visit not found"})
+		return
+	}
+	if visit.UserID != userID.(int64) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+	if visit.Status == req.Status {
+		c.JSON(http.StatusOK, visit)
+		return
+	}
+
+	if err := h.Store.UpdateVisitStatus(ctx, visit.ID, req.Status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update visit"})
+		return
+	}
+	oldStatus := visit.Status
+	visit.Status = req.Status
+
+	if h.GraphWalker != nil {
+		h.GraphWalker.CheckGoals(ctx, visit.ContactID)
+		h.GraphWalker.EmitContactEvent(ctx, engine.ContactEvent{
+			Type:      engine.ContactEventVisitStatusChanged,
+			UserID:    visit.UserID,
+			ContactID: visit.ContactID,
+			VisitID:   visit.ID,
+			OldValue:  oldStatus,
+			NewValue:  visit.Status,
+		})
+	}
+
+	c.JSON(http.StatusOK, visit)
+}
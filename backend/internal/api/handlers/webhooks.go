@@ -3,9 +3,11 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -286,14 +288,23 @@ func (h *WebhookHandler) storeIncomingMessage(platform, accountID, senderID, sen
 
 	log.Printf("[Webhook] ✅ Stored message #%d from contact #%d (user #%d)", msg.ID, contact.ID, channel.UserID)
 
-	// 4. Handle Property Visit Q&A Flow
-	if h.processVisitBookingFlow(ctx, channel, contact, content) {
-		// If flow handled it, skip generic workflow orchestrator
-		return
+	// Lead fields are read from the conversation once the contact stops writing
+	if h.GraphWalker != nil && strings.TrimSpace(content) != "" {
+		h.GraphWalker.ScheduleLeadExtraction(ctx, contact.ID, msg.ID)
 	}
 
-	// 5. Trigger the new Workflow DAG Orchestrator
-	h.triggerWorkflows(ctx, channel, contact, content)
+	// 4. Resume workflows that were waiting for this contact's answer instead of starting new
+	// ones. The visit flow and new workflows are skipped; legacy automations still run below.
+	if !h.resumeWaitingExecutions(ctx, contact, content) {
+		// 5. Handle Property Visit Q&A Flow
+		if h.processVisitBookingFlow(ctx, channel, contact, content) {
+			// If flow handled it, skip generic workflow orchestrator
+			return
+		}
+
+		// 6. Trigger the new Workflow DAG Orchestrator
+		h.triggerWorkflows(ctx, channel, contact, content)
+	}
 
 	// Legacy automation triggers
 	h.checkAutomationTriggers(ctx, channel, contact, content)
@@ -325,35 +336,32 @@ func (h *WebhookHandler) processVisitBookingFlow(ctx context.Context, channel *m
 		contact.BookingState = "qualified"
 
 	case "qualified":
+		slots := h.visitSlots(ctx, channel.UserID)
+		if len(slots) == 0 {
+			reply = fmt.Sprintf("Thanks! Our team will contact you shortly to schedule a site visit to %s.", cfg.ProjectName)
+			break
+		}
 		if cfg.BrochureURL != "" {
-			reply = fmt.Sprintf("Great choice! Here is the %s brochure: %s\n\nWould you like to schedule a site visit? We have slots tomorrow at 10 AM, 2 PM, or 4 PM. Reply with your preferred time.", cfg.ProjectName, cfg.BrochureURL)
+			reply = fmt.Sprintf("Great choice! Here is the %s brochure: %s\n\nWould you like to schedule a site visit? We have slots %s. Reply with your preferred time.", cfg.ProjectName, cfg.BrochureURL, describeSlots(slots))
 		} else {
-			reply = fmt.Sprintf("Great! Would you like to schedule a site visit for %s? We have slots tomorrow at 10 AM, 2 PM, or 4 PM. Reply with your preferred time.", cfg.ProjectName)
+			reply = fmt.Sprintf("Great! Would you like to schedule a site visit for %s? We have slots %s. Reply with your preferred time.", cfg.ProjectName, describeSlots(slots))
 		}
 		contact.BookingState = "offered_slots"
 
 	case "offered_slots":
-		slot := ""
-		visitTime := time.Now().AddDate(0, 0, 1)
-		if strings.Contains(contentLower, "10") {
-			slot = "10:00 AM"
-			visitTime = time.Date(visitTime.Year(), visitTime.Month(), visitTime.Day(), 10, 0, 0, 0, visitTime.Location())
-		} else if strings.Contains(contentLower, "2") {
-			slot = "2:00 PM"
-			visitTime = time.Date(visitTime.Year(), visitTime.Month(), visitTime.Day(), 14, 0, 0, 0, visitTime.Location())
-		} else if strings.Contains(contentLower, "4") {
-			slot = "4:00 PM"
-			visitTime = time.Date(visitTime.Year(), visitTime.Month(), visitTime.Day(), 16, 0, 0, 0, visitTime.Location())
-		} else {
-			reply = "I can answer more questions, but to ensure you get the best experience, would you like to book a site visit? We have slots tomorrow at 10 AM, 2 PM, or 4 PM."
+		slots := h.visitSlots(ctx, channel.UserID)
+		visitTime, ok := matchSlot(contentLower, slots)
+		if !ok {
+			reply = fmt.Sprintf("I can answer more questions, but to ensure you get the best experience, would you like to book a site visit? We have slots %s.", describeSlots(slots))
 			break
 		}
+		slot := visitTime.Format("3:04 PM")
 
 		// Prevent double booking via Redis TTL lock
 		if h.Cache != nil {
 			locked, err := h.Cache.ReserveSlot(ctx, cfg.ProjectName, visitTime, contact.ID, 5*time.Minute)
 			if err != nil || !locked {
-				reply = fmt.Sprintf("I'm sorry, the %s slot just got taken! Please choose another time: 10 AM, 2 PM, or 4 PM.", slot)
+				reply = fmt.Sprintf("I'm sorry, the %s slot just got taken! Please choose another time: %s.", slot, slotTimes(slots))
 				break
 			}
 		}
@@ -372,9 +380,18 @@ func (h *WebhookHandler) processVisitBookingFlow(ctx context.Context, channel *m
 			reply = "There was an error booking your visit. Please hold on, our agent will contact you."
 			contact.BotPaused = true
 		} else {
-			reply = fmt.Sprintf("Perfect! Your visit to %s is confirmed for tomorrow at %s. Our agent will be in touch shortly to confirm details.", cfg.ProjectName, slot)
+			reply = fmt.Sprintf("Perfect! Your visit to %s is confirmed for %s at %s. Our agent will be in touch shortly to confirm details.", cfg.ProjectName, slotDay(visitTime), slot)
 			contact.BookingState = "booked"
 			go h.sendAgentNotification(channel, contact, visitTime)
+			if h.GraphWalker != nil {
+				// Visit goals end the lead's nurture workflows
+				go func(contactID, visitID int64) {
+					h.GraphWalker.CheckGoals(context.Background(), contactID)
+					h.GraphWalker.EmitContactEvent(context.Background(), engine.ContactEvent{
+						Type: engine.ContactEventVisitBooked, UserID: channel.UserID, ContactID: contactID, VisitID: visitID,
+					})
+				}(contact.ID, visit.ID)
+			}
 		}
 
 	case "booked":
@@ -390,6 +407,67 @@ func (h *WebhookHandler) processVisitBookingFlow(ctx context.Context, channel *m
 	return false
 }
 
+// visitSlotTimes are the visit times offered on a day, as far as business hours allow
+var visitSlotTimes = []string{"10:00", "14:00", "16:00"}
+
+// visitSlots returns the visit slots of the tenant's next open day
+func (h *WebhookHandler) visitSlots(ctx context.Context, userID int64) []time.Time {
+	return engine.TenantCalendar(ctx, h.Store, userID).NextDaySlots(time.Now(), visitSlotTimes)
+}
+
+// slotLabel formats a slot the way leads are asked to answer: "10 AM", "2:30 PM"
+func slotLabel(t time.Time) string {
+	if t.Minute() == 0 {
+		return t.Format("3 PM")
+	}
+	return t.Format("3:04 PM")
+}
+
+// slotTimes lists the slot times: "10 AM, 2 PM, or 4 PM"
+func slotTimes(slots []time.Time) string {
+	labels := make([]string, len(slots))
+	for i, s := range slots {
+		labels[i] = slotLabel(s)
+	}
+	if len(labels) <= 1 {
+		return strings.Join(labels, "")
+	}
+	return strings.Join(labels[:len(labels)-1], ", ") + ", or " + labels[len(labels)-1]
+}
+
+// slotDay names the day of a slot relative to now in the slot's timezone
+func slotDay(t time.Time) string {
+	now := time.Now().In(t.Location())
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, t.Location())
+	if t.Year() == tomorrow.Year() && t.YearDay() == tomorrow.YearDay() {
+		return "tomorrow"
+	}
+	return t.Format("Monday, Jan 2")
+}
+
+// describeSlots renders the offer: "tomorrow at 10 AM, 2 PM, or 4 PM"
+func describeSlots(slots []time.Time) string {
+	if len(slots) == 0 {
+		return "soon"
+	}
+	return slotDay(slots[0]) + " at " + slotTimes(slots)
+}
+
+// matchSlot finds the offered slot a reply refers to by its hour ("10", "2 pm"). Longer hours
+// are tried first so that "12" is not read as "2".
+func matchSlot(reply string, slots []time.Time) (time.Time, bool) {
+	ordered := append([]time.Time(nil), slots...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return len(ordered[i].Format("3")) > len(ordered[j].Format("3"))
+	})
+	for _, s := range ordered {
+		if strings.Contains(reply, s.Format("3")) {
+			return s, true
+		}
+	}
+	return time.Time{}, false
+}
+
 // loadTenantConfig fetches the wizard config from Redis (10 min TTL) or falls back to Postgres.
 func (h *WebhookHandler) loadTenantConfig(ctx context.Context, userID int64) *models.PropertyVisitConfig {
 	if h.Cache != nil {
@@ -465,30 +543,74 @@ Summary: Intent (Qualified), Time Booked.
 }
 
 
-// triggerWorkflows executes any active DAG workflow matching the meta_dm_received trigger
-func (h *WebhookHandler) triggerWorkflows(ctx context.Context, channel *models.Channel, contact *models.Contact, content string) {
-	workflows, err := h.Store.GetActiveWorkflowsByTrigger(ctx, channel.UserID, "trigger_meta_dm")
+// resumeWaitingExecutions hands the inbound message to every execution parked on an
+// action_wait_for_reply node for this contact. Returns true if there was at least one.
+func (h *WebhookHandler) resumeWaitingExecutions(ctx context.Context, contact *models.Contact, content string) bool {
+	if h.GraphWalker == nil {
+		return false
+	}
+
+	execs, err := h.Store.GetExecutionsAwaitingReply(ctx, contact.ID)
 	if err != nil {
-		log.Printf("[Webhook] Failed to fetch active workflows: %v", err)
-		return
+		log.Printf("[Webhook] Failed to fetch executions awaiting reply for contact %d: %v", contact.ID, err)
+		return false
+	}
+	if len(execs) == 0 {
+		return false
 	}
 
-	for _, w := range workflows {
-		log.Printf("[Webhook] Execution Engine starting Workflow %d: '%s'", w.ID, w.Name)
-		
+	for _, exec := range execs {
+		log.Printf("[Webhook] Resuming execution %d (workflow %d) with reply from contact %d", exec.ID, exec.WorkflowID, contact.ID)
+		go func(executionID int64) {
+			if err := h.GraphWalker.ResumeWithReply(context.Background(), executionID, content); err != nil {
+				log.Printf("[Engine] Failed to resume execution %d with reply: %v", executionID, err)
+			}
+		}(exec.ID)
+	}
+	return true
+}
+
+// triggerWorkflows starts every published DM/keyword workflow whose trigger matches the inbound message.
+// Matching and ordering are delegated to engine.MatchTriggers so the result is deterministic.
+func (h *WebhookHandler) triggerWorkflows(ctx context.Context, channel *models.Channel, contact *models.Contact, content string) {
+	var workflows []models.Workflow
+	for _, triggerType := range []models.NodeType{models.NodeTypeTriggerDM, models.NodeTypeTriggerKeyword} {
+		flows, err := h.Store.GetActiveWorkflowsByTrigger(ctx, channel.UserID, string(triggerType))
+		if err != nil {
+			log.Printf("[Webhook] Failed to fetch active %s workflows: %v", triggerType, err)
+			continue
+		}
+		workflows = append(workflows, flows...)
+	}
+
+	matches := engine.MatchTriggers(workflows, engine.InboundEvent{
+		Platform:  contact.Platform,
+		ChannelID: channel.ID,
+		Text:      content,
+	})
+
+	for _, m := range matches {
+		log.Printf("[Webhook] Execution Engine starting Workflow %d: '%s' (trigger %s)", m.Workflow.ID, m.Workflow.Name, m.TriggerNodeID)
+
 		initialState := map[string]interface{}{
 			"received_message": content,
 			"platform":         contact.Platform,
 			"contact_name":     contact.Name,
+			"trigger_node_id":  m.TriggerNodeID,
+		}
+		if m.MatchedKeyword != "" {
+			initialState["matched_keyword"] = m.MatchedKeyword
 		}
-		
+
 		// Run GraphWalker in a separate goroutine so it doesn't block the webhook response
 		go func(workflowID, contactID int64, state map[string]interface{}) {
 			err := h.GraphWalker.StartWorkflow(context.Background(), workflowID, contactID, state)
-			if err != nil {
+			if errors.Is(err, engine.ErrEntrySkipped) {
+				log.Printf("[Engine] Workflow %d not started for contact %d: %v", workflowID, contactID, err)
+			} else if err != nil {
 				log.Printf("[Engine] Workflow %d execution failed for contact %d: %v", workflowID, contactID, err)
 			}
-		}(w.ID, contact.ID, initialState)
+		}(m.Workflow.ID, contact.ID, initialState)
 	}
 }
 
@@ -507,7 +629,15 @@ func (h *WebhookHandler) checkAutomationTriggers(ctx context.Context, channel *m
 		return
 	}
 
+	cal := engine.TenantCalendar(ctx, h.Store, channel.UserID)
+	open := cal.IsOpen(time.Now())
+
 	for _, automation := range automations {
+		// Automations may be limited to (or kept out of) business hours
+		if (automation.ActiveHours == "open" && !open) || (automation.ActiveHours == "closed" && open) {
+			continue
+		}
+
 		matched := false
 
 		switch automation.TriggerType {
@@ -534,12 +664,17 @@ func (h *WebhookHandler) checkAutomationTriggers(ctx context.Context, channel *m
 			time.Sleep(time.Duration(automation.DelayMs) * time.Millisecond)
 		}
 
+		replyText, err := engine.RenderTemplate(automation.ReplyText, &engine.Variables{Contact: contact, Message: content})
+		if err != nil {
+			log.Printf("[Automation] Invalid reply template in '%s', sending raw text: %v", automation.Name, err)
+		}
+
 		// Send the auto-reply via Meta API
 		result, err := h.MetaClient.SendMessage(
 			contact.Platform,
 			channel.AccountID,
 			contact.PlatformUserID,
-			automation.ReplyText,
+			replyText,
 			channel.AccessToken,
 		)
 		if err != nil {
@@ -559,7 +694,7 @@ func (h *WebhookHandler) checkAutomationTriggers(ctx context.Context, channel *m
 			ContactID:     contact.ID,
 			Platform:      contact.Platform,
 			Direction:     "outbound",
-			Content:       automation.ReplyText,
+			Content:       replyText,
 			MessageType:   "text",
 			PlatformMsgID: result.MessageID,
 			Status:        "sent",
@@ -11,6 +11,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/social-media-lead/backend/internal/api/handlers"
 	"github.com/social-media-lead/backend/internal/config"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/meta"
+	"github.com/social-media-lead/backend/internal/models"
 )
 
 func TestVerifyWebhook(t *testing.T) {
@@ -194,3 +197,46 @@ func TestHandleWebhook(t *testing.T) {
 	})
 }
 
+
+// A reply that resumes a waiting workflow must not start new workflows or the visit flow, but
+// the tenant's legacy auto-replies still answer it
+func TestWebhookReplyToWaitKeepsAutomations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	mockStore.AwaitingReply = []models.WorkflowExecution{{ID: 5, WorkflowID: 2, ContactID: 1, Status: "waiting", WaitingFor: engine.WaitingForReply}}
+	mockStore.Automations = []models.Automation{{ID: 1, UserID: 1, Name: "price", TriggerType: "keyword", Keywords: []string{"price"}, ReplyText: "Prices start at 80L", ActiveHours: "always", IsActive: true}}
+
+	metaClient := meta.NewClient()
+	metaClient.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"message_id": "mid.2"}`)), Header: make(http.Header)}, nil
+	})}
+	handler := &handlers.WebhookHandler{
+		Store:       mockStore,
+		MetaClient:  metaClient,
+		GraphWalker: engine.NewGraphWalker(mockStore, nil, nil, metaClient),
+	}
+
+	r := gin.Default()
+	r.POST("/webhooks/meta", handler.HandleWebhook)
+
+	payload := `{"object": "instagram", "entry": [{"id": "ig_page_123", "messaging": [
+		{"sender": {"id": "ig_user_456"}, "message": {"mid": "mid.1", "text": "what is the price?"}}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/meta", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var msgs []models.Message
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if msgs = mockStore.SentMessages(); len(msgs) >= 2 {
+			break
+		}
+	}
+	if len(msgs) != 2 || msgs[1].Direction != "outbound" || msgs[1].Content != "Prices start at 80L" {
+		t.Fatalf("expected the inbound message and the automation's reply only, got %+v", msgs)
+	}
+}
+
+// roundTripFunc lets tests answer the Meta API without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
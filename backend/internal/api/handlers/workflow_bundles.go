@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+)
+
+// ImportWorkflowRequest is the body of POST /workflows/import
+type ImportWorkflowRequest struct {
+	Bundle *engine.WorkflowBundle `json:"bundle" binding:"required"`
+	Name   string                 `json:"name"` // renames the imported workflow
+}
+
+// InstantiateTemplateRequest is the body of POST /workflow-templates/:id/instantiate
+type InstantiateTemplateRequest struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params"`
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ExportWorkflow downloads a workflow, and the modules it calls, as a portable bundle.
+func (h *WorkflowHandler) ExportWorkflow(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := engine.ExportWorkflow(c.Request.Context(), h.Store, w)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.Trim(unsafeFilenameChars.ReplaceAllString(strings.ToLower(w.Name), "-"), "-")
+	if name == "" {
+		name = "workflow"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.workflow.json"`, name))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportWorkflow creates draft workflows from a bundle exported by this or another tenant.
+// The response lists the new IDs, a validation report per workflow and warnings about secrets,
+// agent teams and knowledge base documents the bundle expects but this tenant lacks.
+func (h *WorkflowHandler) ImportWorkflow(c *gin.Context) {
+	var req ImportWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.importBundle(c, req.Bundle, req.Name)
+}
+
+// ListTemplates returns the starter template catalogue.
+func (h *WorkflowHandler) ListTemplates(c *gin.Context) {
+	templates := engine.StarterTemplates()
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// InstantiateTemplate creates a draft workflow from a starter template and its parameters.
+func (h *WorkflowHandler) InstantiateTemplate(c *gin.Context) {
+	tmpl, ok := engine.FindStarterTemplate(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle, err := tmpl.Instantiate(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.importBundle(c, bundle, req.Name)
+}
+
+func (h *WorkflowHandler) importBundle(c *gin.Context, bundle *engine.WorkflowBundle, name string) {
+	if err := engine.CheckBundle(bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bundle: " + err.Error()})
+		return
+	}
+
+	result, err := engine.ImportWorkflowBundle(c.Request.Context(), h.Store, c.GetInt64("user_id"), bundle, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import workflow: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
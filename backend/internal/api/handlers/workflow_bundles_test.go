@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestWorkflowExportImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	handler := &handlers.WorkflowHandler{Store: mockStore}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		// X-Test-User switches tenants so a bundle can be moved between them
+		if c.GetHeader("X-Test-User") == "2" {
+			c.Set("user_id", int64(2))
+		} else {
+			c.Set("user_id", int64(1))
+		}
+	})
+	r.GET("/api/v1/workflows/:id/export", handler.ExportWorkflow)
+	r.POST("/api/v1/workflows/import", handler.ImportWorkflow)
+	r.GET("/api/v1/workflow-templates", handler.ListTemplates)
+	r.POST("/api/v1/workflow-templates/:id/instantiate", handler.InstantiateTemplate)
+
+	send := func(method, path, user string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	edges, _ := json.Marshal(minimalEdges)
+	mockStore.Workflows[1] = &models.Workflow{
+		ID: 1, UserID: 1, Name: "Qualify", TriggerType: "trigger_workflow_call", Status: "published",
+		Nodes: json.RawMessage(`[{"id":"1","type":"trigger_workflow_call","data":{}},{"id":"2","type":"action_add_tag","data":{"tag":"qualified"}}]`),
+		Edges: edges,
+	}
+	mockStore.Workflows[2] = &models.Workflow{
+		ID: 2, UserID: 1, Name: "Main flow", TriggerType: "trigger_meta_dm", Status: "published",
+		Nodes: json.RawMessage(`[{"id":"1","type":"trigger_meta_dm","data":{}},` +
+			`{"id":"2","type":"action_call_workflow","data":{"workflowId":1}},` +
+			`{"id":"3","type":"action_handoff","data":{"team":"sales","agentId":7}}]`),
+		Edges: json.RawMessage(`[{"id":"e1","source":"1","target":"2"},{"id":"e2","source":"2","target":"3"}]`),
+		Goals: json.RawMessage(`[{"name":"Bought","type":"tag_added","tag":"customer"}]`),
+	}
+
+	w := send(http.MethodGet, "/api/v1/workflows/2/export", "1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="main-flow.workflow.json"`) {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	var bundle engine.WorkflowBundle
+	if err := json.Unmarshal(w.Body.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Format != engine.BundleFormat || bundle.Version != engine.BundleVersion {
+		t.Errorf("unexpected header %s v%d", bundle.Format, bundle.Version)
+	}
+	if len(bundle.Modules) != 1 || bundle.Modules[0].Ref != 1 {
+		t.Fatalf("expected the called module in the bundle, got %+v", bundle.Modules)
+	}
+	if strings.Join(bundle.Tags, ",") != "customer,qualified" || strings.Join(bundle.Teams, ",") != "sales" {
+		t.Errorf("unexpected requirements tags=%v teams=%v", bundle.Tags, bundle.Teams)
+	}
+
+	t.Run("ScheduleTriggerTags", func(t *testing.T) {
+		mockStore.Workflows[3] = &models.Workflow{
+			ID: 3, UserID: 1, Name: "Weekly nudge", TriggerType: "trigger_schedule", Status: "published",
+			Nodes: json.RawMessage(`[{"id":"1","type":"trigger_schedule","data":{"cron":"0 9 * * 1","tags":["vip","site-visit"]}},` +
+				`{"id":"2","type":"action_send_message","data":{"message":"New launches this week"}}]`),
+			Edges: edges,
+		}
+		w := send(http.MethodGet, "/api/v1/workflows/3/export", "1", nil)
+		var b engine.WorkflowBundle
+		if err := json.Unmarshal(w.Body.Bytes(), &b); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("export: %d %s", w.Code, w.Body.String())
+		}
+		if strings.Join(b.Tags, ",") != "site-visit,vip" {
+			t.Errorf("unexpected tags %v", b.Tags)
+		}
+	})
+
+	t.Run("OtherTenantCannotExport", func(t *testing.T) {
+		if w := send(http.MethodGet, "/api/v1/workflows/2/export", "2", nil); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("ImportRemapsIDs", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/v1/workflows/import", "2", map[string]interface{}{"bundle": bundle, "name": "Imported"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("import: %d %s", w.Code, w.Body.String())
+		}
+		var res engine.ImportResult
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Workflow.UserID != 2 || res.Workflow.Status != "draft" || res.Workflow.Name != "Imported" {
+			t.Errorf("unexpected workflow %+v", res.Workflow)
+		}
+		moduleID := res.IDMap[1]
+		if moduleID == 0 || moduleID == 1 || len(res.Modules) != 1 || res.Modules[0].ID != moduleID {
+			t.Fatalf("module not remapped: id_map=%v", res.IDMap)
+		}
+
+		imported := mockStore.Workflows[res.Workflow.ID]
+		graph, err := models.ParseWorkflowGraph(imported.Nodes, imported.Edges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := graph.Nodes[1].DataFloat("workflowId", 0); int64(got) != moduleID {
+			t.Errorf("call points at %v, want %d", got, moduleID)
+		}
+		if _, ok := graph.Nodes[2].Data["agentId"]; ok {
+			t.Error("pinned agent should be dropped on import")
+		}
+
+		warnings := strings.Join(res.Warnings, "\n")
+		for _, want := range []string{"pinned agent", `no active agent in team "sales"`} {
+			if !strings.Contains(warnings, want) {
+				t.Errorf("warnings %q do not mention %q", warnings, want)
+			}
+		}
+	})
+
+	t.Run("RejectsForeignCall", func(t *testing.T) {
+		bad := bundle
+		bad.Modules = nil
+		w := send(http.MethodPost, "/api/v1/workflows/import", "2", map[string]interface{}{"bundle": bad})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "not in the bundle") {
+			t.Errorf("expected 400, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("RejectsNewerVersion", func(t *testing.T) {
+		bad := bundle
+		bad.Version = engine.BundleVersion + 1
+		if w := send(http.MethodPost, "/api/v1/workflows/import", "2", map[string]interface{}{"bundle": bad}); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("StarterTemplates", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/v1/workflow-templates", "1", nil)
+		var list struct {
+			Count int `json:"count"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &list)
+		if w.Code != http.StatusOK || list.Count != len(engine.StarterTemplates()) {
+			t.Fatalf("list: %d %s", w.Code, w.Body.String())
+		}
+
+		w = send(http.MethodPost, "/api/v1/workflow-templates/brochure_follow_up/instantiate", "1", map[string]interface{}{
+			"params": map[string]interface{}{"project_name": "Skyline Towers", "brochure_url": "https://example.com/skyline.pdf"},
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("instantiate: %d %s", w.Code, w.Body.String())
+		}
+		var res engine.ImportResult
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Workflow.Status != "draft" || !strings.Contains(string(res.Workflow.Nodes), "Skyline Towers") {
+			t.Errorf("parameters not filled in: %s", res.Workflow.Nodes)
+		}
+
+		w = send(http.MethodPost, "/api/v1/workflow-templates/brochure_follow_up/instantiate", "1", map[string]interface{}{
+			"params": map[string]interface{}{"project_name": "Skyline Towers"},
+		})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("missing required param: expected 400, got %d", w.Code)
+		}
+		if w := send(http.MethodPost, "/api/v1/workflow-templates/nope/instantiate", "1", map[string]interface{}{}); w.Code != http.StatusNotFound {
+			t.Errorf("unknown template: expected 404, got %d", w.Code)
+		}
+	})
+}
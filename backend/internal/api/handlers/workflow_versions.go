@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// snapshotVersion records the workflow's current graph as a new immutable version, unless it
+// behaves exactly like the latest version (e.g. only the name or node positions changed).
+func (h *WorkflowHandler) snapshotVersion(ctx context.Context, w *models.Workflow) (*models.WorkflowVersion, error) {
+	graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+	if err != nil {
+		return nil, err
+	}
+	if latest, err := h.Store.GetLatestWorkflowVersion(ctx, w.ID); err == nil {
+		prev, err := models.ParseWorkflowGraph(latest.Nodes, latest.Edges)
+		if err == nil && engine.DiffGraphs(prev, graph).Empty() {
+			return latest, nil
+		}
+	}
+
+	v := &models.WorkflowVersion{WorkflowID: w.ID, Nodes: w.Nodes, Edges: w.Edges}
+	if err := h.Store.CreateWorkflowVersion(ctx, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ownedWorkflow loads the :id workflow and checks it belongs to the current user,
+// writing the error response itself when it does not.
+func (h *WorkflowHandler) ownedWorkflow(c *gin.Context) (*models.Workflow, bool) {
+	workflowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+		return nil, false
+	}
+
+	w, err := h.Store.GetWorkflowByID(c.Request.Context(), workflowID)
+	if err != nil || w.UserID != c.GetInt64("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return nil, false
+	}
+	return w, true
+}
+
+// loadVersion resolves a version number of workflow w, writing the error response on failure
+func (h *WorkflowHandler) loadVersion(c *gin.Context, w *models.Workflow, raw string) (*models.WorkflowVersion, bool) {
+	number, err := strconv.Atoi(raw)
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version number"})
+		return nil, false
+	}
+
+	v, err := h.Store.GetWorkflowVersion(c.Request.Context(), w.ID, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return nil, false
+	}
+	return v, true
+}
+
+// ListVersions returns the versions of a workflow, AI drafts included, newest first.
+func (h *WorkflowHandler) ListVersions(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.Store.ListWorkflowVersions(c.Request.Context(), w.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// GetVersion returns a single version including its graph.
+func (h *WorkflowHandler) GetVersion(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	v, ok := h.loadVersion(c, w, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// DiffVersions compares two versions: GET /workflows/:id/diff?from=1&to=2
+func (h *WorkflowHandler) DiffVersions(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	from, ok := h.loadVersion(c, w, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := h.loadVersion(c, w, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	fromGraph, err := models.ParseWorkflowGraph(from.Nodes, from.Edges)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse version graph"})
+		return
+	}
+	toGraph, err := models.ParseWorkflowGraph(to.Nodes, to.Edges)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse version graph"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from.Version,
+		"to":   to.Version,
+		"diff": engine.DiffGraphs(fromGraph, toGraph),
+	})
+}
+
+// RollbackWorkflow republishes an earlier version, or publishes a draft of the AI editor. History
+// stays immutable: the old graph is copied into a new version, and executions already running
+// keep their own snapshot.
+func (h *WorkflowHandler) RollbackWorkflow(c *gin.Context) {
+	w, ok := h.ownedWorkflow(c)
+	if !ok {
+		return
+	}
+
+	target, ok := h.loadVersion(c, w, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	report, err := engine.ValidateWorkflow(target.Nodes, target.Edges)
+	if err != nil || !report.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Version can no longer be published", "validation": report})
+		return
+	}
+	if err := engine.CheckWorkflowCalls(c.Request.Context(), h.Store, w.UserID, w.ID, target.Nodes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Version can no longer be published: " + err.Error()})
+		return
+	}
+
+	w.Nodes = target.Nodes
+	w.Edges = target.Edges
+	w.TriggerType = engine.GraphTriggerType(target.Nodes, w.TriggerType)
+	w.Status = "published"
+	v := &models.WorkflowVersion{WorkflowID: w.ID, Nodes: target.Nodes, Edges: target.Edges}
+	if err := h.Store.PublishWorkflowVersion(c.Request.Context(), w, v); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish workflow version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflow":       w,
+		"version":        v.Version,
+		"rolled_back_to": target.Version,
+	})
+}
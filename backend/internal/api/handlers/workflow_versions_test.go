@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/api/handlers"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestWorkflowVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStore := NewMockStore()
+	handler := &handlers.WorkflowHandler{Store: mockStore}
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+	})
+	r.POST("/api/v1/workflows", handler.CreateWorkflow)
+	r.PUT("/api/v1/workflows/:id", handler.UpdateWorkflow)
+	r.GET("/api/v1/workflows/:id/versions", handler.ListVersions)
+	r.GET("/api/v1/workflows/:id/diff", handler.DiffVersions)
+	r.POST("/api/v1/workflows/:id/versions/:version/rollback", handler.RollbackWorkflow)
+
+	send := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	workflow := func(message string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":         "Versioned",
+			"trigger_type": "trigger_meta_dm",
+			"status":       "published",
+			"nodes": []interface{}{
+				map[string]interface{}{"id": "1", "type": "trigger_meta_dm", "data": map[string]interface{}{}},
+				map[string]interface{}{"id": "2", "type": "action_send_message", "data": map[string]interface{}{"message": message}},
+			},
+			"edges": minimalEdges,
+		}
+	}
+
+	if w := send(http.MethodPost, "/api/v1/workflows", workflow("v1")); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	// Re-publishing an identical graph does not create a version
+	send(http.MethodPut, "/api/v1/workflows/1", workflow("v1"))
+	send(http.MethodPut, "/api/v1/workflows/1", workflow("v2"))
+	if len(mockStore.Versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(mockStore.Versions))
+	}
+
+	t.Run("Diff", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/v1/workflows/1/diff?from=1&to=2", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var resp struct {
+			Diff struct {
+				ChangedNodes []struct {
+					NodeID string `json:"node_id"`
+				} `json:"changed_nodes"`
+			} `json:"diff"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if len(resp.Diff.ChangedNodes) != 1 || resp.Diff.ChangedNodes[0].NodeID != "2" {
+			t.Errorf("unexpected diff %s", w.Body.String())
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/v1/workflows/1/versions/1/rollback", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+		}
+		if len(mockStore.Versions) != 3 || string(mockStore.Versions[2].Nodes) != string(mockStore.Versions[0].Nodes) {
+			t.Errorf("expected rollback to copy version 1 into version 3")
+		}
+		if string(mockStore.Workflows[1].Nodes) != string(mockStore.Versions[0].Nodes) {
+			t.Errorf("expected live graph to be restored")
+		}
+	})
+
+	t.Run("Rollback checks workflow calls", func(t *testing.T) {
+		// A draft that calls another tenant's workflow must not be published
+		mockStore.Workflows[99] = &models.Workflow{ID: 99, UserID: 2, Name: "Foreign", Status: "published"}
+		draft := &models.WorkflowVersion{
+			WorkflowID: 1, Draft: true,
+			Nodes: json.RawMessage(`[{"id":"1","type":"trigger_meta_dm","data":{}},{"id":"2","type":"action_call_workflow","data":{"workflowId":99}}]`),
+			Edges: json.RawMessage(`[{"id":"e1","source":"1","target":"2"}]`),
+		}
+		_ = mockStore.CreateWorkflowVersion(context.Background(), draft)
+		live := string(mockStore.Workflows[1].Nodes)
+
+		w := send(http.MethodPost, fmt.Sprintf("/api/v1/workflows/1/versions/%d/rollback", draft.Version), nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d %s", w.Code, w.Body.String())
+		}
+		if string(mockStore.Workflows[1].Nodes) != live || len(mockStore.Versions) != draft.Version {
+			t.Errorf("rejected rollback changed the workflow")
+		}
+	})
+
+	t.Run("Unknown Version", func(t *testing.T) {
+		if w := send(http.MethodPost, "/api/v1/workflows/1/versions/9/rollback", nil); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+}
@@ -6,12 +6,14 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/social-media-lead/backend/internal/engine"
 	"github.com/social-media-lead/backend/internal/models"
 	"github.com/social-media-lead/backend/internal/store"
 )
 
 type WorkflowHandler struct {
-	Store store.Store
+	Store       store.Store
+	GraphWalker *engine.GraphWalker
 }
 
 // CreateWorkflowRequest holds data for creating/updating a workflow
@@ -22,6 +24,60 @@ type CreateWorkflowRequest struct {
 	Prompt      string          `json:"prompt"`
 	Nodes       json.RawMessage `json:"nodes" binding:"required"`
 	Edges       json.RawMessage `json:"edges" binding:"required"`
+	Goals       json.RawMessage `json:"goals"`
+	// ReentryPolicy and CooldownMinutes are kept as they are on update when omitted
+	ReentryPolicy   string `json:"reentry_policy"`
+	CooldownMinutes *int   `json:"cooldown_minutes"`
+}
+
+// ValidateWorkflowRequest is the body of POST /workflows/validate
+type ValidateWorkflowRequest struct {
+	Nodes json.RawMessage `json:"nodes" binding:"required"`
+	Edges json.RawMessage `json:"edges" binding:"required"`
+}
+
+// checkGraph validates the submitted graph and goals and writes a 400 response when they cannot
+// be saved. Drafts may be saved with validation errors so work in progress is never lost;
+// publishing requires a clean report (POST /workflows/validate returns the full report for the
+// builder). Goals are always checked, they are small and apply to running executions at once.
+func checkGraph(c *gin.Context, req *CreateWorkflowRequest) bool {
+	if _, err := engine.ParseGoals(req.Goals); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goals: " + err.Error()})
+		return false
+	}
+	if !engine.IsValidReentryPolicy(req.ReentryPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reentry_policy: must be parallel, skip, restart or queue"})
+		return false
+	}
+	if req.CooldownMinutes != nil && *req.CooldownMinutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cooldown_minutes must not be negative"})
+		return false
+	}
+
+	report, err := engine.ValidateWorkflow(req.Nodes, req.Edges)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if req.Status == "published" && !report.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workflow cannot be published: " + report.Err().Error(), "validation": report})
+		return false
+	}
+	return true
+}
+
+// checkCalls follows the graph's action_call_workflow nodes through the user's other workflows
+// before publishing, writing a 400 response for cross-tenant calls, call cycles and chains nested
+// too deeply. workflowID is 0 for a new workflow.
+func (h *WorkflowHandler) checkCalls(c *gin.Context, workflowID int64, req *CreateWorkflowRequest) bool {
+	if req.Status != "published" {
+		return true
+	}
+	if err := engine.CheckWorkflowCalls(c.Request.Context(), h.Store, c.GetInt64("user_id"), workflowID, req.Nodes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workflow cannot be published: " + err.Error()})
+		return false
+	}
+	return true
 }
 
 func (h *WorkflowHandler) ListWorkflows(c *gin.Context) {
@@ -67,14 +123,23 @@ func (h *WorkflowHandler) CreateWorkflow(c *gin.Context) {
 		return
 	}
 
+	if !checkGraph(c, &req) || !h.checkCalls(c, 0, &req) {
+		return
+	}
+
 	w := &models.Workflow{
-		UserID:      userID,
-		Name:        req.Name,
-		TriggerType: req.TriggerType,
-		Status:      req.Status,
-		Prompt:      req.Prompt,
-		Nodes:       []byte(req.Nodes),
-		Edges:       []byte(req.Edges),
+		UserID:        userID,
+		Name:          req.Name,
+		TriggerType:   engine.GraphTriggerType(req.Nodes, req.TriggerType),
+		Status:        req.Status,
+		Prompt:        req.Prompt,
+		Nodes:         []byte(req.Nodes),
+		Edges:         []byte(req.Edges),
+		Goals:         req.Goals,
+		ReentryPolicy: req.ReentryPolicy,
+	}
+	if req.CooldownMinutes != nil {
+		w.CooldownMinutes = *req.CooldownMinutes
 	}
 
 	if err := h.Store.CreateWorkflow(c.Request.Context(), w); err != nil {
@@ -82,6 +147,13 @@ func (h *WorkflowHandler) CreateWorkflow(c *gin.Context) {
 		return
 	}
 
+	if w.Status == "published" {
+		if _, err := h.snapshotVersion(c.Request.Context(), w); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish workflow version"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusCreated, w)
 }
 
@@ -99,27 +171,68 @@ func (h *WorkflowHandler) UpdateWorkflow(c *gin.Context) {
 		return
 	}
 
+	if !checkGraph(c, &req) {
+		return
+	}
+
 	// Verify ownership before update
 	existing, err := h.Store.GetWorkflowByID(c.Request.Context(), workflowID)
 	if err != nil || existing.UserID != userID {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
 		return
 	}
+	if !h.checkCalls(c, existing.ID, &req) {
+		return
+	}
 
 	existing.Name = req.Name
+	existing.TriggerType = engine.GraphTriggerType(req.Nodes, req.TriggerType)
 	existing.Status = req.Status
 	existing.Prompt = req.Prompt
 	existing.Nodes = []byte(req.Nodes)
 	existing.Edges = []byte(req.Edges)
+	if req.Goals != nil {
+		existing.Goals = req.Goals
+	}
+	if req.ReentryPolicy != "" {
+		existing.ReentryPolicy = req.ReentryPolicy
+	}
+	if req.CooldownMinutes != nil {
+		existing.CooldownMinutes = *req.CooldownMinutes
+	}
 
 	if err := h.Store.UpdateWorkflow(c.Request.Context(), existing); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow"})
 		return
 	}
 
+	if existing.Status == "published" {
+		if _, err := h.snapshotVersion(c.Request.Context(), existing); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish workflow version"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, existing)
 }
 
+// ValidateWorkflow reports errors and warnings for a graph without saving it
+func (h *WorkflowHandler) ValidateWorkflow(c *gin.Context) {
+	var req ValidateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := engine.ValidateWorkflow(req.Nodes, req.Edges)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 func (h *WorkflowHandler) DeleteWorkflow(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 	workflowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
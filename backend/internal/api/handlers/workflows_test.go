@@ -12,6 +12,17 @@ import (
 	"github.com/social-media-lead/backend/internal/models"
 )
 
+// minimalNodes/minimalEdges form the smallest graph that passes publish validation
+var (
+	minimalNodes = []interface{}{
+		map[string]interface{}{"id": "1", "type": "trigger_meta_dm", "data": map[string]interface{}{}},
+		map[string]interface{}{"id": "2", "type": "action_send_message", "data": map[string]interface{}{"message": "Hi {{contact.name}}"}},
+	}
+	minimalEdges = []interface{}{
+		map[string]interface{}{"id": "e1", "source": "1", "target": "2"},
+	}
+)
+
 func TestWorkflowHandlers(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockStore := NewMockStore()
@@ -30,6 +41,7 @@ func TestWorkflowHandlers(t *testing.T) {
 	r.POST("/api/v1/workflows", handler.CreateWorkflow)
 	r.PUT("/api/v1/workflows/:id", handler.UpdateWorkflow)
 	r.DELETE("/api/v1/workflows/:id", handler.DeleteWorkflow)
+	r.POST("/api/v1/workflows/validate", handler.ValidateWorkflow)
 
 	t.Run("Create Workflow", func(t *testing.T) {
 		payload := map[string]interface{}{
@@ -37,6 +49,89 @@ func TestWorkflowHandlers(t *testing.T) {
 			"trigger_type": "trigger_meta_dm",
 			"status":       "published",
 			"prompt":       "Respond nicely",
+			"nodes":        minimalNodes,
+			"edges":        minimalEdges,
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected 201 Created, got %v", w.Code)
+		}
+	})
+
+	t.Run("Create Workflow Rejects Invalid Condition", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"name":         "Broken Condition",
+			"trigger_type": "trigger_meta_dm",
+			"status":       "published",
+			"nodes": []interface{}{
+				map[string]interface{}{"id": "1", "type": "trigger_meta_dm", "data": map[string]interface{}{}},
+				map[string]interface{}{"id": "2", "type": "logic_condition", "data": map[string]interface{}{
+					"condition": map[string]interface{}{"field": "contact.salary", "operator": "gt", "value": 10},
+				}},
+			},
+			"edges": []interface{}{},
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 Bad Request, got %v", w.Code)
+		}
+	})
+
+	t.Run("Create Workflow Rejects Invalid Goals", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"name":         "Nurture",
+			"trigger_type": "trigger_meta_dm",
+			"status":       "draft",
+			"nodes":        minimalNodes,
+			"edges":        minimalEdges,
+			"goals":        []interface{}{map[string]interface{}{"type": "tag_added"}},
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 Bad Request, got %v", w.Code)
+		}
+	})
+
+	t.Run("Create Workflow Rejects Unknown Reentry Policy", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"name":           "Nurture",
+			"trigger_type":   "trigger_meta_dm",
+			"status":         "draft",
+			"nodes":          minimalNodes,
+			"edges":          minimalEdges,
+			"reentry_policy": "sometimes",
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 Bad Request, got %v", w.Code)
+		}
+	})
+
+	t.Run("Draft With Errors Is Saved", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"name":         "Work In Progress",
+			"trigger_type": "trigger_meta_dm",
+			"status":       "draft",
 			"nodes":        []interface{}{},
 			"edges":        []interface{}{},
 		}
@@ -51,6 +146,36 @@ func TestWorkflowHandlers(t *testing.T) {
 		}
 	})
 
+	t.Run("Validate Workflow Reports Cycle", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"nodes": append(append([]interface{}{}, minimalNodes...),
+				map[string]interface{}{"id": "3", "type": "action_send_message", "data": map[string]interface{}{"message": "again"}},
+			),
+			"edges": append(append([]interface{}{}, minimalEdges...),
+				map[string]interface{}{"id": "e2", "source": "2", "target": "3"},
+				map[string]interface{}{"id": "e3", "source": "3", "target": "2"},
+			),
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows/validate", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 OK, got %v", w.Code)
+		}
+		var report struct {
+			Errors []struct {
+				NodeID string `json:"node_id"`
+			} `json:"errors"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &report)
+		if len(report.Errors) != 1 || report.Errors[0].NodeID != "2" {
+			t.Errorf("expected one cycle error on node 2, got %s", w.Body.String())
+		}
+	})
+
 	t.Run("List Workflows", func(t *testing.T) {
 		// Populate mock
 		mockStore.Workflows[2] = &models.Workflow{ID: 2, UserID: 1, Name: "Test Flow"}
@@ -84,8 +209,8 @@ func TestWorkflowHandlers(t *testing.T) {
 			"trigger_type": "trigger_meta_dm",
 			"status":       "published",
 			"prompt":       "Respond nicely",
-			"nodes":        []interface{}{},
-			"edges":        []interface{}{},
+			"nodes":        minimalNodes,
+			"edges":        minimalEdges,
 		}
 		body, _ := json.Marshal(payload)
 		req := httptest.NewRequest(http.MethodPut, "/api/v1/workflows/4", bytes.NewBuffer(body))
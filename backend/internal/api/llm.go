@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/config"
+)
+
+// newLLMClient builds the providers enabled in the configuration and routes between them
+func newLLMClient(cfg *config.Config) (*ai.Router, error) {
+	llm := cfg.LLM
+	model := func(provider string) string {
+		if provider == llm.Provider {
+			return llm.Model
+		}
+		return ""
+	}
+
+	providers := map[string]ai.LLMClient{
+		ai.ProviderOpenAI: ai.NewOpenAIClient(cfg.OpenAI.APIKey, model(ai.ProviderOpenAI)),
+	}
+	if llm.AnthropicAPIKey != "" {
+		providers[ai.ProviderAnthropic] = ai.NewAnthropicClient(llm.AnthropicAPIKey, model(ai.ProviderAnthropic))
+	}
+	if llm.GeminiAPIKey != "" {
+		providers[ai.ProviderGemini] = ai.NewGeminiClient(llm.GeminiAPIKey, model(ai.ProviderGemini))
+	}
+	if llm.CompatibleBaseURL != "" {
+		compatibleModel := llm.CompatibleModel
+		if m := model(ai.ProviderOpenAICompatible); m != "" {
+			compatibleModel = m
+		}
+		providers[ai.ProviderOpenAICompatible] = ai.NewOpenAICompatibleClient(
+			llm.CompatibleBaseURL, llm.CompatibleAPIKey, compatibleModel, llm.CompatibleEmbeddingModel)
+	}
+
+	tenants := make(map[int64]ai.Selection, len(llm.TenantProviders))
+	for userID, value := range llm.TenantProviders {
+		tenants[userID] = ai.ParseSelection(value)
+	}
+
+	return ai.NewRouter(ai.RouterConfig{
+		Providers:          providers,
+		DefaultProvider:    llm.Provider,
+		FallbackProviders:  llm.FallbackProviders,
+		EmbeddingsProvider: llm.EmbeddingsProvider,
+		Tenants:            tenants,
+	})
+}
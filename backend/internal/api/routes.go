@@ -1,6 +1,9 @@
 package api
 
 import (
+	"log"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
 	"github.com/social-media-lead/backend/internal/ai"
@@ -57,8 +60,22 @@ func SetupRouter(cfg *config.Config, storage store.Store, redisClient *cache.Red
 	tokenRefresher := meta.NewTokenRefresher(cfg.Meta.AppID, cfg.Meta.AppSecret, redisClient)
 
 	// AI Orchestrator Client & DAG Engine
-	llmClient := ai.NewOpenAIClient(cfg.OpenAI.APIKey, "")
+	llmRouter, err := newLLMClient(cfg)
+	if err != nil {
+		log.Fatalf("Invalid LLM configuration: %v", err)
+	}
+	llmClient := ai.NewMeteredClient(llmRouter, storage, ai.Budgets{PerPlan: cfg.LLM.PlanBudgets, WarnPercent: cfg.LLM.BudgetWarnPercent})
 	graphWalker := engine.NewGraphWalker(storage, llmClient, asynqClient, metaClient)
+	httpClient, err := engine.NewSafeHTTPClient(cfg.HTTPNode.AllowedPrivateCIDRs)
+	if err != nil {
+		log.Fatalf("Invalid HTTP node configuration: %v", err)
+	}
+	graphWalker.HTTPClient = httpClient
+	graphWalker.HTTPRateLimit = cfg.HTTPNode.RateLimitPerMinute
+	graphWalker.LeadExtractionDelay = time.Duration(cfg.LLM.LeadExtractionDelay) * time.Second
+	if redisClient != nil {
+		graphWalker.RateLimiter = redisClient
+	}
 
 	// Initialize handlers
 	authHandler := &handlers.AuthHandler{Store: storage, JWTSecret: cfg.JWT.Secret}
@@ -68,13 +85,19 @@ func SetupRouter(cfg *config.Config, storage store.Store, redisClient *cache.Red
 		cfg.FrontendURL,
 	)
 	webhookHandler := &handlers.WebhookHandler{Store: storage, Config: cfg, MetaClient: metaClient, GraphWalker: graphWalker, Cache: redisClient}
-	inboxHandler := &handlers.InboxHandler{Store: storage, MetaClient: metaClient}
+	inboxHandler := &handlers.InboxHandler{Store: storage, MetaClient: metaClient, GraphWalker: graphWalker}
 	automationHandler := &handlers.AutomationHandler{Store: storage}
 	channelHandler := &handlers.ChannelHandler{Store: storage, TokenRefresher: tokenRefresher}
 	broadcastHandler := &handlers.BroadcastHandler{Store: storage, MetaClient: metaClient, Redis: redisClient}
-	workflowHandler := &handlers.WorkflowHandler{Store: storage}
-	aiHandler := &handlers.AIHandler{LLMClient: llmClient}
+	workflowHandler := &handlers.WorkflowHandler{Store: storage, GraphWalker: graphWalker}
+	secretHandler := &handlers.SecretHandler{Store: storage}
+	executionHandler := &handlers.ExecutionHandler{Store: storage, GraphWalker: graphWalker}
+	aiHandler := &handlers.AIHandler{LLMClient: llmClient, Meter: llmClient, Store: storage}
 	propertyVisitHandler := &handlers.PropertyVisitHandler{Store: storage, Cache: redisClient}
+	businessHoursHandler := &handlers.BusinessHoursHandler{Store: storage}
+	visitHandler := &handlers.VisitHandler{Store: storage, GraphWalker: graphWalker}
+	agentHandler := &handlers.AgentHandler{Store: storage}
+	handoffHandler := &handlers.HandoffHandler{Store: storage}
 
 	// --- Public Routes ---
 	v1 := r.Group("/api/v1")
@@ -120,6 +143,25 @@ func SetupRouter(cfg *config.Config, storage store.Store, redisClient *cache.Red
 			inbox.GET("/messages/:contact_id", inboxHandler.GetMessages)
 			inbox.POST("/messages/:contact_id", inboxHandler.SendMessage)
 			inbox.GET("/contacts", inboxHandler.GetContacts)
+			inbox.PUT("/contacts/:contact_id/bot", inboxHandler.SetBotPaused)
+			inbox.PATCH("/contacts/:contact_id", inboxHandler.UpdateContact)
+			inbox.GET("/contacts/:contact_id/field-sources", inboxHandler.GetFieldSources)
+			inbox.GET("/contacts/:contact_id/notes", inboxHandler.GetNotes)
+			inbox.POST("/contacts/:contact_id/notes", inboxHandler.AddNote)
+		}
+
+		// Human agents and the conversations workflows hand off to them
+		agents := protected.Group("/agents")
+		{
+			agents.GET("", agentHandler.ListAgents)
+			agents.POST("", agentHandler.CreateAgent)
+			agents.PUT("/:id", agentHandler.UpdateAgent)
+			agents.DELETE("/:id", agentHandler.DeleteAgent)
+		}
+		handoffs := protected.Group("/handoffs")
+		{
+			handoffs.GET("", handoffHandler.ListHandoffs)
+			handoffs.POST("/:id/resolve", handoffHandler.ResolveHandoff)
 		}
 
 		// Automations
@@ -143,10 +185,61 @@ func SetupRouter(cfg *config.Config, storage store.Store, redisClient *cache.Red
 		{
 			workflows.GET("", workflowHandler.ListWorkflows)
 			workflows.POST("", workflowHandler.CreateWorkflow)
+			workflows.POST("/validate", workflowHandler.ValidateWorkflow)
+			workflows.POST("/import", workflowHandler.ImportWorkflow)
 			workflows.GET("/:id", workflowHandler.GetWorkflow)
 			workflows.PUT("/:id", workflowHandler.UpdateWorkflow)
 			workflows.DELETE("/:id", workflowHandler.DeleteWorkflow)
+			workflows.GET("/:id/versions", workflowHandler.ListVersions)
+			workflows.GET("/:id/versions/:version", workflowHandler.GetVersion)
+			workflows.POST("/:id/versions/:version/rollback", workflowHandler.RollbackWorkflow)
+			workflows.GET("/:id/diff", workflowHandler.DiffVersions)
+			workflows.GET("/:id/split-report", workflowHandler.SplitReport)
+			workflows.GET("/:id/export", workflowHandler.ExportWorkflow)
+			workflows.GET("/:id/executions", executionHandler.ListExecutions)
+			workflows.POST("/:id/simulate", workflowHandler.SimulateWorkflow)
 			workflows.POST("/generate", aiHandler.GenerateWorkflow)
+			workflows.POST("/:id/ai-edit", aiHandler.EditWorkflow)
+		}
+
+		// LLM usage and cost per workflow, node, model, feature or day, with the monthly budget
+		protected.GET("/ai/usage", aiHandler.GetUsage)
+
+		// Built-in starter workflows, instantiated as drafts
+		templates := protected.Group("/workflow-templates")
+		{
+			templates.GET("", workflowHandler.ListTemplates)
+			templates.POST("/:id/instantiate", workflowHandler.InstantiateTemplate)
+		}
+
+		// Workflow executions and their step timelines
+		executions := protected.Group("/executions")
+		{
+			executions.GET("", executionHandler.ListExecutions)
+			executions.GET("/:id", executionHandler.GetExecution)
+			executions.POST("/:id/cancel", executionHandler.CancelExecution)
+			executions.POST("/:id/pause", executionHandler.PauseExecution)
+			executions.POST("/:id/resume", executionHandler.ResumeExecution)
+			executions.POST("/bulk", executionHandler.BulkControlExecutions)
+		}
+
+		// Tenant secrets referenced by HTTP request nodes as {{secret.NAME}}
+		secrets := protected.Group("/secrets")
+		{
+			secrets.GET("", secretHandler.ListSecrets)
+			secrets.PUT("/:name", secretHandler.PutSecret)
+			secrets.DELETE("/:name", secretHandler.DeleteSecret)
+		}
+
+		// Tenant timezone, weekly business hours and holidays
+		protected.GET("/business-hours", businessHoursHandler.GetBusinessHours)
+		protected.PUT("/business-hours", businessHoursHandler.PutBusinessHours)
+
+		// Booked property visits
+		visits := protected.Group("/visits")
+		{
+			visits.GET("", visitHandler.ListVisits)
+			visits.PUT("/:id/status", visitHandler.UpdateVisitStatus)
 		}
 
 		// Property Visit System (Wizard Activation)
@@ -0,0 +1,189 @@
+// Package calendar answers "are we open?" for a tenant's business hours: weekly opening
+// intervals and holidays in the tenant's timezone.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // tenant timezones must resolve on hosts without a zoneinfo database
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// DateLayout is the format of holiday dates
+const DateLayout = "2006-01-02"
+
+// maxSearchDays bounds the search for the next open time
+const maxSearchDays = 370
+
+// dayKeys maps time.Weekday to the keys of BusinessCalendar.WeeklyHours
+var dayKeys = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// span is an opening interval in minutes since midnight, [open, close)
+type span struct{ open, close int }
+
+// Calendar is a compiled BusinessCalendar
+type Calendar struct {
+	loc      *time.Location
+	always   bool
+	week     [7][]span
+	holidays map[string]bool
+}
+
+// AlwaysOpen is the calendar of tenants that have not set up business hours
+func AlwaysOpen() *Calendar {
+	return &Calendar{loc: time.UTC, always: true}
+}
+
+// New compiles a tenant's calendar. A nil calendar, or one without any opening hours, is
+// always open.
+func New(cfg *models.BusinessCalendar) (*Calendar, error) {
+	if cfg == nil {
+		return AlwaysOpen(), nil
+	}
+	tz := cfg.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	c := &Calendar{loc: loc, holidays: make(map[string]bool, len(cfg.Holidays))}
+
+	open := false
+	for key, intervals := range cfg.WeeklyHours {
+		day := dayIndex(key)
+		if day < 0 {
+			return nil, fmt.Errorf("unknown day %q (use mon, tue, wed, thu, fri, sat or sun)", key)
+		}
+		for _, iv := range intervals {
+			from, err := parseClock(iv.Open)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			to, err := parseClock(iv.Close)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if to <= from {
+				return nil, fmt.Errorf("%s: %s-%s closes before it opens", key, iv.Open, iv.Close)
+			}
+			c.week[day] = append(c.week[day], span{from, to})
+			open = true
+		}
+		sort.Slice(c.week[day], func(i, j int) bool { return c.week[day][i].open < c.week[day][j].open })
+	}
+	for _, d := range cfg.Holidays {
+		if _, err := time.Parse(DateLayout, d); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q (use YYYY-MM-DD)", d)
+		}
+		c.holidays[d] = true
+	}
+	c.always = !open
+	return c, nil
+}
+
+// Location is the tenant's timezone
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// IsOpen reports whether t falls within business hours
+func (c *Calendar) IsOpen(t time.Time) bool {
+	if c.always {
+		return true
+	}
+	t = t.In(c.loc)
+	if c.holidays[t.Format(DateLayout)] {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, s := range c.week[t.Weekday()] {
+		if minute >= s.open && minute < s.close {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns t if business is open then, otherwise the start of the next opening
+// interval. It returns false if the calendar never opens within a year.
+func (c *Calendar) NextOpen(t time.Time) (time.Time, bool) {
+	if c.IsOpen(t) {
+		return t, true
+	}
+	local := t.In(c.loc)
+	for i := 0; i < maxSearchDays; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, c.loc)
+		if c.holidays[day.Format(DateLayout)] {
+			continue
+		}
+		for _, s := range c.week[day.Weekday()] {
+			start := c.at(day, s.open)
+			if start.After(t) {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextDaySlots returns the given wall-clock times ("15:04") on the first day after `after` on
+// which at least one of them is within business hours, leaving out those that are not.
+func (c *Calendar) NextDaySlots(after time.Time, times []string) []time.Time {
+	local := after.In(c.loc)
+	for i := 1; i <= maxSearchDays; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, c.loc)
+		var slots []time.Time
+		for _, hhmm := range times {
+			minute, err := parseClock(hhmm)
+			if err != nil {
+				continue
+			}
+			slot := c.at(day, minute)
+			if c.IsOpen(slot) {
+				slots = append(slots, slot)
+			}
+		}
+		if len(slots) > 0 {
+			return slots
+		}
+	}
+	return nil
+}
+
+// Validate checks a calendar submitted over the API
+func Validate(cfg *models.BusinessCalendar) error {
+	_, err := New(cfg)
+	return err
+}
+
+// at returns the wall-clock minute of a day, so DST changes do not shift opening times
+func (c *Calendar) at(day time.Time, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, c.loc)
+}
+
+func dayIndex(key string) int {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for i, k := range dayKeys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseClock parses "15:04" (or "24:00") into minutes since midnight
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,104 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/calendar"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func weekdays(open, close string) map[string][]models.BusinessHours {
+	hours := make(map[string][]models.BusinessHours)
+	for _, d := range []string{"mon", "tue", "wed", "thu", "fri"} {
+		hours[d] = []models.BusinessHours{{Open: open, Close: close}}
+	}
+	return hours
+}
+
+func TestCalendar(t *testing.T) {
+	cal, err := calendar.New(&models.BusinessCalendar{
+		Timezone:    "Asia/Kolkata",
+		WeeklyHours: weekdays("09:00", "18:00"),
+		Holidays:    []string{"2026-10-20"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ist := cal.Location()
+
+	cases := []struct {
+		name     string
+		at       time.Time
+		open     bool
+		nextOpen time.Time
+	}{
+		{"Monday morning", time.Date(2026, 10, 19, 10, 0, 0, 0, ist), true, time.Date(2026, 10, 19, 10, 0, 0, 0, ist)},
+		{"Monday 2 AM", time.Date(2026, 10, 19, 2, 0, 0, 0, ist), false, time.Date(2026, 10, 19, 9, 0, 0, 0, ist)},
+		{"Closing time", time.Date(2026, 10, 19, 18, 0, 0, 0, ist), false, time.Date(2026, 10, 21, 9, 0, 0, 0, ist)}, // Tuesday is a holiday
+		{"Saturday", time.Date(2026, 10, 24, 12, 0, 0, 0, ist), false, time.Date(2026, 10, 26, 9, 0, 0, 0, ist)},
+		{"Same instant in UTC", time.Date(2026, 10, 19, 4, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 19, 9, 30, 0, 0, ist)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cal.IsOpen(tc.at); got != tc.open {
+				t.Errorf("IsOpen = %v, want %v", got, tc.open)
+			}
+			next, ok := cal.NextOpen(tc.at)
+			if !ok || !next.Equal(tc.nextOpen) {
+				t.Errorf("NextOpen = %v, want %v", next, tc.nextOpen)
+			}
+		})
+	}
+}
+
+func TestNextDaySlots(t *testing.T) {
+	cal, err := calendar.New(&models.BusinessCalendar{
+		Timezone:    "UTC",
+		WeeklyHours: map[string][]models.BusinessHours{"sat": {{Open: "09:00", Close: "13:00"}}, "mon": {{Open: "09:00", Close: "18:00"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// From Friday the next open day is Saturday, where only the morning slot fits
+	slots := cal.NextDaySlots(time.Date(2026, 10, 23, 15, 0, 0, 0, time.UTC), []string{"10:00", "14:00", "16:00"})
+	if len(slots) != 1 || !slots[0].Equal(time.Date(2026, 10, 24, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected Saturday slots %v", slots)
+	}
+
+	// From Saturday, Sunday is closed and Monday offers all three
+	slots = cal.NextDaySlots(time.Date(2026, 10, 24, 15, 0, 0, 0, time.UTC), []string{"10:00", "14:00", "16:00"})
+	if len(slots) != 3 || slots[0].Weekday() != time.Monday {
+		t.Errorf("unexpected Monday slots %v", slots)
+	}
+}
+
+func TestAlwaysOpen(t *testing.T) {
+	cal, err := calendar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if next, ok := cal.NextOpen(now); !cal.IsOpen(now) || !ok || !next.Equal(now) {
+		t.Error("a tenant without business hours should always be open")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []*models.BusinessCalendar{
+		{Timezone: "Mars/Olympus"},
+		{WeeklyHours: map[string][]models.BusinessHours{"funday": {{Open: "09:00", Close: "10:00"}}}},
+		{WeeklyHours: map[string][]models.BusinessHours{"mon": {{Open: "18:00", Close: "09:00"}}}},
+		{WeeklyHours: map[string][]models.BusinessHours{"mon": {{Open: "9am", Close: "17:00"}}}},
+		{Holidays: []string{"25/12/2026"}},
+	}
+	for _, cfg := range bad {
+		if err := calendar.Validate(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+	if err := calendar.Validate(&models.BusinessCalendar{Timezone: "Europe/Berlin", WeeklyHours: weekdays("08:30", "24:00")}); err != nil {
+		t.Errorf("expected a valid calendar, got %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Config holds all configuration for the application.
@@ -17,6 +19,20 @@ type Config struct {
 	Meta        MetaConfig
 	Google      GoogleOAuthConfig
 	OpenAI      OpenAIConfig
+	LLM         LLMConfig
+	HTTPNode    HTTPNodeConfig
+	Secrets     SecretsConfig
+}
+
+// SecretsConfig holds the server key that encrypts tenant secrets at rest.
+type SecretsConfig struct {
+	EncryptionKey string // 32 bytes, hex or base64 encoded
+}
+
+// HTTPNodeConfig controls outbound calls made by workflow HTTP request nodes.
+type HTTPNodeConfig struct {
+	AllowedPrivateCIDRs []string // private ranges reachable despite SSRF protection, e.g. an internal CRM
+	RateLimitPerMinute  int64    // per tenant
 }
 
 // OpenAIConfig holds LLM API keys.
@@ -24,6 +40,29 @@ type OpenAIConfig struct {
 	APIKey string
 }
 
+// LLMConfig selects the model providers. OpenAI is always available; the others are enabled by
+// their key (or base URL for OpenAI-compatible servers such as Ollama or vLLM).
+type LLMConfig struct {
+	Provider           string           // default chat provider: openai, anthropic, gemini or openai_compatible
+	Model              string           // default model of Provider, empty for the provider's default
+	FallbackProviders  []string         // tried in order when a provider fails
+	EmbeddingsProvider string           // provider of knowledge base embeddings
+	TenantProviders    map[int64]string // user ID -> "provider" or "provider:model"
+
+	PlanBudgets       map[string]float64 // monthly USD budget per User.Plan; plans not listed are not limited
+	BudgetWarnPercent float64            // share of the budget at which tenants are warned
+
+	LeadExtractionDelay int64 // seconds after a contact's last message before its lead fields are extracted; 0 disables
+
+	AnthropicAPIKey string
+	GeminiAPIKey    string
+
+	CompatibleBaseURL        string
+	CompatibleAPIKey         string
+	CompatibleModel          string
+	CompatibleEmbeddingModel string
+}
+
 // GoogleOAuthConfig holds Google OAuth2 settings.
 type GoogleOAuthConfig struct {
 	ClientID     string
@@ -101,6 +140,29 @@ func Load() *Config {
 		OpenAI: OpenAIConfig{
 			APIKey: getEnv("OPENAI_API_KEY", ""),
 		},
+		LLM: LLMConfig{
+			Provider:                 getEnv("LLM_PROVIDER", "openai"),
+			Model:                    getEnv("LLM_MODEL", ""),
+			FallbackProviders:        getEnvList("LLM_FALLBACK_PROVIDERS"),
+			EmbeddingsProvider:       getEnv("LLM_EMBEDDINGS_PROVIDER", "openai"),
+			TenantProviders:          getEnvTenantMap("LLM_TENANT_PROVIDERS"),
+			PlanBudgets:              getEnvAmounts("LLM_PLAN_BUDGETS"),
+			BudgetWarnPercent:        float64(getEnvInt("LLM_BUDGET_WARN_PERCENT", 80)),
+			LeadExtractionDelay:      getEnvInt("LEAD_EXTRACTION_DELAY_SECONDS", 120),
+			AnthropicAPIKey:          getEnv("ANTHROPIC_API_KEY", ""),
+			GeminiAPIKey:             getEnv("GEMINI_API_KEY", ""),
+			CompatibleBaseURL:        getEnv("OPENAI_COMPATIBLE_BASE_URL", ""),
+			CompatibleAPIKey:         getEnv("OPENAI_COMPATIBLE_API_KEY", ""),
+			CompatibleModel:          getEnv("OPENAI_COMPATIBLE_MODEL", ""),
+			CompatibleEmbeddingModel: getEnv("OPENAI_COMPATIBLE_EMBEDDING_MODEL", ""),
+		},
+		HTTPNode: HTTPNodeConfig{
+			AllowedPrivateCIDRs: getEnvList("HTTP_NODE_ALLOWED_CIDRS"),
+			RateLimitPerMinute:  getEnvInt("HTTP_NODE_RATE_LIMIT_PER_MINUTE", 60),
+		},
+		Secrets: SecretsConfig{
+			EncryptionKey: getEnv("SECRETS_ENCRYPTION_KEY", ""),
+		},
 	}
 
 	// Build DATABASE_URL if not explicitly set
@@ -125,3 +187,54 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// getEnvTenantMap parses a comma-separated list of userID=value pairs, e.g.
+// "12=anthropic,31=gemini:gemini-2.5-pro", dropping malformed entries.
+func getEnvTenantMap(key string) map[int64]string {
+	out := map[int64]string{}
+	for _, item := range getEnvList(key) {
+		id, value, ok := strings.Cut(item, "=")
+		userID, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		out[userID] = strings.TrimSpace(value)
+	}
+	return out
+}
+
+// getEnvAmounts parses a comma-separated list of name=amount pairs, e.g. "starter=10,pro=100",
+// dropping malformed entries.
+func getEnvAmounts(key string) map[string]float64 {
+	out := map[string]float64{}
+	for _, item := range getEnvList(key) {
+		name, value, ok := strings.Cut(item, "=")
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil {
+			continue
+		}
+		out[strings.TrimSpace(name)] = amount
+	}
+	return out
+}
+
+// getEnvInt parses an integer variable, using fallback when unset or invalid.
+func getEnvInt(key string, fallback int64) int64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
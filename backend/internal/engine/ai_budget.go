@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+	"log"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// HandleBudgetExceeded is the source handle an AI node takes when the tenant has spent its
+// monthly AI budget. Like the error handle it is only ever taken explicitly.
+const HandleBudgetExceeded = "budget_exceeded"
+
+// aiContext attributes the LLM calls a node makes to its tenant, workflow and node for metering
+func aiContext(ctx context.Context, userID int64, exec *models.WorkflowExecution, node *models.ReactFlowNode) context.Context {
+	return ai.WithAttribution(ctx, ai.Attribution{
+		UserID:     userID,
+		WorkflowID: exec.WorkflowID,
+		NodeID:     node.ID,
+		Feature:    string(node.Type),
+	})
+}
+
+// budgetFallback continues an AI node whose tenant is over budget: the node's fallbackMessage is
+// sent if it has one, then the budget_exceeded edge is followed, or the normal one when the node
+// has none so the workflow goes on without the AI step
+func (gw *GraphWalker) budgetFallback(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, vars *Variables, rec *stepRecord, cause error) (string, error) {
+	log.Printf("[GraphWalker] Node %s skipped the AI call: %v", node.ID, cause)
+	rec.out("budget_exceeded", true)
+
+	if msg := node.DataString("fallbackMessage", ""); msg != "" {
+		rendered, err := RenderTemplate(msg, vars)
+		if err != nil {
+			log.Printf("[GraphWalker] Node %s fallback message template error, using raw text: %v", node.ID, err)
+		}
+		rec.out("reply", rendered)
+		if err := gw.sendMetaMessage(ctx, exec.ContactID, rendered); err != nil {
+			return "", err
+		}
+	}
+
+	if next := gw.findNextNode(graph.Edges, node.ID, HandleBudgetExceeded); next != "" {
+		rec.branch = HandleBudgetExceeded
+		return next, nil
+	}
+	return gw.findNextNode(graph.Edges, node.ID, ""), nil
+}
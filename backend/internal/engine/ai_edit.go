@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// GraphPatch is a structured change to a workflow graph, as proposed by the AI editor. It is
+// applied in the order remove edges, remove nodes, update nodes, add nodes, add edges.
+type GraphPatch struct {
+	Summary     string                 `json:"summary"` // one sentence for the preview
+	AddNodes    []models.ReactFlowNode `json:"add_nodes"`
+	UpdateNodes []NodeUpdate           `json:"update_nodes"`
+	RemoveNodes []string               `json:"remove_nodes"` // their edges are removed as well
+	AddEdges    []models.ReactFlowEdge `json:"add_edges"`
+	RemoveEdges []EdgeRef              `json:"remove_edges"`
+}
+
+// NodeUpdate sets data fields of an existing node. Null fields are left unchanged.
+type NodeUpdate struct {
+	ID   string                 `json:"id"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// EdgeRef identifies an edge the way GraphDiff does: by source, handle and target
+type EdgeRef struct {
+	Source       string `json:"source"`
+	SourceHandle string `json:"sourceHandle,omitempty"`
+	Target       string `json:"target"`
+}
+
+// GraphEdit is a patch together with the graph it produces, that graph's validation report and
+// the diff against the current graph
+type GraphEdit struct {
+	Patch        *GraphPatch       `json:"patch"`
+	Nodes        json.RawMessage   `json:"nodes"`
+	Edges        json.RawMessage   `json:"edges"`
+	Validation   *ValidationReport `json:"validation"`
+	Diff         *GraphDiff        `json:"diff"`
+	RepairRounds int               `json:"repair_rounds"`
+
+	newErrors []ValidationIssue
+}
+
+// NewErrors lists the validation errors the patch introduces. Errors the graph already had are
+// not held against the patch.
+func (e *GraphEdit) NewErrors() []ValidationIssue {
+	return e.newErrors
+}
+
+var graphPatchSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"summary":   schemaString,
+		"add_nodes": map[string]interface{}{"type": "array", "items": nodeSchema},
+		"update_nodes": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{"id": schemaString, "data": dataSchema(nullable(schemaString))},
+				"required":             []string{"id", "data"},
+				"additionalProperties": false,
+			},
+		},
+		"remove_nodes": schemaStringList,
+		"add_edges":    map[string]interface{}{"type": "array", "items": edgeSchema},
+		"remove_edges": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"source":       schemaString,
+					"sourceHandle": nullable(schemaString),
+					"target":       schemaString,
+				},
+				"required":             []string{"source", "sourceHandle", "target"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"summary", "add_nodes", "update_nodes", "remove_nodes", "add_edges", "remove_edges"},
+	"additionalProperties": false,
+}
+
+const editPrompt = `You are an expert AI architect editing an automation workflow of a Social Media Lead SaaS app.
+You receive the current workflow graph and an instruction from the user. Answer with a patch that
+changes only what the instruction asks for:
+- add_nodes: new nodes, with ids that are not used yet, positioned near the nodes they connect to.
+- update_nodes: data fields to change on existing nodes; set every field you do not change to null.
+- remove_nodes: ids of nodes to delete; their edges are deleted with them.
+- add_edges / remove_edges: connections to create or delete. To insert a node between A and B,
+  remove the edge A->B and add A->new and new->B, keeping A's sourceHandle.
+- summary: one sentence describing the change for the user.
+
+` + nodeCatalogue + `
+Current workflow:
+`
+
+// EditWorkflow asks the model for a patch that carries out instruction on the graph in nodes and
+// edges. A patch that cannot be applied, or that introduces validation errors the graph did not
+// already have, is sent back for repair at most MaxRepairRounds times. Nothing is saved; see
+// PreviewGraphPatch.
+func EditWorkflow(ctx context.Context, llm ai.LLMClient, nodes, edges json.RawMessage, instruction string) (*GraphEdit, error) {
+	graph, err := models.ParseWorkflowGraph(nodes, edges)
+	if err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(graph)
+	if err != nil {
+		return nil, err
+	}
+	base := editPrompt + string(current) + "\n\nInstruction: " + instruction
+	request := base
+
+	var edit *GraphEdit
+	for round := 0; round <= MaxRepairRounds; round++ {
+		raw, err := llm.GenerateStructuredJSON(ctx, request, graphPatchSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate edit: %w", err)
+		}
+
+		var issues []string
+		var patch GraphPatch
+		if err := json.Unmarshal([]byte(raw), &patch); err != nil {
+			issues = []string{"the response is not valid JSON: " + err.Error()}
+		} else if next, err := PreviewGraphPatch(nodes, edges, &patch); err != nil {
+			issues = []string{"the patch cannot be applied: " + err.Error()}
+		} else {
+			edit = next
+			edit.RepairRounds = round
+			for _, issue := range edit.NewErrors() {
+				issues = append(issues, issue.String())
+			}
+			if len(issues) == 0 {
+				return edit, nil
+			}
+		}
+
+		request = repairPrompt(base, raw, issues)
+	}
+
+	if edit == nil {
+		return nil, fmt.Errorf("failed to generate edit: the model did not return an applicable patch")
+	}
+	return edit, nil
+}
+
+// PreviewGraphPatch applies patch to the graph in nodes and edges and reports the result, its
+// validation and the diff. It fails when the patch does not fit the graph (e.g. it was made for an
+// older version).
+func PreviewGraphPatch(nodes, edges json.RawMessage, patch *GraphPatch) (*GraphEdit, error) {
+	graph, err := models.ParseWorkflowGraph(nodes, edges)
+	if err != nil {
+		return nil, err
+	}
+	nextNodes, nextEdges, err := ApplyGraphPatch(nodes, edges, patch)
+	if err != nil {
+		return nil, err
+	}
+	next, err := models.ParseWorkflowGraph(nextNodes, nextEdges)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &GraphEdit{
+		Patch:      patch,
+		Nodes:      nextNodes,
+		Edges:      nextEdges,
+		Validation: ValidateWorkflowGraph(next),
+		Diff:       DiffGraphs(graph, next),
+	}
+	known := make(map[string]bool)
+	for _, issue := range ValidateWorkflowGraph(graph).Errors {
+		known[issue.String()] = true
+	}
+	for _, issue := range edit.Validation.Errors {
+		if !known[issue.String()] {
+			edit.newErrors = append(edit.newErrors, issue)
+		}
+	}
+	return edit, nil
+}
+
+// ApplyGraphPatch returns the nodes and edges JSON with patch applied. Like remapNodes it works
+// on the raw React Flow objects, so properties the engine does not model (edge animation and
+// style, node sizes) survive the edit.
+func ApplyGraphPatch(nodesJSON, edgesJSON json.RawMessage, patch *GraphPatch) (json.RawMessage, json.RawMessage, error) {
+	var nodes, edges []map[string]interface{}
+	if err := json.Unmarshal(nodesJSON, &nodes); err != nil {
+		return nil, nil, fmt.Errorf("invalid nodes: %w", err)
+	}
+	if len(edgesJSON) > 0 {
+		if err := json.Unmarshal(edgesJSON, &edges); err != nil {
+			return nil, nil, fmt.Errorf("invalid edges: %w", err)
+		}
+	}
+	field := func(m map[string]interface{}, key string) string {
+		s, _ := m[key].(string)
+		return s
+	}
+
+	for _, ref := range patch.RemoveEdges {
+		kept := edges[:0]
+		for _, e := range edges {
+			if field(e, "source") != ref.Source || field(e, "sourceHandle") != ref.SourceHandle || field(e, "target") != ref.Target {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == len(edges) {
+			return nil, nil, fmt.Errorf("edge %s -> %s does not exist", edgeLabel(ref.Source, ref.SourceHandle), ref.Target)
+		}
+		edges = kept
+	}
+
+	index := func(id string) int {
+		for i := range nodes {
+			if field(nodes[i], "id") == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, id := range patch.RemoveNodes {
+		i := index(id)
+		if i < 0 {
+			return nil, nil, fmt.Errorf("cannot remove node %s: it does not exist", id)
+		}
+		nodes = append(nodes[:i], nodes[i+1:]...)
+		kept := edges[:0]
+		for _, e := range edges {
+			if field(e, "source") != id && field(e, "target") != id {
+				kept = append(kept, e)
+			}
+		}
+		edges = kept
+	}
+
+	for _, u := range patch.UpdateNodes {
+		i := index(u.ID)
+		if i < 0 {
+			return nil, nil, fmt.Errorf("cannot update node %s: it does not exist", u.ID)
+		}
+		data, _ := nodes[i]["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+			nodes[i]["data"] = data
+		}
+		for k, v := range u.Data {
+			if v != nil {
+				data[k] = v
+			}
+		}
+	}
+
+	for _, n := range patch.AddNodes {
+		if n.ID == "" {
+			return nil, nil, fmt.Errorf("added %s node has no id", n.Type)
+		}
+		if index(n.ID) >= 0 {
+			return nil, nil, fmt.Errorf("cannot add node %s: the id is already used", n.ID)
+		}
+		if n.Data == nil {
+			n.Data = map[string]interface{}{}
+		}
+		dropNulls(n.Data)
+		node := map[string]interface{}{"id": n.ID, "type": string(n.Type), "data": n.Data}
+		if n.Position != nil {
+			node["position"] = n.Position
+		}
+		nodes = append(nodes, node)
+	}
+
+	usedIDs := make(map[string]bool, len(edges))
+	for _, e := range edges {
+		usedIDs[field(e, "id")] = true
+	}
+	for _, e := range patch.AddEdges {
+		if e.ID == "" || usedIDs[e.ID] {
+			e.ID = fmt.Sprintf("e-%s-%s", e.Source, e.Target)
+			for i := 2; usedIDs[e.ID]; i++ {
+				e.ID = fmt.Sprintf("e-%s-%s-%d", e.Source, e.Target, i)
+			}
+		}
+		usedIDs[e.ID] = true
+		edge := map[string]interface{}{"id": e.ID, "source": e.Source, "target": e.Target}
+		if e.SourceHandle != "" {
+			edge["sourceHandle"] = e.SourceHandle
+		}
+		if e.TargetHandle != "" {
+			edge["targetHandle"] = e.TargetHandle
+		}
+		edges = append(edges, edge)
+	}
+
+	if edges == nil {
+		edges = []map[string]interface{}{}
+	}
+	outNodes, err := json.Marshal(nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	outEdges, err := json.Marshal(edges)
+	if err != nil {
+		return nil, nil, err
+	}
+	return outNodes, outEdges, nil
+}
+
+func edgeLabel(source, handle string) string {
+	if handle == "" {
+		return source
+	}
+	return source + "." + handle
+}
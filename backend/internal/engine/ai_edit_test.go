@@ -0,0 +1,116 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// brochureGraph is trigger -> send brochure
+func brochureGraph() *models.WorkflowGraph {
+	return &models.WorkflowGraph{
+		Nodes: []models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM, Data: map[string]interface{}{}},
+			{ID: "2", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": "Here is the brochure"}},
+		},
+		Edges: []models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}},
+	}
+}
+
+// brochureJSON is brochureGraph as stored, with the builder's edge styling
+func brochureJSON() (nodes, edges json.RawMessage) {
+	nodes, _ = json.Marshal(brochureGraph().Nodes)
+	edges = json.RawMessage(`[{"id":"e1","source":"1","target":"2","animated":true,"style":{"stroke":"#6366f1"}}]`)
+	return nodes, edges
+}
+
+// Inserting a follow-up after the brochure: a delay and a message appended to node 2
+const followUpPatch = `{"summary":"Follow up a day after the brochure",` +
+	`"add_nodes":[` +
+	`{"id":"3","type":"action_delay","position":{"x":250,"y":350},"data":{"label":"Wait a day","delayMs":86400000,"message":null}},` +
+	`{"id":"4","type":"action_send_message","position":{"x":250,"y":500},"data":{"label":"Follow up","message":"Any questions about the brochure?"}}],` +
+	`"update_nodes":[{"id":"2","data":{"label":null,"message":"Here is the brochure for you"}}],` +
+	`"remove_nodes":[],` +
+	`"add_edges":[{"id":"e1","source":"2","target":"3","sourceHandle":null},{"id":"","source":"3","target":"4","sourceHandle":null}],` +
+	`"remove_edges":[]}`
+
+func TestEditWorkflowPatch(t *testing.T) {
+	nodes, edges := brochureJSON()
+	llm := &scriptedLLM{graphs: []string{followUpPatch}}
+
+	edit, err := engine.EditWorkflow(context.Background(), llm, nodes, edges, "add a 1-day follow-up after the brochure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !edit.Validation.Valid() || edit.RepairRounds != 0 {
+		t.Fatalf("expected a valid edit, got rounds=%d errors=%v", edit.RepairRounds, edit.Validation.Errors)
+	}
+	if strings.Join(edit.Diff.AddedNodes, ",") != "3,4" || len(edit.Diff.AddedEdges) != 2 {
+		t.Errorf("unexpected diff %+v", edit.Diff)
+	}
+	if len(edit.Diff.ChangedNodes) != 1 || strings.Join(edit.Diff.ChangedNodes[0].Fields, ",") != "data.message" {
+		t.Errorf("null fields of an update must be left unchanged: %+v", edit.Diff.ChangedNodes)
+	}
+	if strings.Contains(string(edit.Nodes), `"message":null`) {
+		t.Errorf("nulls not stripped from added nodes: %s", edit.Nodes)
+	}
+	// The colliding edge id is replaced, the empty one filled in
+	if !strings.Contains(string(edit.Edges), `"id":"e-2-3"`) || !strings.Contains(string(edit.Edges), `"id":"e-3-4"`) {
+		t.Errorf("edge ids not assigned: %s", edit.Edges)
+	}
+	// Builder properties the engine does not model are kept
+	if !strings.Contains(string(edit.Edges), `"animated":true`) || !strings.Contains(string(edit.Edges), `"stroke":"#6366f1"`) {
+		t.Errorf("edge styling lost: %s", edit.Edges)
+	}
+	if !strings.Contains(string(nodes), `"Here is the brochure"`) {
+		t.Error("the original graph was modified")
+	}
+	if !strings.Contains(llm.prompts[0], `"Here is the brochure"`) {
+		t.Error("the prompt does not include the current graph")
+	}
+}
+
+func TestEditWorkflowRepairsPatch(t *testing.T) {
+	// The first patch removes an edge that does not exist
+	bad := `{"summary":"x","add_nodes":[],"update_nodes":[],"remove_nodes":[],"add_edges":[],` +
+		`"remove_edges":[{"source":"2","sourceHandle":null,"target":"9"}]}`
+	llm := &scriptedLLM{graphs: []string{bad, followUpPatch}}
+
+	nodes, edges := brochureJSON()
+	edit, err := engine.EditWorkflow(context.Background(), llm, nodes, edges, "add a follow-up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit.RepairRounds != 1 {
+		t.Errorf("expected 1 repair round, got %d", edit.RepairRounds)
+	}
+	if !strings.Contains(llm.prompts[1], "edge 2 -> 9 does not exist") {
+		t.Errorf("repair prompt does not explain the failure: %q", llm.prompts[1])
+	}
+}
+
+func TestApplyGraphPatchRemovesNodeEdges(t *testing.T) {
+	nodes, edges := brochureJSON()
+	nextNodes, nextEdges, err := engine.ApplyGraphPatch(nodes, edges, &engine.GraphPatch{RemoveNodes: []string{"2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := models.ParseWorkflowGraph(nextNodes, nextEdges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next.Nodes) != 1 || len(next.Edges) != 0 {
+		t.Errorf("expected node 2 and its edge gone, got %+v", next)
+	}
+
+	if _, _, err := engine.ApplyGraphPatch(nodes, edges, &engine.GraphPatch{UpdateNodes: []engine.NodeUpdate{{ID: "7"}}}); err == nil {
+		t.Error("updating a missing node should fail")
+	}
+	if _, _, err := engine.ApplyGraphPatch(nodes, edges, &engine.GraphPatch{AddNodes: []models.ReactFlowNode{{ID: "1", Type: models.NodeTypeActionDelay}}}); err == nil {
+		t.Error("adding a node with a used id should fail")
+	}
+}
@@ -0,0 +1,124 @@
+package engine_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Golden files live in testdata/golden; rewrite them with `go test ./internal/engine -update`.
+// LLM fixtures live in testdata/fixtures; record them against OpenAI with
+// `AI_FIXTURES=record OPENAI_API_KEY=... go test ./internal/engine -run Golden -update`.
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+// checkGolden compares got with testdata/golden/<name>.golden
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file, run with -update: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file (run with -update if the change is intended)\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// fixtureLLM replays the fixtures of one test, or records them with the OpenAI key of the environment
+func fixtureLLM(t *testing.T, name string) ai.LLMClient {
+	t.Helper()
+	mode := ai.FixtureModeFromEnv()
+	var live ai.LLMClient
+	if mode == ai.FixtureRecord {
+		key := os.Getenv("OPENAI_API_KEY")
+		if key == "" {
+			t.Skip("recording fixtures needs OPENAI_API_KEY")
+		}
+		live = ai.NewOpenAIClient(key, "")
+	}
+	return ai.NewFixtureClient(filepath.Join("testdata", "fixtures", name), mode, live)
+}
+
+func indentJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, '\n')
+}
+
+func TestGenerateWorkflowGolden(t *testing.T) {
+	llm := fixtureLLM(t, "generate_brochure")
+
+	res, err := engine.GenerateWorkflow(context.Background(), llm,
+		"When someone DMs us asking for the brochure, send it and tag them as brochure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Validation.Valid() {
+		t.Errorf("recorded graph does not validate: %v", res.Validation.Errors)
+	}
+	checkGolden(t, "generate_brochure", indentJSON(t, res))
+}
+
+func TestAINodesGolden(t *testing.T) {
+	s := newHandoffStore()
+	s.contacts[1].Budget = "80 lakh"
+	seedConversation(s.memStore, 4)
+	s.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "reply", Type: models.NodeTypeActionAIReply, Data: map[string]interface{}{
+				"prompt": "You are the sales assistant of {{contact.name}}'s builder. Answer briefly.", "temperature": 0.2,
+			}},
+			{ID: "handoff", Type: models.NodeTypeActionHandoff, Data: map[string]interface{}{"team": "sales", "note": "AI replied, please follow up"}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "reply"}, {ID: "e2", Source: "reply", Target: "handoff"}},
+	)
+
+	llm := ai.NewFakeClient().
+		Respond(ai.MethodChat, `(?s)sales assistant.*user: message 5`, "The 2BHK in Baner starts at 78 lakh. Shall I book a visit?").
+		Respond(ai.MethodGenerateText, `human agent is taking over`, "- Wants a 2BHK in Baner\n- Budget 80 lakh")
+
+	gw := engine.NewGraphWalker(s, llm, nil, fakeMeta(http.StatusOK))
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "message 5"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The prompts the AI nodes built, the steps they recorded and the note left for the agent
+	var out strings.Builder
+	for _, call := range llm.Calls() {
+		fmt.Fprintf(&out, "== %s\n%s\n", call.Method, strings.TrimRight(call.Prompt, "\n"))
+		if call.Chat != nil {
+			fmt.Fprintf(&out, "(temperature %.1f, max tokens %d)\n", call.Chat.Temperature, call.Chat.MaxTokens)
+		}
+	}
+	for _, step := range s.steps {
+		fmt.Fprintf(&out, "== step %s %s\ninput: %s\noutput: %s\n", step.NodeID, step.NodeType, step.Input, step.Output)
+	}
+	for _, n := range s.notes {
+		fmt.Fprintf(&out, "== note\n%s\n", n.Body)
+	}
+	checkGolden(t, "ai_nodes", []byte(out.String()))
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// DefaultRAGResults is how many Knowledge Base entries action_rag_search passes on when the node
+// does not set data.limit
+const DefaultRAGResults = 3
+
+// runRAGSearch looks up the Knowledge Base entries closest to the inbound message and keeps them
+// in state as kb_context, where the next action_ai_reply picks them up
+func (gw *GraphWalker) runRAGSearch(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
+	next := gw.findNextNode(graph.Edges, node.ID, "")
+	delete(stateData, "kb_context")
+
+	vars, err := gw.buildVariables(ctx, exec, stateData)
+	if err != nil {
+		return "", err
+	}
+	query := strings.TrimSpace(vars.Message)
+	rec.in("query", query)
+	if query == "" {
+		return next, nil
+	}
+
+	llm := gw.llmFor(vars.Contact.UserID, node)
+	embedding, err := llm.GenerateEmbedding(aiContext(ctx, vars.Contact.UserID, exec, node), query)
+	if errors.Is(err, ai.ErrBudgetExceeded) {
+		log.Printf("[GraphWalker] Node %s skipped the Knowledge Base search: %v", node.ID, err)
+		rec.out("budget_exceeded", true)
+		return next, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to embed message: %w", err)
+	}
+
+	limit := int(node.DataFloat("limit", DefaultRAGResults))
+	entries, err := gw.Store.SearchKnowledgeBase(ctx, vars.Contact.UserID, embedding, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to search knowledge base: %w", err)
+	}
+	titles := make([]string, 0, len(entries))
+	var kb strings.Builder
+	for _, e := range entries {
+		titles = append(titles, e.Title)
+		fmt.Fprintf(&kb, "## %s\n%s\n\n", e.Title, strings.TrimSpace(e.Content))
+	}
+	rec.out("results", titles)
+	if kb.Len() > 0 {
+		stateData["kb_context"] = strings.TrimSpace(kb.String())
+	}
+	return next, nil
+}
+
+const routerPrompt = `Classify the latest message of a lead in a real estate sales conversation into exactly one of these routes: %s.
+%s
+Latest message:
+%s`
+
+// runAIRouter asks the model which of the node's routes the inbound message belongs to and follows
+// that handle. When the model cannot answer within the tenant's budget, or answers with an
+// unknown route, the last route is taken.
+func (gw *GraphWalker) runAIRouter(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
+	routes := node.DataStrings("routes")
+	if len(routes) == 0 {
+		routes = DefaultRouterHandles
+	}
+	route := routes[len(routes)-1]
+
+	vars, err := gw.buildVariables(ctx, exec, stateData)
+	if err != nil {
+		return "", err
+	}
+	rec.in("message", vars.Message)
+
+	var instructions string
+	if raw := node.DataString("prompt", ""); raw != "" {
+		if instructions, err = RenderTemplate(raw, vars); err != nil {
+			log.Printf("[GraphWalker] Node %s prompt template error, using raw text: %v", node.ID, err)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"route": map[string]interface{}{"type": "string", "enum": routes}},
+		"required":             []string{"route"},
+		"additionalProperties": false,
+	}
+
+	llm := gw.llmFor(vars.Contact.UserID, node)
+	prompt := fmt.Sprintf(routerPrompt, strings.Join(routes, ", "), instructions, vars.Message)
+	raw, err := llm.GenerateStructuredJSON(aiContext(ctx, vars.Contact.UserID, exec, node), prompt, schema)
+	switch {
+	case errors.Is(err, ai.ErrBudgetExceeded):
+		log.Printf("[GraphWalker] Node %s skipped the AI call: %v", node.ID, err)
+		rec.out("budget_exceeded", true)
+	case err != nil:
+		return "", fmt.Errorf("failed to classify message: %w", err)
+	default:
+		var answer struct {
+			Route string `json:"route"`
+		}
+		_ = json.Unmarshal([]byte(raw), &answer)
+		for _, r := range routes {
+			if r == answer.Route {
+				route = r
+			}
+		}
+	}
+
+	log.Printf("AI router node %s took route %q", node.ID, route)
+	rec.branch = route
+	return gw.findNextNode(graph.Edges, node.ID, route), nil
+}
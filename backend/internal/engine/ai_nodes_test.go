@@ -0,0 +1,87 @@
+package engine_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestRAGSearchFeedsAIReply(t *testing.T) {
+	ms := newMemStore()
+	ms.knowledge = []models.KnowledgeBase{
+		{ID: 1, UserID: 1, Title: "Parking", Content: "Every 3BHK comes with two covered parking slots."},
+		{ID: 2, UserID: 1, Title: "Clubhouse", Content: "The clubhouse has a pool and a gym."},
+		{ID: 3, UserID: 2, Title: "Other tenant", Content: "How many parking slots? None."},
+	}
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionRAGSearch, Data: map[string]interface{}{"limit": float64(1)}},
+			{ID: "3", Type: models.NodeTypeActionAIReply, Data: map[string]interface{}{"prompt": "Answer the lead."}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}, {ID: "e2", Source: "2", Target: "3"}},
+	)
+
+	llm := ai.NewFakeClient().Respond(ai.MethodChat, "two covered parking slots", "Two covered slots.")
+	gw := engine.NewGraphWalker(ms, llm, nil, fakeMeta(http.StatusOK))
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "how many parking slots with a 3BHK?"}); err != nil {
+		t.Fatal(err)
+	}
+
+	chats := llm.CallsTo(ai.MethodChat)
+	if len(chats) != 1 {
+		t.Fatalf("expected one reply, got %d calls", len(chats))
+	}
+	system := chats[0].Chat.System
+	if !strings.Contains(system, "Knowledge Base:\n## Parking") || strings.Contains(system, "Clubhouse") || strings.Contains(system, "None.") {
+		t.Errorf("unexpected knowledge in the prompt:\n%s", system)
+	}
+}
+
+func TestAIRouter(t *testing.T) {
+	cases := []struct {
+		name   string
+		answer string
+		want   string
+	}{
+		{"Follows the classified route", `{"route": "pricing"}`, "pricing"},
+		{"Unknown route falls back to the last one", `{"route": "spam"}`, "other"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := newMemStore()
+			ms.addWorkflow(t, 1,
+				[]models.ReactFlowNode{
+					{ID: "1", Type: models.NodeTypeTriggerDM},
+					{ID: "2", Type: models.NodeTypeLogicAIRouter, Data: map[string]interface{}{
+						"routes": []interface{}{"pricing", "visit", "other"},
+						"prompt": "Questions about cost are pricing.",
+					}},
+					{ID: "pricing", Type: models.NodeTypeActionDelay},
+					{ID: "visit", Type: models.NodeTypeActionDelay},
+					{ID: "other", Type: models.NodeTypeActionDelay},
+				},
+				[]models.ReactFlowEdge{
+					{ID: "e1", Source: "1", Target: "2"},
+					{ID: "e2", Source: "2", SourceHandle: "pricing", Target: "pricing"},
+					{ID: "e3", Source: "2", SourceHandle: "visit", Target: "visit"},
+					{ID: "e4", Source: "2", SourceHandle: "other", Target: "other"},
+				},
+			)
+
+			llm := ai.NewFakeClient().Respond(ai.MethodStructuredJSON, `(?s)pricing, visit, other.*cost are pricing.*what does a 2BHK cost`, tc.answer)
+			gw := engine.NewGraphWalker(ms, llm, nil, nil)
+			if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "what does a 2BHK cost?"}); err != nil {
+				t.Fatal(err)
+			}
+			if exec := ms.onlyExecution(t); exec.CurrentNodeID != tc.want {
+				t.Errorf("expected route %s, got node %s", tc.want, exec.CurrentNodeID)
+			}
+		})
+	}
+}
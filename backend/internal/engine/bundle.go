@@ -0,0 +1,388 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// A workflow bundle is a portable copy of a workflow that can be imported into another tenant.
+// Besides the graph it carries the modules the workflow calls (action_call_workflow) and lists
+// what the graph expects the target tenant to have: the tags it sets or listens for, the
+// secrets its HTTP requests use, the agent teams it hands off to and the knowledge base
+// documents its RAG searches ran against. Workflow IDs inside a bundle are "refs", the IDs in
+// the exporting tenant; import creates new workflows and rewrites every call to the new IDs.
+
+// Format and current version of workflow bundles. Import accepts any version up to BundleVersion.
+const (
+	BundleFormat  = "workflow-bundle"
+	BundleVersion = 1
+)
+
+// WorkflowBundle is the document produced by GET /workflows/:id/export
+type WorkflowBundle struct {
+	Format        string           `json:"format"`
+	Version       int              `json:"version"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	Workflow      BundleWorkflow   `json:"workflow"`
+	Modules       []BundleWorkflow `json:"modules"`
+	Tags          []string         `json:"tags"`
+	Secrets       []string         `json:"secrets"`
+	Teams         []string         `json:"teams"`
+	KnowledgeBase []BundleDocument `json:"knowledge_base"`
+}
+
+// BundleWorkflow is one workflow of a bundle
+type BundleWorkflow struct {
+	Ref             int64           `json:"ref"`
+	Name            string          `json:"name"`
+	TriggerType     string          `json:"trigger_type"`
+	Prompt          string          `json:"prompt,omitempty"`
+	Nodes           json.RawMessage `json:"nodes"`
+	Edges           json.RawMessage `json:"edges"`
+	Goals           json.RawMessage `json:"goals,omitempty"`
+	ReentryPolicy   string          `json:"reentry_policy,omitempty"`
+	CooldownMinutes int             `json:"cooldown_minutes,omitempty"`
+}
+
+// BundleDocument references a knowledge base document by title. Contents are not exported.
+type BundleDocument struct {
+	Title string `json:"title"`
+}
+
+// ImportResult describes the workflows an import created. Everything is imported as a draft so
+// the tenant can review it (and add missing secrets or agents) before publishing.
+type ImportResult struct {
+	Workflow   *models.Workflow            `json:"workflow"`
+	Modules    []*models.Workflow          `json:"modules"`
+	IDMap      map[int64]int64             `json:"id_map"`     // bundle ref -> new workflow ID
+	Validation map[int64]*ValidationReport `json:"validation"` // by new workflow ID
+	Warnings   []string                    `json:"warnings"`
+}
+
+var secretRefPattern = regexp.MustCompile(`\{\{\s*secret\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// ExportWorkflow bundles a workflow with every module it calls, directly or through other modules
+func ExportWorkflow(ctx context.Context, st store.Store, w *models.Workflow) (*WorkflowBundle, error) {
+	b := &WorkflowBundle{
+		Format:        BundleFormat,
+		Version:       BundleVersion,
+		ExportedAt:    time.Now().UTC(),
+		Workflow:      bundleWorkflow(w),
+		Modules:       []BundleWorkflow{},
+		KnowledgeBase: []BundleDocument{},
+	}
+
+	tags, secrets, teams := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	usesKB := false
+	seen := map[int64]bool{w.ID: true}
+	queue := []*models.Workflow{w}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		graph, err := models.ParseWorkflowGraph(cur.Nodes, cur.Edges)
+		if err != nil {
+			return nil, fmt.Errorf("workflow %d has an invalid graph: %w", cur.ID, err)
+		}
+		goals, err := ParseGoals(cur.Goals)
+		if err != nil {
+			return nil, fmt.Errorf("workflow %d has invalid goals: %w", cur.ID, err)
+		}
+		for _, g := range goals {
+			if g.Type == GoalTagAdded {
+				tags[strings.TrimSpace(g.Tag)] = true
+			}
+		}
+		for _, m := range secretRefPattern.FindAllSubmatch(cur.Nodes, -1) {
+			secrets[string(m[1])] = true
+		}
+		for i := range graph.Nodes {
+			node := &graph.Nodes[i]
+			switch node.Type {
+			case models.NodeTypeActionAddTag, models.NodeTypeTriggerContactEvent:
+				if tag := strings.TrimSpace(node.DataString("tag", "")); tag != "" {
+					tags[tag] = true
+				}
+			case models.NodeTypeTriggerSchedule, models.NodeTypeTriggerInactivity:
+				for _, tag := range node.DataStrings("tags") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						tags[tag] = true
+					}
+				}
+			case models.NodeTypeActionHandoff:
+				for _, key := range []string{"team", "escalateTeam"} {
+					if team := strings.TrimSpace(node.DataString(key, "")); team != "" {
+						teams[team] = true
+					}
+				}
+			case models.NodeTypeActionRAGSearch:
+				usesKB = true
+			case models.NodeTypeActionCallWorkflow:
+				targetID := callTarget(node)
+				if seen[targetID] {
+					continue
+				}
+				target, err := st.GetWorkflowByID(ctx, targetID)
+				if err != nil || target.UserID != w.UserID {
+					return nil, fmt.Errorf("node %s of workflow %d calls workflow %d, which does not exist", node.ID, cur.ID, targetID)
+				}
+				seen[targetID] = true
+				b.Modules = append(b.Modules, bundleWorkflow(target))
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	if usesKB {
+		docs, err := st.GetKnowledgeBaseEntriesByUser(ctx, w.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load knowledge base: %w", err)
+		}
+		titles := map[string]bool{}
+		for _, d := range docs {
+			if !titles[d.Title] {
+				titles[d.Title] = true
+				b.KnowledgeBase = append(b.KnowledgeBase, BundleDocument{Title: d.Title})
+			}
+		}
+	}
+	b.Tags, b.Secrets, b.Teams = sortedKeys(tags), sortedKeys(secrets), sortedKeys(teams)
+	return b, nil
+}
+
+func bundleWorkflow(w *models.Workflow) BundleWorkflow {
+	return BundleWorkflow{
+		Ref:             w.ID,
+		Name:            w.Name,
+		TriggerType:     w.TriggerType,
+		Prompt:          w.Prompt,
+		Nodes:           json.RawMessage(w.Nodes),
+		Edges:           json.RawMessage(w.Edges),
+		Goals:           w.Goals,
+		ReentryPolicy:   w.ReentryPolicy,
+		CooldownMinutes: w.CooldownMinutes,
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CheckBundle validates a bundle before import: its format and version, the settings of every
+// workflow, and that every workflow call points at a workflow inside the bundle. Graph
+// problems that a draft may have (see ValidateWorkflowGraph) are reported by the import, not here.
+func CheckBundle(b *WorkflowBundle) error {
+	if b.Format != BundleFormat {
+		return fmt.Errorf("not a workflow bundle (format %q)", b.Format)
+	}
+	if b.Version < 1 || b.Version > BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d (this server reads up to %d)", b.Version, BundleVersion)
+	}
+
+	refs := make(map[int64]bool, len(b.Modules)+1)
+	for _, bw := range b.workflows() {
+		if refs[bw.Ref] {
+			return fmt.Errorf("duplicate workflow ref %d", bw.Ref)
+		}
+		refs[bw.Ref] = true
+	}
+	for _, bw := range b.workflows() {
+		if strings.TrimSpace(bw.Name) == "" {
+			return fmt.Errorf("workflow %d has no name", bw.Ref)
+		}
+		graph, err := models.ParseWorkflowGraph(bw.Nodes, bw.Edges)
+		if err != nil {
+			return fmt.Errorf("workflow %q has an invalid graph: %w", bw.Name, err)
+		}
+		if _, err := ParseGoals(bw.Goals); err != nil {
+			return fmt.Errorf("workflow %q has invalid goals: %w", bw.Name, err)
+		}
+		if !IsValidReentryPolicy(bw.ReentryPolicy) {
+			return fmt.Errorf("workflow %q has an invalid reentry_policy %q", bw.Name, bw.ReentryPolicy)
+		}
+		if bw.CooldownMinutes < 0 {
+			return fmt.Errorf("workflow %q has a negative cooldown", bw.Name)
+		}
+		for i := range graph.Nodes {
+			node := &graph.Nodes[i]
+			if node.Type == models.NodeTypeActionCallWorkflow && !refs[callTarget(node)] {
+				return fmt.Errorf("node %s of workflow %q calls workflow %d, which is not in the bundle", node.ID, bw.Name, callTarget(node))
+			}
+		}
+	}
+	return nil
+}
+
+// workflows returns the modules followed by the main workflow
+func (b *WorkflowBundle) workflows() []*BundleWorkflow {
+	all := make([]*BundleWorkflow, 0, len(b.Modules)+1)
+	for i := range b.Modules {
+		all = append(all, &b.Modules[i])
+	}
+	return append(all, &b.Workflow)
+}
+
+// ImportWorkflowBundle creates the bundle's workflows as drafts of userID and rewrites their
+// workflow calls to the new IDs. Pinned agents (agentId of handoff nodes) belong to the exporting
+// tenant and are dropped; handoffs fall back to team assignment. name, when set, renames the main
+// workflow. Workflows created before a failure are deleted again.
+func ImportWorkflowBundle(ctx context.Context, st store.Store, userID int64, b *WorkflowBundle, name string) (*ImportResult, error) {
+	if err := CheckBundle(b); err != nil {
+		return nil, err
+	}
+
+	res := &ImportResult{
+		IDMap:      make(map[int64]int64),
+		Validation: make(map[int64]*ValidationReport),
+		Modules:    []*models.Workflow{},
+		Warnings:   []string{},
+	}
+	var created []*models.Workflow
+	fail := func(err error) (*ImportResult, error) {
+		for _, w := range created {
+			_ = st.DeleteWorkflow(ctx, w.ID, userID)
+		}
+		return nil, err
+	}
+
+	for _, bw := range b.workflows() {
+		nodes, dropped, err := remapNodes(bw.Nodes, nil)
+		if err != nil {
+			return fail(fmt.Errorf("workflow %q: %w", bw.Name, err))
+		}
+		if dropped > 0 {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("workflow %q: %d handoff node(s) had a pinned agent that was removed; they assign by team", bw.Name, dropped))
+		}
+		w := &models.Workflow{
+			UserID:          userID,
+			Name:            bw.Name,
+			TriggerType:     GraphTriggerType(nodes, bw.TriggerType),
+			Status:          "draft",
+			Prompt:          bw.Prompt,
+			Nodes:           nodes,
+			Edges:           bw.Edges,
+			Goals:           bw.Goals,
+			ReentryPolicy:   bw.ReentryPolicy,
+			CooldownMinutes: bw.CooldownMinutes,
+		}
+		if bw == &b.Workflow && strings.TrimSpace(name) != "" {
+			w.Name = strings.TrimSpace(name)
+		}
+		if err := st.CreateWorkflow(ctx, w); err != nil {
+			return fail(fmt.Errorf("failed to create workflow %q: %w", w.Name, err))
+		}
+		created = append(created, w)
+		res.IDMap[bw.Ref] = w.ID
+	}
+
+	// Now that every workflow has its new ID, point the calls at them
+	for _, w := range created {
+		nodes, _, err := remapNodes(w.Nodes, res.IDMap)
+		if err != nil {
+			return fail(err)
+		}
+		if string(nodes) != string(w.Nodes) {
+			w.Nodes = nodes
+			if err := st.UpdateWorkflow(ctx, w); err != nil {
+				return fail(fmt.Errorf("failed to update workflow %q: %w", w.Name, err))
+			}
+		}
+		report, err := ValidateWorkflow(w.Nodes, w.Edges)
+		if err != nil {
+			return fail(err)
+		}
+		res.Validation[w.ID] = report
+	}
+	res.Workflow = created[len(created)-1]
+	res.Modules = append(res.Modules, created[:len(created)-1]...)
+
+	res.Warnings = append(res.Warnings, missingRequirements(ctx, st, userID, b)...)
+	return res, nil
+}
+
+// remapNodes rewrites the workflowId of call nodes through idMap and removes the agentId of
+// handoff nodes (when idMap is nil). Nodes are edited as plain JSON so fields the builder stores
+// alongside id, type, position and data survive the import.
+func remapNodes(raw json.RawMessage, idMap map[int64]int64) (json.RawMessage, int, error) {
+	var nodes []map[string]interface{}
+	if err := json.Unmarshal(raw, &nodes); err != nil {
+		return nil, 0, fmt.Errorf("invalid nodes: %w", err)
+	}
+	dropped := 0
+	for _, n := range nodes {
+		data, _ := n["data"].(map[string]interface{})
+		if data == nil {
+			continue
+		}
+		switch models.NodeType(fmt.Sprint(n["type"])) {
+		case models.NodeTypeActionCallWorkflow:
+			if idMap == nil {
+				continue
+			}
+			node := models.ReactFlowNode{Data: data}
+			if id, ok := idMap[callTarget(&node)]; ok {
+				data["workflowId"] = float64(id)
+			}
+		case models.NodeTypeActionHandoff:
+			if _, ok := data["agentId"]; ok && idMap == nil {
+				delete(data, "agentId")
+				dropped++
+			}
+		}
+	}
+	out, err := json.Marshal(nodes)
+	return out, dropped, err
+}
+
+// missingRequirements lists what the bundle expects that the importing tenant does not have yet
+func missingRequirements(ctx context.Context, st store.Store, userID int64, b *WorkflowBundle) []string {
+	var warnings []string
+	if len(b.Secrets) > 0 {
+		have, _ := st.GetTenantSecrets(ctx, userID)
+		for _, name := range b.Secrets {
+			if _, ok := have[name]; !ok {
+				warnings = append(warnings, fmt.Sprintf("secret %q is not set; add it before publishing", name))
+			}
+		}
+	}
+	if len(b.Teams) > 0 {
+		agents, _ := st.ListAgents(ctx, userID)
+		staffed := map[string]bool{}
+		for _, a := range agents {
+			if a.IsActive {
+				staffed[a.Team] = true
+			}
+		}
+		for _, team := range b.Teams {
+			if !staffed[team] {
+				warnings = append(warnings, fmt.Sprintf("no active agent in team %q", team))
+			}
+		}
+	}
+	if len(b.KnowledgeBase) > 0 {
+		docs, _ := st.GetKnowledgeBaseEntriesByUser(ctx, userID)
+		have := map[string]bool{}
+		for _, d := range docs {
+			have[d.Title] = true
+		}
+		for _, d := range b.KnowledgeBase {
+			if !have[d.Title] {
+				warnings = append(warnings, fmt.Sprintf("knowledge base document %q is missing", d.Title))
+			}
+		}
+	}
+	return warnings
+}
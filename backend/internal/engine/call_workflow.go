@@ -0,0 +1,371 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// An action_call_workflow node runs another published workflow of the same tenant (a module) for
+// the same contact:
+//
+//	{"workflowId": 12, "inputs": {"budget": "{{contact.budget}}"}, "wait": true,
+//	 "outputs": {"qualified_budget": "budget"}}
+//
+// Inputs are rendered like message templates and become the child's initial state. With "wait"
+// (the default) the parent parks until the child ends, then merges the child's final state back:
+// the keys listed in "outputs" (parent key -> child key) or, without outputs, every key the child
+// set that is not engine bookkeeping. A child that fails or is cancelled sends the parent down its
+// error handle. Without "wait" the child runs on its own and the parent continues at once.
+//
+// Modules are started directly, so their re-entry policy and cooldown do not apply. A module
+// usually begins with a trigger_workflow_call node, but any trigger works.
+
+// MaxCallDepth is how deeply action_call_workflow nodes may nest
+const MaxCallDepth = 5
+
+// WaitingForWorkflow is WorkflowExecution.WaitingFor while a parent waits for its child
+const WaitingForWorkflow = "workflow"
+
+// ErrCallCycle is returned when a workflow would (indirectly) call itself
+var ErrCallCycle = errors.New("workflow call cycle")
+
+// errSuspended tells the walk loop that a node has parked the execution and saved its step
+var errSuspended = errors.New("execution suspended")
+
+// internalStateKeys are engine bookkeeping that never flows between caller and module
+var internalStateKeys = map[string]bool{
+	"trigger_node_id": true, "caller": true, "event": true, "simulation": true,
+	"wait_token": true, "delay_until": true, "retry_node": true, "retry_count": true,
+	"last_error": true, "last_error_node": true, "goal_reached": true,
+	"platform": true, "contact_name": true, "received_message": true, "matched_keyword": true,
+}
+
+// callTarget returns the workflow an action_call_workflow node calls
+func callTarget(node *models.ReactFlowNode) int64 {
+	switch v := node.Data["workflowId"].(type) {
+	case float64:
+		return int64(v)
+	case string:
+		id, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return id
+	}
+	return 0
+}
+
+// callMapping reads a string-to-string map from node data ("inputs" values may be any JSON)
+func callMapping(node *models.ReactFlowNode, key string) (map[string]interface{}, error) {
+	raw, ok := node.Data[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", key)
+	}
+	for k := range m {
+		if strings.TrimSpace(k) == "" || internalStateKeys[k] {
+			return nil, fmt.Errorf("%s cannot set %q", key, k)
+		}
+	}
+	return m, nil
+}
+
+// validateCallNode checks an action_call_workflow node's data
+func validateCallNode(node *models.ReactFlowNode) error {
+	if callTarget(node) <= 0 {
+		return fmt.Errorf("workflowId is required")
+	}
+	if _, err := callMapping(node, "inputs"); err != nil {
+		return err
+	}
+	outputs, err := callMapping(node, "outputs")
+	if err != nil {
+		return err
+	}
+	for k, v := range outputs {
+		if s, ok := v.(string); !ok || s == "" {
+			return fmt.Errorf("output %q must name a state key of the called workflow", k)
+		}
+	}
+	return nil
+}
+
+// runCallWorkflow starts the child execution of an action_call_workflow node
+func (gw *GraphWalker) runCallWorkflow(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
+	if err := validateCallNode(node); err != nil {
+		return "", fmt.Errorf("invalid workflow call: %w", err)
+	}
+	targetID := callTarget(node)
+	wait := node.DataBool("wait", true)
+	rec.in("workflow_id", targetID)
+
+	vars, err := gw.buildVariables(ctx, exec, stateData)
+	if err != nil {
+		return "", err
+	}
+	inputs, _ := callMapping(node, "inputs")
+	childState := make(map[string]interface{}, len(inputs)+3)
+	for k, v := range inputs {
+		if tmpl, ok := v.(string); ok {
+			if v, err = RenderTemplate(tmpl, vars); err != nil {
+				log.Printf("[GraphWalker] Node %s input %q template error, passing raw text: %v", node.ID, k, err)
+			}
+		}
+		childState[k] = v
+	}
+	rec.in("inputs", childState)
+
+	if gw.sim != nil {
+		// Simulations do not run the module; its outputs simply stay unset
+		gw.sim.record(EffectCallWorkflow, map[string]interface{}{"workflow_id": targetID, "inputs": childState, "wait": wait})
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+	}
+
+	target, err := gw.checkCall(ctx, exec, targetID)
+	if err != nil {
+		return "", err
+	}
+
+	childState["received_message"] = stateData["received_message"]
+	childState["caller"] = map[string]interface{}{"execution_id": exec.ID, "workflow_id": exec.WorkflowID, "node_id": node.ID}
+	child, err := gw.newExecution(ctx, target, exec.ContactID, childState)
+	if err != nil {
+		return "", err
+	}
+	parentID := exec.ID
+	child.ParentExecutionID = &parentID
+	child.ParentNodeID = node.ID
+	child.CallDepth = exec.CallDepth + 1
+	if err := gw.Store.CreateWorkflowExecution(ctx, child); err != nil {
+		return "", fmt.Errorf("failed to start workflow %d: %w", targetID, err)
+	}
+	log.Printf("Execution %d called workflow %d as execution %d (wait: %v)", exec.ID, targetID, child.ID, wait)
+	rec.out("child_execution_id", child.ID)
+
+	if !wait {
+		if err := gw.scheduleResume(ctx, child.ID, time.Now()); err != nil {
+			log.Printf("[GraphWalker] Failed to start execution %d: %v", child.ID, err)
+		}
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+	}
+
+	// Park before the child runs: without Asynq it finishes inline and returns to us at once
+	stateData["wait_token"] = callToken(child.ID)
+	exec.StateData, _ = json.Marshal(stateData)
+	exec.Status = "waiting"
+	exec.WaitingFor = WaitingForWorkflow
+	exec.CurrentNodeID = node.ID
+	if err := gw.Store.UpdateWorkflowExecution(ctx, exec); err != nil {
+		return "", fmt.Errorf("failed to suspend execution: %w", err)
+	}
+	rec.out("waiting_for", WaitingForWorkflow)
+	gw.saveStep(ctx, exec, node, rec, nil)
+
+	if err := gw.scheduleResume(ctx, child.ID, time.Now()); err != nil {
+		log.Printf("[GraphWalker] Failed to start execution %d: %v", child.ID, err)
+	}
+	return "", errSuspended
+}
+
+// checkCall loads the called workflow and enforces the tenant, depth and cycle rules
+func (gw *GraphWalker) checkCall(ctx context.Context, exec *models.WorkflowExecution, targetID int64) (*models.Workflow, error) {
+	caller, err := gw.Store.GetWorkflowByID(ctx, exec.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+	target, err := gw.Store.GetWorkflowByID(ctx, targetID)
+	if err != nil || target.UserID != caller.UserID {
+		return nil, fmt.Errorf("workflow %d not found", targetID)
+	}
+	if target.Status != "published" {
+		return nil, fmt.Errorf("workflow %d is not published", targetID)
+	}
+	if exec.CallDepth+1 > MaxCallDepth {
+		return nil, fmt.Errorf("workflow calls are nested deeper than %d", MaxCallDepth)
+	}
+
+	// Walk up the callers: the target may not already be running in this chain
+	current := exec
+	for i := 0; current != nil && i <= MaxCallDepth; i++ {
+		if current.WorkflowID == targetID {
+			return nil, fmt.Errorf("%w: workflow %d is already running in this call chain", ErrCallCycle, targetID)
+		}
+		if current.ParentExecutionID == nil {
+			break
+		}
+		if current, err = gw.Store.GetWorkflowExecutionByID(ctx, *current.ParentExecutionID); err != nil {
+			return nil, fmt.Errorf("failed to load calling execution: %w", err)
+		}
+	}
+	return target, nil
+}
+
+func callToken(childID int64) string {
+	return fmt.Sprintf("call:%d", childID)
+}
+
+// returnToCaller hands a finished child's result to the parent waiting on it, if any. It is
+// called whenever an execution ends: completed, failed, cancelled or on a goal.
+func (gw *GraphWalker) returnToCaller(ctx context.Context, exec *models.WorkflowExecution) {
+	if gw.sim != nil || exec.ParentExecutionID == nil {
+		return
+	}
+	// Reload: goals and cancellation end the child outside the walker
+	child, err := gw.Store.GetWorkflowExecutionByID(ctx, exec.ID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to reload execution %d: %v", exec.ID, err)
+		return
+	}
+	parent, err := gw.Store.GetWorkflowExecutionByID(ctx, *child.ParentExecutionID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to load calling execution %d: %v", *child.ParentExecutionID, err)
+		return
+	}
+	parentState := decodeState(parent.StateData)
+	if parent.Status != "waiting" || parent.WaitingFor != WaitingForWorkflow || parentState["wait_token"] != callToken(child.ID) {
+		// Not waiting for this child (wait: false, paused or moved on)
+		return
+	}
+	claimed, err := gw.Store.ClaimWaitingExecution(ctx, parent.ID, WaitingForWorkflow)
+	if err != nil || !claimed {
+		return
+	}
+	if err := gw.resumeCaller(ctx, parent, parentState, child); err != nil {
+		log.Printf("[GraphWalker] Execution %d failed after its call returned: %v", parent.ID, err)
+	}
+}
+
+// resumeCaller merges the child's state into the claimed parent and walks on from its call node
+func (gw *GraphWalker) resumeCaller(ctx context.Context, parent *models.WorkflowExecution, parentState map[string]interface{}, child *models.WorkflowExecution) error {
+	graph, err := gw.loadGraph(ctx, parent)
+	if err != nil {
+		return err
+	}
+	delete(parentState, "wait_token")
+	node := findNode(graph.Nodes, parent.CurrentNodeID)
+	if node == nil {
+		return gw.continueFrom(ctx, parent, parentState, "")
+	}
+
+	rec := newStepRecord()
+	rec.in("child_execution_id", child.ID)
+	rec.out("child_status", child.Status)
+	log.Printf("Execution %d resumed, called execution %d ended %s", parent.ID, child.ID, child.Status)
+
+	if child.Status == "failed" || child.Status == StatusCancelled {
+		callErr := fmt.Errorf("called workflow %d (execution %d) %s", child.WorkflowID, child.ID, child.Status)
+		next := gw.findNextNode(graph.Edges, node.ID, HandleError)
+		if next == "" {
+			gw.saveStep(ctx, parent, node, rec, callErr)
+			parent.StateData, _ = json.Marshal(parentState)
+			return gw.finish(ctx, parent, "failed")
+		}
+		rec.branch = HandleError
+		gw.saveStep(ctx, parent, node, rec, callErr)
+		parentState["last_error"] = callErr.Error()
+		parentState["last_error_node"] = node.ID
+		return gw.continueFrom(ctx, parent, parentState, next)
+	}
+
+	merged := mergeCallOutputs(node, parentState, decodeState(child.StateData))
+	rec.out("merged", merged)
+	gw.saveStep(ctx, parent, node, rec, nil)
+	return gw.continueFrom(ctx, parent, parentState, gw.findNextNode(graph.Edges, node.ID, ""))
+}
+
+// mergeCallOutputs copies the child's returned state into the parent's and returns the keys set
+func mergeCallOutputs(node *models.ReactFlowNode, parentState, childState map[string]interface{}) []string {
+	merged := []string{}
+	outputs, _ := callMapping(node, "outputs")
+	if len(outputs) > 0 {
+		for parentKey, childKey := range outputs {
+			if v, ok := childState[fmt.Sprint(childKey)]; ok {
+				parentState[parentKey] = v
+				merged = append(merged, parentKey)
+			}
+		}
+	} else {
+		for k, v := range childState {
+			if internalStateKeys[k] || strings.HasPrefix(k, "http_") {
+				continue
+			}
+			parentState[k] = v
+			merged = append(merged, k)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// resumeCallWait re-arms a parent that was paused while waiting on its child: if the child ended
+// in the meantime, its result is returned now.
+func (gw *GraphWalker) resumeCallWait(ctx context.Context, parent *models.WorkflowExecution) {
+	token, _ := decodeState(parent.StateData)["wait_token"].(string)
+	childID, err := strconv.ParseInt(strings.TrimPrefix(token, "call:"), 10, 64)
+	if err != nil {
+		return
+	}
+	child, err := gw.Store.GetWorkflowExecutionByID(ctx, childID)
+	if err != nil {
+		return
+	}
+	switch child.Status {
+	case "completed", "failed", StatusCancelled, StatusGoalReached:
+		gw.returnToCaller(ctx, child)
+	}
+}
+
+// CheckWorkflowCalls follows the action_call_workflow nodes of a graph being saved for workflow
+// workflowID (0 for a new one) through the tenant's other workflows, and rejects calls to another
+// tenant's workflows, call chains that lead back to the workflow and chains deeper than
+// MaxCallDepth.
+func CheckWorkflowCalls(ctx context.Context, st store.Store, userID, workflowID int64, nodes json.RawMessage) error {
+	var parsed []models.ReactFlowNode
+	if err := json.Unmarshal(nodes, &parsed); err != nil {
+		return fmt.Errorf("invalid nodes: %w", err)
+	}
+	return checkCalls(ctx, st, userID, workflowID, parsed, []int64{workflowID})
+}
+
+func checkCalls(ctx context.Context, st store.Store, userID, workflowID int64, nodes []models.ReactFlowNode, chain []int64) error {
+	for i := range nodes {
+		if nodes[i].Type != models.NodeTypeActionCallWorkflow {
+			continue
+		}
+		targetID := callTarget(&nodes[i])
+		if targetID <= 0 {
+			continue
+		}
+		for _, id := range chain {
+			if id == targetID {
+				return fmt.Errorf("%w: node %s of workflow %d calls workflow %d, which is already in the call chain", ErrCallCycle, nodes[i].ID, workflowID, targetID)
+			}
+		}
+		if len(chain) > MaxCallDepth {
+			return fmt.Errorf("workflow calls are nested deeper than %d", MaxCallDepth)
+		}
+
+		target, err := st.GetWorkflowByID(ctx, targetID)
+		if err != nil || target.UserID != userID {
+			return fmt.Errorf("node %s calls workflow %d, which does not exist", nodes[i].ID, targetID)
+		}
+		var targetNodes []models.ReactFlowNode
+		if err := json.Unmarshal(target.Nodes, &targetNodes); err != nil {
+			continue
+		}
+		if err := checkCalls(ctx, st, userID, targetID, targetNodes, append(chain[:len(chain):len(chain)], targetID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// callerWorkflow: DM -> call workflow target -> add_tag "after_call", error -> add_tag "call_failed"
+func callerWorkflow(t *testing.T, ms *memStore, id, target int64, data map[string]interface{}) {
+	call := map[string]interface{}{"workflowId": float64(target)}
+	for k, v := range data {
+		call[k] = v
+	}
+	ms.addWorkflow(t, id,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "call", Type: models.NodeTypeActionCallWorkflow, Data: call},
+			{ID: "ok", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "after_call"}},
+			{ID: "failed", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "call_failed"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "call"},
+			{ID: "e2", Source: "call", Target: "ok"},
+			{ID: "e3", Source: "call", SourceHandle: engine.HandleError, Target: "failed"},
+		},
+	)
+}
+
+// qualifyModule: called -> wait for the budget answer
+func qualifyModule(t *testing.T, ms *memStore, id int64) {
+	ms.addWorkflow(t, id,
+		[]models.ReactFlowNode{
+			{ID: "entry", Type: models.NodeTypeTriggerWorkflowCall},
+			{ID: "ask", Type: models.NodeTypeActionWaitForReply, Data: map[string]interface{}{"variable": "answer_budget"}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "entry", Target: "ask"}},
+	)
+}
+
+func decode(t *testing.T, raw []byte) map[string]interface{} {
+	t.Helper()
+	var state map[string]interface{}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func (m *memStore) execution(t *testing.T, id int64) *models.WorkflowExecution {
+	t.Helper()
+	exec, err := m.GetWorkflowExecutionByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exec
+}
+
+func TestCallWorkflowWaitsAndMergesState(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	callerWorkflow(t, ms, 1, 2, map[string]interface{}{"inputs": map[string]interface{}{"lead_name": "{{contact.name}}"}})
+	qualifyModule(t, ms, 2)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{"received_message": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	parent, child := ms.execution(t, 1), ms.execution(t, 2)
+	if parent.Status != "waiting" || parent.WaitingFor != engine.WaitingForWorkflow {
+		t.Fatalf("expected the caller to wait, got %s/%s", parent.Status, parent.WaitingFor)
+	}
+	if child.WorkflowID != 2 || child.ParentExecutionID == nil || *child.ParentExecutionID != 1 || child.ParentNodeID != "call" || child.CallDepth != 1 {
+		t.Fatalf("child not linked to its caller: %+v", child)
+	}
+	if state := decode(t, child.StateData); state["lead_name"] != "Asha" {
+		t.Errorf("expected mapped input in the child's state, got %v", state)
+	}
+
+	if err := gw.ResumeWithReply(ctx, child.ID, "2 Cr"); err != nil {
+		t.Fatal(err)
+	}
+	parent = ms.execution(t, 1)
+	if parent.Status != "completed" {
+		t.Fatalf("expected the caller to complete, got %s", parent.Status)
+	}
+	state := decode(t, parent.StateData)
+	if state["answer_budget"] != "2 Cr" || state["received_message"] != "hi" {
+		t.Errorf("expected the child's answer merged without its bookkeeping, got %v", state)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "after_call" {
+		t.Errorf("expected tag after_call, got %v", tags)
+	}
+
+	// The caller's step log points at the child, before and after the call
+	var linked int
+	for _, s := range ms.steps {
+		if s.ExecutionID == 1 && s.NodeID == "call" {
+			var out, in map[string]interface{}
+			_ = json.Unmarshal(s.Output, &out)
+			_ = json.Unmarshal(s.Input, &in)
+			if out["child_execution_id"] == float64(2) || in["child_execution_id"] == float64(2) {
+				linked++
+			}
+		}
+	}
+	if linked != 2 {
+		t.Errorf("expected two call steps linking execution 2, got %d", linked)
+	}
+}
+
+func TestCallWorkflowOutputsAndNoWait(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	callerWorkflow(t, ms, 1, 2, map[string]interface{}{"wait": false})
+	qualifyModule(t, ms, 2)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if parent := ms.execution(t, 1); parent.Status != "completed" {
+		t.Fatalf("expected the caller to continue at once, got %s", parent.Status)
+	}
+	if child := ms.execution(t, 2); child.Status != "waiting" {
+		t.Fatalf("expected the module to run on its own, got %s", child.Status)
+	}
+}
+
+func TestCallWorkflowFailureTakesErrorEdge(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	callerWorkflow(t, ms, 1, 2, nil)
+	ms.addWorkflow(t, 2,
+		[]models.ReactFlowNode{
+			{ID: "entry", Type: models.NodeTypeTriggerWorkflowCall},
+			{ID: "broken", Type: models.NodeTypeActionAddTag},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "entry", Target: "broken"}},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if child := ms.execution(t, 2); child.Status != "failed" {
+		t.Fatalf("expected the module to fail, got %s", child.Status)
+	}
+	if parent := ms.execution(t, 1); parent.Status != "completed" {
+		t.Fatalf("expected the caller to recover, got %s", parent.Status)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "call_failed" {
+		t.Errorf("expected tag call_failed, got %v", tags)
+	}
+}
+
+func TestCallWorkflowCycles(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	// 1 -> 2 -> 1
+	callerWorkflow(t, ms, 1, 2, nil)
+	callerWorkflow(t, ms, 2, 1, nil)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	// Workflow 2 refuses to call back into 1 and takes its error edge
+	if n := len(ms.statuses()); n != 2 {
+		t.Fatalf("expected two executions, got %d", n)
+	}
+	if child := ms.execution(t, 2); child.Status != "completed" || decode(t, child.StateData)["last_error"] == nil {
+		t.Errorf("expected the nested call to be refused, got %+v", child)
+	}
+
+	nodes := ms.workflows[2].Nodes
+	if err := engine.CheckWorkflowCalls(ctx, ms, 1, 2, nodes); !errors.Is(err, engine.ErrCallCycle) {
+		t.Errorf("expected a cycle error on save, got %v", err)
+	}
+	qualifyModule(t, ms, 9)
+	callerWorkflow(t, ms, 10, 9, nil)
+	if err := engine.CheckWorkflowCalls(ctx, ms, 1, 10, ms.workflows[10].Nodes); err != nil {
+		t.Errorf("expected a plain module call to pass, got %v", err)
+	}
+	if err := engine.CheckWorkflowCalls(ctx, ms, 2, 3, nodes); err == nil {
+		t.Error("expected calls into another tenant's workflow to be rejected")
+	}
+
+	// A chain deeper than MaxCallDepth
+	ms = newMemStore()
+	for id := int64(1); id <= engine.MaxCallDepth+2; id++ {
+		callerWorkflow(t, ms, id, id+1, nil)
+	}
+	qualifyModule(t, ms, engine.MaxCallDepth+3)
+	if err := engine.CheckWorkflowCalls(ctx, ms, 1, 1, ms.workflows[1].Nodes); err == nil {
+		t.Error("expected a too-deep call chain to be rejected")
+	}
+}
@@ -0,0 +1,333 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Condition is a small boolean expression stored in a logic_condition node.
+// A group has Op ("and"/"or") and Conditions; a leaf has Field, Operator and Value.
+//
+//	{"op": "and", "conditions": [
+//	    {"field": "contact.is_hot_lead", "operator": "eq", "value": true},
+//	    {"field": "state.answer_budget", "operator": "gte", "value": 5000000}
+//	]}
+type Condition struct {
+	Op         string      `json:"op,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+	Field      string      `json:"field,omitempty"`
+	Operator   string      `json:"operator,omitempty"`
+	Value      interface{} `json:"value,omitempty"`
+
+	re *regexp.Regexp // compiled once by ParseCondition for the "regex" operator
+}
+
+// ConditionBranch routes to Handle when Condition evaluates to true
+type ConditionBranch struct {
+	Handle    string     `json:"handle"`
+	Condition *Condition `json:"condition"`
+}
+
+// Default source handles of a logic_condition node
+const (
+	HandleTrue    = "true"
+	HandleFalse   = "false"
+	HandleDefault = "default" // used by named branches when nothing matched
+)
+
+var conditionOperators = map[string]bool{
+	"eq": true, "neq": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+	"contains": true, "not_contains": true,
+	"in":     true,
+	"regex":  true,
+	"exists": true, "not_exists": true,
+}
+
+// ParseCondition decodes and validates a condition from node data (as produced by json.Unmarshal
+// into interface{}). Errors describe the first invalid part of the expression.
+func ParseCondition(raw interface{}) (*Condition, error) {
+	if raw == nil {
+		return nil, fmt.Errorf("condition is required")
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("condition is not valid JSON: %w", err)
+	}
+	var c Condition
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("condition has invalid shape: %w", err)
+	}
+	if err := c.compile(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *Condition) compile() error {
+	if c.Op != "" || len(c.Conditions) > 0 {
+		if c.Op != "and" && c.Op != "or" {
+			return fmt.Errorf("group op must be \"and\" or \"or\", got %q", c.Op)
+		}
+		if len(c.Conditions) == 0 {
+			return fmt.Errorf("%s group has no conditions", c.Op)
+		}
+		for i := range c.Conditions {
+			if err := c.Conditions[i].compile(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !IsKnownVariable(c.Field) {
+		return fmt.Errorf("unknown field %q", c.Field)
+	}
+	if !conditionOperators[c.Operator] {
+		return fmt.Errorf("unknown operator %q for field %s", c.Operator, c.Field)
+	}
+	if c.Operator == "exists" || c.Operator == "not_exists" {
+		return nil
+	}
+	if c.Value == nil {
+		return fmt.Errorf("operator %q on %s requires a value", c.Operator, c.Field)
+	}
+	if c.Operator == "regex" {
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("regex on %s must be a string", c.Field)
+		}
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regex on %s: %v", c.Field, err)
+		}
+		c.re = re
+	}
+	return nil
+}
+
+// Evaluate runs the expression against the given variables
+func (c *Condition) Evaluate(vars *Variables) bool {
+	switch c.Op {
+	case "and":
+		for i := range c.Conditions {
+			if !c.Conditions[i].Evaluate(vars) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for i := range c.Conditions {
+			if c.Conditions[i].Evaluate(vars) {
+				return true
+			}
+		}
+		return false
+	}
+
+	actual, found := vars.Lookup(c.Field)
+
+	switch c.Operator {
+	case "exists":
+		return found && !isEmptyValue(actual)
+	case "not_exists":
+		return !found || isEmptyValue(actual)
+	case "eq":
+		return found && valuesEqual(actual, c.Value)
+	case "neq":
+		return !found || !valuesEqual(actual, c.Value)
+	case "gt", "gte", "lt", "lte":
+		a, okA := toFloat(actual)
+		b, okB := toFloat(c.Value)
+		if !found || !okA || !okB {
+			return false
+		}
+		switch c.Operator {
+		case "gt":
+			return a > b
+		case "gte":
+			return a >= b
+		case "lt":
+			return a < b
+		default:
+			return a <= b
+		}
+	case "contains":
+		return found && valueContains(actual, c.Value)
+	case "not_contains":
+		return !found || !valueContains(actual, c.Value)
+	case "in":
+		list, ok := c.Value.([]interface{})
+		if !found || !ok {
+			return false
+		}
+		for _, item := range list {
+			if valuesEqual(actual, item) {
+				return true
+			}
+		}
+		return false
+	case "regex":
+		if !found || c.re == nil {
+			return false
+		}
+		for _, s := range stringValues(actual) {
+			if c.re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// ParseConditionNode compiles the routing rules of a logic_condition node. A node either holds a
+// single "condition" (routes to true/false) or a list of named "branches" evaluated in order.
+func ParseConditionNode(node *models.ReactFlowNode) ([]ConditionBranch, error) {
+	if rawBranches, ok := node.Data["branches"].([]interface{}); ok && len(rawBranches) > 0 {
+		branches := make([]ConditionBranch, 0, len(rawBranches))
+		for i, rb := range rawBranches {
+			m, ok := rb.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("branch %d is not an object", i)
+			}
+			handle, _ := m["handle"].(string)
+			if handle == "" {
+				return nil, fmt.Errorf("branch %d has no handle", i)
+			}
+			if handle == HandleDefault {
+				return nil, fmt.Errorf("branch %d uses reserved handle %q", i, HandleDefault)
+			}
+			cond, err := ParseCondition(m["condition"])
+			if err != nil {
+				return nil, fmt.Errorf("branch %q: %w", handle, err)
+			}
+			branches = append(branches, ConditionBranch{Handle: handle, Condition: cond})
+		}
+		return branches, nil
+	}
+
+	cond, err := ParseCondition(node.Data["condition"])
+	if err != nil {
+		return nil, err
+	}
+	return []ConditionBranch{{Handle: HandleTrue, Condition: cond}}, nil
+}
+
+// ConditionNodeHandles lists every source handle a logic_condition node can route to
+func ConditionNodeHandles(branches []ConditionBranch) []string {
+	if len(branches) == 1 && branches[0].Handle == HandleTrue {
+		return []string{HandleTrue, HandleFalse}
+	}
+	handles := make([]string, 0, len(branches)+1)
+	for _, b := range branches {
+		handles = append(handles, b.Handle)
+	}
+	return append(handles, HandleDefault)
+}
+
+// EvaluateBranches returns the handle of the first branch that matches, or the fallback handle
+func EvaluateBranches(branches []ConditionBranch, vars *Variables) string {
+	for _, b := range branches {
+		if b.Condition.Evaluate(vars) {
+			return b.Handle
+		}
+	}
+	if len(branches) == 1 && branches[0].Handle == HandleTrue {
+		return HandleFalse
+	}
+	return HandleDefault
+}
+
+// ValidateConditionNodes checks every logic_condition node of a graph so broken expressions are
+// rejected when the workflow is saved instead of when a lead reaches the node.
+func ValidateConditionNodes(graph *models.WorkflowGraph) error {
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Type != models.NodeTypeLogicCondition {
+			continue
+		}
+		if _, err := ParseConditionNode(node); err != nil {
+			return fmt.Errorf("node %s: %w", node.ID, err)
+		}
+	}
+	return nil
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []string:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+func valuesEqual(actual, expected interface{}) bool {
+	if a, ok := toFloat(actual); ok {
+		if b, ok := toFloat(expected); ok {
+			return a == b
+		}
+	}
+	if a, ok := actual.(bool); ok {
+		b, ok := expected.(bool)
+		return ok && a == b
+	}
+	return strings.EqualFold(fmt.Sprint(actual), fmt.Sprint(expected))
+}
+
+func valueContains(actual, expected interface{}) bool {
+	needle := strings.ToLower(fmt.Sprint(expected))
+	switch val := actual.(type) {
+	case []string, []interface{}:
+		for _, s := range stringValues(val) {
+			if strings.ToLower(s) == needle {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(strings.ToLower(fmt.Sprint(actual)), needle)
+}
+
+func stringValues(v interface{}) []string {
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	case nil:
+		return nil
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+// toFloat converts JSON numbers and numeric strings (e.g. "5,000,000") to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(val), ",", ""), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
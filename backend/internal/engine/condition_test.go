@@ -0,0 +1,122 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func mustCondition(t *testing.T, raw string) *engine.Condition {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		t.Fatalf("bad test JSON: %v", err)
+	}
+	c, err := engine.ParseCondition(v)
+	if err != nil {
+		t.Fatalf("ParseCondition(%s): %v", raw, err)
+	}
+	return c
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	vars := &engine.Variables{
+		Contact: &models.Contact{
+			Budget:            "7500000",
+			PreferredLocation: "Whitefield, Bangalore",
+			IsHotLead:         true,
+			Tags:              []string{"warm", "2bhk"},
+			BookingState:      "qualified",
+			Platform:          "whatsapp",
+		},
+		State:   map[string]interface{}{"answer_budget": float64(90), "visited": ""},
+		Message: "Is there a 3 BHK?",
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"eq bool", `{"field":"contact.is_hot_lead","operator":"eq","value":true}`, true},
+		{"eq string case-insensitive", `{"field":"contact.platform","operator":"eq","value":"WhatsApp"}`, true},
+		{"gte numeric string", `{"field":"contact.budget","operator":"gte","value":5000000}`, true},
+		{"lt state", `{"field":"state.answer_budget","operator":"lt","value":50}`, false},
+		{"contains substring", `{"field":"contact.preferred_location","operator":"contains","value":"whitefield"}`, true},
+		{"contains tag", `{"field":"contact.tags","operator":"contains","value":"WARM"}`, true},
+		{"not_contains tag", `{"field":"contact.tags","operator":"not_contains","value":"cold"}`, true},
+		{"in", `{"field":"contact.booking_state","operator":"in","value":["new","qualified"]}`, true},
+		{"regex message", `{"field":"message.text","operator":"regex","value":"\\d\\s*bhk"}`, true},
+		{"exists empty", `{"field":"state.visited","operator":"exists"}`, false},
+		{"not_exists missing", `{"field":"state.unknown","operator":"not_exists"}`, true},
+		{"and group", `{"op":"and","conditions":[
+			{"field":"contact.is_hot_lead","operator":"eq","value":true},
+			{"field":"contact.booking_state","operator":"eq","value":"booked"}]}`, false},
+		{"or nested", `{"op":"or","conditions":[
+			{"field":"contact.booking_state","operator":"eq","value":"booked"},
+			{"op":"and","conditions":[
+				{"field":"contact.tags","operator":"contains","value":"2bhk"},
+				{"field":"state.answer_budget","operator":"gt","value":80}]}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustCondition(t, tt.expr).Evaluate(vars); got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	bad := []string{
+		`{"field":"contact.salary","operator":"eq","value":1}`,
+		`{"field":"contact.budget","operator":"between","value":1}`,
+		`{"field":"contact.budget","operator":"gt"}`,
+		`{"field":"message.text","operator":"regex","value":"("}`,
+		`{"op":"xor","conditions":[{"field":"contact.name","operator":"exists"}]}`,
+		`{"op":"and","conditions":[]}`,
+	}
+	for _, raw := range bad {
+		var v interface{}
+		_ = json.Unmarshal([]byte(raw), &v)
+		if _, err := engine.ParseCondition(v); err == nil {
+			t.Errorf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestConditionNodeBranches(t *testing.T) {
+	vars := &engine.Variables{Contact: &models.Contact{Budget: "100"}}
+
+	single := &models.ReactFlowNode{ID: "c1", Type: models.NodeTypeLogicCondition, Data: map[string]interface{}{
+		"condition": map[string]interface{}{"field": "contact.budget", "operator": "gt", "value": float64(500)},
+	}}
+	branches, err := engine.ParseConditionNode(single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.EvaluateBranches(branches, vars); got != engine.HandleFalse {
+		t.Errorf("expected false handle, got %q", got)
+	}
+
+	named := &models.ReactFlowNode{ID: "c2", Type: models.NodeTypeLogicCondition, Data: map[string]interface{}{
+		"branches": []interface{}{
+			map[string]interface{}{"handle": "premium", "condition": map[string]interface{}{"field": "contact.budget", "operator": "gt", "value": float64(500)}},
+			map[string]interface{}{"handle": "budget", "condition": map[string]interface{}{"field": "contact.budget", "operator": "lte", "value": float64(500)}},
+		},
+	}}
+	branches, err = engine.ParseConditionNode(named)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.EvaluateBranches(branches, vars); got != "budget" {
+		t.Errorf("expected budget handle, got %q", got)
+	}
+	handles := engine.ConditionNodeHandles(branches)
+	if len(handles) != 3 || handles[2] != engine.HandleDefault {
+		t.Errorf("unexpected handles %v", handles)
+	}
+}
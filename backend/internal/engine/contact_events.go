@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Contact events that can start a trigger_contact_event workflow (node.Data["event"])
+const (
+	ContactEventTagAdded           = "tag_added"
+	ContactEventFieldChanged       = "field_changed"
+	ContactEventVisitBooked        = "visit_booked"
+	ContactEventVisitStatusChanged = "visit_status_changed"
+	ContactEventBotResumed         = "bot_resumed"
+)
+
+// IsValidContactEvent reports whether a trigger_contact_event node may listen for the event
+func IsValidContactEvent(event string) bool {
+	switch event {
+	case ContactEventTagAdded, ContactEventFieldChanged, ContactEventVisitBooked,
+		ContactEventVisitStatusChanged, ContactEventBotResumed:
+		return true
+	}
+	return false
+}
+
+// ContactEvent is something that happened to a contact outside a conversation. It is the body of
+// the "workflow:contact_event" task and, minus the IDs, the "event" started workflows see in state.
+type ContactEvent struct {
+	Type      string    `json:"type"`
+	UserID    int64     `json:"user_id"`
+	ContactID int64     `json:"contact_id"`
+	Tag       string    `json:"tag,omitempty"`       // tag_added
+	Field     string    `json:"field,omitempty"`     // field_changed
+	OldValue  string    `json:"old_value,omitempty"` // field_changed, visit_status_changed
+	NewValue  string    `json:"new_value,omitempty"` // field_changed, visit_status_changed
+	VisitID   int64     `json:"visit_id,omitempty"`  // visit_booked, visit_status_changed
+	At        time.Time `json:"at"`
+}
+
+// state is the event as stored under StateData["event"]
+func (e ContactEvent) state() map[string]interface{} {
+	s := map[string]interface{}{"type": e.Type, "at": e.At.Format(time.RFC3339)}
+	if e.Tag != "" {
+		s["tag"] = e.Tag
+	}
+	if e.Field != "" {
+		s["field"] = e.Field
+		s["old_value"] = e.OldValue
+		s["new_value"] = e.NewValue
+	}
+	if e.VisitID != 0 {
+		s["visit_id"] = e.VisitID
+		if e.Type == ContactEventVisitStatusChanged {
+			s["old_status"] = e.OldValue
+			s["status"] = e.NewValue
+		}
+	}
+	return s
+}
+
+// EmitContactEvent hands a contact event to the workflows listening for it. With an Asynq client
+// the matching runs in a "workflow:contact_event" task; without one it runs inline. Simulations
+// never emit events.
+func (gw *GraphWalker) EmitContactEvent(ctx context.Context, evt ContactEvent) {
+	if gw.sim != nil {
+		return
+	}
+	if evt.At.IsZero() {
+		evt.At = time.Now()
+	}
+	if evt.UserID == 0 {
+		contact, err := gw.Store.GetContactByID(ctx, evt.ContactID)
+		if err != nil {
+			log.Printf("[Trigger] Dropping %s event, contact %d not found: %v", evt.Type, evt.ContactID, err)
+			return
+		}
+		evt.UserID = contact.UserID
+	}
+
+	if gw.AsynqClient == nil {
+		if err := gw.HandleContactEvent(ctx, evt); err != nil {
+			log.Printf("[Trigger] Failed to handle %s event for contact %d: %v", evt.Type, evt.ContactID, err)
+		}
+		return
+	}
+	payload, _ := json.Marshal(evt)
+	if _, err := gw.AsynqClient.Enqueue(asynq.NewTask("workflow:contact_event", payload)); err != nil {
+		log.Printf("ERROR: Failed to enqueue %s event for contact %d: %v", evt.Type, evt.ContactID, err)
+	}
+}
+
+// EmitFieldChanges emits a field_changed event for every lead field that differs between two
+// versions of a contact
+func (gw *GraphWalker) EmitFieldChanges(ctx context.Context, before, after *models.Contact) {
+	for _, f := range ContactFieldChanges(before, after) {
+		f.UserID = after.UserID
+		f.ContactID = after.ID
+		gw.EmitContactEvent(ctx, f)
+	}
+}
+
+// ContactFieldChanges lists the lead fields that differ between two versions of a contact, named
+// like the contact.* template variables
+func ContactFieldChanges(before, after *models.Contact) []ContactEvent {
+	fields := []struct{ name, old, new string }{
+		{"phone", before.Phone, after.Phone},
+		{"email", before.Email, after.Email},
+		{"budget", before.Budget, after.Budget},
+		{"preferred_location", before.PreferredLocation, after.PreferredLocation},
+		{"purchase_timeline", before.PurchaseTimeline, after.PurchaseTimeline},
+		{"is_hot_lead", fmt.Sprint(before.IsHotLead), fmt.Sprint(after.IsHotLead)},
+	}
+	var events []ContactEvent
+	for _, f := range fields {
+		if f.old != f.new {
+			events = append(events, ContactEvent{Type: ContactEventFieldChanged, Field: f.name, OldValue: f.old, NewValue: f.new})
+		}
+	}
+	return events
+}
+
+// HandleContactEvent starts every published trigger_contact_event workflow of the tenant that
+// listens for the event
+func (gw *GraphWalker) HandleContactEvent(ctx context.Context, evt ContactEvent) error {
+	workflows, err := gw.Store.GetActiveWorkflowsByTrigger(ctx, evt.UserID, string(models.NodeTypeTriggerContactEvent))
+	if err != nil {
+		return fmt.Errorf("failed to fetch contact event workflows: %w", err)
+	}
+	matches := MatchContactEvent(workflows, evt)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	contact, err := gw.Store.GetContactByID(ctx, evt.ContactID)
+	if err != nil {
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	for _, m := range matches {
+		log.Printf("[Trigger] %s event starting workflow %d for contact %d", evt.Type, m.Workflow.ID, contact.ID)
+		gw.startTriggered(ctx, m.Workflow.ID, m.TriggerNodeID, contact, evt.state())
+	}
+	return nil
+}
+
+// MatchContactEvent returns the workflows whose trigger_contact_event node listens for the event,
+// in workflow ID order
+func MatchContactEvent(workflows []models.Workflow, evt ContactEvent) []TriggerMatch {
+	var matches []TriggerMatch
+	for _, w := range workflows {
+		if w.UserID != evt.UserID {
+			continue
+		}
+		graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+		if err != nil {
+			log.Printf("[Trigger] Skipping workflow %d: invalid graph: %v", w.ID, err)
+			continue
+		}
+		for i := range graph.Nodes {
+			node := &graph.Nodes[i]
+			if node.Type != models.NodeTypeTriggerContactEvent || !matchContactEventNode(node, evt) {
+				continue
+			}
+			matches = append(matches, TriggerMatch{Workflow: w, TriggerNodeID: node.ID, TriggerType: node.Type})
+			break
+		}
+	}
+	return matches
+}
+
+// matchContactEventNode checks the event type and the node's optional tag/field/status filter
+func matchContactEventNode(node *models.ReactFlowNode, evt ContactEvent) bool {
+	if node.DataString("event", "") != evt.Type {
+		return false
+	}
+	switch evt.Type {
+	case ContactEventTagAdded:
+		return matchOptional(node.DataString("tag", ""), evt.Tag)
+	case ContactEventFieldChanged:
+		return matchOptional(node.DataString("field", ""), evt.Field)
+	case ContactEventVisitStatusChanged:
+		return matchOptional(node.DataString("status", ""), evt.NewValue)
+	}
+	return true
+}
+
+// matchOptional treats an empty filter as "any"
+func matchOptional(filter, value string) bool {
+	filter = strings.TrimSpace(filter)
+	return filter == "" || strings.EqualFold(filter, value)
+}
+
+// startTriggered starts a workflow for a contact from a non-message trigger. The trigger's
+// payload is kept in state as "event".
+func (gw *GraphWalker) startTriggered(ctx context.Context, workflowID int64, triggerNodeID string, contact *models.Contact, event map[string]interface{}) {
+	initialState := map[string]interface{}{
+		"platform":        contact.Platform,
+		"contact_name":    contact.Name,
+		"trigger_node_id": triggerNodeID,
+		"event":           event,
+	}
+	err := gw.StartWorkflow(ctx, workflowID, contact.ID, initialState)
+	if errors.Is(err, ErrEntrySkipped) {
+		log.Printf("[Engine] Workflow %d not started for contact %d: %v", workflowID, contact.ID, err)
+	} else if err != nil {
+		log.Printf("[Engine] Workflow %d execution failed for contact %d: %v", workflowID, contact.ID, err)
+	}
+}
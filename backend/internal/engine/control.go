@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// Execution statuses set from outside the walker. The walker never overwrites them; it notices
+// them before running its next node and stops.
+const (
+	StatusPaused      = "paused"
+	StatusCancelled   = "cancelled"
+	StatusGoalReached = "goal_reached"
+)
+
+// Actions accepted by ControlExecution and ControlExecutions
+const (
+	ActionCancel = "cancel"
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+// ErrInvalidTransition is returned when an execution's status does not allow the action
+var ErrInvalidTransition = errors.New("execution cannot be changed in its current status")
+
+// ErrBotPaused is returned instead of sending to a contact an agent has taken over
+var ErrBotPaused = errors.New("bot is paused for this contact")
+
+// controllable lists the statuses each action applies to
+var controllable = map[string][]string{
+	ActionCancel: {"running", "waiting", StatusPaused, StatusQueued},
+	ActionPause:  {"running", "waiting"},
+	ActionResume: {StatusPaused},
+}
+
+// ControlExecution cancels, pauses or resumes one execution. Paused executions keep their node
+// and what they were waiting for: resuming a reply wait re-arms the wait (and its timeout), a
+// delay or retry backoff continues at its original wake-up time (or at once if that has passed),
+// a workflow call waits on for the called execution, and anything else continues from its
+// current node.
+func (gw *GraphWalker) ControlExecution(ctx context.Context, exec *models.WorkflowExecution, action string) error {
+	from, ok := controllable[action]
+	if !ok {
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	switch action {
+	case ActionCancel, ActionPause:
+		to := StatusCancelled
+		if action == ActionPause {
+			to = StatusPaused
+		}
+		changed, err := gw.Store.SetWorkflowExecutionStatus(ctx, exec.ID, from, to)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrInvalidTransition
+		}
+		log.Printf("Execution %d %s", exec.ID, to)
+		if to == StatusCancelled {
+			gw.startQueued(ctx, exec.WorkflowID, exec.ContactID)
+			gw.returnToCaller(ctx, exec)
+		}
+		return nil
+	}
+
+	// A caller paused during its call goes back to waiting; the call may have returned meanwhile
+	if exec.WaitingFor == WaitingForWorkflow {
+		changed, err := gw.Store.SetWorkflowExecutionStatus(ctx, exec.ID, from, "waiting")
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrInvalidTransition
+		}
+		gw.resumeCallWait(ctx, exec)
+		return nil
+	}
+
+	// Resume
+	if exec.WaitingFor == WaitingForDelay || exec.WaitingFor == WaitingForRetry {
+		changed, err := gw.Store.SetWorkflowExecutionStatus(ctx, exec.ID, from, "waiting")
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrInvalidTransition
+		}
+		wakeAt := time.Now()
+		if until, err := time.Parse(time.RFC3339, fmt.Sprint(decodeState(exec.StateData)["delay_until"])); err == nil && until.After(wakeAt) {
+			wakeAt = until
+		}
+		return gw.scheduleResume(ctx, exec.ID, wakeAt)
+	}
+
+	changed, err := gw.Store.SetWorkflowExecutionStatus(ctx, exec.ID, from, "running")
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrInvalidTransition
+	}
+	return gw.scheduleResume(ctx, exec.ID, time.Now())
+}
+
+// ControlExecutions applies an action to every matching execution of a user (filter.WorkflowID
+// and/or filter.ContactID) and returns how many changed. Executions that finish concurrently are
+// skipped rather than reported as errors.
+func (gw *GraphWalker) ControlExecutions(ctx context.Context, userID int64, filter store.ExecutionFilter, action string) (int, error) {
+	statuses, ok := controllable[action]
+	if !ok {
+		return 0, fmt.Errorf("unknown action %q", action)
+	}
+
+	// Collect first: acting changes the status the listing filters on
+	const pageSize = 200
+	var targets []models.WorkflowExecution
+	for _, status := range statuses {
+		f := filter
+		f.Status = status
+		f.Limit = pageSize
+		for f.Offset = 0; ; f.Offset += pageSize {
+			page, err := gw.Store.ListWorkflowExecutions(ctx, userID, f)
+			if err != nil {
+				return 0, err
+			}
+			targets = append(targets, page...)
+			if len(page) < pageSize {
+				break
+			}
+		}
+	}
+
+	changed := 0
+	for i := range targets {
+		err := gw.ControlExecution(ctx, &targets[i], action)
+		switch {
+		case err == nil:
+			changed++
+		case errors.Is(err, ErrInvalidTransition):
+		default:
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// ResumeAgentWaits continues the contact's executions that parked because the bot was paused.
+// Call it when an agent hands the conversation back to the bot.
+func (gw *GraphWalker) ResumeAgentWaits(ctx context.Context, contactID int64) {
+	execs, err := gw.Store.GetActiveExecutionsByContact(ctx, contactID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to load executions of contact %d: %v", contactID, err)
+		return
+	}
+	for _, exec := range execs {
+		if exec.Status != "waiting" || exec.WaitingFor != WaitingForAgent {
+			continue
+		}
+		claimed, err := gw.Store.ClaimWaitingExecution(ctx, exec.ID, WaitingForAgent)
+		if err != nil || !claimed {
+			continue
+		}
+		log.Printf("Execution %d resumed, bot is active again for contact %d", exec.ID, contactID)
+		if err := gw.scheduleResume(ctx, exec.ID, time.Now()); err != nil {
+			log.Printf("[GraphWalker] Failed to resume execution %d: %v", exec.ID, err)
+		}
+	}
+}
+
+// scheduleResume enqueues a "workflow:resume" task at the given time. Without an Asynq client
+// due executions are walked inline.
+func (gw *GraphWalker) scheduleResume(ctx context.Context, executionID int64, at time.Time) error {
+	if gw.AsynqClient == nil {
+		if at.After(time.Now()) {
+			log.Printf("WARNING: AsynqClient is nil, execution %d is permanently stalled.", executionID)
+			return nil
+		}
+		return gw.ResumeExecution(ctx, executionID)
+	}
+	payload, _ := json.Marshal(map[string]int64{"execution_id": executionID})
+	if _, err := gw.AsynqClient.Enqueue(asynq.NewTask("workflow:resume", payload), asynq.ProcessAt(at)); err != nil {
+		return fmt.Errorf("failed to enqueue resume task: %w", err)
+	}
+	return nil
+}
+
+// parkForAgent suspends the execution in front of a node that would message a contact whose bot
+// an agent has paused. ResumeAgentWaits picks it up again at the same node.
+func (gw *GraphWalker) parkForAgent(ctx context.Context, exec *models.WorkflowExecution, node *models.ReactFlowNode, stateData map[string]interface{}) error {
+	exec.StateData, _ = json.Marshal(stateData)
+	exec.Status = "waiting"
+	exec.WaitingFor = WaitingForAgent
+	exec.CurrentNodeID = node.ID
+	if err := gw.Store.UpdateWorkflowExecution(ctx, exec); err != nil {
+		return fmt.Errorf("failed to park execution: %w", err)
+	}
+
+	rec := newStepRecord()
+	rec.out("waiting_for", WaitingForAgent)
+	gw.saveStep(ctx, exec, node, rec, nil)
+
+	log.Printf("Execution %d waiting at node %s, bot is paused for contact %d", exec.ID, node.ID, exec.ContactID)
+	return nil
+}
+
+// sendsMessage reports whether a node type messages the contact
+func sendsMessage(t models.NodeType) bool {
+	switch t {
+	case models.NodeTypeActionSendMessage, models.NodeTypeActionAIReply:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// delayWorkflow: trigger -> delay -> add_tag "after_delay"
+func delayWorkflow(t *testing.T, ms *memStore) {
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionDelay, Data: map[string]interface{}{"delayMs": float64(3600000)}},
+			{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "after_delay"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", Target: "3"},
+		},
+	)
+}
+
+func TestPauseResumeCancel(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	delayWorkflow(t, ms)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForDelay {
+		t.Fatalf("expected execution waiting on the delay, got %s/%s", exec.Status, exec.WaitingFor)
+	}
+
+	if err := gw.ControlExecution(ctx, exec, engine.ActionPause); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.ControlExecution(ctx, exec, engine.ActionPause); !errors.Is(err, engine.ErrInvalidTransition) {
+		t.Errorf("pausing twice should be an invalid transition, got %v", err)
+	}
+
+	// The delay task fires while paused and must not move the execution
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if exec = ms.onlyExecution(t); exec.Status != engine.StatusPaused {
+		t.Fatalf("expected paused, got %s", exec.Status)
+	}
+
+	// Resuming restores the delay (still an hour away) rather than running the next node early
+	if err := gw.ControlExecution(ctx, exec, engine.ActionResume); err != nil {
+		t.Fatal(err)
+	}
+	if exec = ms.onlyExecution(t); exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForDelay {
+		t.Fatalf("expected the delay to be restored, got %s/%s", exec.Status, exec.WaitingFor)
+	}
+
+	// Two resume tasks for the same delay: only the first walks
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if exec = ms.onlyExecution(t); exec.Status != "completed" {
+		t.Fatalf("expected completed, got %s", exec.Status)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "after_delay" {
+		t.Errorf("expected the tag to be added once, got %v", tags)
+	}
+
+	if err := gw.ControlExecution(ctx, exec, engine.ActionCancel); !errors.Is(err, engine.ErrInvalidTransition) {
+		t.Errorf("cancelling a completed execution should be an invalid transition, got %v", err)
+	}
+}
+
+func TestCancelStopsExecution(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	delayWorkflow(t, ms)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if err := gw.ControlExecution(ctx, exec, engine.ActionCancel); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if exec = ms.onlyExecution(t); exec.Status != engine.StatusCancelled {
+		t.Fatalf("expected cancelled, got %s", exec.Status)
+	}
+	if len(ms.contacts[1].Tags) != 0 {
+		t.Error("cancelled execution kept running")
+	}
+	if err := gw.ControlExecution(ctx, exec, engine.ActionResume); !errors.Is(err, engine.ErrInvalidTransition) {
+		t.Errorf("resuming a cancelled execution should be an invalid transition, got %v", err)
+	}
+}
+
+func TestBotPausedHoldsMessages(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.contacts[1].BotPaused = true
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "seen"}},
+			{ID: "3", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": "Hello"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", Target: "3"},
+		},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, fakeMeta(http.StatusOK))
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForAgent || exec.CurrentNodeID != "3" {
+		t.Fatalf("expected execution held in front of the message, got %s/%s at %s", exec.Status, exec.WaitingFor, exec.CurrentNodeID)
+	}
+	if len(ms.contacts[1].Tags) != 1 {
+		t.Error("nodes that do not message the contact should still run")
+	}
+
+	// Still paused: nothing happens
+	gw.ResumeAgentWaits(ctx, 1)
+	if exec = ms.onlyExecution(t); exec.Status != "waiting" {
+		t.Fatalf("expected execution to keep waiting while the bot is paused, got %s", exec.Status)
+	}
+
+	ms.contacts[1].BotPaused = false
+	gw.ResumeAgentWaits(ctx, 1)
+	if exec = ms.onlyExecution(t); exec.Status != "completed" {
+		t.Fatalf("expected completion once the bot is back, got %s", exec.Status)
+	}
+	last := ms.steps[len(ms.steps)-1]
+	var out map[string]interface{}
+	_ = json.Unmarshal(last.Output, &out)
+	if last.NodeID != "3" || out["message"] != "Hello" {
+		t.Errorf("expected the held message to be sent last, got %s %v", last.NodeID, out)
+	}
+	if len(ms.messages) != 1 {
+		t.Errorf("expected one message sent, got %d", len(ms.messages))
+	}
+}
+
+func TestGoals(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Tag goal ends the running execution", func(t *testing.T) {
+		ms := newMemStore()
+		ms.addWorkflow(t, 1,
+			[]models.ReactFlowNode{
+				{ID: "1", Type: models.NodeTypeTriggerDM},
+				{ID: "2", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "booked"}},
+				{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "nurtured"}},
+			},
+			[]models.ReactFlowEdge{
+				{ID: "e1", Source: "1", Target: "2"},
+				{ID: "e2", Source: "2", Target: "3"},
+			},
+		)
+		ms.workflows[1].Goals = json.RawMessage(`[{"name": "Booked", "type": "tag_added", "tag": "booked"}]`)
+		gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		exec := ms.onlyExecution(t)
+		if exec.Status != engine.StatusGoalReached {
+			t.Fatalf("expected goal_reached, got %s", exec.Status)
+		}
+		var state map[string]interface{}
+		_ = json.Unmarshal(exec.StateData, &state)
+		if state["goal_reached"] != "Booked" {
+			t.Errorf("expected goal name in state, got %v", state["goal_reached"])
+		}
+		if tags := ms.contacts[1].Tags; len(tags) != 1 {
+			t.Errorf("execution kept running after its goal, tags %v", tags)
+		}
+	})
+
+	t.Run("CheckGoals ends waiting executions", func(t *testing.T) {
+		ms := newMemStore()
+		delayWorkflow(t, ms)
+		ms.workflows[1].Goals = json.RawMessage(`[{"condition": {"field": "contact.tags", "operator": "contains", "value": "vip"}}]`)
+		gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		gw.CheckGoals(ctx, 1)
+		if exec := ms.onlyExecution(t); exec.Status != "waiting" {
+			t.Fatalf("goal not met yet, got %s", exec.Status)
+		}
+
+		ms.contacts[1].Tags = []string{"vip"}
+		gw.CheckGoals(ctx, 1)
+		if exec := ms.onlyExecution(t); exec.Status != engine.StatusGoalReached {
+			t.Fatalf("expected goal_reached, got %s", exec.Status)
+		}
+	})
+}
+
+func TestParseGoals(t *testing.T) {
+	goals, err := engine.ParseGoals(json.RawMessage(`[{"type": "visit_booked"}, {"type": "tag_added", "tag": "customer"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goals) != 2 || goals[0].Name != engine.GoalVisitBooked {
+		t.Errorf("unexpected goals %+v", goals)
+	}
+	if !goals[0].Met(&engine.Variables{Visit: &models.Visit{Status: "confirmed"}}) {
+		t.Error("a confirmed visit should meet visit_booked")
+	}
+	if goals[0].Met(&engine.Variables{Visit: &models.Visit{Status: "cancelled"}}) {
+		t.Error("a cancelled visit should not meet visit_booked")
+	}
+
+	for _, raw := range []string{
+		`{"type": "visit_booked"}`,
+		`[{"type": "tag_added"}]`,
+		`[{"type": "signed_contract"}]`,
+		`[{"condition": {"field": "contact.shoe_size", "operator": "eq", "value": 9}}]`,
+	} {
+		if _, err := engine.ParseGoals(json.RawMessage(raw)); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// NodeChange describes a node present in both graphs whose behaviour differs
+type NodeChange struct {
+	NodeID string   `json:"node_id"`
+	Fields []string `json:"fields"` // "type" and/or "data.<key>"
+}
+
+// GraphDiff is the behavioural difference between two workflow graphs. Canvas positions are
+// ignored; edges are identified by source, handle, target and their order among the edges leaving
+// that handle rather than their React Flow ID.
+type GraphDiff struct {
+	AddedNodes   []string               `json:"added_nodes"`
+	RemovedNodes []string               `json:"removed_nodes"`
+	ChangedNodes []NodeChange           `json:"changed_nodes"`
+	AddedEdges   []models.ReactFlowEdge `json:"added_edges"`
+	RemovedEdges []models.ReactFlowEdge `json:"removed_edges"`
+}
+
+// Empty reports whether the two graphs behave identically
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.ChangedNodes) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0
+}
+
+// DiffGraphs compares an old and a new graph
+func DiffGraphs(from, to *models.WorkflowGraph) *GraphDiff {
+	d := &GraphDiff{
+		AddedNodes:   []string{},
+		RemovedNodes: []string{},
+		ChangedNodes: []NodeChange{},
+		AddedEdges:   []models.ReactFlowEdge{},
+		RemovedEdges: []models.ReactFlowEdge{},
+	}
+
+	oldNodes := make(map[string]*models.ReactFlowNode, len(from.Nodes))
+	for i := range from.Nodes {
+		oldNodes[from.Nodes[i].ID] = &from.Nodes[i]
+	}
+	newNodes := make(map[string]*models.ReactFlowNode, len(to.Nodes))
+	for i := range to.Nodes {
+		n := &to.Nodes[i]
+		newNodes[n.ID] = n
+		old, ok := oldNodes[n.ID]
+		if !ok {
+			d.AddedNodes = append(d.AddedNodes, n.ID)
+			continue
+		}
+		if fields := changedFields(old, n); len(fields) > 0 {
+			d.ChangedNodes = append(d.ChangedNodes, NodeChange{NodeID: n.ID, Fields: fields})
+		}
+	}
+	for id := range oldNodes {
+		if _, ok := newNodes[id]; !ok {
+			d.RemovedNodes = append(d.RemovedNodes, id)
+		}
+	}
+	sort.Strings(d.AddedNodes)
+	sort.Strings(d.RemovedNodes)
+	sort.Slice(d.ChangedNodes, func(i, j int) bool { return d.ChangedNodes[i].NodeID < d.ChangedNodes[j].NodeID })
+
+	oldKeys, newKeys := edgeKeys(from.Edges), edgeKeys(to.Edges)
+	oldEdges := make(map[edgeKey]bool, len(oldKeys))
+	for _, k := range oldKeys {
+		oldEdges[k] = true
+	}
+	newEdges := make(map[edgeKey]bool, len(newKeys))
+	for i, k := range newKeys {
+		newEdges[k] = true
+		if !oldEdges[k] {
+			d.AddedEdges = append(d.AddedEdges, to.Edges[i])
+		}
+	}
+	for i, k := range oldKeys {
+		if !newEdges[k] {
+			d.RemovedEdges = append(d.RemovedEdges, from.Edges[i])
+		}
+	}
+	return d
+}
+
+// edgeKey identifies an edge by what it connects. rank is its position among the edges leaving
+// the same handle: findNextNode follows the first one, so reordering them changes routing.
+type edgeKey struct {
+	source, handle, target string
+	rank                   int
+}
+
+func edgeKeys(edges []models.ReactFlowEdge) []edgeKey {
+	type handleKey struct{ source, handle string }
+	seen := make(map[handleKey]int)
+	keys := make([]edgeKey, len(edges))
+	for i, e := range edges {
+		h := handleKey{e.Source, e.SourceHandle}
+		keys[i] = edgeKey{e.Source, e.SourceHandle, e.Target, seen[h]}
+		seen[h]++
+	}
+	return keys
+}
+
+func changedFields(old, cur *models.ReactFlowNode) []string {
+	var fields []string
+	if old.Type != cur.Type {
+		fields = append(fields, "type")
+	}
+	keys := make(map[string]bool)
+	for k := range old.Data {
+		keys[k] = true
+	}
+	for k := range cur.Data {
+		keys[k] = true
+	}
+	var changed []string
+	for k := range keys {
+		if !reflect.DeepEqual(old.Data[k], cur.Data[k]) {
+			changed = append(changed, "data."+k)
+		}
+	}
+	sort.Strings(changed)
+	return append(fields, changed...)
+}
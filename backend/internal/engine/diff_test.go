@@ -0,0 +1,91 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestDiffGraphs(t *testing.T) {
+	from := &models.WorkflowGraph{
+		Nodes: []models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": "hi", "label": "Greet"}},
+			{ID: "3", Type: models.NodeTypeActionDelay},
+		},
+		Edges: []models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}, {ID: "e2", Source: "2", Target: "3"}},
+	}
+	to := &models.WorkflowGraph{
+		Nodes: []models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM, Position: map[string]float64{"x": 50}},
+			{ID: "2", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": "hello", "label": "Greet"}},
+			{ID: "4", Type: models.NodeTypeActionAIReply},
+		},
+		// Same connection under a new React Flow ID is not a change
+		Edges: []models.ReactFlowEdge{{ID: "xy-1", Source: "1", Target: "2"}, {ID: "e3", Source: "2", Target: "4"}},
+	}
+
+	d := engine.DiffGraphs(from, to)
+	if len(d.AddedNodes) != 1 || d.AddedNodes[0] != "4" {
+		t.Errorf("added nodes = %v", d.AddedNodes)
+	}
+	if len(d.RemovedNodes) != 1 || d.RemovedNodes[0] != "3" {
+		t.Errorf("removed nodes = %v", d.RemovedNodes)
+	}
+	if len(d.ChangedNodes) != 1 || d.ChangedNodes[0].NodeID != "2" || d.ChangedNodes[0].Fields[0] != "data.message" {
+		t.Errorf("changed nodes = %+v", d.ChangedNodes)
+	}
+	if len(d.AddedEdges) != 1 || d.AddedEdges[0].ID != "e3" || len(d.RemovedEdges) != 1 || d.RemovedEdges[0].ID != "e2" {
+		t.Errorf("edges added=%v removed=%v", d.AddedEdges, d.RemovedEdges)
+	}
+	if !engine.DiffGraphs(from, from).Empty() {
+		t.Error("a graph should not differ from itself")
+	}
+
+	// Swapping edges that leave the same handle changes which one findNextNode follows
+	ordered := &models.WorkflowGraph{Nodes: from.Nodes, Edges: []models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}, {ID: "e2", Source: "1", Target: "3"}}}
+	swapped := &models.WorkflowGraph{Nodes: from.Nodes, Edges: []models.ReactFlowEdge{ordered.Edges[1], ordered.Edges[0]}}
+	if d := engine.DiffGraphs(ordered, swapped); d.Empty() || len(d.AddedEdges) != 2 || len(d.RemovedEdges) != 2 {
+		t.Errorf("reordered edges not reported: added=%v removed=%v", d.AddedEdges, d.RemovedEdges)
+	}
+}
+
+func TestExecutionPinnedToVersion(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionDelay, Data: map[string]interface{}{"delayMs": float64(1000)}},
+			{ID: "3", Type: models.NodeTypeActionDelay, Data: map[string]interface{}{"delayMs": float64(1000)}},
+			{ID: "4", Type: models.NodeTypeActionDelay, Data: map[string]interface{}{"delayMs": float64(1000)}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}, {ID: "e2", Source: "2", Target: "3"}, {ID: "e3", Source: "3", Target: "4"}},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.WorkflowVersionID == nil || exec.Status != "waiting" || exec.CurrentNodeID != "3" {
+		t.Fatalf("expected pinned execution waiting before node 3, got %+v", exec)
+	}
+
+	// Someone edits the live workflow and deletes node 3 while the lead is in the delay
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{{ID: "1", Type: models.NodeTypeTriggerDM}},
+		nil,
+	)
+
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Node 3 still exists in the snapshot: it runs (another delay) instead of silently completing
+	exec = ms.onlyExecution(t)
+	if exec.Status != "waiting" || exec.CurrentNodeID != "4" {
+		t.Fatalf("expected execution to continue on its snapshot, got %s at %s", exec.Status, exec.CurrentNodeID)
+	}
+}
@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// MaxRepairRounds bounds how often GenerateWorkflow sends a graph back to the model with the
+// validator's errors before giving up and returning it as is.
+const MaxRepairRounds = 2
+
+// GeneratableNodeTypes are the node types the AI builder may use. Types whose configuration
+// refers to tenant resources by ID (call_workflow, http_request with secrets) or that need
+// structured settings the model gets wrong too often (condition, split, schedule) are left to
+// the visual builder.
+var GeneratableNodeTypes = []models.NodeType{
+	models.NodeTypeTriggerDM,
+	models.NodeTypeTriggerKeyword,
+	models.NodeTypeTriggerInactivity,
+	models.NodeTypeActionSendMessage,
+	models.NodeTypeActionDelay,
+	models.NodeTypeActionAddTag,
+	models.NodeTypeActionWaitForReply,
+	models.NodeTypeActionAIReply,
+	models.NodeTypeActionRAGSearch,
+	models.NodeTypeActionHandoff,
+	models.NodeTypeLogicAIRouter,
+	models.NodeTypeLogicTimeWindow,
+}
+
+// GenerationResult is a generated graph together with its final validation report
+type GenerationResult struct {
+	Nodes        json.RawMessage   `json:"nodes"`
+	Edges        json.RawMessage   `json:"edges"`
+	Validation   *ValidationReport `json:"validation"`
+	RepairRounds int               `json:"repair_rounds"` // model calls made after the first one
+}
+
+// generatedGraph is the shape workflowSchema asks the model for
+type generatedGraph struct {
+	Nodes []map[string]interface{} `json:"nodes"`
+	Edges []map[string]interface{} `json:"edges"`
+}
+
+// nullable wraps a JSON Schema type so strict structured output accepts a missing value as null
+func nullable(typ interface{}) map[string]interface{} {
+	return map[string]interface{}{"anyOf": []interface{}{typ, map[string]interface{}{"type": "null"}}}
+}
+
+// JSON Schema building blocks shared by the generate and edit schemas
+var (
+	schemaString     = map[string]interface{}{"type": "string"}
+	schemaNumber     = map[string]interface{}{"type": "number"}
+	schemaStringList = map[string]interface{}{"type": "array", "items": schemaString}
+)
+
+// nodeDataSchema lists the data fields the model may set. Every field is required by strict
+// structured output, so the optional ones are nullable and normalizeGenerated strips the nulls.
+var nodeDataSchema = dataSchema(schemaString)
+
+// dataSchema builds the node data schema with the given schema for the label
+func dataSchema(label map[string]interface{}) map[string]interface{} {
+	props := map[string]interface{}{
+		"label":        label,
+		"description":  nullable(schemaString),
+		"message":      nullable(schemaString),
+		"prompt":       nullable(schemaString),
+		"keywords":     nullable(schemaStringList),
+		"tag":          nullable(schemaString),
+		"delayMs":      nullable(schemaNumber),
+		"timeoutMs":    nullable(schemaNumber),
+		"variable":     nullable(schemaString),
+		"routes":       nullable(schemaStringList),
+		"team":         nullable(schemaString),
+		"note":         nullable(schemaString),
+		"mode":         nullable(schemaString),
+		"inactiveDays": nullable(schemaNumber),
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             sortedKeys(keySet(props)),
+		"additionalProperties": false,
+	}
+}
+
+// nodeSchema is a single React Flow node of one of the GeneratableNodeTypes
+var nodeSchema = func() map[string]interface{} {
+	nodeTypes := make([]string, len(GeneratableNodeTypes))
+	for i, t := range GeneratableNodeTypes {
+		nodeTypes[i] = string(t)
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":   schemaString,
+			"type": map[string]interface{}{"type": "string", "enum": nodeTypes},
+			"position": map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{"x": schemaNumber, "y": schemaNumber},
+				"required":             []string{"x", "y"},
+				"additionalProperties": false,
+			},
+			"data": nodeDataSchema,
+		},
+		"required":             []string{"id", "type", "position", "data"},
+		"additionalProperties": false,
+	}
+}()
+
+// edgeSchema is a single React Flow edge
+var edgeSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"id":           schemaString,
+		"source":       schemaString,
+		"target":       schemaString,
+		"sourceHandle": nullable(schemaString),
+	},
+	"required":             []string{"id", "source", "target", "sourceHandle"},
+	"additionalProperties": false,
+}
+
+// workflowSchema follows OpenAI's strict JSON Schema rules: every property is required and
+// optional ones are nullable.
+var workflowSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"nodes": map[string]interface{}{"type": "array", "items": nodeSchema},
+		"edges": map[string]interface{}{"type": "array", "items": edgeSchema},
+	},
+	"required":             []string{"nodes", "edges"},
+	"additionalProperties": false,
+}
+
+func keySet(m map[string]interface{}) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
+
+// nodeCatalogue describes the GeneratableNodeTypes to the model
+const nodeCatalogue = `Valid Node Types:
+- trigger_meta_dm: A new inbound Instagram/Messenger DM arrives.
+- trigger_keyword: Fires if the message contains one of data.keywords.
+- trigger_inactivity: Fires when the contact has not replied for data.inactiveDays days.
+- action_send_message: Sends a static text reply (put it in data.message). Placeholders such as {{contact.name}} and {{state.<variable>}} are allowed.
+- action_delay: Pauses the workflow for data.delayMs milliseconds.
+- action_add_tag: Tags the contact with data.tag.
+- action_wait_for_reply: Waits for the contact's next message and stores it in state under data.variable. Outputs: sourceHandle="reply", or "timeout" after data.timeoutMs milliseconds.
+- action_ai_reply: Uses the Knowledge Base to answer a question (put instructions in data.prompt).
+- action_rag_search: Looks up the Knowledge Base for the latest message; place it before action_ai_reply.
+- action_handoff: Pauses the bot and hands the conversation to a human agent of data.team, with data.note for the agent.
+- logic_ai_router: Branches based on intent. Outputs: one sourceHandle per entry of data.routes (default "hot" and "cold").
+- logic_time_window: Branches on business hours with data.mode="branch". Outputs: sourceHandle="in" or "out".
+`
+
+const generatePrompt = `You are an expert AI architect generating automation workflows for a Social Media Lead SaaS app.
+The user will provide a desired behavior (e.g., "reply to DM pricing inquiries, wait a day, follow up").
+Your goal is to output a strictly formatted graph containing nodes and edges.
+
+` + nodeCatalogue + `
+Requirements:
+- Always start with exactly 1 trigger node (ID: "1", positioned at x: 250, y: 50).
+- Sequence all subsequent nodes cleanly, spacing them vertically (y + 150 each).
+- Ensure edges connect source node IDs to target node IDs. Use sourceHandle only for the outputs listed above, otherwise null.
+- Every node must be reachable from the trigger and must not loop back without a delay or wait.
+- Use visually descriptive text for data.label and data.description. Set every data field the node type does not use to null.
+
+User Prompt: `
+
+// GenerateWorkflow asks the model for a workflow graph matching prompt and runs it through the
+// graph validator. When the validator finds errors the graph and the errors are sent back for
+// repair, at most MaxRepairRounds times. The last graph is returned even if it is still invalid;
+// callers decide whether an invalid graph is useful (as a draft it is).
+func GenerateWorkflow(ctx context.Context, llm ai.LLMClient, prompt string) (*GenerationResult, error) {
+	base := generatePrompt + prompt
+	request := base
+
+	var res *GenerationResult
+	for round := 0; round <= MaxRepairRounds; round++ {
+		raw, err := llm.GenerateStructuredJSON(ctx, request, workflowSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate workflow: %w", err)
+		}
+
+		var issues []string
+		var graph generatedGraph
+		if err := json.Unmarshal([]byte(raw), &graph); err != nil {
+			issues = []string{"the response is not valid JSON: " + err.Error()}
+		} else {
+			res, err = validateGenerated(&graph)
+			if err != nil {
+				return nil, err
+			}
+			res.RepairRounds = round
+			if res.Validation.Valid() {
+				return res, nil
+			}
+			for _, issue := range res.Validation.Errors {
+				issues = append(issues, issue.String())
+			}
+		}
+
+		request = repairPrompt(base, raw, issues)
+	}
+
+	if res == nil {
+		return nil, fmt.Errorf("failed to generate workflow: the model did not return a parsable graph")
+	}
+	return res, nil
+}
+
+// validateGenerated normalizes a generated graph and validates it
+func validateGenerated(graph *generatedGraph) (*GenerationResult, error) {
+	normalizeGenerated(graph)
+
+	nodes, err := json.Marshal(graph.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := json.Marshal(graph.Edges)
+	if err != nil {
+		return nil, err
+	}
+	report, err := ValidateWorkflow(nodes, edges)
+	if err != nil {
+		return nil, err
+	}
+	return &GenerationResult{Nodes: nodes, Edges: edges, Validation: report}, nil
+}
+
+// normalizeGenerated drops the nulls strict structured output fills unused fields with, so the
+// stored graph looks like one drawn in the builder
+func normalizeGenerated(graph *generatedGraph) {
+	if graph.Nodes == nil {
+		graph.Nodes = []map[string]interface{}{}
+	}
+	if graph.Edges == nil {
+		graph.Edges = []map[string]interface{}{}
+	}
+	for _, n := range graph.Nodes {
+		if data, ok := n["data"].(map[string]interface{}); ok {
+			dropNulls(data)
+		}
+	}
+	for _, e := range graph.Edges {
+		dropNulls(e)
+		if h, ok := e["sourceHandle"].(string); ok && h == "" {
+			delete(e, "sourceHandle")
+		}
+	}
+}
+
+func dropNulls(m map[string]interface{}) {
+	for k, v := range m {
+		if v == nil {
+			delete(m, k)
+		}
+	}
+}
+
+// repairPrompt asks the model to fix the answer it returned last round
+func repairPrompt(base, previous string, issues []string) string {
+	var sb strings.Builder
+	sb.WriteString(base)
+	sb.WriteString("\n\nYour previous answer was:\n")
+	sb.WriteString(previous)
+	sb.WriteString("\n\nThe workflow validator rejected it:\n")
+	for _, issue := range issues {
+		sb.WriteString("- ")
+		sb.WriteString(issue)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nReturn a corrected answer. Keep everything that was not wrong unchanged.")
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+)
+
+// scriptedLLM answers structured requests with canned graphs, one per call, and records the prompts
+type scriptedLLM struct {
+	graphs  []string
+	prompts []string
+}
+
+func (l *scriptedLLM) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return "", nil
+}
+
+func (l *scriptedLLM) Chat(ctx context.Context, req ai.ChatRequest) (string, error) {
+	return "", nil
+}
+
+func (l *scriptedLLM) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, nil
+}
+
+func (l *scriptedLLM) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	g := l.graphs[0]
+	if len(l.graphs) > 1 {
+		l.graphs = l.graphs[1:]
+	}
+	return g, nil
+}
+
+const (
+	// The edge points at a node that does not exist
+	danglingGraph = `{"nodes":[` +
+		`{"id":"1","type":"trigger_meta_dm","position":{"x":250,"y":50},"data":{"label":"DM","message":null}},` +
+		`{"id":"2","type":"action_send_message","position":{"x":250,"y":200},"data":{"label":"Reply","message":"Hi!"}}],` +
+		`"edges":[{"id":"e1","source":"1","target":"3","sourceHandle":null}]}`
+	repairedGraph = `{"nodes":[` +
+		`{"id":"1","type":"trigger_meta_dm","position":{"x":250,"y":50},"data":{"label":"DM","message":null}},` +
+		`{"id":"2","type":"action_send_message","position":{"x":250,"y":200},"data":{"label":"Reply","message":"Hi!"}}],` +
+		`"edges":[{"id":"e1","source":"1","target":"2","sourceHandle":null}]}`
+)
+
+func TestGenerateWorkflowRepairs(t *testing.T) {
+	llm := &scriptedLLM{graphs: []string{danglingGraph, repairedGraph}}
+
+	res, err := engine.GenerateWorkflow(context.Background(), llm, "say hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Validation.Valid() || res.RepairRounds != 1 {
+		t.Fatalf("expected a valid graph after 1 repair, got rounds=%d errors=%v", res.RepairRounds, res.Validation.Errors)
+	}
+	if len(llm.prompts) != 2 || !strings.Contains(llm.prompts[1], `target node "3" does not exist`) {
+		t.Errorf("repair prompt does not carry the validator errors: %q", llm.prompts[len(llm.prompts)-1])
+	}
+	if strings.Contains(string(res.Nodes), "null") || strings.Contains(string(res.Edges), "sourceHandle") {
+		t.Errorf("nulls not stripped: %s %s", res.Nodes, res.Edges)
+	}
+}
+
+func TestGenerateWorkflowGivesUp(t *testing.T) {
+	llm := &scriptedLLM{graphs: []string{`not json`, danglingGraph}}
+
+	res, err := engine.GenerateWorkflow(context.Background(), llm, "say hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.prompts) != engine.MaxRepairRounds+1 {
+		t.Errorf("expected %d model calls, got %d", engine.MaxRepairRounds+1, len(llm.prompts))
+	}
+	if res.Validation.Valid() || res.RepairRounds != engine.MaxRepairRounds {
+		t.Errorf("expected the last invalid graph, got rounds=%d valid=%v", res.RepairRounds, res.Validation.Valid())
+	}
+	if !strings.Contains(llm.prompts[1], "not valid JSON") {
+		t.Errorf("unparsable answer not reported back: %q", llm.prompts[1])
+	}
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Goal types a workflow can declare. GoalCondition takes an arbitrary Condition; the others are
+// shorthands the builder offers for the common exits.
+const (
+	GoalVisitBooked = "visit_booked"
+	GoalTagAdded    = "tag_added"
+	GoalCondition   = "condition"
+)
+
+// Goal is an exit condition of a workflow. As soon as it holds for the contact, their running,
+// waiting or paused executions of that workflow end with status "goal_reached".
+//
+//	[{"name": "Booked", "type": "visit_booked"},
+//	 {"name": "Bought", "type": "tag_added", "tag": "customer"},
+//	 {"name": "Rich", "type": "condition", "condition": {"field": "contact.budget", "operator": "gte", "value": 10000000}}]
+type Goal struct {
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Tag       string      `json:"tag,omitempty"`
+	Condition interface{} `json:"condition,omitempty"`
+
+	cond *Condition
+}
+
+// Met reports whether the goal holds for the given variables
+func (g *Goal) Met(vars *Variables) bool {
+	return g.cond != nil && g.cond.Evaluate(vars)
+}
+
+// ParseGoals decodes and compiles a workflow's goals. Empty input means no goals.
+func ParseGoals(raw json.RawMessage) ([]Goal, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var goals []Goal
+	if err := json.Unmarshal(raw, &goals); err != nil {
+		return nil, fmt.Errorf("goals must be a list: %w", err)
+	}
+	for i := range goals {
+		if err := goals[i].compile(); err != nil {
+			name := goals[i].Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("goal %s: %w", name, err)
+		}
+	}
+	return goals, nil
+}
+
+func (g *Goal) compile() error {
+	if g.Type == "" && g.Condition != nil {
+		g.Type = GoalCondition
+	}
+	var raw interface{}
+	switch g.Type {
+	case GoalVisitBooked:
+		raw = map[string]interface{}{"field": "visit.status", "operator": "in", "value": []interface{}{"confirmed", "rescheduled", "completed"}}
+	case GoalTagAdded:
+		if strings.TrimSpace(g.Tag) == "" {
+			return fmt.Errorf("tag is required")
+		}
+		raw = map[string]interface{}{"field": "contact.tags", "operator": "contains", "value": g.Tag}
+	case GoalCondition:
+		raw = g.Condition
+	default:
+		return fmt.Errorf("unknown goal type %q", g.Type)
+	}
+	if g.Name == "" {
+		g.Name = g.Type
+	}
+	cond, err := ParseCondition(raw)
+	if err != nil {
+		return err
+	}
+	g.cond = cond
+	return nil
+}
+
+// workflowGoals loads the live goals of a workflow. Goals are read from the workflow rather than
+// the pinned version so that adding an exit rule also releases leads already in the flow.
+func (gw *GraphWalker) workflowGoals(ctx context.Context, workflowID int64) []Goal {
+	w, err := gw.Store.GetWorkflowByID(ctx, workflowID)
+	if err != nil {
+		return nil
+	}
+	goals, err := ParseGoals(w.Goals)
+	if err != nil {
+		log.Printf("[GraphWalker] Workflow %d has invalid goals, ignoring them: %v", workflowID, err)
+		return nil
+	}
+	return goals
+}
+
+// reachedGoal returns the first goal the execution's contact meets, or nil
+func (gw *GraphWalker) reachedGoal(ctx context.Context, exec *models.WorkflowExecution, goals []Goal, stateData map[string]interface{}) *Goal {
+	if len(goals) == 0 {
+		return nil
+	}
+	vars, err := gw.buildVariables(ctx, exec, stateData)
+	if err != nil {
+		log.Printf("[GraphWalker] Could not evaluate goals for execution %d: %v", exec.ID, err)
+		return nil
+	}
+	for i := range goals {
+		if goals[i].Met(vars) {
+			return &goals[i]
+		}
+	}
+	return nil
+}
+
+// endOnGoal marks the execution goal_reached. Returns false if it had already finished.
+func (gw *GraphWalker) endOnGoal(ctx context.Context, exec *models.WorkflowExecution, goal *Goal) bool {
+	ended, err := gw.Store.MarkExecutionGoalReached(ctx, exec.ID, goal.Name)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to end execution %d on goal %q: %v", exec.ID, goal.Name, err)
+		return false
+	}
+	if ended {
+		log.Printf("Execution %d reached goal %q", exec.ID, goal.Name)
+		gw.startQueued(ctx, exec.WorkflowID, exec.ContactID)
+		gw.returnToCaller(ctx, exec)
+	}
+	return ended
+}
+
+// CheckGoals evaluates the goals of every unfinished execution of a contact and ends those whose
+// goal is now met. Call it after anything that can satisfy a goal outside the walker, such as a
+// visit being booked.
+func (gw *GraphWalker) CheckGoals(ctx context.Context, contactID int64) {
+	execs, err := gw.Store.GetActiveExecutionsByContact(ctx, contactID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to load executions of contact %d for goal check: %v", contactID, err)
+		return
+	}
+
+	goalsByWorkflow := make(map[int64][]Goal)
+	for i := range execs {
+		exec := &execs[i]
+		goals, ok := goalsByWorkflow[exec.WorkflowID]
+		if !ok {
+			goals = gw.workflowGoals(ctx, exec.WorkflowID)
+			goalsByWorkflow[exec.WorkflowID] = goals
+		}
+		if goal := gw.reachedGoal(ctx, exec, goals, decodeState(exec.StateData)); goal != nil {
+			gw.endOnGoal(ctx, exec, goal)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// An action_handoff node hands the conversation to a human:
+//
+//	{"team": "sales", "strategy": "least_load", "note": "Hot lead for {{contact.preferred_location}}",
+//	 "slaMinutes": 15, "onBreach": "escalate", "escalateTeam": "managers", "maxEscalations": 1}
+//
+// It pauses the bot for the contact, assigns an active agent of the team ("agentId" pins one),
+// and posts an internal note with the rendered "note" and an AI summary of the conversation
+// ("summarize", on by default). Nodes after it that message the contact wait until an agent hands
+// the conversation back. With "slaMinutes", a "workflow:handoff_sla" task fires when no agent has
+// replied in time and either re-routes the handoff to another agent of the team or escalates it
+// to "escalateTeam", up to "maxEscalations" times.
+
+// Assignment strategies of an action_handoff node
+const (
+	HandoffRoundRobin = "round_robin"
+	HandoffLeastLoad  = "least_load"
+)
+
+// What an action_handoff node does when its SLA is breached
+const (
+	HandoffEscalate = "escalate"
+	HandoffReroute  = "reroute"
+)
+
+// Values of models.Handoff.Status
+const (
+	HandoffOpen     = "open"
+	HandoffResolved = "resolved"
+)
+
+// handoffTranscriptSize is how many of the latest messages the handoff summary is written from
+const handoffTranscriptSize = 30
+
+// HandoffSLAPayload is the body of the "workflow:handoff_sla" task. Escalation is the handoff's
+// escalation count when the timer was armed, so a timer outlived by a later one is ignored.
+type HandoffSLAPayload struct {
+	HandoffID  int64 `json:"handoff_id"`
+	Escalation int   `json:"escalation"`
+}
+
+// handoffConfig is the parsed data of an action_handoff node
+type handoffConfig struct {
+	team           string
+	strategy       string
+	agentID        int64
+	note           string
+	summarize      bool
+	slaMinutes     int
+	onBreach       string
+	escalateTeam   string
+	maxEscalations int
+}
+
+func parseHandoffNode(node *models.ReactFlowNode) (*handoffConfig, error) {
+	cfg := &handoffConfig{
+		team:           strings.TrimSpace(node.DataString("team", "")),
+		strategy:       node.DataString("strategy", HandoffRoundRobin),
+		agentID:        int64(node.DataFloat("agentId", 0)),
+		note:           node.DataString("note", ""),
+		summarize:      node.DataBool("summarize", true),
+		slaMinutes:     int(node.DataFloat("slaMinutes", 0)),
+		onBreach:       node.DataString("onBreach", HandoffEscalate),
+		escalateTeam:   strings.TrimSpace(node.DataString("escalateTeam", "")),
+		maxEscalations: int(node.DataFloat("maxEscalations", 1)),
+	}
+	switch {
+	case cfg.strategy != HandoffRoundRobin && cfg.strategy != HandoffLeastLoad:
+		return nil, fmt.Errorf("strategy must be %q or %q", HandoffRoundRobin, HandoffLeastLoad)
+	case cfg.agentID < 0:
+		return nil, fmt.Errorf("agentId must be a positive agent ID")
+	case cfg.slaMinutes < 0:
+		return nil, fmt.Errorf("slaMinutes cannot be negative")
+	case cfg.onBreach != HandoffEscalate && cfg.onBreach != HandoffReroute:
+		return nil, fmt.Errorf("onBreach must be %q or %q", HandoffEscalate, HandoffReroute)
+	case cfg.slaMinutes > 0 && cfg.onBreach == HandoffEscalate && cfg.escalateTeam == "":
+		return nil, fmt.Errorf("escalateTeam is required to escalate on an SLA breach")
+	case cfg.maxEscalations < 1:
+		return nil, fmt.Errorf("maxEscalations must be at least 1")
+	}
+	return cfg, nil
+}
+
+// runHandoff executes an action_handoff node
+func (gw *GraphWalker) runHandoff(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
+	cfg, err := parseHandoffNode(node)
+	if err != nil {
+		return "", fmt.Errorf("invalid handoff: %w", err)
+	}
+	rec.in("team", cfg.team)
+	rec.in("strategy", cfg.strategy)
+
+	contact, err := gw.Store.GetContactByID(ctx, exec.ContactID)
+	if err != nil {
+		return "", err
+	}
+	if err := gw.Store.UpdateContactState(ctx, contact.ID, contact.BookingState, true); err != nil {
+		return "", fmt.Errorf("failed to pause the bot: %w", err)
+	}
+
+	vars, err := gw.buildVariables(ctx, exec, stateData)
+	if err != nil {
+		return "", err
+	}
+	note, err := RenderTemplate(cfg.note, vars)
+	if err != nil {
+		log.Printf("[GraphWalker] Node %s note template error, using raw text: %v", node.ID, err)
+	}
+
+	if gw.sim != nil {
+		// Simulations pause the synthetic contact but assign nobody and start no timer
+		gw.sim.record(EffectHandoff, map[string]interface{}{
+			"team": cfg.team, "strategy": cfg.strategy, "note": note, "sla_minutes": cfg.slaMinutes,
+		})
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+	}
+
+	summary := ""
+	if cfg.summarize {
+		if summary, err = gw.summarizeConversation(aiContext(ctx, contact.UserID, exec, node), gw.llmFor(contact.UserID, node), exec.ContactID); err != nil {
+			// The agent can still read the thread; a missing summary must not block the handoff
+			log.Printf("[GraphWalker] Node %s could not summarise the conversation: %v", node.ID, err)
+			rec.out("summary_error", err.Error())
+		}
+	}
+	body := handoffNote(note, summary)
+
+	// A contact already waiting for an agent keeps their handoff; the new context goes on it
+	if open, err := gw.Store.GetOpenHandoffByContact(ctx, exec.ContactID); err != nil {
+		return "", fmt.Errorf("failed to load open handoff: %w", err)
+	} else if open != nil {
+		gw.addNote(ctx, open.UserID, open.ContactID, body)
+		stateData["handoff_id"] = open.ID
+		rec.out("handoff_id", open.ID)
+		rec.out("reused", true)
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+	}
+
+	agent, err := gw.assignAgent(ctx, contact.UserID, cfg)
+	if err != nil {
+		return "", err
+	}
+	execID := exec.ID
+	h := &models.Handoff{
+		UserID:         contact.UserID,
+		ContactID:      contact.ID,
+		ExecutionID:    &execID,
+		NodeID:         node.ID,
+		Team:           cfg.team,
+		Strategy:       cfg.strategy,
+		Status:         HandoffOpen,
+		Summary:        summary,
+		SLAMinutes:     cfg.slaMinutes,
+		OnBreach:       cfg.onBreach,
+		EscalationTeam: cfg.escalateTeam,
+		MaxEscalations: cfg.maxEscalations,
+	}
+	if agent != nil {
+		h.AgentID = &agent.ID
+		rec.out("agent_id", agent.ID)
+		rec.out("agent_name", agent.Name)
+	}
+	if cfg.slaMinutes > 0 {
+		due := time.Now().Add(time.Duration(cfg.slaMinutes) * time.Minute)
+		h.SLADueAt = &due
+		rec.out("sla_due_at", due.Format(time.RFC3339))
+	}
+	if err := gw.Store.CreateHandoff(ctx, h); err != nil {
+		return "", fmt.Errorf("failed to create handoff: %w", err)
+	}
+	log.Printf("Execution %d handed contact %d off to team %q (handoff %d)", exec.ID, contact.ID, cfg.team, h.ID)
+
+	gw.addNote(ctx, h.UserID, h.ContactID, body)
+	gw.armHandoffSLA(h)
+
+	stateData["handoff_id"] = h.ID
+	rec.out("handoff_id", h.ID)
+	return gw.findNextNode(graph.Edges, node.ID, ""), nil
+}
+
+// assignAgent returns the node's pinned agent, or picks one of the team. A nil agent leaves the
+// handoff queued for the whole team.
+func (gw *GraphWalker) assignAgent(ctx context.Context, userID int64, cfg *handoffConfig) (*models.Agent, error) {
+	if cfg.agentID > 0 {
+		agent, err := gw.Store.GetAgentByID(ctx, cfg.agentID)
+		if err == nil && agent.UserID == userID && agent.IsActive {
+			return agent, nil
+		}
+		log.Printf("[GraphWalker] Agent %d is not available, assigning from team %q", cfg.agentID, cfg.team)
+	}
+	agent, err := gw.Store.PickAgent(ctx, userID, cfg.team, cfg.strategy, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign an agent: %w", err)
+	}
+	return agent, nil
+}
+
+// summarizeConversation asks the LLM for a short briefing on the contact's latest messages
+func (gw *GraphWalker) summarizeConversation(ctx context.Context, llm ai.LLMClient, contactID int64) (string, error) {
+	if llm == nil {
+		return "", errors.New("no LLM client configured")
+	}
+	msgs, err := gw.Store.GetRecentMessagesByContact(ctx, contactID, handoffTranscriptSize)
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "", nil
+	}
+
+	var transcript strings.Builder
+	for _, m := range msgs {
+		speaker := "Lead"
+		if m.Direction == "outbound" {
+			speaker = "Agent"
+			if m.IsAutomated {
+				speaker = "Bot"
+			}
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", speaker, m.Content)
+	}
+	prompt := "A human agent is taking over this conversation with a lead. Summarise it for them in at most " +
+		"five short bullet points: what the lead wants, details they shared (budget, location, timeline), " +
+		"open questions, and anything they are unhappy about.\n\nConversation:\n" + transcript.String()
+	summary, err := llm.GenerateText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// handoffNote combines the node's note with the conversation summary
+func handoffNote(note, summary string) string {
+	parts := []string{"Handed off by workflow."}
+	if note = strings.TrimSpace(note); note != "" {
+		parts = append(parts, note)
+	}
+	if summary != "" {
+		parts = append(parts, "Summary:\n"+summary)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// addNote posts an internal note written by the bot. Failures are logged: a missing note never
+// undoes an assignment.
+func (gw *GraphWalker) addNote(ctx context.Context, userID, contactID int64, body string) {
+	n := &models.ContactNote{UserID: userID, ContactID: contactID, Author: "bot", Body: body}
+	if err := gw.Store.CreateContactNote(ctx, n); err != nil {
+		log.Printf("[GraphWalker] Failed to add note to contact %d: %v", contactID, err)
+	}
+}
+
+// armHandoffSLA enqueues the SLA check of a handoff at its due time
+func (gw *GraphWalker) armHandoffSLA(h *models.Handoff) {
+	if h.SLADueAt == nil {
+		return
+	}
+	if gw.AsynqClient == nil {
+		log.Printf("WARNING: AsynqClient is nil, the SLA of handoff %d will never fire.", h.ID)
+		return
+	}
+	payload, _ := json.Marshal(HandoffSLAPayload{HandoffID: h.ID, Escalation: h.Escalations})
+	taskID := fmt.Sprintf("handoff_sla:%d:%d", h.ID, h.Escalations)
+	_, err := gw.AsynqClient.Enqueue(asynq.NewTask("workflow:handoff_sla", payload), asynq.ProcessAt(*h.SLADueAt), asynq.TaskID(taskID))
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		log.Printf("ERROR: Failed to enqueue SLA check for handoff %d: %v", h.ID, err)
+	}
+}
+
+// HandleHandoffSLA escalates or re-routes a handoff no agent has replied to in time. Timers of
+// handoffs that were answered, resolved or already escalated since are ignored.
+func (gw *GraphWalker) HandleHandoffSLA(ctx context.Context, p HandoffSLAPayload) error {
+	h, err := gw.Store.GetHandoffByID(ctx, p.HandoffID)
+	if err != nil {
+		return err
+	}
+	if h.Status != HandoffOpen || h.FirstResponseAt != nil || h.Escalations != p.Escalation {
+		log.Printf("Handoff %d SLA timer is stale, skipping", h.ID)
+		return nil
+	}
+
+	team, exclude := h.Team, int64(0)
+	if h.OnBreach == HandoffReroute {
+		if h.AgentID != nil {
+			exclude = *h.AgentID
+		}
+	} else if h.EscalationTeam != "" {
+		team = h.EscalationTeam
+	}
+	agent, err := gw.Store.PickAgent(ctx, h.UserID, team, h.Strategy, exclude)
+	if err != nil {
+		return fmt.Errorf("failed to assign an agent: %w", err)
+	}
+
+	verb := "Escalated"
+	if h.OnBreach == HandoffReroute {
+		verb = "Re-routed"
+	}
+	note := fmt.Sprintf("No agent replied within %d minutes.", h.SLAMinutes)
+	switch {
+	case agent != nil:
+		h.AgentID = &agent.ID
+		note += fmt.Sprintf(" %s to %s.", verb, agent.Name)
+	case h.OnBreach == HandoffReroute:
+		// Nobody else is available; the current agent keeps it
+		note += " Nobody else in the team is available to take it."
+	case team == "":
+		h.AgentID = nil
+		note += fmt.Sprintf(" %s to any agent.", verb)
+	default:
+		h.AgentID = nil
+		note += fmt.Sprintf(" %s to the %s team.", verb, team)
+	}
+	h.Team = team
+	h.Escalations++
+	h.SLADueAt = nil
+	if h.Escalations < h.MaxEscalations && h.SLAMinutes > 0 {
+		due := time.Now().Add(time.Duration(h.SLAMinutes) * time.Minute)
+		h.SLADueAt = &due
+	}
+	if err := gw.Store.UpdateHandoffAssignment(ctx, h); err != nil {
+		return fmt.Errorf("failed to update handoff: %w", err)
+	}
+
+	gw.addNote(ctx, h.UserID, h.ContactID, note)
+	log.Printf("Handoff %d breached its SLA (escalation %d): %s", h.ID, h.Escalations, note)
+
+	gw.armHandoffSLA(h)
+	return nil
+}
@@ -0,0 +1,315 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// handoffStore adds agents, handoffs and notes to memStore
+type handoffStore struct {
+	*memStore
+	agents   []*models.Agent
+	handoffs []*models.Handoff
+	notes    []models.ContactNote
+}
+
+func (s *handoffStore) GetAgentByID(ctx context.Context, agentID int64) (*models.Agent, error) {
+	for _, a := range s.agents {
+		if a.ID == agentID {
+			cp := *a
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("agent not found")
+}
+
+func (s *handoffStore) openCount(agentID int64) int {
+	n := 0
+	for _, h := range s.handoffs {
+		if h.Status == engine.HandoffOpen && h.AgentID != nil && *h.AgentID == agentID {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *handoffStore) PickAgent(ctx context.Context, userID int64, team, strategy string, excludeAgentID int64) (*models.Agent, error) {
+	var candidates []*models.Agent
+	for _, a := range s.agents {
+		if a.UserID == userID && a.IsActive && (team == "" || a.Team == team) && a.ID != excludeAgentID {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if strategy == engine.HandoffLeastLoad && s.openCount(a.ID) != s.openCount(b.ID) {
+			return s.openCount(a.ID) < s.openCount(b.ID)
+		}
+		if (a.LastAssignedAt == nil) != (b.LastAssignedAt == nil) {
+			return a.LastAssignedAt == nil
+		}
+		if a.LastAssignedAt != nil && !a.LastAssignedAt.Equal(*b.LastAssignedAt) {
+			return a.LastAssignedAt.Before(*b.LastAssignedAt)
+		}
+		return a.ID < b.ID
+	})
+	now := time.Now()
+	candidates[0].LastAssignedAt = &now
+	cp := *candidates[0]
+	return &cp, nil
+}
+
+func (s *handoffStore) CreateHandoff(ctx context.Context, h *models.Handoff) error {
+	h.ID = int64(len(s.handoffs) + 1)
+	cp := *h
+	s.handoffs = append(s.handoffs, &cp)
+	return nil
+}
+
+func (s *handoffStore) GetHandoffByID(ctx context.Context, handoffID int64) (*models.Handoff, error) {
+	for _, h := range s.handoffs {
+		if h.ID == handoffID {
+			cp := *h
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("handoff not found")
+}
+
+func (s *handoffStore) GetOpenHandoffByContact(ctx context.Context, contactID int64) (*models.Handoff, error) {
+	for _, h := range s.handoffs {
+		if h.ContactID == contactID && h.Status == engine.HandoffOpen {
+			cp := *h
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *handoffStore) UpdateHandoffAssignment(ctx context.Context, h *models.Handoff) error {
+	for i, cur := range s.handoffs {
+		if cur.ID == h.ID {
+			cp := *h
+			s.handoffs[i] = &cp
+			return nil
+		}
+	}
+	return errors.New("handoff not found")
+}
+
+func (s *handoffStore) CreateContactNote(ctx context.Context, n *models.ContactNote) error {
+	n.ID = int64(len(s.notes) + 1)
+	s.notes = append(s.notes, *n)
+	return nil
+}
+
+// summaryLLM answers every prompt with a fixed summary and keeps the last prompt
+type summaryLLM struct {
+	ai.LLMClient
+	prompt string
+}
+
+func (l *summaryLLM) GenerateText(ctx context.Context, prompt string) (string, error) {
+	l.prompt = prompt
+	return "- Wants a 2BHK in Baner", nil
+}
+
+func newHandoffStore() *handoffStore {
+	earlier := time.Now().Add(-time.Hour)
+	return &handoffStore{
+		memStore: newMemStore(),
+		agents: []*models.Agent{
+			{ID: 1, UserID: 1, Name: "Ravi", Team: "sales", IsActive: true, LastAssignedAt: &earlier},
+			{ID: 2, UserID: 1, Name: "Meera", Team: "sales", IsActive: true},
+			{ID: 3, UserID: 1, Name: "Kiran", Team: "managers", IsActive: true},
+			{ID: 4, UserID: 1, Name: "Off duty", Team: "sales", IsActive: false},
+		},
+	}
+}
+
+// handoffWorkflow: DM -> handoff -> send message
+func handoffWorkflow(t *testing.T, s *handoffStore, id int64, data map[string]interface{}) {
+	s.addWorkflow(t, id,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "handoff", Type: models.NodeTypeActionHandoff, Data: data},
+			{ID: "msg", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": "Still there?"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "handoff"},
+			{ID: "e2", Source: "handoff", Target: "msg"},
+		},
+	)
+}
+
+func TestHandoffAssignsAgentAndPausesBot(t *testing.T) {
+	ctx := context.Background()
+	s := newHandoffStore()
+	s.messages = []models.Message{
+		{ContactID: 1, Direction: "inbound", Content: "Looking for a 2BHK in Baner"},
+		{ContactID: 1, Direction: "outbound", Content: "What is your budget?", IsAutomated: true},
+	}
+	handoffWorkflow(t, s, 1, map[string]interface{}{
+		"team": "sales", "note": "Hot lead {{contact.name}}", "slaMinutes": float64(15),
+		"onBreach": "escalate", "escalateTeam": "managers",
+	})
+	llm := &summaryLLM{}
+	gw := engine.NewGraphWalker(s, llm, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{"received_message": "call me"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.contacts[1].BotPaused {
+		t.Error("expected the bot to be paused")
+	}
+	if len(s.handoffs) != 1 {
+		t.Fatalf("expected one handoff, got %d", len(s.handoffs))
+	}
+	h := s.handoffs[0]
+	// Round-robin: Meera was never assigned, Ravi an hour ago, the inactive agent never counts
+	if h.AgentID == nil || *h.AgentID != 2 || h.Team != "sales" || h.Status != engine.HandoffOpen {
+		t.Errorf("expected an open handoff to Meera, got %+v", h)
+	}
+	if h.SLADueAt == nil || time.Until(*h.SLADueAt) < 14*time.Minute {
+		t.Errorf("expected the SLA to be due in 15 minutes, got %v", h.SLADueAt)
+	}
+	if !strings.Contains(llm.prompt, "Lead: Looking for a 2BHK in Baner") || !strings.Contains(llm.prompt, "Bot: What is your budget?") {
+		t.Errorf("expected the transcript in the summary prompt, got %q", llm.prompt)
+	}
+	if len(s.notes) != 1 || s.notes[0].Author != "bot" ||
+		!strings.Contains(s.notes[0].Body, "Hot lead Asha") || !strings.Contains(s.notes[0].Body, "Wants a 2BHK in Baner") {
+		t.Errorf("expected a note with the rendered text and the summary, got %+v", s.notes)
+	}
+
+	// The message after the handoff waits for the agent
+	exec := s.onlyExecution(t)
+	if exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForAgent || exec.CurrentNodeID != "msg" {
+		t.Errorf("expected the execution to wait for the agent at msg, got %s/%s at %s", exec.Status, exec.WaitingFor, exec.CurrentNodeID)
+	}
+	if decode(t, exec.StateData)["handoff_id"] != float64(h.ID) {
+		t.Errorf("expected handoff_id in state, got %s", exec.StateData)
+	}
+}
+
+func TestHandoffSummarizesLatestMessages(t *testing.T) {
+	s := newHandoffStore()
+	seedConversation(s.memStore, 600)
+	handoffWorkflow(t, s, 1, map[string]interface{}{"team": "sales"})
+	llm := &summaryLLM{}
+	gw := engine.NewGraphWalker(s, llm, nil, nil)
+
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(llm.prompt, "Agent: message 600\n") || strings.Contains(llm.prompt, "message 500\n") {
+		t.Errorf("expected the newest messages in the summary prompt, got %q", llm.prompt)
+	}
+}
+
+func TestHandoffLeastLoadAndReuse(t *testing.T) {
+	ctx := context.Background()
+	s := newHandoffStore()
+	// Meera already has two open conversations
+	meera := int64(2)
+	s.handoffs = []*models.Handoff{
+		{ID: 1, ContactID: 7, AgentID: &meera, Status: engine.HandoffOpen},
+		{ID: 2, ContactID: 8, AgentID: &meera, Status: engine.HandoffOpen},
+	}
+	handoffWorkflow(t, s, 1, map[string]interface{}{"team": "sales", "strategy": "least_load", "summarize": false})
+	gw := engine.NewGraphWalker(s, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.handoffs) != 3 || s.handoffs[2].AgentID == nil || *s.handoffs[2].AgentID != 1 {
+		t.Fatalf("expected the least loaded agent Ravi, got %+v", s.handoffs[len(s.handoffs)-1])
+	}
+
+	// A second handoff for the same contact adds to the open one
+	handoffWorkflow(t, s, 2, map[string]interface{}{"team": "sales", "summarize": false})
+	if err := gw.StartWorkflow(ctx, 2, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.handoffs) != 3 || len(s.notes) != 2 {
+		t.Errorf("expected the open handoff to be reused with a second note, got %d handoffs, %d notes", len(s.handoffs), len(s.notes))
+	}
+}
+
+func TestHandoffSLABreach(t *testing.T) {
+	ctx := context.Background()
+	ravi := int64(1)
+	due := time.Now()
+
+	t.Run("escalate", func(t *testing.T) {
+		s := newHandoffStore()
+		s.handoffs = []*models.Handoff{{ID: 1, UserID: 1, ContactID: 1, AgentID: &ravi, Team: "sales", Strategy: engine.HandoffRoundRobin,
+			Status: engine.HandoffOpen, SLAMinutes: 15, OnBreach: engine.HandoffEscalate, EscalationTeam: "managers", MaxEscalations: 2, SLADueAt: &due}}
+		gw := engine.NewGraphWalker(s, nil, nil, nil)
+
+		if err := gw.HandleHandoffSLA(ctx, engine.HandoffSLAPayload{HandoffID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		h := s.handoffs[0]
+		if h.AgentID == nil || *h.AgentID != 3 || h.Team != "managers" || h.Escalations != 1 {
+			t.Errorf("expected an escalation to Kiran in managers, got %+v", h)
+		}
+		if h.SLADueAt == nil || !h.SLADueAt.After(due) {
+			t.Error("expected the timer to be re-armed for the second escalation")
+		}
+		if len(s.notes) != 1 || !strings.Contains(s.notes[0].Body, "Escalated to Kiran") {
+			t.Errorf("expected an escalation note, got %+v", s.notes)
+		}
+
+		// The first timer firing again is stale
+		if err := gw.HandleHandoffSLA(ctx, engine.HandoffSLAPayload{HandoffID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if s.handoffs[0].Escalations != 1 {
+			t.Error("expected a stale timer to be ignored")
+		}
+	})
+
+	t.Run("reroute", func(t *testing.T) {
+		s := newHandoffStore()
+		s.handoffs = []*models.Handoff{{ID: 1, UserID: 1, ContactID: 1, AgentID: &ravi, Team: "sales", Strategy: engine.HandoffRoundRobin,
+			Status: engine.HandoffOpen, SLAMinutes: 15, OnBreach: engine.HandoffReroute, MaxEscalations: 1, SLADueAt: &due}}
+		gw := engine.NewGraphWalker(s, nil, nil, nil)
+
+		if err := gw.HandleHandoffSLA(ctx, engine.HandoffSLAPayload{HandoffID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		h := s.handoffs[0]
+		if h.AgentID == nil || *h.AgentID != 2 || h.Team != "sales" {
+			t.Errorf("expected a re-route to Meera, got %+v", h)
+		}
+		if h.SLADueAt != nil {
+			t.Error("expected no further timer after the last escalation")
+		}
+	})
+
+	t.Run("answered", func(t *testing.T) {
+		s := newHandoffStore()
+		s.handoffs = []*models.Handoff{{ID: 1, UserID: 1, ContactID: 1, AgentID: &ravi, Team: "sales", Status: engine.HandoffOpen,
+			SLAMinutes: 15, OnBreach: engine.HandoffReroute, MaxEscalations: 1, SLADueAt: &due, FirstResponseAt: &due}}
+		gw := engine.NewGraphWalker(s, nil, nil, nil)
+
+		if err := gw.HandleHandoffSLA(ctx, engine.HandoffSLAPayload{HandoffID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if *s.handoffs[0].AgentID != ravi || len(s.notes) != 0 {
+			t.Error("expected an answered handoff to be left alone")
+		}
+	})
+}
@@ -0,0 +1,369 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/cache"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Source handles of an action_http_request node
+const (
+	HandleSuccess = "success"
+	HandleError   = "error"
+)
+
+const (
+	defaultHTTPTimeout   = 10 * time.Second
+	maxHTTPTimeout       = 30 * time.Second
+	maxHTTPResponseBytes = 1 << 20
+)
+
+var httpMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// RateLimiter is the subset of cache.RedisClient used to throttle outbound calls per tenant
+type RateLimiter interface {
+	CheckRateLimit(ctx context.Context, key string, limit int64, window time.Duration) (*cache.RateLimitResult, error)
+}
+
+// ErrBlockedAddress is returned when an outbound request resolves to a denied IP range
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// blockedNets are never reachable from workflows unless explicitly allowed
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+// NewSafeHTTPClient builds the client used by action_http_request nodes. The dialer checks the
+// resolved IP at connect time (so DNS rebinding cannot bypass it) and refuses private, loopback,
+// link-local and multicast ranges unless they are listed in allowedCIDRs.
+func NewSafeHTTPClient(allowedCIDRs []string) (*http.Client, error) {
+	var allowed []*net.IPNet
+	for _, cidr := range allowedCIDRs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed CIDR %q: %w", cidr, err)
+		}
+		allowed = append(allowed, n)
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isAllowedIP(ip, allowed) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               nil, // a proxy would hide the real destination from the dialer check
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        20,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}, nil
+}
+
+func isAllowedIP(ip net.IP, allowed []*net.IPNet) bool {
+	for _, n := range allowed {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// httpRequestSpec is the parsed configuration of an action_http_request node
+type httpRequestSpec struct {
+	method  string
+	url     *Template
+	headers map[string]*Template
+	body    *Template
+	timeout time.Duration
+	mapping map[string]string // state variable -> JSON path in the response
+}
+
+// parseHTTPRequestNode validates and compiles an action_http_request node's data
+func parseHTTPRequestNode(node *models.ReactFlowNode) (*httpRequestSpec, error) {
+	spec := &httpRequestSpec{
+		method:  strings.ToUpper(node.DataString("method", http.MethodGet)),
+		headers: make(map[string]*Template),
+		mapping: make(map[string]string),
+		timeout: defaultHTTPTimeout,
+	}
+	if !httpMethods[spec.method] {
+		return nil, fmt.Errorf("unsupported method %q", spec.method)
+	}
+
+	rawURL := strings.TrimSpace(node.DataString("url", ""))
+	if rawURL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") && !strings.HasPrefix(rawURL, "{{") {
+		return nil, fmt.Errorf("url must start with http:// or https://")
+	}
+	var err error
+	if spec.url, err = ParseRequestTemplate(rawURL); err != nil {
+		return nil, fmt.Errorf("url: %w", err)
+	}
+
+	if headers, ok := node.Data["headers"].(map[string]interface{}); ok {
+		for name, raw := range headers {
+			val, ok := raw.(string)
+			if !ok {
+				return nil, fmt.Errorf("header %q must be a string", name)
+			}
+			if spec.headers[name], err = ParseRequestTemplate(val); err != nil {
+				return nil, fmt.Errorf("header %q: %w", name, err)
+			}
+		}
+	}
+
+	if body := node.DataString("body", ""); body != "" {
+		if spec.body, err = ParseRequestTemplate(body); err != nil {
+			return nil, fmt.Errorf("body: %w", err)
+		}
+	}
+
+	if ms := node.DataFloat("timeoutMs", 0); ms > 0 {
+		spec.timeout = time.Duration(ms) * time.Millisecond
+		if spec.timeout > maxHTTPTimeout {
+			spec.timeout = maxHTTPTimeout
+		}
+	}
+
+	if mapping, ok := node.Data["responseMapping"].(map[string]interface{}); ok {
+		for variable, raw := range mapping {
+			path, ok := raw.(string)
+			if !ok || path == "" {
+				return nil, fmt.Errorf("responseMapping %q must be a JSON path string", variable)
+			}
+			if _, err := parseJSONPath(path); err != nil {
+				return nil, fmt.Errorf("responseMapping %q: %w", variable, err)
+			}
+			spec.mapping[variable] = path
+		}
+	}
+	return spec, nil
+}
+
+// ValidateHTTPRequestNodes checks every action_http_request node's configuration at save time
+func ValidateHTTPRequestNodes(graph *models.WorkflowGraph) error {
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Type != models.NodeTypeActionHTTPRequest {
+			continue
+		}
+		if _, err := parseHTTPRequestNode(node); err != nil {
+			return fmt.Errorf("node %s: %w", node.ID, err)
+		}
+	}
+	return nil
+}
+
+// runHTTPRequest executes an action_http_request node. It returns the handle to follow
+// ("success" or "error") and the error that caused the error handle, if any.
+func (gw *GraphWalker) runHTTPRequest(ctx context.Context, node *models.ReactFlowNode, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
+	spec, err := parseHTTPRequestNode(node)
+	if err != nil {
+		return HandleError, fmt.Errorf("invalid http request node: %w", err)
+	}
+
+	vars, err := gw.buildVariables(ctx, exec, stateData)
+	if err != nil {
+		return HandleError, err
+	}
+	if vars.Secrets, err = gw.Store.GetTenantSecrets(ctx, vars.Contact.UserID); err != nil {
+		return HandleError, fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	target, err := url.Parse(spec.url.Render(vars))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return HandleError, fmt.Errorf("invalid request url")
+	}
+
+	var body io.Reader
+	if spec.body != nil {
+		body = strings.NewReader(spec.body.RenderJSON(vars))
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, spec.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, spec.method, target.String(), body)
+	if err != nil {
+		return HandleError, fmt.Errorf("failed to build request: %w", err)
+	}
+	if spec.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, tmpl := range spec.headers {
+		req.Header.Set(name, tmpl.Render(vars))
+	}
+
+	if gw.sim != nil {
+		detail := map[string]interface{}{"method": spec.method, "url": target.String()}
+		if spec.body != nil {
+			detail["body"] = spec.body.RenderJSON(vars)
+		}
+		gw.sim.record(EffectHTTPRequest, detail)
+		return HandleSuccess, nil
+	}
+
+	client := gw.HTTPClient
+	if client == nil {
+		return HandleError, fmt.Errorf("http requests are not configured")
+	}
+
+	if gw.RateLimiter != nil {
+		key := fmt.Sprintf("http_node:user:%d", vars.Contact.UserID)
+		res, err := gw.RateLimiter.CheckRateLimit(ctx, key, gw.HTTPRateLimit, time.Minute)
+		if err != nil {
+			// Fail closed: without the limiter a tenant could flood the destination
+			return HandleError, fmt.Errorf("http request rate limit unavailable: %w", err)
+		}
+		if !res.Allowed {
+			return HandleError, fmt.Errorf("http request rate limit exceeded for user %d", vars.Contact.UserID)
+		}
+	}
+
+	// Only the host is logged: URLs, headers and bodies may contain secrets
+	log.Printf("[HTTPNode] %s %s for execution %d", spec.method, target.Host, exec.ID)
+	rec.in("method", spec.method)
+	rec.in("host", target.Host)
+	resp, err := client.Do(req)
+	if err != nil {
+		return HandleError, fmt.Errorf("request to %s failed: %w", target.Host, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBytes))
+	if err != nil {
+		return HandleError, fmt.Errorf("failed to read response from %s: %w", target.Host, err)
+	}
+	stateData["http_status"] = resp.StatusCode
+	rec.out("status", resp.StatusCode)
+
+	if resp.StatusCode >= 400 {
+		return HandleError, fmt.Errorf("%s responded with status %d", target.Host, resp.StatusCode)
+	}
+
+	if len(spec.mapping) > 0 {
+		var parsed interface{}
+		if err := json.Unmarshal(respBody, &parsed); err != nil {
+			return HandleError, fmt.Errorf("response from %s is not JSON: %w", target.Host, err)
+		}
+		for variable, path := range spec.mapping {
+			if val, ok := LookupJSONPath(parsed, path); ok {
+				stateData[variable] = val
+				rec.out(variable, val)
+			}
+		}
+	}
+	return HandleSuccess, nil
+}
+
+var (
+	jsonPathSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+	jsonPathIndex   = regexp.MustCompile(`\d+`)
+)
+
+// parseJSONPath splits "$.data.items[0].price" into keys and indexes
+func parseJSONPath(path string) ([]interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, nil
+	}
+	var steps []interface{}
+	for _, seg := range strings.Split(path, ".") {
+		m := jsonPathSegment.FindStringSubmatch(seg)
+		if m == nil || (m[1] == "" && m[2] == "") {
+			return nil, fmt.Errorf("invalid path segment %q", seg)
+		}
+		if m[1] != "" {
+			steps = append(steps, m[1])
+		}
+		for _, idx := range jsonPathIndex.FindAllString(m[2], -1) {
+			n, _ := strconv.Atoi(idx)
+			steps = append(steps, n)
+		}
+	}
+	return steps, nil
+}
+
+// LookupJSONPath resolves a dotted path with optional [index] segments inside decoded JSON
+func LookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+	cur := doc
+	for _, step := range steps {
+		switch s := step.(type) {
+		case string:
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = obj[s]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := cur.([]interface{})
+			if !ok || s >= len(arr) {
+				return nil, false
+			}
+			cur = arr[s]
+		}
+	}
+	return cur, true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
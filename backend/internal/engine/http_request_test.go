@@ -0,0 +1,241 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/cache"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+type denyLimiter struct{}
+
+func (denyLimiter) CheckRateLimit(ctx context.Context, key string, limit int64, window time.Duration) (*cache.RateLimitResult, error) {
+	return &cache.RateLimitResult{Allowed: false, RetryAfter: window}, nil
+}
+
+type brokenLimiter struct{}
+
+func (brokenLimiter) CheckRateLimit(ctx context.Context, key string, limit int64, window time.Duration) (*cache.RateLimitResult, error) {
+	return nil, errors.New("redis: connection refused")
+}
+
+// httpWorkflow: trigger -> http request -> success: 3, error: 4
+func httpWorkflow(t *testing.T, ms *memStore, data map[string]interface{}) {
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionHTTPRequest, Data: data},
+			{ID: "3", Type: models.NodeTypeActionDelay},
+			{ID: "4", Type: models.NodeTypeActionDelay},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", SourceHandle: engine.HandleSuccess, Target: "3"},
+			{ID: "e3", Source: "2", SourceHandle: engine.HandleError, Target: "4"},
+		},
+	)
+}
+
+func newTestWalker(t *testing.T, ms *memStore) *engine.GraphWalker {
+	t.Helper()
+	client, err := engine.NewSafeHTTPClient([]string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+	gw.HTTPClient = client
+	return gw
+}
+
+func TestHTTPRequestNode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Renders secrets and maps response", func(t *testing.T) {
+		var gotAuth, gotBody string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotAuth = r.Header.Get("Authorization")
+			b, _ := io.ReadAll(r.Body)
+			gotBody = string(b)
+			w.Write([]byte(`{"data":{"items":[{"id":"L-42","price":9500000}]}}`))
+		}))
+		defer srv.Close()
+
+		ms := newMemStore()
+		ms.secrets = map[string]string{"CRM_TOKEN": "s3cret"}
+		httpWorkflow(t, ms, map[string]interface{}{
+			"method":  "POST",
+			"url":     srv.URL + "/leads",
+			"headers": map[string]interface{}{"Authorization": "Bearer {{secret.CRM_TOKEN}}"},
+			"body":    `{"name":"{{contact.name | json}}"}`,
+			"responseMapping": map[string]interface{}{
+				"crm_lead_id": "$.data.items[0].id",
+				"price":       "data.items[0].price",
+			},
+		})
+
+		if err := newTestWalker(t, ms).StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		exec := ms.onlyExecution(t)
+		if exec.CurrentNodeID != "3" {
+			t.Fatalf("expected success path (node 3), got %s", exec.CurrentNodeID)
+		}
+		if gotAuth != "Bearer s3cret" || gotBody != `{"name":"Asha"}` {
+			t.Errorf("unexpected request: auth=%q body=%q", gotAuth, gotBody)
+		}
+		var state map[string]interface{}
+		_ = json.Unmarshal(exec.StateData, &state)
+		if state["crm_lead_id"] != "L-42" || state["price"] != float64(9500000) {
+			t.Errorf("unexpected mapped state: %v", state)
+		}
+	})
+
+	t.Run("Escapes substitutions in the body", func(t *testing.T) {
+		var got map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("body is not valid JSON: %v", err)
+			}
+		}))
+		defer srv.Close()
+
+		ms := newMemStore()
+		ms.contacts[1].Name = `Asha "AK" K\`
+		httpWorkflow(t, ms, map[string]interface{}{
+			"method": "POST",
+			"url":    srv.URL,
+			"body":   `{"name":"{{contact.name}}","greeting":"Hi {{contact.name | json}}"}`,
+		})
+		if err := newTestWalker(t, ms).StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if got["name"] != `Asha "AK" K\` || got["greeting"] != `Hi Asha "AK" K\` {
+			t.Errorf("unexpected body: %v", got)
+		}
+	})
+
+	t.Run("Error status follows error handle", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		ms := newMemStore()
+		httpWorkflow(t, ms, map[string]interface{}{"url": srv.URL})
+		if err := newTestWalker(t, ms).StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if exec := ms.onlyExecution(t); exec.CurrentNodeID != "4" {
+			t.Fatalf("expected error path (node 4), got %s", exec.CurrentNodeID)
+		}
+	})
+
+	t.Run("Rate limited request follows error handle", func(t *testing.T) {
+		ms := newMemStore()
+		httpWorkflow(t, ms, map[string]interface{}{"url": "http://127.0.0.1:1/"})
+		gw := newTestWalker(t, ms)
+		gw.RateLimiter = denyLimiter{}
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if exec := ms.onlyExecution(t); exec.CurrentNodeID != "4" {
+			t.Fatalf("expected error path (node 4), got %s", exec.CurrentNodeID)
+		}
+	})
+
+	t.Run("Unavailable rate limiter fails closed", func(t *testing.T) {
+		hits := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+		defer srv.Close()
+
+		ms := newMemStore()
+		httpWorkflow(t, ms, map[string]interface{}{"url": srv.URL})
+		gw := newTestWalker(t, ms)
+		gw.RateLimiter = brokenLimiter{}
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if exec := ms.onlyExecution(t); exec.CurrentNodeID != "4" || hits != 0 {
+			t.Fatalf("expected error path (node 4) without a request, got %s after %d requests", exec.CurrentNodeID, hits)
+		}
+	})
+
+	t.Run("Simulation is not rate limited", func(t *testing.T) {
+		ms := newMemStore()
+		httpWorkflow(t, ms, map[string]interface{}{"url": "http://127.0.0.1:1/"})
+		gw := newTestWalker(t, ms)
+		gw.RateLimiter = denyLimiter{}
+		res, err := gw.Simulate(ctx, ms.workflows[1], engine.SimulationOptions{Message: "hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Effects) != 1 || res.Effects[0].Kind != engine.EffectHTTPRequest {
+			t.Fatalf("expected the simulated request to be recorded, got %+v (%s)", res.Effects, res.Error)
+		}
+	})
+}
+
+func TestSafeHTTPClientBlocksPrivateRanges(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := engine.NewSafeHTTPClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Get(srv.URL)
+	if !errors.Is(err, engine.ErrBlockedAddress) {
+		t.Fatalf("expected loopback to be blocked, got %v", err)
+	}
+
+	if _, err := engine.NewSafeHTTPClient([]string{"not-a-cidr"}); err == nil {
+		t.Error("expected invalid CIDR to be rejected")
+	}
+}
+
+func TestValidateHTTPRequestNodes(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		{"Valid", map[string]interface{}{"url": "https://api.example.com/{{state.id}}", "method": "get"}, false},
+		{"Missing URL", map[string]interface{}{}, true},
+		{"Bad scheme", map[string]interface{}{"url": "file:///etc/passwd"}, true},
+		{"Bad method", map[string]interface{}{"url": "https://x.test", "method": "TRACE"}, true},
+		{"Unknown variable", map[string]interface{}{"url": "https://x.test/{{contact.nope}}"}, true},
+		{"Bad mapping path", map[string]interface{}{"url": "https://x.test", "responseMapping": map[string]interface{}{"a": "items[x]"}}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			graph := &models.WorkflowGraph{Nodes: []models.ReactFlowNode{{ID: "n", Type: models.NodeTypeActionHTTPRequest, Data: tc.data}}}
+			err := engine.ValidateHTTPRequestNodes(graph)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("wantErr=%v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc interface{}
+	_ = json.Unmarshal([]byte(`{"a":{"b":[{"c":1},{"c":2}]},"list":[[10,20]]}`), &doc)
+
+	if v, ok := engine.LookupJSONPath(doc, "$.a.b[1].c"); !ok || v != float64(2) {
+		t.Errorf("a.b[1].c = %v, %v", v, ok)
+	}
+	if v, ok := engine.LookupJSONPath(doc, "list[0][1]"); !ok || v != float64(20) {
+		t.Errorf("list[0][1] = %v, %v", v, ok)
+	}
+	if _, ok := engine.LookupJSONPath(doc, "a.b[5].c"); ok {
+		t.Error("out of range index should not resolve")
+	}
+}
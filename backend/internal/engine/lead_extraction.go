@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hibiken/asynq"
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Lead extraction reads a contact's budget, preferred location, purchase timeline, phone and
+// buying intent from the conversation once the contact has stopped writing for
+// GraphWalker.LeadExtractionDelay. Values below minLeadConfidence are dropped, and fields an
+// agent has set are never touched.
+const (
+	leadHistoryMessages = 30
+	minLeadConfidence   = 0.6
+
+	// FeatureLeadExtraction attributes extraction calls in the LLM usage report
+	FeatureLeadExtraction = "lead_extraction"
+)
+
+// LeadExtractionPayload is the body of the "workflow:lead_extract" task. MessageID is the inbound
+// message that armed it; a later message re-arms the timer and makes this one stale.
+type LeadExtractionPayload struct {
+	ContactID int64 `json:"contact_id"`
+	MessageID int64 `json:"message_id"`
+}
+
+// leadValue is one extracted field as the model reports it
+type leadValue struct {
+	Value      *string `json:"value"`
+	Confidence float64 `json:"confidence"`
+	Evidence   *string `json:"evidence"`
+}
+
+// leadExtraction is the shape leadSchema asks the model for
+type leadExtraction struct {
+	Budget            leadValue `json:"budget"`
+	PreferredLocation leadValue `json:"preferred_location"`
+	PurchaseTimeline  leadValue `json:"purchase_timeline"`
+	Phone             leadValue `json:"phone"`
+	IsHotLead         struct {
+		Value      *bool   `json:"value"`
+		Confidence float64 `json:"confidence"`
+		Evidence   *string `json:"evidence"`
+	} `json:"is_hot_lead"`
+}
+
+// leadFieldSchema is one extracted field with the model's confidence and the lead's words
+func leadFieldSchema(value map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"value":      nullable(value),
+			"confidence": schemaNumber,
+			"evidence":   nullable(schemaString),
+		},
+		"required":             []string{"value", "confidence", "evidence"},
+		"additionalProperties": false,
+	}
+}
+
+// leadSchema follows the strict structured output rules, like workflowSchema
+var leadSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"budget":             leadFieldSchema(schemaString),
+		"preferred_location": leadFieldSchema(schemaString),
+		"purchase_timeline":  leadFieldSchema(map[string]interface{}{"type": "string", "enum": TimelineBuckets}),
+		"phone":              leadFieldSchema(schemaString),
+		"is_hot_lead":        leadFieldSchema(map[string]interface{}{"type": "boolean"}),
+	},
+	"required":             []string{"budget", "preferred_location", "purchase_timeline", "phone", "is_hot_lead"},
+	"additionalProperties": false,
+}
+
+const leadExtractionPrompt = `You read a real estate sales conversation and extract what the lead told us about themselves.
+Only report what the lead said, never what we suggested. Use null for anything not mentioned.
+- budget: the amount or range as the lead put it, e.g. "80L-1.2Cr" or "under 90 lakhs"
+- preferred_location: the cities or localities the lead wants to buy in, separated by commas
+- purchase_timeline: when the lead plans to buy, as one of the allowed buckets
+- phone: a phone number the lead shared
+- is_hot_lead: true if the lead is ready to buy or visit soon, false if they are clearly not interested
+For every field give your confidence from 0 to 1 and quote the lead's words as evidence.
+
+Conversation:
+`
+
+// ScheduleLeadExtraction arms the extraction of a contact's lead fields after an inbound message.
+// Each message pushes the extraction back, so it runs once per burst of messages. Without an
+// Asynq client it runs right away.
+func (gw *GraphWalker) ScheduleLeadExtraction(ctx context.Context, contactID, messageID int64) {
+	if gw.LeadExtractionDelay <= 0 || gw.LLMClient == nil || gw.sim != nil {
+		return
+	}
+	p := LeadExtractionPayload{ContactID: contactID, MessageID: messageID}
+	if gw.AsynqClient == nil {
+		go func() {
+			if err := gw.HandleLeadExtraction(context.Background(), p); err != nil {
+				log.Printf("[LeadExtraction] Contact %d failed: %v", contactID, err)
+			}
+		}()
+		return
+	}
+	payload, _ := json.Marshal(p)
+	taskID := fmt.Sprintf("lead_extract:%d:%d", contactID, messageID)
+	_, err := gw.AsynqClient.Enqueue(asynq.NewTask("workflow:lead_extract", payload), asynq.ProcessIn(gw.LeadExtractionDelay), asynq.TaskID(taskID))
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		log.Printf("ERROR: Failed to enqueue lead extraction for contact %d: %v", contactID, err)
+	}
+}
+
+// HandleLeadExtraction extracts the lead fields of a contact from its recent conversation and
+// saves the confident ones that no agent has set, with their provenance. Timers armed by a
+// message that is no longer the contact's latest are ignored.
+func (gw *GraphWalker) HandleLeadExtraction(ctx context.Context, p LeadExtractionPayload) error {
+	history, err := gw.recentMessages(ctx, p.ContactID, leadHistoryMessages)
+	if err != nil {
+		return err
+	}
+	var latest int64
+	for _, m := range history {
+		if m.Direction == "inbound" {
+			latest = m.ID
+		}
+	}
+	if latest == 0 || latest != p.MessageID {
+		log.Printf("[LeadExtraction] Timer of contact %d is stale, skipping", p.ContactID)
+		return nil
+	}
+
+	contact, err := gw.Store.GetContactByID(ctx, p.ContactID)
+	if err != nil {
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	sources, err := gw.Store.GetContactFieldSources(ctx, contact.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load lead field sources: %w", err)
+	}
+	if len(openLeadFields(contact, sources)) == 0 {
+		return nil
+	}
+
+	var transcript strings.Builder
+	for _, m := range history {
+		speaker := "Lead"
+		if m.Direction == "outbound" {
+			speaker = "Us"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", speaker, m.Content)
+	}
+
+	llm := ai.Select(gw.LLMClient, contact.UserID, ai.Selection{})
+	aiCtx := ai.WithAttribution(ctx, ai.Attribution{UserID: contact.UserID, Feature: FeatureLeadExtraction})
+	raw, err := llm.GenerateStructuredJSON(aiCtx, leadExtractionPrompt+transcript.String(), leadSchema)
+	if errors.Is(err, ai.ErrBudgetExceeded) {
+		log.Printf("[LeadExtraction] Skipping contact %d: %v", contact.ID, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract lead fields: %w", err)
+	}
+	var ext leadExtraction
+	if err := json.Unmarshal([]byte(raw), &ext); err != nil {
+		return fmt.Errorf("invalid lead extraction: %w", err)
+	}
+
+	// An agent may have edited the contact while the model was answering
+	if contact, err = gw.Store.GetContactByID(ctx, p.ContactID); err != nil {
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	if sources, err = gw.Store.GetContactFieldSources(ctx, contact.ID); err != nil {
+		return fmt.Errorf("failed to load lead field sources: %w", err)
+	}
+	before := *contact
+	changed := applyLeadExtraction(contact, openLeadFields(contact, sources), &ext, latest)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(changed))
+	for _, s := range changed {
+		values[s.Field] = contactFields[s.Field](contact)
+	}
+	written, err := gw.Store.SaveContactLeadFields(ctx, contact.ID, values, changed)
+	if err != nil {
+		return fmt.Errorf("failed to update contact: %w", err)
+	}
+	if len(written) == 0 {
+		return nil
+	}
+	log.Printf("[LeadExtraction] Updated %d lead field(s) of contact %d", len(written), contact.ID)
+
+	// The store skips fields an agent took in the meantime; only the written ones changed
+	after := before
+	only := make(map[string]bool, len(written))
+	for _, field := range written {
+		only[field] = true
+	}
+	applyLeadExtraction(&after, only, &ext, latest)
+
+	gw.CheckGoals(ctx, contact.ID)
+	gw.EmitFieldChanges(ctx, &before, &after)
+	return nil
+}
+
+// openLeadFields returns the lead fields extraction may write: those nobody has set and those
+// set by an earlier extraction. Values entered before provenance was recorded count as an
+// agent's, and a lead already marked hot stays hot.
+func openLeadFields(c *models.Contact, sources []models.ContactFieldSource) map[string]bool {
+	bySource := make(map[string]string, len(sources))
+	for _, s := range sources {
+		bySource[s.Field] = s.Source
+	}
+	current := map[string]string{
+		"budget":             c.Budget,
+		"preferred_location": c.PreferredLocation,
+		"purchase_timeline":  c.PurchaseTimeline,
+		"phone":              c.Phone,
+		"is_hot_lead":        "",
+	}
+	if c.IsHotLead {
+		current["is_hot_lead"] = "true"
+	}
+
+	open := make(map[string]bool)
+	for field, value := range current {
+		switch bySource[field] {
+		case models.FieldSourceAgent:
+			// never overwritten
+		case models.FieldSourceAI:
+			if field != "is_hot_lead" || value == "" {
+				open[field] = true
+			}
+		default:
+			if value == "" {
+				open[field] = true
+			}
+		}
+	}
+	return open
+}
+
+// applyLeadExtraction writes the normalised, confident values of the open fields to the contact
+// and returns the provenance of the ones that changed
+func applyLeadExtraction(c *models.Contact, open map[string]bool, ext *leadExtraction, messageID int64) []models.ContactFieldSource {
+	var changed []models.ContactFieldSource
+	set := func(field string, target *string, v leadValue, normalize func(string) string) {
+		if !open[field] || v.Value == nil || v.Confidence < minLeadConfidence {
+			return
+		}
+		value := normalize(*v.Value)
+		if value == "" || value == *target {
+			return
+		}
+		*target = value
+		changed = append(changed, leadSource(c.ID, field, v.Confidence, v.Evidence, messageID))
+	}
+	set("budget", &c.Budget, ext.Budget, NormalizeBudget)
+	set("preferred_location", &c.PreferredLocation, ext.PreferredLocation, NormalizeLocation)
+	set("purchase_timeline", &c.PurchaseTimeline, ext.PurchaseTimeline, NormalizeTimeline)
+	set("phone", &c.Phone, ext.Phone, NormalizePhone)
+
+	// Extraction only ever promotes a lead; cooling one down is an agent's call
+	hot := ext.IsHotLead
+	if open["is_hot_lead"] && hot.Value != nil && *hot.Value && hot.Confidence >= minLeadConfidence && !c.IsHotLead {
+		c.IsHotLead = true
+		changed = append(changed, leadSource(c.ID, "is_hot_lead", hot.Confidence, hot.Evidence, messageID))
+	}
+	return changed
+}
+
+func leadSource(contactID int64, field string, confidence float64, evidence *string, messageID int64) models.ContactFieldSource {
+	s := models.ContactFieldSource{ContactID: contactID, Field: field, Source: models.FieldSourceAI, Confidence: confidence, MessageID: messageID}
+	if evidence != nil {
+		s.Evidence = strings.TrimSpace(*evidence)
+	}
+	return s
+}
@@ -0,0 +1,206 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestNormalizeBudget(t *testing.T) {
+	cases := map[string]string{
+		"80L-1.2Cr":                   "80L-1.2Cr",
+		"80 to 120 lakhs":             "80L-1.2Cr",
+		"between 80 lakh and 1 crore": "80L-1Cr",
+		"₹75,00,000":                  "75L",
+		"Rs 1,20,00,000":              "1.2Cr",
+		"around 90 lacs":              "90L",
+		"under 1.5 cr":                "up to 1.5Cr",
+		"2 BHK, above 2 crore":        "2Cr+",
+		"1.2 crore - 80 lakh":         "80L-1.2Cr",
+		"50k per month":               "50K",
+		"2 bhk":                       "",
+		"flexible":                    "",
+	}
+	for in, want := range cases {
+		if got := engine.NormalizeBudget(in); got != want {
+			t.Errorf("NormalizeBudget(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeLocation(t *testing.T) {
+	cases := map[string]string{
+		"bangalore":             "Bengaluru",
+		"whitefield, bangalore": "Whitefield, Bengaluru",
+		"Gurgaon or noida":      "Gurugram, Noida",
+		"HSR layout / BLR":      "HSR Layout, Bengaluru",
+		"  new delhi. ":         "Delhi",
+		"Pune, pune":            "Pune",
+	}
+	for in, want := range cases {
+		if got := engine.NormalizeLocation(in); got != want {
+			t.Errorf("NormalizeLocation(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeTimeline(t *testing.T) {
+	cases := map[string]string{
+		"3-6 months":              engine.TimelineThreeToSix,
+		"ASAP":                    engine.TimelineImmediate,
+		"in 2 weeks":              engine.TimelineImmediate,
+		"within 3 months":         engine.TimelineOneToThree,
+		"in a few months":         engine.TimelineThreeToSix,
+		"6 months to a year":      engine.TimelineSixToTwelve,
+		"next year":               engine.TimelineSixToTwelve,
+		"2 years":                 engine.TimelineLater,
+		"just exploring for now":  engine.TimelineExploring,
+		"when the price is right": "",
+	}
+	for in, want := range cases {
+		if got := engine.NormalizeTimeline(in); got != want {
+			t.Errorf("NormalizeTimeline(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	cases := map[string]string{
+		"+91 98765 43210": "+919876543210",
+		"0091-9876543210": "+919876543210",
+		"98765-43210":     "9876543210",
+		"12345":           "",
+	}
+	for in, want := range cases {
+		if got := engine.NormalizePhone(in); got != want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+const leadAnswer = `{
+	"budget": {"value": "80 lakhs to 1.2 crore", "confidence": 0.9, "evidence": "somewhere between 80L and 1.2Cr"},
+	"preferred_location": {"value": "whitefield, bangalore", "confidence": 0.85, "evidence": "near Whitefield"},
+	"purchase_timeline": {"value": "1-3 months", "confidence": 0.4, "evidence": "soon-ish"},
+	"phone": {"value": "+91 98765 43210", "confidence": 0.95, "evidence": "call me on +91 98765 43210"},
+	"is_hot_lead": {"value": true, "confidence": 0.8, "evidence": "want to visit this weekend"}
+}`
+
+func leadConversation(ms *memStore) {
+	ms.messages = []models.Message{
+		{ID: 1, ContactID: 1, Direction: "inbound", Content: "Hi, looking for a 3BHK near Whitefield"},
+		{ID: 2, ContactID: 1, Direction: "outbound", Content: "Great! What budget do you have in mind?"},
+		{ID: 3, ContactID: 1, Direction: "inbound", Content: "somewhere between 80L and 1.2Cr, call me on +91 98765 43210"},
+	}
+}
+
+func TestLeadExtraction(t *testing.T) {
+	ms := newMemStore()
+	leadConversation(ms)
+	ms.contacts[1].Phone = "+911111111111" // typed in before provenance was recorded
+	ms.sources = map[int64][]models.ContactFieldSource{
+		1: {{ContactID: 1, Field: "preferred_location", Source: models.FieldSourceAgent}},
+	}
+	llm := ai.NewFakeClient().Respond(ai.MethodStructuredJSON, "Lead: somewhere between", leadAnswer)
+	gw := engine.NewGraphWalker(ms, llm, nil, nil)
+
+	if err := gw.HandleLeadExtraction(context.Background(), engine.LeadExtractionPayload{ContactID: 1, MessageID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ms.contacts[1]
+	if c.Budget != "80L-1.2Cr" || !c.IsHotLead {
+		t.Errorf("extracted fields not saved: %+v", c)
+	}
+	if c.PreferredLocation != "" || c.Phone != "+911111111111" {
+		t.Errorf("agent-entered fields overwritten: %+v", c)
+	}
+	if c.PurchaseTimeline != "" {
+		t.Errorf("low confidence timeline saved: %q", c.PurchaseTimeline)
+	}
+
+	got := map[string]models.ContactFieldSource{}
+	for _, s := range ms.sources[1] {
+		got[s.Field] = s
+	}
+	if s := got["budget"]; s.Source != models.FieldSourceAI || s.Confidence != 0.9 || s.MessageID != 3 || s.Evidence != "somewhere between 80L and 1.2Cr" {
+		t.Errorf("budget provenance not recorded: %+v", s)
+	}
+	if s := got["preferred_location"]; s.Source != models.FieldSourceAgent {
+		t.Errorf("agent provenance replaced: %+v", s)
+	}
+	if _, ok := got["phone"]; ok {
+		t.Error("provenance recorded for a field that was not written")
+	}
+	if calls := llm.CallsTo(ai.MethodStructuredJSON); len(calls) != 1 || calls[0].Schema == nil {
+		t.Errorf("expected one structured call, got %+v", calls)
+	}
+}
+
+func TestLeadExtractionDebounce(t *testing.T) {
+	ms := newMemStore()
+	leadConversation(ms)
+	llm := ai.NewFakeClient().Respond(ai.MethodStructuredJSON, ".", leadAnswer)
+	gw := engine.NewGraphWalker(ms, llm, nil, nil)
+
+	// The timer armed by message 1 was overtaken by message 3
+	if err := gw.HandleLeadExtraction(context.Background(), engine.LeadExtractionPayload{ContactID: 1, MessageID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.Calls()) != 0 || ms.contacts[1].Budget != "" {
+		t.Fatal("a stale timer extracted the lead")
+	}
+
+	// Fields already extracted are refreshed by later extractions; a hot lead stays hot
+	ms.contacts[1].IsHotLead = true
+	ms.sources = map[int64][]models.ContactFieldSource{
+		1: {
+			{ContactID: 1, Field: "budget", Source: models.FieldSourceAI, Confidence: 0.7},
+			{ContactID: 1, Field: "is_hot_lead", Source: models.FieldSourceAI, Confidence: 0.7},
+		},
+	}
+	llm = ai.NewFakeClient().Respond(ai.MethodStructuredJSON, ".",
+		`{"budget": {"value": "1.5 cr", "confidence": 0.8, "evidence": null},
+		  "preferred_location": {"value": null, "confidence": 0, "evidence": null},
+		  "purchase_timeline": {"value": null, "confidence": 0, "evidence": null},
+		  "phone": {"value": null, "confidence": 0, "evidence": null},
+		  "is_hot_lead": {"value": false, "confidence": 0.9, "evidence": null}}`)
+	gw = engine.NewGraphWalker(ms, llm, nil, nil)
+	if err := gw.HandleLeadExtraction(context.Background(), engine.LeadExtractionPayload{ContactID: 1, MessageID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if c := ms.contacts[1]; c.Budget != "1.5Cr" || !c.IsHotLead {
+		t.Errorf("unexpected contact after re-extraction: %+v", c)
+	}
+}
+
+func TestLeadExtractionKeepsConcurrentAgentEdit(t *testing.T) {
+	ms := newMemStore()
+	leadConversation(ms)
+	// An agent sets the budget after the extraction read the contact, before it writes
+	ms.beforeLeadSave = func() {
+		ms.contacts[1].Budget = "95L"
+		ms.sources = map[int64][]models.ContactFieldSource{1: {{ContactID: 1, Field: "budget", Source: models.FieldSourceAgent}}}
+	}
+	llm := ai.NewFakeClient().Respond(ai.MethodStructuredJSON, ".", leadAnswer)
+	gw := engine.NewGraphWalker(ms, llm, nil, nil)
+
+	if err := gw.HandleLeadExtraction(context.Background(), engine.LeadExtractionPayload{ContactID: 1, MessageID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	c := ms.contacts[1]
+	if c.Budget != "95L" {
+		t.Errorf("agent's budget overwritten: %q", c.Budget)
+	}
+	if c.PreferredLocation != "Whitefield, Bengaluru" || c.Phone != "+919876543210" {
+		t.Errorf("other fields not extracted: %+v", c)
+	}
+	for _, s := range ms.sources[1] {
+		if s.Field == "budget" && s.Source != models.FieldSourceAgent {
+			t.Errorf("agent provenance replaced: %+v", s)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package engine
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Buckets of Contact.PurchaseTimeline written by lead extraction
+const (
+	TimelineImmediate   = "immediate" // within a month
+	TimelineOneToThree  = "1-3 months"
+	TimelineThreeToSix  = "3-6 months"
+	TimelineSixToTwelve = "6-12 months"
+	TimelineLater       = "12+ months"
+	TimelineExploring   = "exploring" // no plan to buy yet
+)
+
+// TimelineBuckets lists the purchase timeline buckets, soonest first
+var TimelineBuckets = []string{
+	TimelineImmediate, TimelineOneToThree, TimelineThreeToSix, TimelineSixToTwelve, TimelineLater, TimelineExploring,
+}
+
+const amountUnit = `(crores?|cr|lakhs?|lacs?|lac|l|thousand|k|millions?|mn|m)`
+
+var (
+	digitGroupRe  = regexp.MustCompile(`(\d),(\d)`)
+	amountRangeRe = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*` + amountUnit + `?\s*(?:-|–|to)\s*(\d+(?:\.\d+)?)\s*` + amountUnit + `?\b`)
+	amountRe      = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*` + amountUnit + `?\b`)
+	budgetCapRe   = regexp.MustCompile(`\b(under|below|upto|up to|within|max|maximum|less than|not more than)\b`)
+	budgetFloorRe = regexp.MustCompile(`\b(above|over|more than|at least|minimum|min|starting|plus)\b|\+`)
+)
+
+// NormalizeBudget rewrites a budget the way leads state it in India into rupee amounts in lakhs
+// (L) and crores (Cr): "80 to 120 lakhs" becomes "80L-1.2Cr", "under 1.5 crore" "up to 1.5Cr"
+// and "₹75,00,000" "75L". It returns "" when no amount can be read.
+func NormalizeBudget(s string) string {
+	s = strings.ToLower(s)
+	for prev := ""; prev != s; {
+		prev, s = s, digitGroupRe.ReplaceAllString(s, "$1$2")
+	}
+
+	if m := amountRangeRe.FindStringSubmatch(s); m != nil {
+		loUnit, hiUnit := m[2], m[4]
+		if loUnit == "" {
+			loUnit = hiUnit // "80-120 lakhs"
+		}
+		lo, okLo := rupees(m[1], loUnit)
+		hi, okHi := rupees(m[3], hiUnit)
+		if okLo && okHi {
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if lo == hi {
+				return formatRupees(lo)
+			}
+			return formatRupees(lo) + "-" + formatRupees(hi)
+		}
+	}
+
+	var amounts []float64
+	for _, m := range amountRe.FindAllStringSubmatch(s, -1) {
+		if v, ok := rupees(m[1], m[2]); ok {
+			amounts = append(amounts, v)
+		}
+	}
+	switch {
+	case len(amounts) == 0:
+		return ""
+	case len(amounts) > 1: // "between 80 lakh and 1 crore"
+		lo, hi := amounts[0], amounts[0]
+		for _, v := range amounts[1:] {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		if lo != hi {
+			return formatRupees(lo) + "-" + formatRupees(hi)
+		}
+	}
+	switch {
+	case budgetCapRe.MatchString(s):
+		return "up to " + formatRupees(amounts[0])
+	case budgetFloorRe.MatchString(s):
+		return formatRupees(amounts[0]) + "+"
+	}
+	return formatRupees(amounts[0])
+}
+
+// rupees converts an amount and its unit. Amounts without a unit are taken as rupees, and small
+// ones as something else ("2 BHK").
+func rupees(num, unit string) (float64, bool) {
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	switch {
+	case strings.HasPrefix(unit, "cr"):
+		v *= 1e7
+	case strings.HasPrefix(unit, "l"):
+		v *= 1e5
+	case unit == "k" || unit == "thousand":
+		v *= 1e3
+	case strings.HasPrefix(unit, "m"):
+		v *= 1e6
+	case v < 1000:
+		return 0, false
+	}
+	return v, true
+}
+
+func formatRupees(v float64) string {
+	round := func(x float64) string {
+		return strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64)
+	}
+	switch {
+	case v >= 1e7:
+		return round(v/1e7) + "Cr"
+	case v >= 1e5:
+		return round(v/1e5) + "L"
+	case v >= 1e3:
+		return round(v/1e3) + "K"
+	}
+	return round(v)
+}
+
+// cityAliases maps old and informal city names to the ones contacts are stored with
+var cityAliases = map[string]string{
+	"bangalore":   "Bengaluru",
+	"bengaluru":   "Bengaluru",
+	"blr":         "Bengaluru",
+	"bombay":      "Mumbai",
+	"mumbai":      "Mumbai",
+	"navi mumbai": "Navi Mumbai",
+	"gurgaon":     "Gurugram",
+	"gurugram":    "Gurugram",
+	"new delhi":   "Delhi",
+	"delhi":       "Delhi",
+	"ncr":         "Delhi NCR",
+	"delhi ncr":   "Delhi NCR",
+	"madras":      "Chennai",
+	"calcutta":    "Kolkata",
+	"poona":       "Pune",
+	"hyd":         "Hyderabad",
+	"cochin":      "Kochi",
+	"trivandrum":  "Thiruvananthapuram",
+	"mysore":      "Mysuru",
+	"baroda":      "Vadodara",
+	"vizag":       "Visakhapatnam",
+}
+
+var locationSepRe = regexp.MustCompile(`\s*(?:,|/|;|\bor\b|\band\b|&)\s*`)
+
+// NormalizeLocation cleans up a preferred location: the places are separated by commas, known
+// cities get their current names ("whitefield, bangalore" becomes "Whitefield, Bengaluru") and
+// other places are capitalised.
+func NormalizeLocation(s string) string {
+	var places []string
+	seen := map[string]bool{}
+	for _, p := range locationSepRe.Split(strings.TrimSpace(s), -1) {
+		p = strings.Join(strings.Fields(strings.Trim(p, ".")), " ")
+		if p == "" {
+			continue
+		}
+		if city, ok := cityAliases[strings.ToLower(p)]; ok {
+			p = city
+		} else {
+			words := strings.Fields(p)
+			for i, w := range words {
+				words[i] = strings.ToUpper(w[:1]) + w[1:]
+			}
+			p = strings.Join(words, " ")
+		}
+		if !seen[strings.ToLower(p)] {
+			seen[strings.ToLower(p)] = true
+			places = append(places, p)
+		}
+	}
+	return strings.Join(places, ", ")
+}
+
+var (
+	timelineSpanRe = regexp.MustCompile(`\b(\d+(?:\.\d+)?|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|couple of|few)\s*(?:(?:-|to)\s*(\d+(?:\.\d+)?)\s*)?(days?|weeks?|months?|mos?|years?|yrs?)\b`)
+	timelineNowRe  = regexp.MustCompile(`\b(asap|immediate|immediately|right away|urgent|urgently|this month|now|ready to move)\b`)
+	timelineNoneRe = regexp.MustCompile(`\b(exploring|just looking|browsing|not sure|undecided|no hurry|no rush|no plan)\b`)
+)
+
+var timelineNumbers = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+	"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "couple of": 2, "few": 4,
+}
+
+// NormalizeTimeline puts a purchase timeline into one of the TimelineBuckets: "in 2 weeks" is
+// immediate, "6 months to a year" is 6-12 months. It returns "" when the text names no time.
+func NormalizeTimeline(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, b := range TimelineBuckets {
+		if s == b {
+			return b
+		}
+	}
+	if timelineNoneRe.MatchString(s) {
+		return TimelineExploring
+	}
+
+	months := -1.0
+	for _, m := range timelineSpanRe.FindAllStringSubmatch(s, -1) {
+		n, ok := timelineNumbers[m[1]]
+		if !ok {
+			n, _ = strconv.ParseFloat(m[1], 64)
+		}
+		if m[2] != "" { // a range counts by its end
+			n, _ = strconv.ParseFloat(m[2], 64)
+		}
+		switch m[3][0] {
+		case 'd':
+			n /= 30
+		case 'w':
+			n /= 4.3
+		case 'y':
+			n *= 12
+		}
+		months = math.Max(months, n)
+	}
+	switch {
+	case months < 0:
+		if timelineNowRe.MatchString(s) {
+			return TimelineImmediate
+		}
+		if strings.Contains(s, "next year") {
+			return TimelineSixToTwelve
+		}
+		return ""
+	case months <= 1:
+		return TimelineImmediate
+	case months <= 3:
+		return TimelineOneToThree
+	case months <= 6:
+		return TimelineThreeToSix
+	case months <= 12:
+		return TimelineSixToTwelve
+	}
+	return TimelineLater
+}
+
+// NormalizePhone keeps the digits of a phone number and its leading + (or 00). Numbers with
+// fewer than 10 or more than 15 digits are rejected with "".
+func NormalizePhone(s string) string {
+	s = strings.TrimSpace(s)
+	plus := strings.HasPrefix(s, "+")
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if !plus && strings.HasPrefix(d, "00") {
+		plus, d = true, d[2:]
+	}
+	if len(d) < 10 || len(d) > 15 {
+		return ""
+	}
+	if plus {
+		return "+" + d
+	}
+	return d
+}
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Conversation memory of action_ai_reply. The reply is generated from the most recent messages,
+// as many as fit in historyMessages and maxContextTokens, plus a rolling summary of everything
+// older and what is known about the contact. The summary is rewritten once summarizeBatch
+// messages have fallen out of the window since it was last written.
+const (
+	DefaultHistoryMessages = 20
+	DefaultContextTokens   = 2000
+
+	summarizeBatch     = 10
+	maxSummaryMessages = 200 // unsummarised messages folded into the summary at once
+	tokensPerMessage   = 4   // role and separators
+)
+
+// conversationMemory is the context given to the model for one reply
+type conversationMemory struct {
+	Messages []ai.ChatMessage
+	Summary  string
+	Tokens   int // estimated tokens of Messages
+}
+
+// estimateTokens approximates a token count without a tokenizer (about 4 characters per token)
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s)+3)/4 + tokensPerMessage
+}
+
+// buildMemory assembles the conversation context for a reply to latest, the message that
+// triggered or resumed the execution.
+func (gw *GraphWalker) buildMemory(ctx context.Context, llm ai.LLMClient, contactID int64, latest string, historyLimit, tokenBudget int) (*conversationMemory, error) {
+	msgs, err := gw.recentMessages(ctx, contactID, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk back from the newest message while the window has room. The newest message is
+	// always kept, however long it is.
+	start, tokens := len(msgs), 0
+	for start > 0 && len(msgs)-start < historyLimit {
+		cost := estimateTokens(msgs[start-1].Content)
+		if start < len(msgs) && tokens+cost > tokenBudget {
+			break
+		}
+		tokens += cost
+		start--
+	}
+
+	mem := &conversationMemory{Tokens: tokens}
+	for _, m := range msgs[start:] {
+		mem.add(chatRole(m), m.Content)
+	}
+	// The triggering message is normally stored already; simulations and callers that start a
+	// workflow without an inbound message are the exception
+	if latest = strings.TrimSpace(latest); latest != "" {
+		if n := len(mem.Messages); n == 0 || mem.Messages[n-1].Role != ai.RoleUser || !strings.HasSuffix(mem.Messages[n-1].Content, latest) {
+			mem.add(ai.RoleUser, latest)
+			mem.Tokens += estimateTokens(latest)
+		}
+	}
+
+	// Everything before the window is the summary's
+	var windowStart int64
+	if start < len(msgs) {
+		windowStart = msgs[start].ID
+	}
+	mem.Summary = gw.rollingSummary(ctx, llm, contactID, windowStart)
+	return mem, nil
+}
+
+// add appends a turn, merging consecutive turns of the same role (several messages in a row
+// from the lead, or a workflow that sent two messages) so roles alternate
+func (m *conversationMemory) add(role, content string) {
+	if n := len(m.Messages); n > 0 && m.Messages[n-1].Role == role {
+		m.Messages[n-1].Content += "\n" + content
+		return
+	}
+	m.Messages = append(m.Messages, ai.ChatMessage{Role: role, Content: content})
+}
+
+func chatRole(m models.Message) string {
+	if m.Direction == "outbound" {
+		return ai.RoleAssistant
+	}
+	return ai.RoleUser
+}
+
+// recentMessages returns the contact's newest text messages, at most limit, oldest first
+func (gw *GraphWalker) recentMessages(ctx context.Context, contactID int64, limit int) ([]models.Message, error) {
+	msgs, err := gw.Store.GetRecentMessagesByContact(ctx, contactID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	return textMessages(msgs), nil
+}
+
+func textMessages(msgs []models.Message) []models.Message {
+	text := msgs[:0]
+	for _, m := range msgs {
+		if strings.TrimSpace(m.Content) != "" {
+			text = append(text, m)
+		}
+	}
+	return text
+}
+
+// rollingSummary returns the summary of the messages older than the reply window, which starts
+// at message windowStart, folding in the ones it does not cover yet when there are enough of
+// them (at most maxSummaryMessages at a time). Failures are logged and the previous summary is
+// used; a reply is never blocked on its memory.
+func (gw *GraphWalker) rollingSummary(ctx context.Context, llm ai.LLMClient, contactID, windowStart int64) string {
+	cs, err := gw.Store.GetConversationSummary(ctx, contactID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to load conversation summary of contact %d: %v", contactID, err)
+		return ""
+	}
+	if cs == nil {
+		cs = &models.ConversationSummary{ContactID: contactID}
+	}
+
+	if windowStart <= cs.LastMessageID+1 {
+		return cs.Summary
+	}
+	unsummarised, err := gw.Store.GetMessagesByContactAfter(ctx, contactID, cs.LastMessageID, maxSummaryMessages)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to load the unsummarised messages of contact %d: %v", contactID, err)
+		return cs.Summary
+	}
+	var pending []models.Message
+	for _, m := range textMessages(unsummarised) {
+		if m.ID < windowStart {
+			pending = append(pending, m)
+		}
+	}
+	// A first summary is written as soon as anything falls out of the window
+	if len(pending) == 0 || (cs.Summary != "" && len(pending) < summarizeBatch) || gw.sim != nil {
+		return cs.Summary
+	}
+
+	var transcript strings.Builder
+	for _, m := range pending {
+		speaker := "Lead"
+		if m.Direction == "outbound" {
+			speaker = "Us"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", speaker, m.Content)
+	}
+	prompt := "You keep a running summary of a sales conversation with a lead. Update the summary with the new " +
+		"messages. Keep every fact the lead shared (needs, budget, location, timeline, objections, promises we made) " +
+		"and drop small talk. Answer with the summary only, at most 150 words.\n\n" +
+		"Current summary:\n" + cs.Summary + "\n\nNew messages:\n" + transcript.String()
+	summary, err := llm.GenerateText(ctx, prompt)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to summarise conversation of contact %d: %v", contactID, err)
+		return cs.Summary
+	}
+
+	cs.Summary = strings.TrimSpace(summary)
+	cs.LastMessageID = pending[len(pending)-1].ID
+	if err := gw.Store.SaveConversationSummary(ctx, cs); err != nil {
+		log.Printf("[GraphWalker] Failed to save conversation summary of contact %d: %v", contactID, err)
+	}
+	return cs.Summary
+}
+
+// llmFor returns the model client for a tenant's AI node, which may pick its own provider and model
+func (gw *GraphWalker) llmFor(userID int64, node *models.ReactFlowNode) ai.LLMClient {
+	if gw.LLMClient == nil {
+		return nil
+	}
+	return ai.Select(gw.LLMClient, userID, ai.Selection{
+		Provider: node.DataString("provider", ""),
+		Model:    node.DataString("model", ""),
+	})
+}
+
+// contactFacts lists what is known about the contact so the model does not ask again
+func contactFacts(c *models.Contact, visit *models.Visit) string {
+	var facts []string
+	add := func(label, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			facts = append(facts, fmt.Sprintf("- %s: %s", label, value))
+		}
+	}
+	add("Name", c.Name)
+	add("Phone", c.Phone)
+	add("Email", c.Email)
+	add("Budget", c.Budget)
+	add("Preferred location", c.PreferredLocation)
+	add("Purchase timeline", c.PurchaseTimeline)
+	add("Tags", strings.Join(c.Tags, ", "))
+	if visit != nil && visit.Status != "cancelled" {
+		add("Site visit", fmt.Sprintf("%s on %s (%s)", visit.ProjectName, visit.VisitTime.Format("Mon 2 Jan 2006 15:04"), visit.Status))
+	}
+	return strings.Join(facts, "\n")
+}
+
+// replySystemPrompt combines the node's instructions with the Knowledge Base entries, the
+// contact's facts and the summary
+func replySystemPrompt(instructions, facts, summary, knowledge string) string {
+	parts := []string{instructions}
+	if knowledge != "" {
+		parts = append(parts, "Relevant information from the Knowledge Base:\n"+knowledge)
+	}
+	if facts != "" {
+		parts = append(parts, "What we know about the lead (do not ask for it again):\n"+facts)
+	}
+	if summary != "" {
+		parts = append(parts, "Summary of the earlier conversation:\n"+summary)
+	}
+	return strings.Join(parts, "\n\n")
+}
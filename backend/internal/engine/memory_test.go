@@ -0,0 +1,206 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// chatLLM records chat requests and answers summary prompts with a fixed summary
+type chatLLM struct {
+	chats     []ai.ChatRequest
+	summaries []string
+}
+
+func (l *chatLLM) GenerateText(ctx context.Context, prompt string) (string, error) {
+	l.summaries = append(l.summaries, prompt)
+	return "Lead wants a 2BHK in Baner.", nil
+}
+
+func (l *chatLLM) Chat(ctx context.Context, req ai.ChatRequest) (string, error) {
+	l.chats = append(l.chats, req)
+	return "Happy to help!", nil
+}
+
+func (l *chatLLM) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, nil
+}
+
+func (l *chatLLM) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return "{}", nil
+}
+
+// seedConversation stores n messages for contact 1, alternating lead and bot, oldest first
+func seedConversation(ms *memStore, n int) {
+	for i := 1; i <= n; i++ {
+		direction := "inbound"
+		if i%2 == 0 {
+			direction = "outbound"
+		}
+		ms.messages = append(ms.messages, models.Message{ID: int64(i), ContactID: 1, Direction: direction, Content: fmt.Sprintf("message %d", i)})
+	}
+}
+
+func aiReplyWorkflow(t *testing.T, ms *memStore, data map[string]interface{}) {
+	t.Helper()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionAIReply, Data: data},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}},
+	)
+}
+
+func TestAIReplyMemory(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.contacts[1].Budget = "80 lakh"
+	ms.contacts[1].PreferredLocation = "Baner"
+	seedConversation(ms, 25)
+	aiReplyWorkflow(t, ms, map[string]interface{}{"prompt": "You are a sales assistant.", "historyMessages": float64(6), "temperature": 0.3, "maxTokens": float64(200)})
+
+	llm := &chatLLM{}
+	gw := engine.NewGraphWalker(ms, llm, nil, fakeMeta(http.StatusOK))
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{"received_message": "message 25"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(llm.chats) != 1 {
+		t.Fatalf("expected 1 chat call, got %d", len(llm.chats))
+	}
+	req := llm.chats[0]
+	if len(req.Messages) != 6 {
+		t.Fatalf("expected the last 6 messages, got %d: %+v", len(req.Messages), req.Messages)
+	}
+	if req.Messages[0].Content != "message 20" || req.Messages[0].Role != ai.RoleAssistant {
+		t.Errorf("window starts at %+v, want message 20 from the bot", req.Messages[0])
+	}
+	if last := req.Messages[5]; last.Role != ai.RoleUser || last.Content != "message 25" {
+		t.Errorf("latest message not last or duplicated: %+v", last)
+	}
+	if req.Temperature != 0.3 || req.MaxTokens != 200 {
+		t.Errorf("node options not passed: temperature=%v max_tokens=%d", req.Temperature, req.MaxTokens)
+	}
+	for _, want := range []string{"You are a sales assistant.", "Budget: 80 lakh", "Preferred location: Baner", "Lead wants a 2BHK in Baner."} {
+		if !strings.Contains(req.System, want) {
+			t.Errorf("system prompt does not contain %q:\n%s", want, req.System)
+		}
+	}
+
+	// The 19 older messages were summarised once
+	if len(llm.summaries) != 1 || !strings.Contains(llm.summaries[0], "Lead: message 1\n") || strings.Contains(llm.summaries[0], "message 20") {
+		t.Errorf("unexpected summary prompts %q", llm.summaries)
+	}
+	if cs := ms.summaries[1]; cs == nil || cs.LastMessageID != 19 {
+		t.Errorf("summary not saved up to message 19: %+v", cs)
+	}
+
+	t.Run("Summary is only rewritten after a batch", func(t *testing.T) {
+		ms.messages = append(ms.messages, models.Message{ID: 26, ContactID: 1, Direction: "outbound", Content: "Happy to help!"},
+			models.Message{ID: 27, ContactID: 1, Direction: "inbound", Content: "message 27"})
+		ms.executions = map[int64]*models.WorkflowExecution{}
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{"received_message": "message 27"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(llm.summaries) != 1 {
+			t.Errorf("summary rewritten for only 2 new older messages")
+		}
+		if !strings.Contains(llm.chats[1].System, "Lead wants a 2BHK in Baner.") {
+			t.Error("stored summary not used")
+		}
+	})
+}
+
+func TestAIReplyLongHistory(t *testing.T) {
+	ms := newMemStore()
+	seedConversation(ms, 6001)
+	aiReplyWorkflow(t, ms, map[string]interface{}{"historyMessages": float64(4)})
+
+	llm := &chatLLM{}
+	gw := engine.NewGraphWalker(ms, llm, nil, fakeMeta(http.StatusOK))
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "message 6001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The window ends at the newest message, beyond the 5,000 the history used to stop at
+	msgs := llm.chats[0].Messages
+	if len(msgs) != 4 || msgs[3].Content != "message 6001" {
+		t.Fatalf("unexpected window %+v", msgs)
+	}
+	// The summary catches up on the oldest unsummarised messages, a bounded batch at a time
+	if len(llm.summaries) != 1 || !strings.Contains(llm.summaries[0], "Lead: message 1\n") || strings.Contains(llm.summaries[0], "message 201\n") {
+		t.Errorf("unexpected summary prompt")
+	}
+	if cs := ms.summaries[1]; cs == nil || cs.LastMessageID != 200 {
+		t.Errorf("summary not saved up to message 200: %+v", cs)
+	}
+}
+
+func TestAIReplyTokenBudget(t *testing.T) {
+	ms := newMemStore()
+	long := strings.Repeat("word ", 400) // ~500 tokens
+	for i := 1; i <= 4; i++ {
+		ms.messages = append(ms.messages, models.Message{ID: int64(i), ContactID: 1, Direction: "inbound", Content: long})
+	}
+	ms.messages = append(ms.messages, models.Message{ID: 5, ContactID: 1, Direction: "outbound", Content: "short"})
+	aiReplyWorkflow(t, ms, map[string]interface{}{"maxContextTokens": float64(1100)})
+
+	llm := &chatLLM{}
+	gw := engine.NewGraphWalker(ms, llm, nil, fakeMeta(http.StatusOK))
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "and parking?"}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := llm.chats[0].Messages
+	// Two long messages fit next to "short"; consecutive lead messages are merged into one turn
+	if len(msgs) != 3 || msgs[0].Role != ai.RoleUser || strings.Count(msgs[0].Content, "\n") != 1 || msgs[1].Content != "short" {
+		t.Fatalf("unexpected window %d messages", len(msgs))
+	}
+	if msgs[2].Role != ai.RoleUser || msgs[2].Content != "and parking?" {
+		t.Errorf("unsaved triggering message missing: %+v", msgs[2])
+	}
+}
+
+func TestAIReplyBudgetFallback(t *testing.T) {
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionAIReply, Data: map[string]interface{}{"fallbackMessage": "Thanks {{contact.name}}, an agent will reply soon."}},
+			{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "replied"}},
+			{ID: "4", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "needs_agent"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", Target: "3"},
+			{ID: "e3", Source: "2", SourceHandle: engine.HandleBudgetExceeded, Target: "4"},
+		},
+	)
+	llm := ai.NewFakeClient().Fail(ai.MethodChat, ".", fmt.Errorf("%w: spent $10.00 of $10.00", ai.ErrBudgetExceeded))
+
+	gw := engine.NewGraphWalker(ms, llm, nil, fakeMeta(http.StatusOK))
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "price?"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if exec := ms.onlyExecution(t); exec.Status != "completed" {
+		t.Fatalf("expected the execution to complete on the fallback path, got %s", exec.Status)
+	}
+	var path []string
+	for _, s := range ms.steps {
+		path = append(path, s.NodeID+s.Branch)
+	}
+	if strings.Join(path, ",") != "1,2"+engine.HandleBudgetExceeded+",4" {
+		t.Errorf("unexpected path %v", path)
+	}
+	if last := ms.messages[len(ms.messages)-1]; last.Content != "Thanks Asha, an agent will reply soon." {
+		t.Errorf("fallback message not sent: %+v", last)
+	}
+}
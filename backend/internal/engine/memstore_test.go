@@ -0,0 +1,656 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/meta"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// memStore is an in-memory store for GraphWalker tests. Only the methods the walker touches are
+// implemented; the embedded interface panics on anything else so missing coverage is obvious.
+type memStore struct {
+	store.Store
+
+	mu         sync.Mutex
+	workflows  map[int64]*models.Workflow
+	contacts   map[int64]*models.Contact
+	executions map[int64]*models.WorkflowExecution
+	nextExecID int64
+	secrets    map[string]string
+	versions   []*models.WorkflowVersion
+	steps      []models.WorkflowExecutionStep
+	messages   []models.Message
+	summaries  map[int64]*models.ConversationSummary
+	sources    map[int64][]models.ContactFieldSource
+	calendar   *models.BusinessCalendar
+	knowledge  []models.KnowledgeBase
+	runs       map[string]time.Time // last run of a schedule or inactivity trigger, by "workflow/node"
+
+	beforeLeadSave func() // runs as SaveContactLeadFields starts, to race it with an agent
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		workflows:  make(map[int64]*models.Workflow),
+		contacts:   map[int64]*models.Contact{1: {ID: 1, UserID: 1, Name: "Asha", Platform: "instagram"}},
+		executions: make(map[int64]*models.WorkflowExecution),
+	}
+}
+
+// addWorkflow stores a workflow built from typed nodes and edges
+func (m *memStore) addWorkflow(t *testing.T, id int64, nodes []models.ReactFlowNode, edges []models.ReactFlowEdge) {
+	t.Helper()
+	nodesJSON, err := json.Marshal(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edgesJSON, err := json.Marshal(edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triggerType := engine.GraphTriggerType(nodesJSON, "")
+	m.workflows[id] = &models.Workflow{ID: id, UserID: 1, Name: "test", TriggerType: triggerType, Status: "published", Nodes: nodesJSON, Edges: edgesJSON}
+}
+
+// onlyExecution returns the single execution created during a test
+func (m *memStore) onlyExecution(t *testing.T) *models.WorkflowExecution {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.executions) != 1 {
+		t.Fatalf("expected 1 execution, got %d", len(m.executions))
+	}
+	for _, e := range m.executions {
+		cp := *e
+		return &cp
+	}
+	return nil
+}
+
+func (m *memStore) GetWorkflowByID(ctx context.Context, workflowID int64) (*models.Workflow, error) {
+	if w, ok := m.workflows[workflowID]; ok {
+		return w, nil
+	}
+	return nil, errors.New("workflow not found")
+}
+
+func (m *memStore) GetContactByID(ctx context.Context, contactID int64) (*models.Contact, error) {
+	if c, ok := m.contacts[contactID]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, errors.New("contact not found")
+}
+
+func (m *memStore) GetVisitByContact(ctx context.Context, contactID int64) (*models.Visit, error) {
+	return nil, errors.New("no visit")
+}
+
+func (m *memStore) GetPropertyVisitConfig(ctx context.Context, userID int64) (*models.PropertyVisitConfig, error) {
+	return nil, errors.New("no config")
+}
+
+func (m *memStore) CreateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Mirrors the partial unique index over exclusive active executions
+	if exec.Exclusive && isActive(exec.Status) && m.hasActiveExclusive(exec.WorkflowID, exec.ContactID) {
+		return store.ErrActiveExecution
+	}
+	m.nextExecID++
+	exec.CreatedAt = time.Now()
+	exec.ID = m.nextExecID
+	cp := *exec
+	m.executions[exec.ID] = &cp
+	return nil
+}
+
+func (m *memStore) GetWorkflowExecutionByID(ctx context.Context, executionID int64) (*models.WorkflowExecution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.executions[executionID]; ok {
+		cp := *e
+		return &cp, nil
+	}
+	return nil, errors.New("execution not found")
+}
+
+func (m *memStore) UpdateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.executions[exec.ID]; ok {
+		switch current.Status {
+		case "paused", "cancelled", "goal_reached":
+			return errors.New("no rows in result set")
+		}
+	}
+	cp := *exec
+	m.executions[exec.ID] = &cp
+	return nil
+}
+
+func (m *memStore) SetExecutionSplitBranch(ctx context.Context, executionID int64, nodeID, branch string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	exec, ok := m.executions[executionID]
+	if !ok {
+		return errors.New("execution not found")
+	}
+	if exec.SplitBranches == nil {
+		exec.SplitBranches = make(map[string]string)
+	}
+	exec.SplitBranches[nodeID] = branch
+	return nil
+}
+
+func (m *memStore) GetExecutionsAwaitingReply(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.WorkflowExecution
+	for _, e := range m.executions {
+		if e.ContactID == contactID && e.Status == "waiting" && e.WaitingFor == "reply" {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) ClaimWaitingExecution(ctx context.Context, executionID int64, waitingFor string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.executions[executionID]
+	if !ok || e.Status != "waiting" || e.WaitingFor != waitingFor {
+		return false, nil
+	}
+	e.Status = "running"
+	e.WaitingFor = ""
+	return true, nil
+}
+
+func (m *memStore) GetTenantSecrets(ctx context.Context, userID int64) (map[string]string, error) {
+	return m.secrets, nil
+}
+
+func (m *memStore) CreateWorkflowVersion(ctx context.Context, v *models.WorkflowVersion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v.ID = int64(len(m.versions) + 1)
+	v.Version = 1
+	for _, existing := range m.versions {
+		if existing.WorkflowID == v.WorkflowID && existing.Version >= v.Version {
+			v.Version = existing.Version + 1
+		}
+	}
+	cp := *v
+	m.versions = append(m.versions, &cp)
+	return nil
+}
+
+func (m *memStore) GetWorkflowVersionByID(ctx context.Context, versionID int64) (*models.WorkflowVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.versions {
+		if v.ID == versionID {
+			return v, nil
+		}
+	}
+	return nil, errors.New("version not found")
+}
+
+func (m *memStore) GetLatestWorkflowVersion(ctx context.Context, workflowID int64) (*models.WorkflowVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest *models.WorkflowVersion
+	for _, v := range m.versions {
+		if v.WorkflowID == workflowID && !v.Draft && (latest == nil || v.Version > latest.Version) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return nil, errors.New("version not found")
+	}
+	return latest, nil
+}
+
+func (m *memStore) CreateWorkflowExecutionStep(ctx context.Context, step *models.WorkflowExecutionStep) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	step.ID = int64(len(m.steps) + 1)
+	m.steps = append(m.steps, *step)
+	return nil
+}
+
+func (m *memStore) GetActiveExecutionsByContact(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.WorkflowExecution
+	for _, e := range m.executions {
+		if e.ContactID == contactID && (e.Status == "running" || e.Status == "waiting" || e.Status == "paused") {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) SetWorkflowExecutionStatus(ctx context.Context, executionID int64, from []string, status string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.executions[executionID]
+	if !ok {
+		return false, nil
+	}
+	for _, f := range from {
+		if e.Status == f {
+			e.Status = status
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memStore) MarkExecutionGoalReached(ctx context.Context, executionID int64, goal string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.executions[executionID]
+	if !ok || (e.Status != "running" && e.Status != "waiting" && e.Status != "paused") {
+		return false, nil
+	}
+	state := map[string]interface{}{}
+	_ = json.Unmarshal(e.StateData, &state)
+	state["goal_reached"] = goal
+	e.StateData, _ = json.Marshal(state)
+	e.Status = "goal_reached"
+	e.WaitingFor = ""
+	return true, nil
+}
+
+func (m *memStore) UpdateContactState(ctx context.Context, contactID int64, bookingState string, botPaused bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.contacts[contactID]
+	if !ok {
+		return errors.New("contact not found")
+	}
+	c.BookingState = bookingState
+	c.BotPaused = botPaused
+	return nil
+}
+
+func (m *memStore) UpdateContactLead(ctx context.Context, contactID int64, budget, location, timeline, phone string, isHot bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.contacts[contactID]
+	if !ok {
+		return errors.New("contact not found")
+	}
+	c.Budget, c.PreferredLocation, c.PurchaseTimeline, c.Phone, c.IsHotLead = budget, location, timeline, phone, isHot
+	return nil
+}
+
+func (m *memStore) GetContactFieldSources(ctx context.Context, contactID int64) ([]models.ContactFieldSource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.ContactFieldSource(nil), m.sources[contactID]...), nil
+}
+
+func (m *memStore) SaveContactFieldSources(ctx context.Context, sources []models.ContactFieldSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveSources(sources)
+	return nil
+}
+
+func (m *memStore) SaveContactLeadFields(ctx context.Context, contactID int64, values map[string]interface{}, sources []models.ContactFieldSource) ([]string, error) {
+	if m.beforeLeadSave != nil {
+		m.beforeLeadSave()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.contacts[contactID]
+	if !ok {
+		return nil, errors.New("contact not found")
+	}
+	agent := map[string]bool{}
+	for _, s := range m.sources[contactID] {
+		agent[s.Field] = s.Source == models.FieldSourceAgent
+	}
+	var written []string
+	var saved []models.ContactFieldSource
+	for _, s := range sources {
+		if agent[s.Field] && s.Source != models.FieldSourceAgent {
+			continue
+		}
+		switch v := values[s.Field]; s.Field {
+		case "budget":
+			c.Budget = v.(string)
+		case "preferred_location":
+			c.PreferredLocation = v.(string)
+		case "purchase_timeline":
+			c.PurchaseTimeline = v.(string)
+		case "phone":
+			c.Phone = v.(string)
+		case "is_hot_lead":
+			c.IsHotLead = v.(bool)
+		}
+		written = append(written, s.Field)
+		saved = append(saved, s)
+	}
+	m.saveSources(saved)
+	return written, nil
+}
+
+func (m *memStore) saveSources(sources []models.ContactFieldSource) {
+	if m.sources == nil {
+		m.sources = make(map[int64][]models.ContactFieldSource)
+	}
+	for _, s := range sources {
+		list := m.sources[s.ContactID]
+		replaced := false
+		for i := range list {
+			if list[i].Field == s.Field {
+				list[i], replaced = s, true
+			}
+		}
+		if !replaced {
+			list = append(list, s)
+		}
+		m.sources[s.ContactID] = list
+	}
+}
+
+func (m *memStore) GetMessagesByContact(ctx context.Context, contactID int64, limit, offset int) ([]models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []models.Message
+	for _, msg := range m.messages {
+		if msg.ContactID == contactID {
+			msgs = append(msgs, msg)
+		}
+	}
+	if offset >= len(msgs) {
+		return nil, nil
+	}
+	msgs = msgs[offset:]
+	if len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	return msgs, nil
+}
+
+func (m *memStore) GetRecentMessagesByContact(ctx context.Context, contactID int64, limit int) ([]models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []models.Message
+	for _, msg := range m.messages {
+		if msg.ContactID == contactID {
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) > limit {
+		msgs = msgs[len(msgs)-limit:]
+	}
+	return msgs, nil
+}
+
+func (m *memStore) GetMessagesByContactAfter(ctx context.Context, contactID, afterID int64, limit int) ([]models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []models.Message
+	for _, msg := range m.messages {
+		if msg.ContactID == contactID && msg.ID > afterID && len(msgs) < limit {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (m *memStore) GetConversationSummary(ctx context.Context, contactID int64) (*models.ConversationSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cs, ok := m.summaries[contactID]; ok {
+		cp := *cs
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m *memStore) SaveConversationSummary(ctx context.Context, cs *models.ConversationSummary) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.summaries == nil {
+		m.summaries = make(map[int64]*models.ConversationSummary)
+	}
+	cp := *cs
+	m.summaries[cs.ContactID] = &cp
+	return nil
+}
+
+func (m *memStore) AddContactTag(ctx context.Context, contactID int64, tag string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.contacts[contactID]
+	if !ok {
+		return false, errors.New("contact not found")
+	}
+	for _, t := range c.Tags {
+		if t == tag {
+			return false, nil
+		}
+	}
+	c.Tags = append(c.Tags, tag)
+	return true, nil
+}
+
+func (m *memStore) GetBusinessCalendar(ctx context.Context, userID int64) (*models.BusinessCalendar, error) {
+	return m.calendar, nil
+}
+
+func (m *memStore) GetChannelByID(ctx context.Context, channelID int64) (*models.Channel, error) {
+	return &models.Channel{ID: channelID, UserID: 1, Platform: "instagram", AccountID: "acct", AccessToken: "token"}, nil
+}
+
+func (m *memStore) CreateMessage(ctx context.Context, msg *models.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// roundTripFunc lets tests answer the Meta API without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// fakeMeta returns a Meta client whose sends all get the given HTTP status
+func fakeMeta(status int) *meta.Client {
+	c := meta.NewClient()
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(`{"message_id": "mid.1"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+	return c
+}
+
+func isActive(status string) bool {
+	return status == "running" || status == "waiting" || status == "paused"
+}
+
+func (m *memStore) hasActiveExclusive(workflowID, contactID int64) bool {
+	for _, e := range m.executions {
+		if e.Exclusive && e.WorkflowID == workflowID && e.ContactID == contactID && isActive(e.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memStore) GetLastExecutionStart(ctx context.Context, workflowID, contactID int64) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var last time.Time
+	for _, e := range m.executions {
+		if e.WorkflowID == workflowID && e.ContactID == contactID && e.CreatedAt.After(last) {
+			last = e.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+func (m *memStore) ClaimQueuedExecution(ctx context.Context, workflowID, contactID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hasActiveExclusive(workflowID, contactID) {
+		return 0, nil
+	}
+	var next *models.WorkflowExecution
+	for _, e := range m.executions {
+		if e.WorkflowID == workflowID && e.ContactID == contactID && e.Status == "queued" && (next == nil || e.ID < next.ID) {
+			next = e
+		}
+	}
+	if next == nil {
+		return 0, nil
+	}
+	next.Status = "running"
+	return next.ID, nil
+}
+
+func (m *memStore) GetActiveWorkflowsByTrigger(ctx context.Context, userID int64, triggerType string) ([]models.Workflow, error) {
+	var out []models.Workflow
+	for _, w := range m.publishedByTrigger(triggerType) {
+		if w.UserID == userID {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) GetPublishedWorkflowsByTrigger(ctx context.Context, triggerType string) ([]models.Workflow, error) {
+	return m.publishedByTrigger(triggerType), nil
+}
+
+func (m *memStore) GetTriggerLastRun(ctx context.Context, workflowID int64, nodeID string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.runs[fmt.Sprintf("%d/%s", workflowID, nodeID)], nil
+}
+
+func (m *memStore) AdvanceTriggerRun(ctx context.Context, workflowID int64, nodeID string, prev, next time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := fmt.Sprintf("%d/%s", workflowID, nodeID)
+	if !m.runs[key].Equal(prev) {
+		return false, nil
+	}
+	if m.runs == nil {
+		m.runs = make(map[string]time.Time)
+	}
+	m.runs[key] = next
+	return true, nil
+}
+
+func (m *memStore) publishedByTrigger(triggerType string) []models.Workflow {
+	var out []models.Workflow
+	for _, w := range m.workflows {
+		if w.TriggerType == triggerType && w.Status == "published" {
+			out = append(out, *w)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (m *memStore) ListTriggerContacts(ctx context.Context, userID int64, f store.TriggerContactFilter) ([]models.Contact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]int64, 0, len(m.contacts))
+	for id := range m.contacts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var out []models.Contact
+	for _, id := range ids {
+		c := m.contacts[id]
+		if c.UserID != userID || c.ID <= f.AfterID || (len(f.Tags) > 0 && !hasAnyTag(c.Tags, f.Tags)) {
+			continue
+		}
+		if !f.InactiveSince.IsZero() {
+			var lastIn time.Time
+			for _, msg := range m.messages {
+				if msg.ContactID == c.ID && msg.Direction == "inbound" && msg.CreatedAt.After(lastIn) {
+					lastIn = msg.CreatedAt
+				}
+			}
+			if lastIn.IsZero() || !lastIn.Before(f.InactiveSince) || m.startedSince(f.WorkflowID, c.ID, lastIn) {
+				continue
+			}
+		}
+		out = append(out, *c)
+		if len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// SearchKnowledgeBase ranks the entries by the similarity of their ai.HashEmbedding to the query
+func (m *memStore) SearchKnowledgeBase(ctx context.Context, userID int64, queryEmbedding []float32, limit int) ([]models.KnowledgeBase, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	score := func(e models.KnowledgeBase) float32 {
+		var dot float32
+		for i, v := range ai.HashEmbedding(e.Title + " " + e.Content) {
+			dot += v * queryEmbedding[i]
+		}
+		return dot
+	}
+	var out []models.KnowledgeBase
+	for _, e := range m.knowledge {
+		if e.UserID == userID {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return score(out[i]) > score(out[j]) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *memStore) startedSince(workflowID, contactID int64, since time.Time) bool {
+	for _, e := range m.executions {
+		if e.WorkflowID == workflowID && e.ContactID == contactID && e.CreatedAt.After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTag(tags, want []string) bool {
+	for _, t := range tags {
+		for _, w := range want {
+			if t == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// Re-entry policies: what StartWorkflow does when the contact already has an active run of the
+// workflow. An empty policy means ReentrySkip.
+const (
+	ReentryParallel = "parallel" // start another run alongside the active one
+	ReentrySkip     = "skip"     // ignore the trigger
+	ReentryRestart  = "restart"  // cancel the active run and start over
+	ReentryQueue    = "queue"    // start once the active run has finished
+)
+
+// StatusQueued marks an execution waiting for the contact's active run of the same workflow
+const StatusQueued = "queued"
+
+// ErrEntrySkipped is returned by StartWorkflow when the re-entry policy or cooldown keeps the
+// contact out of the workflow. It is not a failure; callers should just log it.
+var ErrEntrySkipped = errors.New("workflow entry skipped")
+
+// IsValidReentryPolicy reports whether p is a known policy ("" selects the default)
+func IsValidReentryPolicy(p string) bool {
+	switch p {
+	case "", ReentryParallel, ReentrySkip, ReentryRestart, ReentryQueue:
+		return true
+	}
+	return false
+}
+
+// checkCooldown refuses a run that starts within the workflow's cooldown of the contact's last one
+func (gw *GraphWalker) checkCooldown(ctx context.Context, w *models.Workflow, contactID int64) error {
+	if w.CooldownMinutes <= 0 {
+		return nil
+	}
+	last, err := gw.Store.GetLastExecutionStart(ctx, w.ID, contactID)
+	if err != nil {
+		return fmt.Errorf("failed to check cooldown: %w", err)
+	}
+	cooldown := time.Duration(w.CooldownMinutes) * time.Minute
+	if since := time.Since(last); !last.IsZero() && since < cooldown {
+		return fmt.Errorf("%w: contact %d entered workflow %d %s ago (cooldown %s)", ErrEntrySkipped, contactID, w.ID, since.Round(time.Second), cooldown)
+	}
+	return nil
+}
+
+// createExecution inserts exec according to the workflow's re-entry policy. The one-active-run
+// rule itself is enforced by the store (a unique index over exclusive executions), so two
+// triggers racing for the same contact cannot both win. Returns true when exec was queued
+// rather than started.
+func (gw *GraphWalker) createExecution(ctx context.Context, w *models.Workflow, exec *models.WorkflowExecution) (bool, error) {
+	policy := w.ReentryPolicy
+	if policy == "" {
+		policy = ReentrySkip
+	}
+	exec.Exclusive = policy != ReentryParallel
+
+	if policy == ReentryRestart {
+		gw.cancelActiveRuns(ctx, w.ID, exec.ContactID)
+	}
+
+	err := gw.Store.CreateWorkflowExecution(ctx, exec)
+	if !errors.Is(err, store.ErrActiveExecution) {
+		return false, err
+	}
+
+	if policy == ReentryQueue {
+		exec.Status = StatusQueued
+		if err := gw.Store.CreateWorkflowExecution(ctx, exec); err != nil {
+			return false, err
+		}
+		log.Printf("Execution %d queued behind the active run of workflow %d for contact %d", exec.ID, w.ID, exec.ContactID)
+		return true, nil
+	}
+	return false, fmt.Errorf("%w: contact %d already has an active run of workflow %d", ErrEntrySkipped, exec.ContactID, w.ID)
+}
+
+// cancelActiveRuns cancels the contact's active runs of a workflow (the "restart" policy). It goes
+// through ControlExecution so a cancelled module still returns to its caller.
+func (gw *GraphWalker) cancelActiveRuns(ctx context.Context, workflowID, contactID int64) {
+	execs, err := gw.Store.GetActiveExecutionsByContact(ctx, contactID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to load active runs of contact %d: %v", contactID, err)
+		return
+	}
+	for i := range execs {
+		if execs[i].WorkflowID != workflowID {
+			continue
+		}
+		if err := gw.ControlExecution(ctx, &execs[i], ActionCancel); err == nil {
+			log.Printf("Execution %d cancelled, workflow %d restarted for contact %d", execs[i].ID, workflowID, contactID)
+		}
+	}
+}
+
+// finish ends an execution with a final status and starts the next queued run, if any
+func (gw *GraphWalker) finish(ctx context.Context, exec *models.WorkflowExecution, status string) error {
+	exec.Status = status
+	exec.WaitingFor = ""
+	err := gw.Store.UpdateWorkflowExecution(ctx, exec)
+	gw.startQueued(ctx, exec.WorkflowID, exec.ContactID)
+	gw.returnToCaller(ctx, exec)
+	return err
+}
+
+// startQueued promotes the contact's next queued run of a workflow once the active one is done
+func (gw *GraphWalker) startQueued(ctx context.Context, workflowID, contactID int64) {
+	if gw.sim != nil {
+		return
+	}
+	next, err := gw.Store.ClaimQueuedExecution(ctx, workflowID, contactID)
+	if err != nil {
+		log.Printf("[GraphWalker] Failed to start queued run of workflow %d for contact %d: %v", workflowID, contactID, err)
+		return
+	}
+	if next == 0 {
+		return
+	}
+	log.Printf("Execution %d dequeued for contact %d", next, contactID)
+	if err := gw.scheduleResume(ctx, next, time.Now()); err != nil {
+		log.Printf("[GraphWalker] Failed to resume execution %d: %v", next, err)
+	}
+}
@@ -0,0 +1,179 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// statuses returns the status of every execution in creation order
+func (m *memStore) statuses() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]int64, 0, len(m.executions))
+	for id := range m.executions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = m.executions[id].Status
+	}
+	return out
+}
+
+func equalStatuses(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReentryPolicies(t *testing.T) {
+	cases := []struct {
+		policy  string
+		wantErr error
+		want    []string
+	}{
+		{policy: "", wantErr: engine.ErrEntrySkipped, want: []string{"waiting"}},
+		{policy: engine.ReentrySkip, wantErr: engine.ErrEntrySkipped, want: []string{"waiting"}},
+		{policy: engine.ReentryParallel, want: []string{"waiting", "waiting"}},
+		{policy: engine.ReentryRestart, want: []string{engine.StatusCancelled, "waiting"}},
+		{policy: engine.ReentryQueue, want: []string{"waiting", engine.StatusQueued}},
+	}
+
+	for _, tc := range cases {
+		t.Run("policy "+tc.policy, func(t *testing.T) {
+			ctx := context.Background()
+			ms := newMemStore()
+			delayWorkflow(t, ms)
+			ms.workflows[1].ReentryPolicy = tc.policy
+			gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+			if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+				t.Fatal(err)
+			}
+			err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if got := ms.statuses(); !equalStatuses(got, tc.want...) {
+				t.Errorf("expected executions %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestQueuedRunStartsWhenActiveRunEnds(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionWaitForReply},
+			{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "replied"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", Target: "3", SourceHandle: engine.HandleReply},
+		},
+	)
+	ms.workflows[1].ReentryPolicy = engine.ReentryQueue
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	for i := 0; i < 3; i++ {
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := ms.statuses(); !equalStatuses(got, "waiting", "queued", "queued") {
+		t.Fatalf("expected one waiting and two queued runs, got %v", got)
+	}
+
+	// The reply completes the first run; the oldest queued run takes its place
+	if err := gw.ResumeWithReply(ctx, 1, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ms.statuses(); !equalStatuses(got, "completed", "waiting", "queued") {
+		t.Fatalf("expected the second run to start, got %v", got)
+	}
+
+	// Cancelling the active run releases the last one
+	exec, _ := ms.GetWorkflowExecutionByID(ctx, 2)
+	if err := gw.ControlExecution(ctx, exec, engine.ActionCancel); err != nil {
+		t.Fatal(err)
+	}
+	if got := ms.statuses(); !equalStatuses(got, "completed", "cancelled", "waiting") {
+		t.Fatalf("expected the third run to start, got %v", got)
+	}
+}
+
+func TestRestartReturnsCancelledModuleToCaller(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	callerWorkflow(t, ms, 1, 2, nil)
+	ms.addWorkflow(t, 2,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionWaitForReply},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}},
+	)
+	ms.workflows[2].ReentryPolicy = engine.ReentryRestart
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	// The contact triggers the called workflow directly, restarting the run the caller waits on
+	if err := gw.StartWorkflow(ctx, 2, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ms.statuses(); !equalStatuses(got, "completed", engine.StatusCancelled, "waiting") {
+		t.Fatalf("expected the caller to finish after its module was cancelled, got %v", got)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "call_failed" {
+		t.Errorf("expected the caller to take its error edge, got tags %v", tags)
+	}
+}
+
+func TestReentryCooldown(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	delayWorkflow(t, ms)
+	ms.workflows[1].ReentryPolicy = engine.ReentryParallel
+	ms.workflows[1].CooldownMinutes = 60
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); !errors.Is(err, engine.ErrEntrySkipped) {
+		t.Fatalf("expected the cooldown to skip the second run, got %v", err)
+	}
+
+	// Another contact is not affected
+	ms.contacts[2] = &models.Contact{ID: 2, UserID: 1, Name: "Other"}
+	if err := gw.StartWorkflow(ctx, 1, 2, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the cooldown has passed the contact may enter again
+	ms.executions[1].CreatedAt = ms.executions[1].CreatedAt.Add(-61 * time.Minute)
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ms.statuses(); len(got) != 3 {
+		t.Errorf("expected three executions, got %v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Every non-trigger node may carry an error policy in its data:
+//
+//	{"retryCount": 3, "retryBackoffMs": 2000, "nodeTimeoutMs": 15000}
+//
+// A failing node is retried retryCount times. The first retry waits retryBackoffMs and each
+// further one doubles the wait, up to maxRetryBackoff; the waits are scheduled through Asynq like
+// a delay node, so no worker is held. nodeTimeoutMs bounds a single attempt. Once the retries are
+// used up the execution follows the node's "error" edge, or fails if it has none.
+const (
+	maxNodeRetries      = 10
+	defaultRetryBackoff = 5 * time.Second
+	maxRetryBackoff     = time.Hour
+	maxNodeTimeout      = 10 * time.Minute
+)
+
+// nodePolicy is the parsed error policy of a node
+type nodePolicy struct {
+	retries int
+	backoff time.Duration
+	timeout time.Duration
+}
+
+// parseNodePolicy reads a node's retry and timeout settings
+func parseNodePolicy(node *models.ReactFlowNode) (nodePolicy, error) {
+	p := nodePolicy{
+		retries: int(node.DataFloat("retryCount", 0)),
+		backoff: time.Duration(node.DataFloat("retryBackoffMs", float64(defaultRetryBackoff/time.Millisecond))) * time.Millisecond,
+		timeout: time.Duration(node.DataFloat("nodeTimeoutMs", 0)) * time.Millisecond,
+	}
+	switch {
+	case p.retries < 0 || p.retries > maxNodeRetries:
+		return nodePolicy{}, fmt.Errorf("retryCount must be between 0 and %d", maxNodeRetries)
+	case p.backoff < 0:
+		return nodePolicy{}, fmt.Errorf("retryBackoffMs must not be negative")
+	case p.timeout < 0 || p.timeout > maxNodeTimeout:
+		return nodePolicy{}, fmt.Errorf("nodeTimeoutMs must be between 0 and %d", maxNodeTimeout/time.Millisecond)
+	}
+	return p, nil
+}
+
+// backoffFor returns the wait before the given retry (1 is the first)
+func (p nodePolicy) backoffFor(retry int) time.Duration {
+	d := p.backoff
+	for i := 1; i < retry && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// context bounds one attempt of the node by its timeout, if it has one
+func (p nodePolicy) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.timeout)
+}
+
+// retriesDone returns how many retries of the node the execution has already made
+func retriesDone(stateData map[string]interface{}, nodeID string) int {
+	if stateData["retry_node"] != nodeID {
+		return 0
+	}
+	n, _ := stateData["retry_count"].(float64)
+	return int(n)
+}
+
+// clearRetry forgets the retry counter once a node has been left behind
+func clearRetry(stateData map[string]interface{}) {
+	delete(stateData, "retry_node")
+	delete(stateData, "retry_count")
+}
+
+// scheduleRetry parks the execution on the failed node and schedules the next attempt
+func (gw *GraphWalker) scheduleRetry(ctx context.Context, exec *models.WorkflowExecution, node *models.ReactFlowNode, stateData map[string]interface{}, retry int, wait time.Duration) error {
+	stateData["retry_node"] = node.ID
+	stateData["retry_count"] = float64(retry)
+	wakeAt := time.Now().Add(wait)
+	stateData["delay_until"] = wakeAt.Format(time.RFC3339)
+
+	exec.StateData, _ = json.Marshal(stateData)
+	exec.Status = "waiting"
+	exec.WaitingFor = WaitingForRetry
+	exec.CurrentNodeID = node.ID
+	if err := gw.Store.UpdateWorkflowExecution(ctx, exec); err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+
+	log.Printf("Execution %d retrying node %s (attempt %d) in %v", exec.ID, node.ID, retry+1, wait)
+	return gw.scheduleResume(ctx, exec.ID, wakeAt)
+}
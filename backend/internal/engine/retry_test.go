@@ -0,0 +1,192 @@
+package engine_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/meta"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// flakyMeta fails the first `failures` sends with a 500 and accepts the rest
+func flakyMeta(failures int) (*meta.Client, *int) {
+	calls := 0
+	c := meta.NewClient()
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		status := http.StatusOK
+		if calls <= failures {
+			status = http.StatusInternalServerError
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+	})}
+	return c, &calls
+}
+
+// sendWorkflow: trigger -> send_message (with the given policy) -> add_tag "sent",
+// plus an optional error edge to add_tag "fallback"
+func sendWorkflow(t *testing.T, ms *memStore, policy map[string]interface{}, errorEdge bool) {
+	data := map[string]interface{}{"message": "Hello"}
+	for k, v := range policy {
+		data[k] = v
+	}
+	edges := []models.ReactFlowEdge{
+		{ID: "e1", Source: "1", Target: "2"},
+		{ID: "e2", Source: "2", Target: "3"},
+	}
+	if errorEdge {
+		edges = append(edges, models.ReactFlowEdge{ID: "e3", Source: "2", SourceHandle: engine.HandleError, Target: "4"})
+	}
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionSendMessage, Data: data},
+			{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "sent"}},
+			{ID: "4", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "fallback"}},
+		},
+		edges,
+	)
+}
+
+func TestSendFailureFailsExecution(t *testing.T) {
+	ms := newMemStore()
+	sendWorkflow(t, ms, nil, false)
+	client, _ := flakyMeta(1)
+	gw := engine.NewGraphWalker(ms, nil, nil, client)
+
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{}); err == nil {
+		t.Fatal("expected the failed send to fail the execution")
+	}
+	if exec := ms.onlyExecution(t); exec.Status != "failed" {
+		t.Errorf("expected failed, got %s", exec.Status)
+	}
+	if len(ms.contacts[1].Tags) != 0 {
+		t.Errorf("the flow must not continue as if the message went out, got tags %v", ms.contacts[1].Tags)
+	}
+}
+
+func TestNodeRetries(t *testing.T) {
+	ms := newMemStore()
+	sendWorkflow(t, ms, map[string]interface{}{"retryCount": float64(2), "retryBackoffMs": float64(0)}, false)
+	client, calls := flakyMeta(2)
+	gw := engine.NewGraphWalker(ms, nil, nil, client)
+
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.Status != "completed" || *calls != 3 || len(ms.messages) != 1 {
+		t.Fatalf("expected completion on the third attempt, got %s after %d sends (%d stored)", exec.Status, *calls, len(ms.messages))
+	}
+	if strings.Contains(string(exec.StateData), "retry_count") {
+		t.Errorf("retry counter should be cleared once the node succeeds: %s", exec.StateData)
+	}
+
+	attempts := 0
+	for _, s := range ms.steps {
+		if s.NodeID == "2" {
+			attempts++
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("expected a step per attempt, got %d", attempts)
+	}
+}
+
+func TestRetryBackoffWaits(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	sendWorkflow(t, ms, map[string]interface{}{"retryCount": float64(1), "retryBackoffMs": float64(60000)}, false)
+	client, calls := flakyMeta(1)
+	gw := engine.NewGraphWalker(ms, nil, nil, client)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForRetry || exec.CurrentNodeID != "2" {
+		t.Fatalf("expected the execution to back off on node 2, got %s/%s at %s", exec.Status, exec.WaitingFor, exec.CurrentNodeID)
+	}
+
+	// The scheduled resume retries the node
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if exec = ms.onlyExecution(t); exec.Status != "completed" || *calls != 2 {
+		t.Errorf("expected completion after the retry, got %s after %d sends", exec.Status, *calls)
+	}
+}
+
+func TestErrorHandle(t *testing.T) {
+	ms := newMemStore()
+	sendWorkflow(t, ms, map[string]interface{}{"retryCount": float64(1), "retryBackoffMs": float64(0)}, true)
+	client, calls := flakyMeta(5)
+	gw := engine.NewGraphWalker(ms, nil, nil, client)
+
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.Status != "completed" || *calls != 2 {
+		t.Fatalf("expected completion through the error edge after 2 attempts, got %s after %d", exec.Status, *calls)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "fallback" {
+		t.Errorf("expected only the fallback path to run, got %v", tags)
+	}
+	if !strings.Contains(string(exec.StateData), "last_error") {
+		t.Errorf("expected the error in state, got %s", exec.StateData)
+	}
+
+	var branch string
+	for _, s := range ms.steps {
+		if s.NodeID == "2" {
+			branch = s.Branch
+		}
+	}
+	if branch != engine.HandleError {
+		t.Errorf("expected the last attempt to record the error branch, got %q", branch)
+	}
+}
+
+// slowLLM never answers before the context ends
+type slowLLM struct{ ai.LLMClient }
+
+func (slowLLM) GenerateText(ctx context.Context, prompt string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (l slowLLM) Chat(ctx context.Context, req ai.ChatRequest) (string, error) {
+	return l.GenerateText(ctx, req.System)
+}
+
+func TestNodeTimeout(t *testing.T) {
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionAIReply, Data: map[string]interface{}{"prompt": "Hi", "nodeTimeoutMs": float64(20)}},
+			{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "ai_down"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", SourceHandle: engine.HandleError, Target: "3"},
+		},
+	)
+	gw := engine.NewGraphWalker(ms, slowLLM{}, nil, fakeMeta(http.StatusOK))
+
+	if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{"received_message": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "ai_down" {
+		t.Fatalf("expected the timeout to take the error edge, got %v", tags)
+	}
+	if err := ms.steps[1].Error; !strings.Contains(err, "timed out") {
+		t.Errorf("expected a timeout error on the step, got %q", err)
+	}
+}
@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// Scheduled and inactivity triggers are driven by a "workflow:schedule_tick" task that the Asynq
+// scheduler enqueues every minute (see workers.StartScheduler). A tick finds the trigger nodes
+// that are due and fans each one out to the tenant's matching contacts, one batch per
+// "workflow:trigger_fanout" task, so large contact lists never tie up a single worker.
+//
+//	trigger_schedule:   {"cron": "0 9 * * MON", "timezone": "Asia/Kolkata", "tags": ["warm"]}
+//	trigger_inactivity: {"inactiveDays": 3, "tags": ["warm"]}
+//
+// The schedule timezone defaults to the tenant's business hours timezone. Inactivity counts from
+// the contact's last inbound message and fires once per silence.
+//
+// Each trigger remembers when it last fired, and a tick fires every occurrence since then, so a
+// tick that runs late, or not at all while the workers are down, does not lose one. Occurrences
+// older than scheduleCatchUp are dropped.
+const (
+	FanoutBatchSize = 200
+
+	// inactivitySweepInterval is how often inactivity triggers look for newly silent contacts
+	inactivitySweepInterval = 15 * time.Minute
+
+	// scheduleCatchUp is how far back a tick fires occurrences it missed
+	scheduleCatchUp = 24 * time.Hour
+)
+
+// Events a fan-out puts in StateData["event"]["type"]
+const (
+	EventSchedule   = "schedule"
+	EventInactivity = "inactivity"
+)
+
+// FanoutPayload is the body of the "workflow:trigger_fanout" task: start a workflow for the next
+// batch of contacts after AfterContactID
+type FanoutPayload struct {
+	WorkflowID     int64                  `json:"workflow_id"`
+	TriggerNodeID  string                 `json:"trigger_node_id"`
+	Event          map[string]interface{} `json:"event"`
+	AfterContactID int64                  `json:"after_contact_id"`
+}
+
+// ParseCron parses a five-field cron expression ("0 9 * * MON") or a descriptor such as "@daily"
+func ParseCron(spec string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(strings.TrimSpace(spec))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	return sched, nil
+}
+
+// RunScheduledTriggers fans out every schedule occurrence between a trigger's last run and the
+// minute of now, and every inactivity trigger whose last sweep is inactivitySweepInterval old
+func (gw *GraphWalker) RunScheduledTriggers(ctx context.Context, now time.Time) error {
+	minute := now.Truncate(time.Minute)
+
+	flows, err := gw.Store.GetPublishedWorkflowsByTrigger(ctx, string(models.NodeTypeTriggerSchedule))
+	if err != nil {
+		return fmt.Errorf("failed to fetch scheduled workflows: %w", err)
+	}
+	for _, w := range flows {
+		for _, node := range triggerNodes(&w, models.NodeTypeTriggerSchedule) {
+			due, err := gw.scheduleDue(ctx, &w, node, minute)
+			if err != nil {
+				log.Printf("[Scheduler] Skipping workflow %d trigger %s: %v", w.ID, node.ID, err)
+				continue
+			}
+			for _, at := range due {
+				log.Printf("[Scheduler] Workflow %d trigger %s is due at %s", w.ID, node.ID, at.Format(time.RFC3339))
+				gw.enqueueFanout(ctx, FanoutPayload{
+					WorkflowID:    w.ID,
+					TriggerNodeID: node.ID,
+					Event:         map[string]interface{}{"type": EventSchedule, "scheduled_at": at.Format(time.RFC3339)},
+				})
+			}
+		}
+	}
+
+	flows, err = gw.Store.GetPublishedWorkflowsByTrigger(ctx, string(models.NodeTypeTriggerInactivity))
+	if err != nil {
+		return fmt.Errorf("failed to fetch inactivity workflows: %w", err)
+	}
+	for _, w := range flows {
+		for _, node := range triggerNodes(&w, models.NodeTypeTriggerInactivity) {
+			last, err := gw.Store.GetTriggerLastRun(ctx, w.ID, node.ID)
+			if err != nil {
+				log.Printf("[Scheduler] Skipping workflow %d trigger %s: %v", w.ID, node.ID, err)
+				continue
+			}
+			if !last.IsZero() && minute.Before(last.Add(inactivitySweepInterval)) {
+				continue
+			}
+			if ok, err := gw.Store.AdvanceTriggerRun(ctx, w.ID, node.ID, last, minute); err != nil || !ok {
+				continue
+			}
+			gw.enqueueFanout(ctx, FanoutPayload{
+				WorkflowID:    w.ID,
+				TriggerNodeID: node.ID,
+				Event: map[string]interface{}{
+					"type":          EventInactivity,
+					"inactive_days": node.DataFloat("inactiveDays", 0),
+					"checked_at":    minute.Format(time.RFC3339),
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// scheduleDue claims and returns the occurrences of a trigger_schedule node after its last run,
+// up to and including the given minute. A trigger that never ran starts from the previous minute.
+func (gw *GraphWalker) scheduleDue(ctx context.Context, w *models.Workflow, node *models.ReactFlowNode, minute time.Time) ([]time.Time, error) {
+	sched, err := ParseCron(node.DataString("cron", ""))
+	if err != nil {
+		return nil, err
+	}
+	loc := TenantCalendar(ctx, gw.Store, w.UserID).Location()
+	if tz := node.DataString("timezone", ""); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", tz)
+		}
+	}
+
+	last, err := gw.Store.GetTriggerLastRun(ctx, w.ID, node.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !minute.After(last) {
+		return nil, nil
+	}
+	from := last
+	if from.IsZero() {
+		from = minute.Add(-time.Minute)
+	}
+	if oldest := minute.Add(-scheduleCatchUp); from.Before(oldest) {
+		log.Printf("[Scheduler] Workflow %d trigger %s missed its runs before %s", w.ID, node.ID, oldest.Format(time.RFC3339))
+		from = oldest
+	}
+
+	var due []time.Time
+	for at := sched.Next(from.In(loc)); !at.After(minute); at = sched.Next(at) {
+		due = append(due, at)
+	}
+	// Advance even when nothing is due, so the next tick only looks at what is new
+	if ok, err := gw.Store.AdvanceTriggerRun(ctx, w.ID, node.ID, last, minute); err != nil || !ok {
+		return nil, err
+	}
+	return due, nil
+}
+
+// enqueueFanout schedules one fan-out batch. Without an Asynq client the whole fan-out runs
+// inline.
+func (gw *GraphWalker) enqueueFanout(ctx context.Context, p FanoutPayload) {
+	if gw.AsynqClient == nil {
+		for {
+			next, more, err := gw.FanOut(ctx, p)
+			if err != nil {
+				log.Printf("[Scheduler] Fan-out of workflow %d failed: %v", p.WorkflowID, err)
+				return
+			}
+			if !more {
+				return
+			}
+			p = next
+		}
+	}
+
+	payload, _ := json.Marshal(p)
+	// The task ID makes a batch run once even if two ticks race for the same occurrence
+	taskID := fmt.Sprintf("fanout:%d:%s:%v:%v:%d", p.WorkflowID, p.TriggerNodeID, p.Event["scheduled_at"], p.Event["checked_at"], p.AfterContactID)
+	_, err := gw.AsynqClient.Enqueue(asynq.NewTask("workflow:trigger_fanout", payload), asynq.TaskID(taskID))
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		log.Printf("ERROR: Failed to enqueue fan-out of workflow %d: %v", p.WorkflowID, err)
+	}
+}
+
+// RunFanout processes one fan-out batch and enqueues the next one, if any
+func (gw *GraphWalker) RunFanout(ctx context.Context, p FanoutPayload) error {
+	next, more, err := gw.FanOut(ctx, p)
+	if err != nil {
+		return err
+	}
+	if more {
+		gw.enqueueFanout(ctx, next)
+	}
+	return nil
+}
+
+// FanOut starts the workflow for one batch of matching contacts. It returns the payload of the
+// following batch and whether there may be one.
+func (gw *GraphWalker) FanOut(ctx context.Context, p FanoutPayload) (FanoutPayload, bool, error) {
+	w, err := gw.Store.GetWorkflowByID(ctx, p.WorkflowID)
+	if err != nil {
+		return p, false, fmt.Errorf("failed to get workflow: %w", err)
+	}
+	if w.Status != "published" {
+		log.Printf("[Scheduler] Workflow %d is no longer published, stopping fan-out", w.ID)
+		return p, false, nil
+	}
+	version, err := gw.publishedVersion(ctx, w)
+	if err != nil {
+		return p, false, fmt.Errorf("failed to get workflow version: %w", err)
+	}
+	graph, err := models.ParseWorkflowGraph(version.Nodes, version.Edges)
+	if err != nil {
+		return p, false, fmt.Errorf("failed to parse workflow graph: %w", err)
+	}
+	node := findNode(graph.Nodes, p.TriggerNodeID)
+	if node == nil || (node.Type != models.NodeTypeTriggerSchedule && node.Type != models.NodeTypeTriggerInactivity) {
+		log.Printf("[Scheduler] Workflow %d no longer has trigger %s, stopping fan-out", w.ID, p.TriggerNodeID)
+		return p, false, nil
+	}
+
+	filter := store.TriggerContactFilter{
+		Tags:    node.DataStrings("tags"),
+		AfterID: p.AfterContactID,
+		Limit:   FanoutBatchSize,
+	}
+	if node.Type == models.NodeTypeTriggerInactivity {
+		days := node.DataFloat("inactiveDays", 0)
+		if days <= 0 {
+			return p, false, fmt.Errorf("trigger %s has no inactiveDays", node.ID)
+		}
+		filter.InactiveSince = time.Now().Add(-time.Duration(days * float64(24*time.Hour)))
+		filter.WorkflowID = w.ID
+	}
+
+	contacts, err := gw.Store.ListTriggerContacts(ctx, w.UserID, filter)
+	if err != nil {
+		return p, false, fmt.Errorf("failed to list contacts: %w", err)
+	}
+	for i := range contacts {
+		gw.startTriggered(ctx, w.ID, node.ID, &contacts[i], p.Event)
+	}
+	log.Printf("[Scheduler] Workflow %d trigger %s fanned out to %d contacts", w.ID, node.ID, len(contacts))
+
+	if len(contacts) < FanoutBatchSize {
+		return p, false, nil
+	}
+	p.AfterContactID = contacts[len(contacts)-1].ID
+	return p, true, nil
+}
+
+// triggerNodes returns a workflow's trigger nodes of one type, read from its live graph
+func triggerNodes(w *models.Workflow, t models.NodeType) []*models.ReactFlowNode {
+	graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+	if err != nil {
+		log.Printf("[Scheduler] Skipping workflow %d: invalid graph: %v", w.ID, err)
+		return nil
+	}
+	var out []*models.ReactFlowNode
+	for i := range graph.Nodes {
+		if graph.Nodes[i].Type == t {
+			out = append(out, &graph.Nodes[i])
+		}
+	}
+	return out
+}
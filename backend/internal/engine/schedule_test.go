@@ -0,0 +1,202 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// triggeredWorkflow: the given trigger -> add_tag "followed_up"
+func triggeredWorkflow(t *testing.T, ms *memStore, id int64, trigger models.NodeType, data map[string]interface{}) {
+	ms.addWorkflow(t, id,
+		[]models.ReactFlowNode{
+			{ID: "t", Type: trigger, Data: data},
+			{ID: "tag", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "followed_up"}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "t", Target: "tag"}},
+	)
+}
+
+// startedContacts returns the contacts a workflow has executions for
+func (m *memStore) startedContacts(workflowID int64) map[int64]bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[int64]bool)
+	for _, e := range m.executions {
+		if e.WorkflowID == workflowID {
+			out[e.ContactID] = true
+		}
+	}
+	return out
+}
+
+func eventOf(t *testing.T, exec *models.WorkflowExecution) map[string]interface{} {
+	t.Helper()
+	var state struct {
+		Event map[string]interface{} `json:"event"`
+	}
+	if err := json.Unmarshal(exec.StateData, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state.Event
+}
+
+func TestContactEventTrigger(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "warm"}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}},
+	)
+	triggeredWorkflow(t, ms, 2, models.NodeTypeTriggerContactEvent, map[string]interface{}{"event": "tag_added", "tag": "Warm"})
+	triggeredWorkflow(t, ms, 3, models.NodeTypeTriggerContactEvent, map[string]interface{}{"event": "tag_added", "tag": "cold"})
+	triggeredWorkflow(t, ms, 4, models.NodeTypeTriggerContactEvent, map[string]interface{}{"event": "bot_resumed"})
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ms.startedContacts(2)[1] {
+		t.Fatal("expected the tag_added workflow to start")
+	}
+	if len(ms.startedContacts(3)) != 0 || len(ms.startedContacts(4)) != 0 {
+		t.Error("expected workflows listening for other events to stay idle")
+	}
+	for _, e := range ms.executions {
+		if e.WorkflowID != 2 {
+			continue
+		}
+		if evt := eventOf(t, e); evt["type"] != engine.ContactEventTagAdded || evt["tag"] != "warm" {
+			t.Errorf("unexpected event in state: %v", evt)
+		}
+	}
+}
+
+func TestContactFieldChanges(t *testing.T) {
+	before := &models.Contact{Budget: "1 Cr", Phone: "98"}
+	after := &models.Contact{Budget: "2 Cr", Phone: "98", IsHotLead: true}
+
+	changes := engine.ContactFieldChanges(before, after)
+	if len(changes) != 2 || changes[0].Field != "budget" || changes[0].OldValue != "1 Cr" || changes[0].NewValue != "2 Cr" || changes[1].Field != "is_hot_lead" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
+
+func TestScheduleTriggerFansOutInBatches(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	// More warm contacts than fit in one batch, plus one that is not tagged
+	total := engine.FanoutBatchSize + 50
+	for i := int64(2); i <= int64(total)+1; i++ {
+		ms.contacts[i] = &models.Contact{ID: i, UserID: 1, Tags: []string{"warm"}}
+	}
+	ms.contacts[1].Tags = []string{"cold"}
+	triggeredWorkflow(t, ms, 1, models.NodeTypeTriggerSchedule, map[string]interface{}{
+		"cron": "0 9 * * MON", "timezone": "Asia/Kolkata", "tags": []interface{}{"warm"},
+	})
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	monday := time.Date(2026, 10, 19, 9, 0, 0, 0, ist)
+
+	// Not due a minute before
+	if err := gw.RunScheduledTriggers(ctx, monday.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ms.startedContacts(1)); n != 0 {
+		t.Fatalf("expected no runs outside the schedule, got %d", n)
+	}
+
+	if err := gw.RunScheduledTriggers(ctx, monday.Add(20*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	started := ms.startedContacts(1)
+	if len(started) != total || started[1] {
+		t.Fatalf("expected %d warm contacts to start, got %d (cold started: %v)", total, len(started), started[1])
+	}
+	exec, _ := ms.GetWorkflowExecutionByID(ctx, 1)
+	if evt := eventOf(t, exec); evt["type"] != engine.EventSchedule || evt["scheduled_at"] != monday.Format(time.RFC3339) {
+		t.Errorf("unexpected event in state: %v", evt)
+	}
+
+	// Not again in the same minute, a minute later, nor at 09:00 UTC
+	for _, at := range []time.Time{monday.Add(40 * time.Second), monday.Add(time.Minute), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)} {
+		if err := gw.RunScheduledTriggers(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(ms.statuses()); n != total {
+		t.Errorf("expected the occurrence to fire once, got %d executions", n)
+	}
+}
+
+func TestScheduleTriggerCatchesUpLateTicks(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	triggeredWorkflow(t, ms, 1, models.NodeTypeTriggerSchedule, map[string]interface{}{"cron": "0 9 * * *", "timezone": "UTC"})
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+	nine := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	// The tick before the occurrence runs; the next one only comes three minutes late
+	for _, at := range []time.Time{nine.Add(-time.Minute), nine.Add(3 * time.Minute)} {
+		if err := gw.RunScheduledTriggers(ctx, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(ms.statuses()); n != 1 {
+		t.Fatalf("expected the missed occurrence to fire once, got %d executions", n)
+	}
+	exec, _ := ms.GetWorkflowExecutionByID(ctx, 1)
+	if evt := eventOf(t, exec); evt["scheduled_at"] != nine.Format(time.RFC3339) {
+		t.Errorf("expected the scheduled minute in the event, got %v", evt)
+	}
+
+	// Workers down for two days: the occurrence within the catch-up window still fires
+	if err := gw.RunScheduledTriggers(ctx, nine.Add(48*time.Hour+time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ms.statuses()); n != 2 {
+		t.Errorf("expected only the last day's occurrence to catch up, got %d executions", n)
+	}
+}
+
+func TestInactivityTrigger(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.contacts[2] = &models.Contact{ID: 2, UserID: 1, Name: "Ravi"}
+	ms.contacts[3] = &models.Contact{ID: 3, UserID: 1, Name: "Meera"}
+	now := time.Now()
+	ms.messages = []models.Message{
+		{ContactID: 1, Direction: "inbound", CreatedAt: now.Add(-100 * time.Hour)},
+		{ContactID: 1, Direction: "outbound", CreatedAt: now.Add(-90 * time.Hour)},
+		{ContactID: 2, Direction: "inbound", CreatedAt: now.Add(-100 * time.Hour)},
+		{ContactID: 2, Direction: "inbound", CreatedAt: now.Add(-time.Hour)},
+	}
+	triggeredWorkflow(t, ms, 1, models.NodeTypeTriggerInactivity, map[string]interface{}{"inactiveDays": 3})
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	// A tick off the 15-minute grid still sweeps a trigger that has not swept yet
+	sweep := now.Truncate(15 * time.Minute).Add(7 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := gw.RunScheduledTriggers(ctx, sweep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the contact silent for over 3 days, and only once per silence; contact 3 never wrote
+	started := ms.startedContacts(1)
+	if len(started) != 1 || !started[1] {
+		t.Fatalf("expected only contact 1 to start, got %v", started)
+	}
+	if n := len(ms.statuses()); n != 1 {
+		t.Errorf("expected one execution, got %d", n)
+	}
+}
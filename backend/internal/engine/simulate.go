@@ -0,0 +1,338 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// Kinds of side effect a simulation records instead of performing
+const (
+	EffectMessage      = "message"
+	EffectTag          = "tag"
+	EffectHTTPRequest  = "http_request"
+	EffectDelay        = "delay"
+	EffectCallWorkflow = "call_workflow"
+	EffectHandoff      = "handoff"
+)
+
+// SimulationOptions describe the synthetic lead a workflow is dry-run against
+type SimulationOptions struct {
+	Contact models.Contact         // Synthetic contact; ID and UserID are overwritten
+	Message string                 // Inbound message that fires the trigger
+	Replies []string               // Answers fed to action_wait_for_reply nodes, in order
+	State   map[string]interface{} // Extra initial StateData
+
+	// CannedLLM replaces the LLM with LLMResponses (in order, then a placeholder).
+	// When false the walker's real LLM client is used.
+	CannedLLM    bool
+	LLMResponses []string
+}
+
+// SideEffect is something the workflow would have done to the outside world
+type SideEffect struct {
+	NodeID string                 `json:"node_id"`
+	Kind   string                 `json:"kind"`
+	Detail map[string]interface{} `json:"detail"`
+}
+
+// SimulationResult is what a dry run did: the nodes it walked, what it would have sent and where it stopped
+type SimulationResult struct {
+	Status   string                         `json:"status"` // completed, goal_reached, waiting (ran out of replies) or failed
+	Error    string                         `json:"error,omitempty"`
+	Path     []models.WorkflowExecutionStep `json:"path"`
+	Messages []string                       `json:"messages"`
+	Effects  []SideEffect                   `json:"effects"`
+	State    map[string]interface{}         `json:"state"`
+}
+
+// simulation is attached to a GraphWalker while it dry-runs; every side-effecting code path
+// checks gw.sim and records into it instead of acting.
+type simulation struct {
+	mu      sync.Mutex
+	node    string // node currently executing, for attributing effects
+	replies []string
+	effects []SideEffect
+}
+
+func (s *simulation) record(kind string, detail map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.effects = append(s.effects, SideEffect{NodeID: s.node, Kind: kind, Detail: detail})
+}
+
+func (s *simulation) nextReply() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.replies) == 0 {
+		return "", false
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply, true
+}
+
+// Simulate dry-runs a workflow graph (which need not be saved or published) against a synthetic
+// contact. Messages, tag writes and HTTP calls are recorded rather than executed and delays are
+// skipped. Nothing is written to the database.
+func (gw *GraphWalker) Simulate(ctx context.Context, w *models.Workflow, opts SimulationOptions) (*SimulationResult, error) {
+	sim := &simulation{replies: append([]string(nil), opts.Replies...)}
+
+	contact := opts.Contact
+	contact.ID = simContactID
+	contact.UserID = w.UserID
+	if contact.Name == "" {
+		contact.Name = "Test Lead"
+	}
+	if contact.Platform == "" {
+		contact.Platform = "instagram"
+	}
+
+	ss := &simStore{Store: gw.Store, workflow: w, contact: &contact, sim: sim}
+
+	llm := gw.LLMClient
+	if opts.CannedLLM || llm == nil {
+		llm = &cannedLLM{responses: opts.LLMResponses}
+	}
+
+	walker := &GraphWalker{
+		Store:     ss,
+		LLMClient: llm,
+		MaxSteps:  gw.MaxSteps,
+		sim:       sim,
+	}
+
+	state := map[string]interface{}{"received_message": opts.Message, "simulation": true}
+	for k, v := range opts.State {
+		state[k] = v
+	}
+
+	runErr := walker.StartWorkflow(ctx, w.ID, contact.ID, state)
+
+	res := &SimulationResult{
+		Path:     ss.steps,
+		Messages: []string{},
+		Effects:  sim.effects,
+		State:    map[string]interface{}{},
+	}
+	if res.Path == nil {
+		res.Path = []models.WorkflowExecutionStep{}
+	}
+	if res.Effects == nil {
+		res.Effects = []SideEffect{}
+	}
+	for _, e := range sim.effects {
+		if e.Kind == EffectMessage {
+			res.Messages = append(res.Messages, fmt.Sprint(e.Detail["text"]))
+		}
+	}
+	if ss.exec != nil {
+		res.Status = ss.exec.Status
+		res.State = decodeState(ss.exec.StateData)
+	}
+	if runErr != nil {
+		res.Status = "failed"
+		res.Error = runErr.Error()
+	}
+	return res, nil
+}
+
+// simulateWait answers an action_wait_for_reply node from the scripted replies. It returns the
+// next node and false, or true when no reply is left and the execution should park as usual.
+func (gw *GraphWalker) simulateWait(ctx context.Context, exec *models.WorkflowExecution, node *models.ReactFlowNode, graph *models.WorkflowGraph, stateData map[string]interface{}) (string, bool) {
+	rec := newStepRecord()
+	if reply, ok := gw.sim.nextReply(); ok {
+		stateData[node.DataString("variable", "last_reply")] = reply
+		stateData["received_message"] = reply
+		rec.in("reply", reply)
+		rec.branch = HandleReply
+		gw.saveStep(ctx, exec, node, rec, nil)
+		return findHandleOrDefault(graph.Edges, node.ID, HandleReply), false
+	}
+	if timeoutMs := node.DataFloat("timeoutMs", 0); timeoutMs > 0 {
+		gw.sim.record(EffectDelay, map[string]interface{}{"timeout_ms": timeoutMs})
+		rec.branch = HandleTimeout
+		gw.saveStep(ctx, exec, node, rec, nil)
+		return gw.findNextNode(graph.Edges, node.ID, HandleTimeout), false
+	}
+	return "", true
+}
+
+// simContactID marks the synthetic contact; it never exists in the contacts table
+const simContactID int64 = -1
+
+var errSimulationOnly = errors.New("not available in simulation")
+
+// simStore keeps a simulation's writes in memory. Reads the walker needs for rendering
+// (project config) fall through to the real store; anything that would write for the
+// synthetic contact or execution must be overridden here.
+type simStore struct {
+	store.Store
+
+	workflow *models.Workflow
+	contact  *models.Contact
+	sim      *simulation
+
+	exec  *models.WorkflowExecution
+	steps []models.WorkflowExecutionStep
+}
+
+func (s *simStore) GetWorkflowByID(ctx context.Context, workflowID int64) (*models.Workflow, error) {
+	return s.workflow, nil
+}
+
+func (s *simStore) GetLatestWorkflowVersion(ctx context.Context, workflowID int64) (*models.WorkflowVersion, error) {
+	return &models.WorkflowVersion{WorkflowID: s.workflow.ID, Nodes: s.workflow.Nodes, Edges: s.workflow.Edges}, nil
+}
+
+func (s *simStore) GetWorkflowVersionByID(ctx context.Context, versionID int64) (*models.WorkflowVersion, error) {
+	return s.GetLatestWorkflowVersion(ctx, s.workflow.ID)
+}
+
+func (s *simStore) CreateWorkflowVersion(ctx context.Context, v *models.WorkflowVersion) error {
+	return nil
+}
+
+func (s *simStore) GetContactByID(ctx context.Context, contactID int64) (*models.Contact, error) {
+	cp := *s.contact
+	return &cp, nil
+}
+
+func (s *simStore) GetVisitByContact(ctx context.Context, contactID int64) (*models.Visit, error) {
+	return nil, errSimulationOnly
+}
+
+// GetTenantSecrets masks every secret so simulated requests never carry real credentials
+func (s *simStore) GetTenantSecrets(ctx context.Context, userID int64) (map[string]string, error) {
+	secrets, err := s.Store.GetTenantSecrets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	masked := make(map[string]string, len(secrets))
+	for name := range secrets {
+		masked[name] = "{{secret." + name + "}}"
+	}
+	return masked, nil
+}
+
+func (s *simStore) UpdateContactState(ctx context.Context, contactID int64, bookingState string, botPaused bool) error {
+	s.contact.BookingState = bookingState
+	s.contact.BotPaused = botPaused
+	return nil
+}
+
+// The synthetic contact has no stored conversation; replies see only the simulated message
+func (s *simStore) GetMessagesByContact(ctx context.Context, contactID int64, limit, offset int) ([]models.Message, error) {
+	return nil, nil
+}
+
+func (s *simStore) GetRecentMessagesByContact(ctx context.Context, contactID int64, limit int) ([]models.Message, error) {
+	return nil, nil
+}
+
+func (s *simStore) GetMessagesByContactAfter(ctx context.Context, contactID, afterID int64, limit int) ([]models.Message, error) {
+	return nil, nil
+}
+
+func (s *simStore) GetConversationSummary(ctx context.Context, contactID int64) (*models.ConversationSummary, error) {
+	return nil, nil
+}
+
+func (s *simStore) AddContactTag(ctx context.Context, contactID int64, tag string) (bool, error) {
+	for _, t := range s.contact.Tags {
+		if t == tag {
+			return false, nil
+		}
+	}
+	s.contact.Tags = append(s.contact.Tags, tag)
+	s.sim.record(EffectTag, map[string]interface{}{"tag": tag})
+	return true, nil
+}
+
+func (s *simStore) CreateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error {
+	exec.ID = -1
+	exec.CreatedAt = time.Now()
+	cp := *exec
+	s.exec = &cp
+	return nil
+}
+
+func (s *simStore) GetWorkflowExecutionByID(ctx context.Context, executionID int64) (*models.WorkflowExecution, error) {
+	if s.exec == nil {
+		return nil, errSimulationOnly
+	}
+	cp := *s.exec
+	return &cp, nil
+}
+
+func (s *simStore) UpdateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error {
+	// Like the real store, never overwrite an execution a goal has ended
+	if s.exec != nil && s.exec.Status == StatusGoalReached {
+		return nil
+	}
+	cp := *exec
+	s.exec = &cp
+	return nil
+}
+
+func (s *simStore) GetActiveExecutionsByContact(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) {
+	if s.exec == nil || (s.exec.Status != "running" && s.exec.Status != "waiting") {
+		return nil, nil
+	}
+	return []models.WorkflowExecution{*s.exec}, nil
+}
+
+func (s *simStore) MarkExecutionGoalReached(ctx context.Context, executionID int64, goal string) (bool, error) {
+	if s.exec == nil || (s.exec.Status != "running" && s.exec.Status != "waiting") {
+		return false, nil
+	}
+	state := decodeState(s.exec.StateData)
+	state["goal_reached"] = goal
+	s.exec.StateData, _ = json.Marshal(state)
+	s.exec.Status = StatusGoalReached
+	return true, nil
+}
+
+func (s *simStore) CreateWorkflowExecutionStep(ctx context.Context, step *models.WorkflowExecutionStep) error {
+	step.ID = int64(len(s.steps) + 1)
+	s.steps = append(s.steps, *step)
+	return nil
+}
+
+// cannedLLM answers prompts from a fixed list so simulations are free and deterministic
+type cannedLLM struct {
+	mu        sync.Mutex
+	responses []string
+}
+
+var _ ai.LLMClient = (*cannedLLM)(nil)
+
+func (c *cannedLLM) GenerateText(ctx context.Context, prompt string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.responses) == 0 {
+		return "[simulated AI reply]", nil
+	}
+	reply := c.responses[0]
+	c.responses = c.responses[1:]
+	return reply, nil
+}
+
+func (c *cannedLLM) Chat(ctx context.Context, req ai.ChatRequest) (string, error) {
+	return c.GenerateText(ctx, req.System)
+}
+
+func (c *cannedLLM) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return make([]float32, 1536), nil
+}
+
+func (c *cannedLLM) GenerateStructuredJSON(ctx context.Context, prompt string, schema any) (string, error) {
+	return "{}", nil
+}
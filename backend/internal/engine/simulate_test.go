@@ -0,0 +1,115 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestSimulate(t *testing.T) {
+	ms := newMemStore()
+	ms.secrets = map[string]string{"CRM_TOKEN": "real-token"}
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": "Hi {{contact.name}}! What's your budget?"}},
+			{ID: "3", Type: models.NodeTypeActionWaitForReply, Data: map[string]interface{}{"variable": "budget"}},
+			{ID: "4", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "qualified"}},
+			{ID: "5", Type: models.NodeTypeActionDelay, Data: map[string]interface{}{"delayMs": float64(3600000)}},
+			{ID: "6", Type: models.NodeTypeActionHTTPRequest, Data: map[string]interface{}{
+				"method": "POST", "url": "https://crm.example.com/leads?token={{secret.CRM_TOKEN}}",
+				"body": `{"budget":"{{state.budget | json}}"}`,
+			}},
+			{ID: "7", Type: models.NodeTypeActionAIReply, Data: map[string]interface{}{"prompt": "Thank them"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", Target: "3"},
+			{ID: "e3", Source: "3", Target: "4"},
+			{ID: "e4", Source: "4", Target: "5"},
+			{ID: "e5", Source: "5", Target: "6"},
+			{ID: "e6", Source: "6", SourceHandle: engine.HandleSuccess, Target: "7"},
+		},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	res, err := gw.Simulate(context.Background(), ms.workflows[1], engine.SimulationOptions{
+		Contact:      models.Contact{Name: "Ravi"},
+		Message:      "hello",
+		Replies:      []string{"80 lakhs"},
+		CannedLLM:    true,
+		LLMResponses: []string{"Thanks Ravi!"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != "completed" {
+		t.Fatalf("expected completed simulation, got %s (%s)", res.Status, res.Error)
+	}
+
+	wantPath := []string{"1", "2", "3", "4", "5", "6", "7"}
+	if len(res.Path) != len(wantPath) {
+		t.Fatalf("expected path %v, got %d steps", wantPath, len(res.Path))
+	}
+	for i, step := range res.Path {
+		if step.NodeID != wantPath[i] {
+			t.Errorf("step %d = %s, want %s", i, step.NodeID, wantPath[i])
+		}
+	}
+
+	if len(res.Messages) != 2 || res.Messages[0] != "Hi Ravi! What's your budget?" || res.Messages[1] != "Thanks Ravi!" {
+		t.Errorf("unexpected messages %q", res.Messages)
+	}
+
+	kinds := map[string]int{}
+	for _, e := range res.Effects {
+		kinds[e.Kind]++
+		if e.Kind == engine.EffectHTTPRequest {
+			if url := e.Detail["url"].(string); url != "https://crm.example.com/leads?token={{secret.CRM_TOKEN}}" {
+				t.Errorf("secret leaked into simulated request: %s", url)
+			}
+			if e.Detail["body"] != `{"budget":"80 lakhs"}` {
+				t.Errorf("unexpected body %v", e.Detail["body"])
+			}
+		}
+	}
+	if kinds[engine.EffectMessage] != 2 || kinds[engine.EffectTag] != 1 || kinds[engine.EffectHTTPRequest] != 1 || kinds[engine.EffectDelay] != 1 {
+		t.Errorf("unexpected effects %+v", res.Effects)
+	}
+
+	// Nothing touched the real store
+	if len(ms.executions) != 0 || len(ms.steps) != 0 || len(ms.versions) != 0 {
+		t.Error("simulation wrote to the store")
+	}
+}
+
+func TestSimulateStopsWhenOutOfReplies(t *testing.T) {
+	ms := newMemStore()
+	waitWorkflow(t, ms)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	// waitWorkflow has a timeout, so without replies the simulation follows the timeout branch
+	res, err := gw.Simulate(context.Background(), ms.workflows[1], engine.SimulationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := res.Path[len(res.Path)-1]
+	if res.Status != "completed" || last.NodeID != "4" {
+		t.Fatalf("expected timeout path ending at node 4, got %s at %s", res.Status, last.NodeID)
+	}
+
+	// Without a timeout the simulation parks like a real execution would
+	ms.addWorkflow(t, 2,
+		[]models.ReactFlowNode{{ID: "1", Type: models.NodeTypeTriggerDM}, {ID: "2", Type: models.NodeTypeActionWaitForReply}},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}},
+	)
+	res, err = gw.Simulate(context.Background(), ms.workflows[2], engine.SimulationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != "waiting" {
+		t.Fatalf("expected waiting, got %s", res.Status)
+	}
+}
@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// A logic_split node sends executions down weighted branches for A/B tests:
+//
+//	{"branches": [{"handle": "a", "label": "Short opener", "weight": 50},
+//	              {"handle": "b", "label": "Long opener", "weight": 50}]}
+//
+// The branch is picked by hashing the workflow, node and contact, so a contact that re-enters the
+// workflow always lands on the same branch. The first branch is the control in reports.
+
+// significanceLevel is the p-value below which a branch's difference from the control is reported
+// as significant
+const significanceLevel = 0.05
+
+// SplitBranch is one weighted output of a logic_split node
+type SplitBranch struct {
+	Handle string  `json:"handle"`
+	Label  string  `json:"label,omitempty"`
+	Weight float64 `json:"weight"`
+}
+
+// ParseSplitNode reads the branches of a logic_split node
+func ParseSplitNode(node *models.ReactFlowNode) ([]SplitBranch, error) {
+	raw, ok := node.Data["branches"].([]interface{})
+	if !ok || len(raw) < 2 {
+		return nil, fmt.Errorf("split needs at least two branches")
+	}
+	branches := make([]SplitBranch, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	total := 0.0
+	for i, rb := range raw {
+		m, ok := rb.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("branch %d is not an object", i)
+		}
+		b := SplitBranch{Weight: 1}
+		b.Handle, _ = m["handle"].(string)
+		b.Label, _ = m["label"].(string)
+		if w, ok := m["weight"].(float64); ok {
+			b.Weight = w
+		}
+		switch {
+		case b.Handle == "":
+			return nil, fmt.Errorf("branch %d has no handle", i)
+		case b.Handle == HandleError:
+			return nil, fmt.Errorf("branch %d uses reserved handle %q", i, HandleError)
+		case seen[b.Handle]:
+			return nil, fmt.Errorf("duplicate branch handle %q", b.Handle)
+		case b.Weight < 0:
+			return nil, fmt.Errorf("branch %q has a negative weight", b.Handle)
+		}
+		seen[b.Handle] = true
+		total += b.Weight
+		branches = append(branches, b)
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("split branches need a positive total weight")
+	}
+	return branches, nil
+}
+
+// AssignSplitBranch deterministically picks a contact's branch in proportion to the weights
+func AssignSplitBranch(branches []SplitBranch, workflowID int64, nodeID string, contactID int64) SplitBranch {
+	total := 0.0
+	for _, b := range branches {
+		total += b.Weight
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%d", workflowID, nodeID, contactID)))
+	// Top 53 bits of the hash give a uniform float in [0, 1)
+	point := float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53) * total
+
+	for _, b := range branches {
+		if b.Weight <= 0 {
+			continue
+		}
+		if point < b.Weight {
+			return b
+		}
+		point -= b.Weight
+	}
+	// Rounding can leave point just past the last weight
+	for i := len(branches) - 1; i >= 0; i-- {
+		if branches[i].Weight > 0 {
+			return branches[i]
+		}
+	}
+	return branches[0]
+}
+
+// runSplit executes a logic_split node and labels the execution with the branch taken
+func (gw *GraphWalker) runSplit(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, rec *stepRecord) (string, error) {
+	branches, err := ParseSplitNode(node)
+	if err != nil {
+		return "", fmt.Errorf("invalid split: %w", err)
+	}
+	branch := AssignSplitBranch(branches, exec.WorkflowID, node.ID, exec.ContactID)
+	log.Printf("Split node %s sent execution %d down branch %q", node.ID, exec.ID, branch.Handle)
+
+	if exec.SplitBranches == nil {
+		exec.SplitBranches = make(map[string]string)
+	}
+	exec.SplitBranches[node.ID] = branch.Handle
+	if gw.sim == nil {
+		if err := gw.Store.SetExecutionSplitBranch(ctx, exec.ID, node.ID, branch.Handle); err != nil {
+			return "", fmt.Errorf("failed to record split branch: %w", err)
+		}
+	}
+
+	rec.out("branch", branch.Handle)
+	rec.out("label", branch.Label)
+	rec.branch = branch.Handle
+	return gw.findNextNode(graph.Edges, node.ID, branch.Handle), nil
+}
+
+// ParseSplitGoal reads the conversion goal of a split report: "replied" (the default),
+// "visit_booked", "hot_lead", "tag:<tag>", "goal" (any workflow goal) or "goal:<name>"
+func ParseSplitGoal(s string) (store.SplitGoal, error) {
+	kind, value, _ := strings.Cut(strings.TrimSpace(s), ":")
+	value = strings.TrimSpace(value)
+	switch kind {
+	case "", store.SplitGoalReplied:
+		return store.SplitGoal{Kind: store.SplitGoalReplied}, nil
+	case store.SplitGoalVisitBooked, store.SplitGoalHotLead:
+		return store.SplitGoal{Kind: kind}, nil
+	case store.SplitGoalWorkflow:
+		return store.SplitGoal{Kind: kind, Value: value}, nil
+	case store.SplitGoalTag:
+		if value == "" {
+			return store.SplitGoal{}, fmt.Errorf("tag goal needs a tag, e.g. tag:hot")
+		}
+		return store.SplitGoal{Kind: kind, Value: value}, nil
+	}
+	return store.SplitGoal{}, fmt.Errorf("unknown goal %q (use replied, visit_booked, hot_lead, tag:<tag>, goal or goal:<name>)", s)
+}
+
+// SplitReport summarises one logic_split node of a workflow
+type SplitReport struct {
+	NodeID   string              `json:"node_id"`
+	Label    string              `json:"label,omitempty"`
+	Branches []SplitBranchReport `json:"branches"`
+}
+
+// SplitBranchReport is one branch of a split. Lift and PValue compare the branch with the control
+// (the split's first branch) using a two-proportion z-test; they are omitted for the control and
+// when there is not enough data.
+type SplitBranchReport struct {
+	Handle         string   `json:"handle"`
+	Label          string   `json:"label,omitempty"`
+	Weight         float64  `json:"weight,omitempty"`
+	Control        bool     `json:"control,omitempty"`
+	Contacts       int      `json:"contacts"`
+	Conversions    int      `json:"conversions"`
+	ConversionRate float64  `json:"conversion_rate"`
+	Lift           *float64 `json:"lift,omitempty"`
+	PValue         *float64 `json:"p_value,omitempty"`
+	Significant    bool     `json:"significant"`
+}
+
+// BuildSplitReports combines per-branch counts with the workflow's split nodes. Branches are
+// listed in the order the node defines them, including those nobody went down yet; counts for
+// nodes or branches that have since been removed are listed after them.
+func BuildSplitReports(graph *models.WorkflowGraph, counts []store.SplitBranchCount) []SplitReport {
+	byNode := make(map[string][]store.SplitBranchCount)
+	var nodeOrder []string
+	for _, c := range counts {
+		if _, ok := byNode[c.NodeID]; !ok {
+			nodeOrder = append(nodeOrder, c.NodeID)
+		}
+		byNode[c.NodeID] = append(byNode[c.NodeID], c)
+	}
+
+	reports := []SplitReport{}
+	done := make(map[string]bool)
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.Type != models.NodeTypeLogicSplit {
+			continue
+		}
+		branches, _ := ParseSplitNode(node)
+		reports = append(reports, splitReport(node.ID, node.DataString("label", ""), branches, byNode[node.ID]))
+		done[node.ID] = true
+	}
+	for _, id := range nodeOrder {
+		if !done[id] {
+			reports = append(reports, splitReport(id, "", nil, byNode[id]))
+		}
+	}
+	return reports
+}
+
+func splitReport(nodeID, label string, branches []SplitBranch, counts []store.SplitBranchCount) SplitReport {
+	r := SplitReport{NodeID: nodeID, Label: label, Branches: []SplitBranchReport{}}
+	listed := make(map[string]int)
+	for _, b := range branches {
+		listed[b.Handle] = len(r.Branches)
+		r.Branches = append(r.Branches, SplitBranchReport{Handle: b.Handle, Label: b.Label, Weight: b.Weight})
+	}
+	for _, c := range counts {
+		i, ok := listed[c.Branch]
+		if !ok {
+			i = len(r.Branches)
+			listed[c.Branch] = i
+			r.Branches = append(r.Branches, SplitBranchReport{Handle: c.Branch})
+		}
+		r.Branches[i].Contacts = c.Contacts
+		r.Branches[i].Conversions = c.Conversions
+	}
+	if len(r.Branches) == 0 {
+		return r
+	}
+
+	for i := range r.Branches {
+		b := &r.Branches[i]
+		if b.Contacts > 0 {
+			b.ConversionRate = float64(b.Conversions) / float64(b.Contacts)
+		}
+	}
+	control := &r.Branches[0]
+	control.Control = true
+	for i := 1; i < len(r.Branches); i++ {
+		b := &r.Branches[i]
+		if control.Contacts == 0 || b.Contacts == 0 {
+			continue
+		}
+		if control.ConversionRate > 0 {
+			lift := (b.ConversionRate - control.ConversionRate) / control.ConversionRate
+			b.Lift = &lift
+		}
+		if p, ok := twoProportionPValue(control.Conversions, control.Contacts, b.Conversions, b.Contacts); ok {
+			b.PValue = &p
+			b.Significant = p < significanceLevel
+		}
+	}
+	return r
+}
+
+// twoProportionPValue is the two-sided p-value of a pooled two-proportion z-test. It returns false
+// when every contact (or none) converted, where the test is undefined.
+func twoProportionPValue(c1, n1, c2, n2 int) (float64, bool) {
+	pooled := float64(c1+c2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0, false
+	}
+	z := (float64(c2)/float64(n2) - float64(c1)/float64(n1)) / se
+	return math.Erfc(math.Abs(z) / math.Sqrt2), true
+}
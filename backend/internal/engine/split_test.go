@@ -0,0 +1,134 @@
+package engine_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+func splitNode(weightA, weightB float64) models.ReactFlowNode {
+	return models.ReactFlowNode{ID: "split", Type: models.NodeTypeLogicSplit, Data: map[string]interface{}{
+		"branches": []interface{}{
+			map[string]interface{}{"handle": "a", "label": "Short opener", "weight": weightA},
+			map[string]interface{}{"handle": "b", "label": "Long opener", "weight": weightB},
+		},
+	}}
+}
+
+func TestSplitAssignmentIsStableAndWeighted(t *testing.T) {
+	node := splitNode(80, 20)
+	branches, err := engine.ParseSplitNode(&node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for contact := int64(1); contact <= 10000; contact++ {
+		b := engine.AssignSplitBranch(branches, 7, node.ID, contact)
+		if again := engine.AssignSplitBranch(branches, 7, node.ID, contact); again.Handle != b.Handle {
+			t.Fatalf("contact %d moved from %q to %q", contact, b.Handle, again.Handle)
+		}
+		counts[b.Handle]++
+	}
+	if share := float64(counts["a"]) / 10000; math.Abs(share-0.8) > 0.02 {
+		t.Errorf("expected about 80%% on branch a, got %.3f", share)
+	}
+
+	// A zero-weight branch is never picked
+	zero := splitNode(1, 0)
+	branches, _ = engine.ParseSplitNode(&zero)
+	for contact := int64(1); contact <= 1000; contact++ {
+		if b := engine.AssignSplitBranch(branches, 7, node.ID, contact); b.Handle != "a" {
+			t.Fatalf("contact %d went down a zero-weight branch", contact)
+		}
+	}
+}
+
+func TestSplitNodeLabelsExecution(t *testing.T) {
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			splitNode(50, 50),
+			{ID: "ta", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "got_a"}},
+			{ID: "tb", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "got_b"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "split"},
+			{ID: "e2", Source: "split", SourceHandle: "a", Target: "ta"},
+			{ID: "e3", Source: "split", SourceHandle: "b", Target: "tb"},
+		},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	// Re-entering contact takes the same branch every time
+	for i := 0; i < 3; i++ {
+		if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var branch string
+	for _, e := range ms.executions {
+		got := e.SplitBranches["split"]
+		if got == "" || (branch != "" && got != branch) {
+			t.Fatalf("expected a stable branch label, got %v", ms.executions)
+		}
+		branch = got
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "got_"+branch {
+		t.Errorf("expected only tag got_%s, got %v", branch, tags)
+	}
+}
+
+func TestBuildSplitReports(t *testing.T) {
+	graph := &models.WorkflowGraph{Nodes: []models.ReactFlowNode{{ID: "1", Type: models.NodeTypeTriggerDM}, splitNode(50, 50)}}
+	counts := []store.SplitBranchCount{
+		{NodeID: "split", Branch: "a", Contacts: 1000, Conversions: 100},
+		{NodeID: "split", Branch: "b", Contacts: 1000, Conversions: 150},
+		{NodeID: "removed", Branch: "x", Contacts: 3, Conversions: 1},
+	}
+
+	reports := engine.BuildSplitReports(graph, counts)
+	if len(reports) != 2 || reports[0].NodeID != "split" || reports[1].NodeID != "removed" {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+	a, b := reports[0].Branches[0], reports[0].Branches[1]
+	if !a.Control || a.Label != "Short opener" || a.ConversionRate != 0.1 || a.PValue != nil {
+		t.Errorf("unexpected control %+v", a)
+	}
+	if b.Lift == nil || math.Abs(*b.Lift-0.5) > 1e-9 || b.PValue == nil || *b.PValue > 0.01 || !b.Significant {
+		t.Errorf("expected a significant 50%% lift, got %+v", b)
+	}
+
+	// Small samples with the same difference are not significant
+	counts = []store.SplitBranchCount{
+		{NodeID: "split", Branch: "a", Contacts: 20, Conversions: 2},
+		{NodeID: "split", Branch: "b", Contacts: 20, Conversions: 3},
+	}
+	if b := engine.BuildSplitReports(graph, counts)[0].Branches[1]; b.Significant {
+		t.Errorf("expected no significance on 20 contacts, got %+v", b)
+	}
+}
+
+func TestParseSplitGoal(t *testing.T) {
+	cases := map[string]store.SplitGoal{
+		"":             {Kind: store.SplitGoalReplied},
+		"visit_booked": {Kind: store.SplitGoalVisitBooked},
+		"tag:hot":      {Kind: store.SplitGoalTag, Value: "hot"},
+		"goal:Booked":  {Kind: store.SplitGoalWorkflow, Value: "Booked"},
+	}
+	for in, want := range cases {
+		if got, err := engine.ParseSplitGoal(in); err != nil || got != want {
+			t.Errorf("ParseSplitGoal(%q) = %+v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"tag", "clicked"} {
+		if _, err := engine.ParseSplitGoal(in); err == nil {
+			t.Errorf("expected %q to be rejected", in)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Starter templates are built-in workflows a tenant can instantiate instead of starting from an
+// empty canvas. Their text and settings may contain parameters written {{param.NAME}}, which are
+// filled in when the template is instantiated; a string that is exactly one placeholder of a
+// number parameter becomes a JSON number. Instantiating produces a WorkflowBundle that is
+// imported like any other, so the result is a draft.
+
+// Types of a starter template parameter
+const (
+	ParamString = "string"
+	ParamNumber = "number"
+)
+
+// TemplateParam is a value the tenant supplies when instantiating a starter template
+type TemplateParam struct {
+	Name        string      `json:"name"`
+	Label       string      `json:"label"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required"`
+	Description string      `json:"description,omitempty"`
+}
+
+// StarterTemplate is one entry of the template catalogue
+type StarterTemplate struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Category    string          `json:"category"`
+	Params      []TemplateParam `json:"params"`
+
+	workflow BundleWorkflow
+	goals    []Goal
+}
+
+var paramPattern = regexp.MustCompile(`\{\{\s*param\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// StarterTemplates returns the template catalogue
+func StarterTemplates() []StarterTemplate {
+	return starterTemplates
+}
+
+// FindStarterTemplate looks a template up by ID
+func FindStarterTemplate(id string) (*StarterTemplate, bool) {
+	for i := range starterTemplates {
+		if starterTemplates[i].ID == id {
+			return &starterTemplates[i], true
+		}
+	}
+	return nil, false
+}
+
+// Instantiate fills in the template's parameters and returns it as a bundle ready for
+// ImportWorkflowBundle. Missing parameters take their default; required ones without a default
+// and values of the wrong type are errors, as are unknown parameters.
+func (t *StarterTemplate) Instantiate(params map[string]interface{}) (*WorkflowBundle, error) {
+	values := make(map[string]interface{}, len(t.Params))
+	declared := make(map[string]TemplateParam, len(t.Params))
+	for _, p := range t.Params {
+		declared[p.Name] = p
+		v, ok := params[p.Name]
+		if s, isString := v.(string); !ok || v == nil || (isString && strings.TrimSpace(s) == "") {
+			if p.Default == nil {
+				if p.Required {
+					return nil, fmt.Errorf("parameter %q is required", p.Name)
+				}
+				v = ""
+				if p.Type == ParamNumber {
+					v = 0.0
+				}
+			} else {
+				v = p.Default
+			}
+		}
+		switch p.Type {
+		case ParamNumber:
+			n, ok := toFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("parameter %q must be a number", p.Name)
+			}
+			values[p.Name] = n
+		default:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("parameter %q must be a string", p.Name)
+			}
+			values[p.Name] = strings.TrimSpace(s)
+		}
+	}
+	for name := range params {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+
+	wf := t.workflow
+	var err error
+	if wf.Nodes, err = fillParams(wf.Nodes, values); err != nil {
+		return nil, err
+	}
+	if wf.Edges, err = fillParams(wf.Edges, values); err != nil {
+		return nil, err
+	}
+	if len(t.goals) > 0 {
+		goals, _ := json.Marshal(t.goals)
+		if wf.Goals, err = fillParams(goals, values); err != nil {
+			return nil, err
+		}
+	}
+	return &WorkflowBundle{
+		Format:        BundleFormat,
+		Version:       BundleVersion,
+		Workflow:      wf,
+		Modules:       []BundleWorkflow{},
+		Tags:          []string{},
+		Secrets:       []string{},
+		Teams:         []string{},
+		KnowledgeBase: []BundleDocument{},
+	}, nil
+}
+
+// fillParams substitutes {{param.NAME}} placeholders throughout a JSON document
+func fillParams(raw json.RawMessage, values map[string]interface{}) (json.RawMessage, error) {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	var missing error
+	var fill func(v interface{}) interface{}
+	fill = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, item := range v {
+				v[k] = fill(item)
+			}
+			return v
+		case []interface{}:
+			for i, item := range v {
+				v[i] = fill(item)
+			}
+			return v
+		case string:
+			if m := paramPattern.FindStringSubmatch(v); m != nil && m[0] == v {
+				if n, ok := values[m[1]].(float64); ok {
+					return n
+				}
+			}
+			return paramPattern.ReplaceAllStringFunc(v, func(ph string) string {
+				name := paramPattern.FindStringSubmatch(ph)[1]
+				val, ok := values[name]
+				if !ok {
+					missing = fmt.Errorf("template uses undeclared parameter %q", name)
+					return ph
+				}
+				return fmt.Sprint(val)
+			})
+		}
+		return v
+	}
+	doc = fill(doc)
+	if missing != nil {
+		return nil, missing
+	}
+	return json.Marshal(doc)
+}
+
+// starterWorkflow marshals typed nodes and edges into a bundle workflow
+func starterWorkflow(name string, nodes []models.ReactFlowNode, edges []models.ReactFlowEdge) BundleWorkflow {
+	for i := range nodes {
+		nodes[i].Position = map[string]float64{"x": 250, "y": float64(i * 120)}
+	}
+	n, _ := json.Marshal(nodes)
+	e, _ := json.Marshal(edges)
+	return BundleWorkflow{Name: name, TriggerType: GraphTriggerType(n, ""), Nodes: n, Edges: e, ReentryPolicy: ReentrySkip}
+}
+
+func starterEdge(source, handle, target string) models.ReactFlowEdge {
+	id := "e-" + source + "-" + target
+	return models.ReactFlowEdge{ID: id, Source: source, SourceHandle: handle, Target: target}
+}
+
+type nodeData = map[string]interface{}
+
+const hourMs = 60 * 60 * 1000
+
+var starterTemplates = []StarterTemplate{
+	{
+		ID:          "site_visit_booking",
+		Name:        "Site visit booking",
+		Description: "Answers visit requests, asks for a preferred slot and hands the lead to the sales team to confirm.",
+		Category:    "visits",
+		Params: []TemplateParam{
+			{Name: "project_name", Label: "Project name", Type: ParamString, Required: true},
+			{Name: "sales_team", Label: "Sales team", Type: ParamString, Default: "sales", Description: "Agent team that confirms visits"},
+			{Name: "sla_minutes", Label: "Minutes to confirm", Type: ParamNumber, Default: 30.0},
+		},
+		workflow: starterWorkflow("Site visit booking",
+			[]models.ReactFlowNode{
+				{ID: "trigger", Type: models.NodeTypeTriggerKeyword, Data: nodeData{"keywords": []interface{}{"visit", "site visit", "book"}}},
+				{ID: "ask_slot", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": `Hi {{contact.name | default "there"}}! We'd love to show you {{param.project_name}}. Which day and time suit you for a site visit?`}},
+				{ID: "wait_slot", Type: models.NodeTypeActionWaitForReply, Data: nodeData{"variable": "preferred_slot", "timeoutMs": 24.0 * hourMs}},
+				{ID: "tag", Type: models.NodeTypeActionAddTag, Data: nodeData{"tag": "visit_requested"}},
+				{ID: "confirm", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": "Thanks! Our team will confirm your visit for {{state.preferred_slot}} shortly."}},
+				{ID: "handoff", Type: models.NodeTypeActionHandoff, Data: nodeData{"team": "{{param.sales_team}}", "note": "Site visit to {{param.project_name}} requested for {{state.preferred_slot}}.", "slaMinutes": "{{param.sla_minutes}}", "onBreach": HandoffReroute}},
+				{ID: "nudge", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": "Just checking in: would you like to schedule a visit to {{param.project_name}}? Reply with a day that works for you."}},
+			},
+			[]models.ReactFlowEdge{
+				starterEdge("trigger", "", "ask_slot"),
+				starterEdge("ask_slot", "", "wait_slot"),
+				starterEdge("wait_slot", HandleReply, "tag"),
+				starterEdge("tag", "", "confirm"),
+				starterEdge("confirm", "", "handoff"),
+				starterEdge("wait_slot", HandleTimeout, "nudge"),
+			},
+		),
+		goals: []Goal{{Name: "Visit booked", Type: GoalVisitBooked}},
+	},
+	{
+		ID:          "brochure_follow_up",
+		Name:        "Brochure follow-up",
+		Description: "Sends the brochure on request and follows up if the lead has not replied a day later to turn interest into a conversation.",
+		Category:    "nurture",
+		Params: []TemplateParam{
+			{Name: "project_name", Label: "Project name", Type: ParamString, Required: true},
+			{Name: "brochure_url", Label: "Brochure link", Type: ParamString, Required: true},
+		},
+		workflow: starterWorkflow("Brochure follow-up",
+			[]models.ReactFlowNode{
+				{ID: "trigger", Type: models.NodeTypeTriggerKeyword, Data: nodeData{"keywords": []interface{}{"brochure", "floor plan", "details"}}},
+				{ID: "send", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": "Here is the brochure for {{param.project_name}}: {{param.brochure_url}}"}},
+				{ID: "tag_sent", Type: models.NodeTypeActionAddTag, Data: nodeData{"tag": "brochure_sent"}},
+				{ID: "wait", Type: models.NodeTypeActionWaitForReply, Data: nodeData{"variable": "brochure_reply", "timeoutMs": 24.0 * hourMs}},
+				{ID: "engaged", Type: models.NodeTypeActionAddTag, Data: nodeData{"tag": "brochure_engaged"}},
+				{ID: "follow_up", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": `Did you get a chance to look at the {{param.project_name}} brochure? Happy to answer questions or set up a site visit.`}},
+			},
+			[]models.ReactFlowEdge{
+				starterEdge("trigger", "", "send"),
+				starterEdge("send", "", "tag_sent"),
+				starterEdge("tag_sent", "", "wait"),
+				starterEdge("wait", HandleReply, "engaged"),
+				starterEdge("wait", HandleTimeout, "follow_up"),
+			},
+		),
+	},
+	{
+		ID:          "price_inquiry",
+		Name:        "Price inquiry",
+		Description: "Shares the starting price, asks for the lead's budget and passes qualified leads to sales.",
+		Category:    "qualification",
+		Params: []TemplateParam{
+			{Name: "project_name", Label: "Project name", Type: ParamString, Required: true},
+			{Name: "starting_price", Label: "Starting price", Type: ParamString, Required: true, Description: `Shown as written, e.g. "₹85 lakh"`},
+			{Name: "sales_team", Label: "Sales team", Type: ParamString, Default: "sales"},
+		},
+		workflow: starterWorkflow("Price inquiry",
+			[]models.ReactFlowNode{
+				{ID: "trigger", Type: models.NodeTypeTriggerKeyword, Data: nodeData{"keywords": []interface{}{"price", "cost", "rate"}}},
+				{ID: "price", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": "Homes at {{param.project_name}} start from {{param.starting_price}}. What budget range are you considering?"}},
+				{ID: "wait_budget", Type: models.NodeTypeActionWaitForReply, Data: nodeData{"variable": "answer_budget", "timeoutMs": 24.0 * hourMs}},
+				{ID: "tag", Type: models.NodeTypeActionAddTag, Data: nodeData{"tag": "price_inquiry"}},
+				{ID: "handoff", Type: models.NodeTypeActionHandoff, Data: nodeData{"team": "{{param.sales_team}}", "strategy": HandoffLeastLoad, "note": "Price inquiry for {{param.project_name}}, budget: {{state.answer_budget}}"}},
+			},
+			[]models.ReactFlowEdge{
+				starterEdge("trigger", "", "price"),
+				starterEdge("price", "", "wait_budget"),
+				starterEdge("wait_budget", HandleReply, "tag"),
+				starterEdge("tag", "", "handoff"),
+			},
+		),
+	},
+	{
+		ID:          "re_engagement",
+		Name:        "Re-engagement",
+		Description: "Reaches out to leads who went quiet and tags who comes back and who stays dormant.",
+		Category:    "nurture",
+		Params: []TemplateParam{
+			{Name: "inactive_days", Label: "Days of silence", Type: ParamNumber, Default: 14.0},
+			{Name: "offer_text", Label: "Offer or news to share", Type: ParamString, Required: true},
+		},
+		workflow: starterWorkflow("Re-engagement",
+			[]models.ReactFlowNode{
+				{ID: "trigger", Type: models.NodeTypeTriggerInactivity, Data: nodeData{"inactiveDays": "{{param.inactive_days}}"}},
+				{ID: "hello", Type: models.NodeTypeActionSendMessage, Data: nodeData{"message": `Hi {{contact.name | default "there"}}, it's been a while! {{param.offer_text}}`}},
+				{ID: "wait", Type: models.NodeTypeActionWaitForReply, Data: nodeData{"variable": "reengage_reply", "timeoutMs": 72.0 * hourMs}},
+				{ID: "back", Type: models.NodeTypeActionAddTag, Data: nodeData{"tag": "re_engaged"}},
+				{ID: "dormant", Type: models.NodeTypeActionAddTag, Data: nodeData{"tag": "dormant"}},
+			},
+			[]models.ReactFlowEdge{
+				starterEdge("trigger", "", "hello"),
+				starterEdge("hello", "", "wait"),
+				starterEdge("wait", HandleReply, "back"),
+				starterEdge("wait", HandleTimeout, "dormant"),
+			},
+		),
+	},
+}
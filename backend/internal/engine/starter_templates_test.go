@@ -0,0 +1,92 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// sampleParams fills every required parameter of a template
+func sampleParams(tmpl *engine.StarterTemplate) map[string]interface{} {
+	params := map[string]interface{}{}
+	for _, p := range tmpl.Params {
+		if !p.Required {
+			continue
+		}
+		if p.Type == engine.ParamNumber {
+			params[p.Name] = 3.0
+		} else {
+			params[p.Name] = "Sample " + p.Name
+		}
+	}
+	return params
+}
+
+func TestStarterTemplatesValidate(t *testing.T) {
+	for _, tmpl := range engine.StarterTemplates() {
+		tmpl := tmpl
+		t.Run(tmpl.ID, func(t *testing.T) {
+			bundle, err := tmpl.Instantiate(sampleParams(&tmpl))
+			if err != nil {
+				t.Fatalf("instantiate: %v", err)
+			}
+			if err := engine.CheckBundle(bundle); err != nil {
+				t.Fatalf("bundle check: %v", err)
+			}
+			report, err := engine.ValidateWorkflow(bundle.Workflow.Nodes, bundle.Workflow.Edges)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.Valid() {
+				t.Errorf("template does not validate: %+v", report.Errors)
+			}
+			if strings.Contains(string(bundle.Workflow.Nodes), "{{param.") {
+				t.Errorf("unfilled placeholder in %s", bundle.Workflow.Nodes)
+			}
+		})
+	}
+}
+
+func TestStarterTemplateParams(t *testing.T) {
+	tmpl, ok := engine.FindStarterTemplate("site_visit_booking")
+	if !ok {
+		t.Fatal("site_visit_booking missing from the catalogue")
+	}
+
+	bundle, err := tmpl.Instantiate(map[string]interface{}{"project_name": "Lakeview", "sla_minutes": 45.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := models.ParseWorkflowGraph(bundle.Workflow.Nodes, bundle.Workflow.Edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handoff *models.ReactFlowNode
+	for i := range graph.Nodes {
+		if graph.Nodes[i].ID == "handoff" {
+			handoff = &graph.Nodes[i]
+		}
+	}
+	if handoff == nil {
+		t.Fatal("handoff node missing")
+	}
+	// A value that is a single number placeholder stays a number; defaults fill the rest
+	if got := handoff.Data["slaMinutes"]; got != 45.0 {
+		t.Errorf("slaMinutes = %#v, want 45", got)
+	}
+	if got := handoff.DataString("team", ""); got != "sales" {
+		t.Errorf("team = %q, want the default", got)
+	}
+
+	for name, params := range map[string]map[string]interface{}{
+		"missing required": {},
+		"unknown param":    {"project_name": "Lakeview", "colour": "blue"},
+		"wrong type":       {"project_name": "Lakeview", "sla_minutes": "soon"},
+	} {
+		if _, err := tmpl.Instantiate(params); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// stepRecord collects what a node read and produced while it ran. It is written to
+// workflow_execution_steps once the node finishes so tenants can inspect the timeline.
+type stepRecord struct {
+	started time.Time
+	input   map[string]interface{}
+	output  map[string]interface{}
+	branch  string
+}
+
+func newStepRecord() *stepRecord {
+	return &stepRecord{
+		started: time.Now(),
+		input:   make(map[string]interface{}),
+		output:  make(map[string]interface{}),
+	}
+}
+
+func (r *stepRecord) in(key string, val interface{})  { r.input[key] = val }
+func (r *stepRecord) out(key string, val interface{}) { r.output[key] = val }
+
+// saveStep persists a finished node. Logging must never break an execution, so failures are
+// only logged.
+func (gw *GraphWalker) saveStep(ctx context.Context, exec *models.WorkflowExecution, node *models.ReactFlowNode, rec *stepRecord, nodeErr error) {
+	input, _ := json.Marshal(rec.input)
+	output, _ := json.Marshal(rec.output)
+	step := &models.WorkflowExecutionStep{
+		ExecutionID: exec.ID,
+		NodeID:      node.ID,
+		NodeType:    string(node.Type),
+		Branch:      rec.branch,
+		Input:       input,
+		Output:      output,
+		StartedAt:   rec.started,
+		FinishedAt:  time.Now(),
+	}
+	if nodeErr != nil {
+		step.Error = nodeErr.Error()
+	}
+	if err := gw.Store.CreateWorkflowExecutionStep(ctx, step); err != nil {
+		log.Printf("[GraphWalker] Failed to record step %s of execution %d: %v", node.ID, exec.ID, err)
+	}
+}
@@ -0,0 +1,63 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestExecutionSteps(t *testing.T) {
+	ms := newMemStore()
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerKeyword},
+			{ID: "2", Type: models.NodeTypeLogicCondition, Data: map[string]interface{}{
+				"condition": map[string]interface{}{"field": "contact.name", "operator": "eq", "value": "Asha"},
+			}},
+			{ID: "3", Type: models.NodeTypeActionDelay, Data: map[string]interface{}{"delayMs": float64(5000)}},
+			{ID: "4", Type: models.NodeTypeActionDelay},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", SourceHandle: engine.HandleTrue, Target: "3"},
+			{ID: "e3", Source: "3", Target: "4"},
+		},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	state := map[string]interface{}{"received_message": "price please", "matched_keyword": "price"}
+	if err := gw.StartWorkflow(context.Background(), 1, 1, state); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ms.steps) != 3 {
+		t.Fatalf("expected 3 steps (trigger, condition, delay), got %d", len(ms.steps))
+	}
+	exec := ms.onlyExecution(t)
+	wantNodes := []string{"1", "2", "3"}
+	for i, step := range ms.steps {
+		if step.ExecutionID != exec.ID || step.NodeID != wantNodes[i] {
+			t.Errorf("step %d = %s for execution %d", i, step.NodeID, step.ExecutionID)
+		}
+		if step.FinishedAt.Before(step.StartedAt) {
+			t.Errorf("step %d finished before it started", i)
+		}
+	}
+
+	var triggerOut map[string]interface{}
+	_ = json.Unmarshal(ms.steps[0].Output, &triggerOut)
+	if triggerOut["matched_keyword"] != "price" {
+		t.Errorf("trigger output = %s", ms.steps[0].Output)
+	}
+	if ms.steps[1].Branch != engine.HandleTrue {
+		t.Errorf("condition branch = %q", ms.steps[1].Branch)
+	}
+	var delayOut map[string]interface{}
+	_ = json.Unmarshal(ms.steps[2].Output, &delayOut)
+	if delayOut["delay_ms"] != float64(5000) || delayOut["next_node_id"] != "4" {
+		t.Errorf("delay output = %s", ms.steps[2].Output)
+	}
+}
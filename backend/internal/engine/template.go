@@ -0,0 +1,372 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Template is a parsed message/prompt template. Placeholders look like
+//
+//	Hi {{contact.name | default "there"}}, your visit is on {{visit.time | date "Mon 3PM"}}
+//
+// Supported filters: default "x", date "layout" (Go time layout, in the tenant's timezone),
+// upper, lower, trim and json (escapes the value for embedding inside a JSON string).
+// Write \{{ for a literal "{{".
+// Substituted values are never re-interpreted, so contact-supplied text cannot inject placeholders.
+type Template struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	literal string
+	expr    *templateExpr
+}
+
+type templateExpr struct {
+	path    string
+	filters []templateFilter
+}
+
+type templateFilter struct {
+	name string
+	args []string
+}
+
+// DefaultDateLayout is used by the date filter (and for raw time values) when no layout is given
+const DefaultDateLayout = "Mon, 02 Jan 3:04 PM"
+
+// filterArity is the number of arguments each filter accepts
+var filterArity = map[string][2]int{
+	"default": {1, 1},
+	"date":    {0, 1},
+	"upper":   {0, 0},
+	"lower":   {0, 0},
+	"trim":    {0, 0},
+	"json":    {0, 0},
+}
+
+// ParseTemplate parses and validates a template: syntax, filter names/arguments and variable names.
+func ParseTemplate(src string) (*Template, error) {
+	return parseTemplate(src, false)
+}
+
+// ParseRequestTemplate is ParseTemplate for outbound request fields (URL, headers, body), which
+// may additionally reference tenant secrets as {{secret.NAME}}. Secrets are deliberately not
+// available to message templates so they can never be sent to a contact.
+func ParseRequestTemplate(src string) (*Template, error) {
+	return parseTemplate(src, true)
+}
+
+func parseTemplate(src string, allowSecrets bool) (*Template, error) {
+	t := &Template{}
+	var lit strings.Builder
+
+	for i := 0; i < len(src); {
+		if strings.HasPrefix(src[i:], `\{{`) {
+			lit.WriteString("{{")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(src[i:], "{{") {
+			lit.WriteByte(src[i])
+			i++
+			continue
+		}
+
+		end := strings.Index(src[i+2:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder at offset %d", i)
+		}
+		expr, err := parseTemplateExpr(src[i+2:i+2+end], allowSecrets)
+		if err != nil {
+			return nil, err
+		}
+		if lit.Len() > 0 {
+			t.parts = append(t.parts, templatePart{literal: lit.String()})
+			lit.Reset()
+		}
+		t.parts = append(t.parts, templatePart{expr: expr})
+		i += 2 + end + 2
+	}
+	if lit.Len() > 0 {
+		t.parts = append(t.parts, templatePart{literal: lit.String()})
+	}
+	return t, nil
+}
+
+// ValidateTemplate reports the first problem with a template, or nil if it renders cleanly
+func ValidateTemplate(src string) error {
+	_, err := ParseTemplate(src)
+	return err
+}
+
+// TemplatedFields lists the node.Data keys that are rendered through the template engine
+var TemplatedFields = []string{"message", "prompt", "note", "fallbackMessage"}
+
+// ValidateNodeTemplates checks every templated field of every node so unknown variables and
+// syntax errors are reported when the workflow is saved.
+func ValidateNodeTemplates(graph *models.WorkflowGraph) error {
+	for i := range graph.Nodes {
+		if err := validateTemplateFields(&graph.Nodes[i]); err != nil {
+			return fmt.Errorf("node %s %w", graph.Nodes[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// validateTemplateFields reports the first broken templated field of a single node
+func validateTemplateFields(node *models.ReactFlowNode) error {
+	for _, field := range TemplatedFields {
+		src, ok := node.Data[field].(string)
+		if !ok {
+			continue
+		}
+		if err := ValidateTemplate(src); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// RenderTemplate parses and renders in one go. On a parse error the source is returned unchanged
+// so a broken template degrades to the literal text rather than an empty message.
+func RenderTemplate(src string, vars *Variables) (string, error) {
+	t, err := ParseTemplate(src)
+	if err != nil {
+		return src, err
+	}
+	return t.Render(vars), nil
+}
+
+// Render substitutes all placeholders. Variables without a value render as "" unless a default is given.
+func (t *Template) Render(vars *Variables) string {
+	var out strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			out.WriteString(p.literal)
+			continue
+		}
+		out.WriteString(p.expr.eval(vars))
+	}
+	return out.String()
+}
+
+// RenderJSON is Render for JSON documents such as request bodies: every substituted value is
+// escaped as if it had the json filter, so a quote in a contact's name cannot break out of the
+// string it is placed in. Placeholders that already use the json filter are not escaped twice.
+func (t *Template) RenderJSON(vars *Variables) string {
+	var out strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			out.WriteString(p.literal)
+			continue
+		}
+		val := p.expr.eval(vars)
+		if !p.expr.hasFilter("json") {
+			val = jsonEscape(val)
+		}
+		out.WriteString(val)
+	}
+	return out.String()
+}
+
+// Variables returns the variable paths referenced by the template
+func (t *Template) Variables() []string {
+	var paths []string
+	for _, p := range t.parts {
+		if p.expr != nil {
+			paths = append(paths, p.expr.path)
+		}
+	}
+	return paths
+}
+
+func (e *templateExpr) eval(vars *Variables) string {
+	var val interface{}
+	if vars != nil {
+		val, _ = vars.Lookup(e.path)
+	}
+
+	for _, f := range e.filters {
+		switch f.name {
+		case "date":
+			layout := DefaultDateLayout
+			if len(f.args) == 1 {
+				layout = f.args[0]
+			}
+			if ts, ok := toTime(val); ok {
+				if vars != nil && vars.Location != nil {
+					ts = ts.In(vars.Location)
+				}
+				val = ts.Format(layout)
+			}
+		case "default":
+			if isEmptyValue(val) || formatValue(val) == "" {
+				val = f.args[0]
+			}
+		case "upper":
+			val = strings.ToUpper(formatValue(val))
+		case "lower":
+			val = strings.ToLower(formatValue(val))
+		case "trim":
+			val = strings.TrimSpace(formatValue(val))
+		case "json":
+			val = jsonEscape(formatValue(val))
+		}
+	}
+	return formatValue(val)
+}
+
+func (e *templateExpr) hasFilter(name string) bool {
+	for _, f := range e.filters {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEscape escapes s for embedding inside a JSON string, without the surrounding quotes
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+func parseTemplateExpr(raw string, allowSecrets bool) (*templateExpr, error) {
+	tokens, err := tokenizeTemplateExpr(raw)
+	if err != nil {
+		return nil, fmt.Errorf("placeholder {{%s}}: %w", raw, err)
+	}
+
+	// Split on pipes into segments: [path] | [filter args...] | ...
+	var segments [][]templateToken
+	current := []templateToken{}
+	for _, tok := range tokens {
+		if tok.pipe {
+			segments = append(segments, current)
+			current = []templateToken{}
+			continue
+		}
+		current = append(current, tok)
+	}
+	segments = append(segments, current)
+
+	head := segments[0]
+	if len(head) != 1 || head[0].quoted {
+		return nil, fmt.Errorf("placeholder {{%s}} must start with a variable name", strings.TrimSpace(raw))
+	}
+	expr := &templateExpr{path: head[0].text}
+	if !IsKnownVariable(expr.path) && !(allowSecrets && isSecretVariable(expr.path)) {
+		return nil, fmt.Errorf("unknown variable %q", expr.path)
+	}
+
+	for _, seg := range segments[1:] {
+		if len(seg) == 0 || seg[0].quoted {
+			return nil, fmt.Errorf("placeholder {{%s}} has an empty filter", strings.TrimSpace(raw))
+		}
+		f := templateFilter{name: seg[0].text}
+		arity, ok := filterArity[f.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q on %s", f.name, expr.path)
+		}
+		for _, arg := range seg[1:] {
+			if !arg.quoted {
+				return nil, fmt.Errorf("filter %q arguments must be quoted strings", f.name)
+			}
+			f.args = append(f.args, arg.text)
+		}
+		if len(f.args) < arity[0] || len(f.args) > arity[1] {
+			return nil, fmt.Errorf("filter %q takes %d-%d arguments, got %d", f.name, arity[0], arity[1], len(f.args))
+		}
+		expr.filters = append(expr.filters, f)
+	}
+	return expr, nil
+}
+
+type templateToken struct {
+	text   string
+	quoted bool
+	pipe   bool
+}
+
+func tokenizeTemplateExpr(raw string) ([]templateToken, error) {
+	var tokens []templateToken
+	for i := 0; i < len(raw); {
+		switch c := raw[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '|':
+			tokens = append(tokens, templateToken{pipe: true})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(raw) && raw[j] != '"'; j++ {
+				if raw[j] == '\\' && j+1 < len(raw) {
+					j++
+				}
+				sb.WriteByte(raw[j])
+			}
+			if j >= len(raw) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, templateToken{text: sb.String(), quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(raw) && raw[j] != ' ' && raw[j] != '\t' && raw[j] != '|' && raw[j] != '"' {
+				j++
+			}
+			tokens = append(tokens, templateToken{text: raw[i:j]})
+			i = j
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty placeholder")
+	}
+	return tokens, nil
+}
+
+func isSecretVariable(path string) bool {
+	name, ok := strings.CutPrefix(path, "secret.")
+	return ok && name != ""
+}
+
+// formatValue renders a variable for humans: lists are comma-joined, times use DefaultDateLayout
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		return val.Format(DefaultDateLayout)
+	case []string:
+		return strings.Join(val, ", ")
+	case []interface{}:
+		return strings.Join(stringValues(val), ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// toTime accepts time.Time values and RFC 3339 strings (how times come back out of StateData)
+func toTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, !val.IsZero()
+	case string:
+		ts, err := time.Parse(time.RFC3339, val)
+		return ts, err == nil
+	}
+	return time.Time{}, false
+}
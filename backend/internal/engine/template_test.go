@@ -0,0 +1,79 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	vars := &engine.Variables{
+		Contact: &models.Contact{Name: "Asha", Budget: "80L", Tags: []string{"warm", "2bhk"}},
+		State:   map[string]interface{}{"answer_budget": "1.2Cr", "count": float64(3), "injected": "{{contact.budget}}"},
+		Visit:   &models.Visit{VisitTime: time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)},
+		Project: &models.PropertyVisitConfig{ProjectName: "Skyline Towers"},
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"plain", "Hello!", "Hello!"},
+		{"contact and project", "Hi {{contact.name}}, welcome to {{ project.name }}", "Hi Asha, welcome to Skyline Towers"},
+		{"state", "Budget noted: {{state.answer_budget}} ({{state.count}})", "Budget noted: 1.2Cr (3)"},
+		{"date filter", `See you {{visit.time | date "Mon 3PM"}}`, "See you Mon 3PM"},
+		{"default", `Hi {{contact.email | default "there"}}`, "Hi there"},
+		{"missing state default", `{{state.nope | default "n/a" | upper}}`, "N/A"},
+		{"list", "Tags: {{contact.tags}}", "Tags: warm, 2bhk"},
+		{"escape", `Use \{{contact.name}} literally`, "Use {{contact.name}} literally"},
+		{"no reinterpretation", "{{state.injected}}", "{{contact.budget}}"},
+		{"json", `{"note":"{{state.quote | json}}"}`, `{"note":""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.RenderTemplate(tt.src, vars)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Times are shown in the tenant's timezone
+	vars.Location = time.FixedZone("IST", 5*3600+1800)
+	if got, _ := engine.RenderTemplate(`{{visit.time | date "Mon 3:04PM"}}`, vars); got != "Mon 8:30PM" {
+		t.Errorf("date filter ignored the tenant timezone: %s", got)
+	}
+
+	vars.State["quote"] = `say "hi"`
+	got, _ := engine.RenderTemplate(`{"note":"{{state.quote | json}}"}`, vars)
+	if got != `{"note":"say \"hi\""}` {
+		t.Errorf("json filter did not escape quotes: %s", got)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	bad := []string{
+		"Hi {{contact.nickname}}",
+		"Hi {{contact.name",
+		"Hi {{}}",
+		`{{contact.name | shout}}`,
+		`{{contact.name | default}}`,
+		`{{contact.name | default there}}`,
+		`{{visit.time | date "a" "b"}}`,
+	}
+	for _, src := range bad {
+		if err := engine.ValidateTemplate(src); err == nil {
+			t.Errorf("expected validation error for %q", src)
+		}
+	}
+	if err := engine.ValidateTemplate(`{{state.anything | trim}} {{message.text}}`); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/calendar"
+	"github.com/social-media-lead/backend/internal/models"
+	"github.com/social-media-lead/backend/internal/store"
+)
+
+// Source handles of a logic_time_window node
+const (
+	HandleInHours  = "in"
+	HandleOutHours = "out"
+)
+
+// Modes of a logic_time_window node. "branch" follows "in" or "out" depending on whether the
+// tenant is open right now; "wait" holds the execution until the next opening time and then
+// continues on "in" (or the unlabeled edge).
+const (
+	TimeWindowBranch = "branch"
+	TimeWindowWait   = "wait"
+)
+
+// TenantCalendar returns a tenant's business hours. Tenants without a calendar, and calendars
+// that no longer compile, count as always open so flows are never held up by configuration.
+func TenantCalendar(ctx context.Context, s store.Store, userID int64) *calendar.Calendar {
+	cfg, err := s.GetBusinessCalendar(ctx, userID)
+	if err != nil {
+		log.Printf("[Calendar] Failed to load business hours of user %d, treating as open: %v", userID, err)
+		return calendar.AlwaysOpen()
+	}
+	cal, err := calendar.New(cfg)
+	if err != nil {
+		log.Printf("[Calendar] Business hours of user %d are invalid, treating as open: %v", userID, err)
+		return calendar.AlwaysOpen()
+	}
+	return cal
+}
+
+// runTimeWindow executes a logic_time_window node
+func (gw *GraphWalker) runTimeWindow(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
+	contact, err := gw.Store.GetContactByID(ctx, exec.ContactID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get contact: %w", err)
+	}
+	cal := TenantCalendar(ctx, gw.Store, contact.UserID)
+
+	now := time.Now()
+	open := cal.IsOpen(now)
+	rec.out("open", open)
+
+	if node.DataString("mode", TimeWindowBranch) != TimeWindowWait {
+		handle := HandleOutHours
+		if open {
+			handle = HandleInHours
+		}
+		rec.branch = handle
+		return gw.findNextNode(graph.Edges, node.ID, handle), nil
+	}
+
+	if !open {
+		until, ok := cal.NextOpen(now)
+		if !ok {
+			return "", fmt.Errorf("business hours never open")
+		}
+		stateData["delay_until"] = until.Format(time.RFC3339)
+		rec.out("wait_until", until.Format(time.RFC3339))
+	}
+	rec.branch = HandleInHours
+	return findHandleOrDefault(graph.Edges, node.ID, HandleInHours), nil
+}
+
+// wakeTime returns the wake-up time a delay or waiting time window stored in state
+func wakeTime(stateData map[string]interface{}) (time.Time, bool) {
+	s, ok := stateData["delay_until"].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
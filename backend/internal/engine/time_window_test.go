@@ -0,0 +1,121 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// closedToday is open around the clock except for today (UTC), which is a holiday
+func closedToday() *models.BusinessCalendar {
+	hours := make(map[string][]models.BusinessHours)
+	for _, d := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+		hours[d] = []models.BusinessHours{{Open: "00:00", Close: "24:00"}}
+	}
+	return &models.BusinessCalendar{
+		Timezone:    "UTC",
+		WeeklyHours: hours,
+		Holidays:    []string{time.Now().UTC().Format("2006-01-02")},
+	}
+}
+
+// timeWindowWorkflow: trigger -> time window -> add_tag "open" / "closed"
+func timeWindowWorkflow(t *testing.T, ms *memStore, mode string) {
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeLogicTimeWindow, Data: map[string]interface{}{"mode": mode}},
+			{ID: "3", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "open"}},
+			{ID: "4", Type: models.NodeTypeActionAddTag, Data: map[string]interface{}{"tag": "closed"}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", SourceHandle: engine.HandleInHours, Target: "3"},
+			{ID: "e3", Source: "2", SourceHandle: engine.HandleOutHours, Target: "4"},
+		},
+	)
+}
+
+func TestTimeWindowBranch(t *testing.T) {
+	cases := []struct {
+		name     string
+		calendar *models.BusinessCalendar
+		want     string
+	}{
+		{"No calendar is always open", nil, "open"},
+		{"Holiday", closedToday(), "closed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := newMemStore()
+			ms.calendar = tc.calendar
+			timeWindowWorkflow(t, ms, engine.TimeWindowBranch)
+			gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+			if err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{}); err != nil {
+				t.Fatal(err)
+			}
+			if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != tc.want {
+				t.Errorf("expected tag %q, got %v", tc.want, tags)
+			}
+		})
+	}
+}
+
+func TestTimeWindowWait(t *testing.T) {
+	ctx := context.Background()
+	ms := newMemStore()
+	ms.calendar = closedToday()
+	timeWindowWorkflow(t, ms, engine.TimeWindowWait)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	exec := ms.onlyExecution(t)
+	if exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForDelay || exec.CurrentNodeID != "3" {
+		t.Fatalf("expected a wait in front of the in-hours path, got %s/%s at %s", exec.Status, exec.WaitingFor, exec.CurrentNodeID)
+	}
+	tomorrow := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Format(time.RFC3339)
+	var state map[string]interface{}
+	_ = json.Unmarshal(exec.StateData, &state)
+	if until := state["delay_until"]; until != tomorrow {
+		t.Errorf("expected to wait until the next opening %s, got %v", tomorrow, until)
+	}
+
+	// Once the scheduled resume fires the in-hours path runs
+	if err := gw.ResumeExecution(ctx, exec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if tags := ms.contacts[1].Tags; len(tags) != 1 || tags[0] != "open" {
+		t.Errorf("expected the in-hours path after the wait, got %v", tags)
+	}
+}
+
+func TestDateFilterUsesTenantTimezone(t *testing.T) {
+	ms := newMemStore()
+	ms.calendar = &models.BusinessCalendar{Timezone: "Asia/Kolkata"}
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionSendMessage, Data: map[string]interface{}{"message": `See you {{state.visit_at | date "Mon 3:04PM"}}`}},
+		},
+		[]models.ReactFlowEdge{{ID: "e1", Source: "1", Target: "2"}},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+	res, err := gw.Simulate(context.Background(), ms.workflows[1], engine.SimulationOptions{
+		Message: "hi",
+		State:   map[string]interface{}{"visit_at": "2026-03-02T09:30:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Messages) != 1 || res.Messages[0] != "See you Mon 3:00PM" {
+		t.Errorf("expected the visit in IST, got %q", res.Messages)
+	}
+}
@@ -0,0 +1,270 @@
+package engine
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/social-media-lead/backend/internal/models"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Keyword match modes supported by trigger_keyword nodes (node.Data["matchMode"])
+const (
+	MatchModeExact      = "exact"
+	MatchModeContains   = "contains"
+	MatchModeStartsWith = "starts_with"
+	MatchModeRegex      = "regex"
+)
+
+// InboundEvent describes an inbound message that may start one or more workflows
+type InboundEvent struct {
+	Platform  string
+	ChannelID int64
+	Text      string
+}
+
+// TriggerMatch is a workflow selected to run for an InboundEvent
+type TriggerMatch struct {
+	Workflow       models.Workflow
+	TriggerNodeID  string
+	TriggerType    models.NodeType
+	MatchedKeyword string // empty for trigger_meta_dm
+	Priority       int
+	StopAfterMatch bool
+}
+
+// MatchTriggers is the single place where inbound messages are matched against workflow triggers.
+//
+// Candidates are ordered deterministically: higher node priority first, keyword triggers before
+// catch-all DM triggers at equal priority, then lowest workflow ID. A matching trigger with
+// stopAfterMatch=true ends evaluation so lower-ranked workflows do not fire.
+func MatchTriggers(workflows []models.Workflow, evt InboundEvent) []TriggerMatch {
+	var candidates []TriggerMatch
+
+	for _, w := range workflows {
+		graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+		if err != nil {
+			log.Printf("[Trigger] Skipping workflow %d: invalid graph: %v", w.ID, err)
+			continue
+		}
+
+		for i := range graph.Nodes {
+			node := &graph.Nodes[i]
+			if !node.Type.IsInbound() {
+				continue
+			}
+
+			keyword, ok := matchTriggerNode(node, evt)
+			if !ok {
+				continue
+			}
+
+			candidates = append(candidates, TriggerMatch{
+				Workflow:       w,
+				TriggerNodeID:  node.ID,
+				TriggerType:    node.Type,
+				MatchedKeyword: keyword,
+				Priority:       int(node.DataFloat("priority", 0)),
+				StopAfterMatch: node.DataBool("stopAfterMatch", false),
+			})
+			// One trigger per workflow is enough to start it
+			break
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.TriggerType != b.TriggerType {
+			return a.TriggerType == models.NodeTypeTriggerKeyword
+		}
+		return a.Workflow.ID < b.Workflow.ID
+	})
+
+	var matches []TriggerMatch
+	for _, m := range candidates {
+		matches = append(matches, m)
+		if m.StopAfterMatch {
+			break
+		}
+	}
+	return matches
+}
+
+// GraphTriggerType returns the type of a graph's first trigger node, or fallback if it has none.
+// Workflows are looked up by trigger type when a message, event or schedule fires, so the stored
+// type has to follow the canvas.
+func GraphTriggerType(nodes json.RawMessage, fallback string) string {
+	var parsed []models.ReactFlowNode
+	if err := json.Unmarshal(nodes, &parsed); err != nil {
+		return fallback
+	}
+	for _, n := range parsed {
+		if n.Type.IsTrigger() {
+			return string(n.Type)
+		}
+	}
+	return fallback
+}
+
+// matchTriggerNode checks the platform/channel filters and, for keyword triggers, the keyword rules.
+// Returns the keyword that matched (empty for DM triggers).
+func matchTriggerNode(node *models.ReactFlowNode, evt InboundEvent) (string, bool) {
+	if platforms := node.DataStrings("platforms"); len(platforms) > 0 && !containsFold(platforms, evt.Platform) {
+		return "", false
+	}
+	if channelIDs := dataInt64s(node, "channelIds"); len(channelIDs) > 0 && !containsInt64(channelIDs, evt.ChannelID) {
+		return "", false
+	}
+
+	if node.Type == models.NodeTypeTriggerDM {
+		return "", true
+	}
+
+	mode := node.DataString("matchMode", MatchModeContains)
+	caseSensitive := node.DataBool("caseSensitive", false)
+	foldMarks := node.DataBool("foldDiacritics", true)
+
+	text := normalizeKeywordText(evt.Text, caseSensitive, foldMarks)
+	if text == "" {
+		return "", false
+	}
+
+	for _, kw := range triggerKeywords(node) {
+		if MatchKeyword(text, kw, mode, caseSensitive, foldMarks) {
+			return kw, true
+		}
+	}
+	return "", false
+}
+
+// MatchKeyword applies a single keyword rule to text that has already been normalised with the
+// same caseSensitive/foldMarks settings.
+func MatchKeyword(text, keyword, mode string, caseSensitive, foldMarks bool) bool {
+	if mode == MatchModeRegex {
+		re, err := keywordRegexp(keyword, caseSensitive, foldMarks)
+		if err != nil {
+			log.Printf("[Trigger] Invalid keyword regex %q: %v", keyword, err)
+			return false
+		}
+		return re.MatchString(text)
+	}
+
+	kw := normalizeKeywordText(keyword, caseSensitive, foldMarks)
+	if kw == "" {
+		return false
+	}
+
+	switch mode {
+	case MatchModeExact:
+		return text == kw
+	case MatchModeStartsWith:
+		return strings.HasPrefix(text, kw)
+	default:
+		return strings.Contains(text, kw)
+	}
+}
+
+// keywordRegexps caches compiled keyword patterns, which are matched against every inbound
+// message. Invalid patterns are cached as their error.
+var keywordRegexps sync.Map
+
+type compiledKeyword struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// keywordRegexp compiles a regex keyword once. The pattern is folded like the text it is
+// matched against, so "café" still matches when diacritics are folded.
+func keywordRegexp(keyword string, caseSensitive, foldMarks bool) (*regexp.Regexp, error) {
+	pattern := keyword
+	if foldMarks {
+		pattern = foldDiacritics(pattern)
+	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	if c, ok := keywordRegexps.Load(pattern); ok {
+		return c.(compiledKeyword).re, c.(compiledKeyword).err
+	}
+	re, err := regexp.Compile(pattern)
+	keywordRegexps.Store(pattern, compiledKeyword{re: re, err: err})
+	return re, err
+}
+
+// triggerKeywords reads node.Data["keywords"], accepting either a list or a comma-separated string
+func triggerKeywords(node *models.ReactFlowNode) []string {
+	if kws := node.DataStrings("keywords"); len(kws) > 0 {
+		return kws
+	}
+	var out []string
+	for _, kw := range strings.Split(node.DataString("keywords", ""), ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			out = append(out, kw)
+		}
+	}
+	return out
+}
+
+// normalizeKeywordText trims, collapses whitespace and optionally lower-cases and strips diacritics
+func normalizeKeywordText(s string, caseSensitive, foldMarks bool) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if !caseSensitive {
+		s = strings.ToLower(s)
+	}
+	if foldMarks {
+		s = foldDiacritics(s)
+	}
+	return s
+}
+
+// foldDiacritics turns "café" into "cafe" by decomposing and dropping combining marks
+func foldDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+func dataInt64s(node *models.ReactFlowNode, key string) []int64 {
+	raw, ok := node.Data[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]int64, 0, len(raw))
+	for _, item := range raw {
+		if f, ok := item.(float64); ok {
+			out = append(out, int64(f))
+		}
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
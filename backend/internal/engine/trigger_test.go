@@ -0,0 +1,112 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func workflowWithTrigger(id int64, nodeType models.NodeType, data map[string]interface{}) models.Workflow {
+	nodes, _ := json.Marshal([]models.ReactFlowNode{{ID: "1", Type: nodeType, Data: data}})
+	return models.Workflow{ID: id, Name: "wf", Nodes: nodes, Edges: []byte("[]")}
+}
+
+func TestMatchKeyword(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		keyword string
+		mode    string
+		want    bool
+	}{
+		{"contains", "what is the price?", "price", engine.MatchModeContains, true},
+		{"contains miss", "hello", "price", engine.MatchModeContains, false},
+		{"exact", "price", "Price", engine.MatchModeExact, true},
+		{"exact miss", "price please", "price", engine.MatchModeExact, false},
+		{"starts with", "price please", "price", engine.MatchModeStartsWith, true},
+		{"regex", "2 bhk available?", `\d\s*bhk`, engine.MatchModeRegex, true},
+		{"invalid regex", "anything", `(`, engine.MatchModeRegex, false},
+		{"regex with diacritics", "cafe menu", `^café\s+menu$`, engine.MatchModeRegex, true},
+		{"diacritics", "cafe menu", "Café", engine.MatchModeContains, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := engine.MatchKeyword(tt.text, tt.keyword, tt.mode, false, true); got != tt.want {
+				t.Errorf("MatchKeyword(%q, %q, %s) = %v, want %v", tt.text, tt.keyword, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTriggers(t *testing.T) {
+	evt := engine.InboundEvent{Platform: "instagram", ChannelID: 7, Text: "  Quel est le PRIX? "}
+
+	t.Run("Keyword with diacritic folding", func(t *testing.T) {
+		w := workflowWithTrigger(1, models.NodeTypeTriggerKeyword, map[string]interface{}{
+			"keywords": []interface{}{"prix"},
+		})
+		matches := engine.MatchTriggers([]models.Workflow{w}, evt)
+		if len(matches) != 1 || matches[0].MatchedKeyword != "prix" {
+			t.Fatalf("expected keyword match, got %+v", matches)
+		}
+	})
+
+	t.Run("Case sensitive miss", func(t *testing.T) {
+		w := workflowWithTrigger(1, models.NodeTypeTriggerKeyword, map[string]interface{}{
+			"keywords":      []interface{}{"prix"},
+			"caseSensitive": true,
+		})
+		if matches := engine.MatchTriggers([]models.Workflow{w}, evt); len(matches) != 0 {
+			t.Fatalf("expected no match, got %+v", matches)
+		}
+	})
+
+	t.Run("Platform and channel filters", func(t *testing.T) {
+		wrongPlatform := workflowWithTrigger(1, models.NodeTypeTriggerDM, map[string]interface{}{
+			"platforms": []interface{}{"whatsapp"},
+		})
+		wrongChannel := workflowWithTrigger(2, models.NodeTypeTriggerDM, map[string]interface{}{
+			"channelIds": []interface{}{float64(8)},
+		})
+		right := workflowWithTrigger(3, models.NodeTypeTriggerDM, map[string]interface{}{
+			"platforms":  []interface{}{"Instagram"},
+			"channelIds": []interface{}{float64(7)},
+		})
+		matches := engine.MatchTriggers([]models.Workflow{wrongPlatform, wrongChannel, right}, evt)
+		if len(matches) != 1 || matches[0].Workflow.ID != 3 {
+			t.Fatalf("expected only workflow 3, got %+v", matches)
+		}
+	})
+
+	t.Run("Priority order and stop after first match", func(t *testing.T) {
+		dm := workflowWithTrigger(1, models.NodeTypeTriggerDM, map[string]interface{}{})
+		low := workflowWithTrigger(2, models.NodeTypeTriggerKeyword, map[string]interface{}{
+			"keywords": "prix",
+		})
+		high := workflowWithTrigger(3, models.NodeTypeTriggerKeyword, map[string]interface{}{
+			"keywords": []interface{}{"prix"},
+			"priority": float64(10),
+		})
+
+		matches := engine.MatchTriggers([]models.Workflow{dm, low, high}, evt)
+		if len(matches) != 3 {
+			t.Fatalf("expected 3 matches, got %d", len(matches))
+		}
+		if matches[0].Workflow.ID != 3 || matches[1].Workflow.ID != 2 || matches[2].Workflow.ID != 1 {
+			t.Errorf("unexpected order: %d, %d, %d", matches[0].Workflow.ID, matches[1].Workflow.ID, matches[2].Workflow.ID)
+		}
+
+		high = workflowWithTrigger(3, models.NodeTypeTriggerKeyword, map[string]interface{}{
+			"keywords":       []interface{}{"prix"},
+			"priority":       float64(10),
+			"stopAfterMatch": true,
+		})
+		matches = engine.MatchTriggers([]models.Workflow{dm, low, high}, evt)
+		if len(matches) != 1 || matches[0].Workflow.ID != 3 {
+			t.Fatalf("expected only workflow 3 after stopAfterMatch, got %+v", matches)
+		}
+	})
+}
@@ -0,0 +1,425 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/ai"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Severity of a ValidationIssue
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// DefaultRouterHandles are the outputs of a logic_ai_router node that does not list its own "routes"
+var DefaultRouterHandles = []string{"hot", "cold"}
+
+// ValidationIssue is a single finding of the graph validator. NodeID/EdgeID point at the
+// offending element so the builder can highlight it.
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	NodeID   string `json:"node_id,omitempty"`
+	EdgeID   string `json:"edge_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i ValidationIssue) String() string {
+	switch {
+	case i.NodeID != "":
+		return fmt.Sprintf("node %s: %s", i.NodeID, i.Message)
+	case i.EdgeID != "":
+		return fmt.Sprintf("edge %s: %s", i.EdgeID, i.Message)
+	}
+	return i.Message
+}
+
+// ValidationReport collects everything wrong with a workflow graph. Errors block publishing;
+// warnings are surfaced in the builder but never block.
+type ValidationReport struct {
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// Valid reports whether the graph has no errors
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Err returns the errors joined into a single error, or nil when the graph is valid
+func (r *ValidationReport) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	msgs := make([]string, len(r.Errors))
+	for i, issue := range r.Errors {
+		msgs[i] = issue.String()
+	}
+	return fmt.Errorf("invalid workflow: %s", strings.Join(msgs, "; "))
+}
+
+func (r *ValidationReport) errorf(nodeID, edgeID, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, ValidationIssue{Severity: SeverityError, NodeID: nodeID, EdgeID: edgeID, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationReport) warnf(nodeID, edgeID, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, ValidationIssue{Severity: SeverityWarning, NodeID: nodeID, EdgeID: edgeID, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateWorkflow parses raw nodes/edges JSON and validates the resulting graph.
+// A parse failure is returned as an error; everything else ends up in the report.
+func ValidateWorkflow(nodes, edges json.RawMessage) (*ValidationReport, error) {
+	graph, err := models.ParseWorkflowGraph(nodes, edges)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow graph: %w", err)
+	}
+	return ValidateWorkflowGraph(graph), nil
+}
+
+// ValidateWorkflowGraph checks the structure and node configuration of a workflow:
+// exactly one trigger, edges between existing nodes, per-type required data, valid source
+// handles, no cycles the walker could spin in without a delay or wait, and reachability.
+func ValidateWorkflowGraph(graph *models.WorkflowGraph) *ValidationReport {
+	r := &ValidationReport{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+
+	nodes := make(map[string]*models.ReactFlowNode, len(graph.Nodes))
+	var triggers []string
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if node.ID == "" {
+			r.errorf("", "", "node %d has no id", i)
+			continue
+		}
+		if _, dup := nodes[node.ID]; dup {
+			r.errorf(node.ID, "", "duplicate node id")
+			continue
+		}
+		nodes[node.ID] = node
+		if node.Type.IsTrigger() {
+			triggers = append(triggers, node.ID)
+		}
+	}
+
+	switch len(triggers) {
+	case 0:
+		r.errorf("", "", "workflow has no trigger node")
+	case 1:
+	default:
+		for _, id := range triggers[1:] {
+			r.errorf(id, "", "workflow must have exactly one trigger (first is %s)", triggers[0])
+		}
+	}
+
+	// Per-node configuration; handles is nil for nodes whose type is unknown
+	handles := make(map[string]map[string]bool, len(nodes))
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		if nodes[node.ID] != node {
+			continue
+		}
+		handles[node.ID] = validateNode(r, node)
+	}
+
+	// Edges
+	outgoing := make(map[string][]string)
+	edgeCount := make(map[string]int)
+	for _, e := range graph.Edges {
+		src, srcOK := nodes[e.Source]
+		_, dstOK := nodes[e.Target]
+		if !srcOK {
+			r.errorf("", e.ID, "source node %q does not exist", e.Source)
+		}
+		if !dstOK {
+			r.errorf("", e.ID, "target node %q does not exist", e.Target)
+		}
+		if !srcOK || !dstOK {
+			continue
+		}
+		if nodes[e.Target].Type.IsTrigger() {
+			r.errorf(e.Target, e.ID, "trigger nodes cannot have incoming edges")
+		}
+		outgoing[e.Source] = append(outgoing[e.Source], e.Target)
+
+		if valid := handles[e.Source]; valid != nil {
+			if !valid[e.SourceHandle] {
+				r.errorf(src.ID, e.ID, "%s node has no output handle %q (valid: %s)", src.Type, e.SourceHandle, handleList(valid))
+			}
+			// Nodes without named outputs only ever follow their first edge
+			if len(valid) == 1 && valid[""] {
+				edgeCount[src.ID]++
+				if edgeCount[src.ID] == 2 {
+					r.warnf(src.ID, e.ID, "node has several outgoing edges; only the first one is followed")
+				}
+			}
+		}
+	}
+
+	validateCycles(r, graph, nodes, outgoing)
+
+	// Reachability from the trigger
+	if len(triggers) > 0 {
+		seen := map[string]bool{triggers[0]: true}
+		queue := []string{triggers[0]}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, next := range outgoing[id] {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		for i := range graph.Nodes {
+			id := graph.Nodes[i].ID
+			if nodes[id] != nil && !seen[id] && !graph.Nodes[i].Type.IsTrigger() {
+				r.warnf(id, "", "node is not reachable from the trigger")
+			}
+		}
+	}
+
+	return r
+}
+
+// validateNode checks a node's type and data and returns the set of source handles its
+// outgoing edges may use ("" is an unlabeled edge). Unknown types return nil.
+func validateNode(r *ValidationReport, node *models.ReactFlowNode) map[string]bool {
+	if !node.Type.IsKnown() {
+		r.errorf(node.ID, "", "unknown node type %q", node.Type)
+		return nil
+	}
+
+	handles := validateNodeData(r, node)
+	// Any node past the trigger may route its failures through an "error" edge
+	if handles != nil && !node.Type.IsTrigger() {
+		handles[HandleError] = true
+		if _, err := parseNodePolicy(node); err != nil {
+			r.errorf(node.ID, "", "%v", err)
+		}
+	}
+	return handles
+}
+
+// validateNodeData checks the type-specific configuration of a node
+func validateNodeData(r *ValidationReport, node *models.ReactFlowNode) map[string]bool {
+
+	if err := validateTemplateFields(node); err != nil {
+		r.errorf(node.ID, "", "%v", err)
+	}
+
+	plain := map[string]bool{"": true}
+	switch node.Type {
+	case models.NodeTypeTriggerKeyword:
+		keywords := triggerKeywords(node)
+		if len(keywords) == 0 {
+			r.errorf(node.ID, "", "keyword trigger needs at least one keyword")
+		}
+		if node.DataString("matchMode", MatchModeContains) == MatchModeRegex {
+			for _, kw := range keywords {
+				if _, err := keywordRegexp(kw, true, false); err != nil {
+					r.errorf(node.ID, "", "invalid keyword pattern %q: %v", kw, err)
+				}
+			}
+		}
+
+	case models.NodeTypeTriggerSchedule:
+		if _, err := ParseCron(node.DataString("cron", "")); err != nil {
+			r.errorf(node.ID, "", "%v", err)
+		}
+		if tz := node.DataString("timezone", ""); tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				r.errorf(node.ID, "", "unknown timezone %q", tz)
+			}
+		}
+
+	case models.NodeTypeTriggerContactEvent:
+		if event := node.DataString("event", ""); !IsValidContactEvent(event) {
+			r.errorf(node.ID, "", "event must be one of %s, %s, %s, %s or %s", ContactEventTagAdded, ContactEventFieldChanged,
+				ContactEventVisitBooked, ContactEventVisitStatusChanged, ContactEventBotResumed)
+		}
+
+	case models.NodeTypeTriggerInactivity:
+		if node.DataFloat("inactiveDays", 0) <= 0 {
+			r.errorf(node.ID, "", "inactiveDays must be greater than 0")
+		}
+
+	case models.NodeTypeActionCallWorkflow:
+		if err := validateCallNode(node); err != nil {
+			r.errorf(node.ID, "", "%v", err)
+		}
+
+	case models.NodeTypeActionHandoff:
+		if _, err := parseHandoffNode(node); err != nil {
+			r.errorf(node.ID, "", "%v", err)
+		}
+
+	case models.NodeTypeActionSendMessage:
+		if strings.TrimSpace(node.DataString("message", "")) == "" {
+			r.errorf(node.ID, "", "message is required")
+		}
+
+	case models.NodeTypeActionAddTag:
+		if strings.TrimSpace(node.DataString("tag", "")) == "" {
+			r.errorf(node.ID, "", "tag is required")
+		}
+
+	case models.NodeTypeActionDelay:
+		if node.DataFloat("delayMs", 0) <= 0 {
+			r.warnf(node.ID, "", "delayMs is not set, the default of 1 minute is used")
+		}
+
+	case models.NodeTypeActionAIReply:
+		if strings.TrimSpace(node.DataString("prompt", "")) == "" {
+			r.warnf(node.ID, "", "prompt is empty, a generic reply prompt is used")
+		}
+		for _, key := range []string{"historyMessages", "maxContextTokens", "maxTokens"} {
+			if node.DataFloat(key, 0) < 0 {
+				r.errorf(node.ID, "", "%s must not be negative", key)
+			}
+		}
+		if t := node.DataFloat("temperature", 0); t < 0 || t > 2 {
+			r.errorf(node.ID, "", "temperature must be between 0 and 2")
+		}
+		if p := node.DataString("provider", ""); p != "" && !ai.KnownProvider(p) {
+			r.errorf(node.ID, "", "unknown provider %q, expected one of %s", p, strings.Join(ai.ProviderNames, ", "))
+		}
+		return map[string]bool{"": true, HandleBudgetExceeded: true}
+
+	case models.NodeTypeActionWaitForReply:
+		return map[string]bool{"": true, HandleReply: true, HandleTimeout: true}
+
+	case models.NodeTypeActionHTTPRequest:
+		if _, err := parseHTTPRequestNode(node); err != nil {
+			r.errorf(node.ID, "", "%v", err)
+		}
+		return map[string]bool{"": true, HandleSuccess: true, HandleError: true}
+
+	case models.NodeTypeLogicTimeWindow:
+		switch node.DataString("mode", TimeWindowBranch) {
+		case TimeWindowBranch:
+			return map[string]bool{HandleInHours: true, HandleOutHours: true}
+		case TimeWindowWait:
+			return map[string]bool{"": true, HandleInHours: true}
+		default:
+			r.errorf(node.ID, "", "mode must be %q or %q", TimeWindowBranch, TimeWindowWait)
+			return nil
+		}
+
+	case models.NodeTypeLogicSplit:
+		branches, err := ParseSplitNode(node)
+		if err != nil {
+			r.errorf(node.ID, "", "%v", err)
+			return nil
+		}
+		valid := make(map[string]bool, len(branches))
+		for _, b := range branches {
+			valid[b.Handle] = true
+		}
+		return valid
+
+	case models.NodeTypeLogicCondition:
+		branches, err := ParseConditionNode(node)
+		if err != nil {
+			r.errorf(node.ID, "", "%v", err)
+			return nil
+		}
+		valid := make(map[string]bool)
+		for _, h := range ConditionNodeHandles(branches) {
+			valid[h] = true
+		}
+		return valid
+
+	case models.NodeTypeLogicAIRouter:
+		routes := node.DataStrings("routes")
+		if len(routes) == 0 {
+			routes = DefaultRouterHandles
+		}
+		valid := make(map[string]bool, len(routes))
+		for _, h := range routes {
+			valid[h] = true
+		}
+		return valid
+	}
+	return plain
+}
+
+// validateCycles reports every strongly connected component the walker could loop through
+// within a single run, i.e. one that contains no delay or wait-for-reply node.
+func validateCycles(r *ValidationReport, graph *models.WorkflowGraph, nodes map[string]*models.ReactFlowNode, outgoing map[string][]string) {
+	index := 0
+	indexes := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+
+	var strongConnect func(id string)
+	strongConnect = func(id string) {
+		indexes[id] = index
+		low[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		selfLoop := false
+		for _, next := range outgoing[id] {
+			if next == id {
+				selfLoop = true
+			}
+			if _, visited := indexes[next]; !visited {
+				strongConnect(next)
+				low[id] = min(low[id], low[next])
+			} else if onStack[next] {
+				low[id] = min(low[id], indexes[next])
+			}
+		}
+
+		if low[id] != indexes[id] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) == 1 && !selfLoop {
+			return
+		}
+		for _, member := range component {
+			switch nodes[member].Type {
+			case models.NodeTypeActionDelay, models.NodeTypeActionWaitForReply:
+				return
+			}
+		}
+		sort.Strings(component)
+		r.errorf(component[0], "", "cycle without a delay or wait node through %s", strings.Join(component, ", "))
+	}
+
+	for i := range graph.Nodes {
+		id := graph.Nodes[i].ID
+		if _, visited := indexes[id]; !visited && nodes[id] != nil {
+			strongConnect(id)
+		}
+	}
+}
+
+func handleList(valid map[string]bool) string {
+	names := make([]string, 0, len(valid))
+	for h := range valid {
+		if h == "" {
+			h = "(unlabeled)"
+		}
+		names = append(names, h)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,281 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+func node(id string, typ models.NodeType, data map[string]interface{}) models.ReactFlowNode {
+	return models.ReactFlowNode{ID: id, Type: typ, Data: data}
+}
+
+func edge(id, src, handle, dst string) models.ReactFlowEdge {
+	return models.ReactFlowEdge{ID: id, Source: src, SourceHandle: handle, Target: dst}
+}
+
+func TestValidateWorkflowGraph(t *testing.T) {
+	msg := map[string]interface{}{"message": "hi"}
+	cond := map[string]interface{}{"condition": map[string]interface{}{"field": "contact.budget", "operator": "exists"}}
+
+	cases := []struct {
+		name      string
+		graph     models.WorkflowGraph
+		errNode   string // node ID of the expected first error, "-" for none
+		errSubstr string
+		warnings  int
+	}{
+		{
+			name: "Valid linear flow",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode: "-",
+		},
+		{
+			name:      "No trigger",
+			graph:     models.WorkflowGraph{Nodes: []models.ReactFlowNode{node("2", models.NodeTypeActionSendMessage, msg)}},
+			errSubstr: "no trigger",
+			warnings:  0,
+		},
+		{
+			name: "Two triggers",
+			graph: models.WorkflowGraph{Nodes: []models.ReactFlowNode{
+				node("1", models.NodeTypeTriggerDM, nil),
+				node("2", models.NodeTypeTriggerKeyword, map[string]interface{}{"keywords": "price"}),
+			}},
+			errNode:   "2",
+			errSubstr: "exactly one trigger",
+		},
+		{
+			name: "Dangling edge",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "9")},
+			},
+			errSubstr: `target node "9" does not exist`,
+		},
+		{
+			name: "Unknown type",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", "action_teleport", nil)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "unknown node type",
+		},
+		{
+			name: "Missing message",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, nil)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "message is required",
+		},
+		{
+			name: "Invalid condition handle",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeLogicCondition, cond), node("3", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", "maybe", "3")},
+			},
+			errNode:   "2",
+			errSubstr: `no output handle "maybe"`,
+		},
+		{
+			name: "Router with default routes",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeLogicAIRouter, nil), node("3", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", "hot", "3")},
+			},
+			errNode: "-",
+		},
+		{
+			name: "Cycle without delay",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, msg), node("3", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", "", "3"), edge("e3", "3", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "cycle without a delay",
+		},
+		{
+			name: "Cycle through wait is allowed",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, msg), node("3", models.NodeTypeActionWaitForReply, nil)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", "", "3"), edge("e3", "3", engine.HandleReply, "2")},
+			},
+			errNode: "-",
+		},
+		{
+			name: "Error edge on an action",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, msg), node("3", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", engine.HandleError, "3")},
+			},
+			errNode: "-",
+		},
+		{
+			name: "Error edge on a trigger",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", engine.HandleError, "2")},
+			},
+			errNode:   "1",
+			errSubstr: `no output handle "error"`,
+		},
+		{
+			name: "Too many retries",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, map[string]interface{}{"message": "hi", "retryCount": float64(50)})},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "retryCount",
+		},
+		{
+			name: "Time window without a default edge",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeLogicTimeWindow, nil), node("3", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", "", "3")},
+			},
+			errNode:   "2",
+			errSubstr: `no output handle ""`,
+		},
+		{
+			name: "Invalid cron schedule",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerSchedule, map[string]interface{}{"cron": "every monday"}), node("2", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "1",
+			errSubstr: "invalid cron expression",
+		},
+		{
+			name: "Unknown contact event",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerContactEvent, map[string]interface{}{"event": "tag_removed"}), node("2", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "1",
+			errSubstr: "event must be one of",
+		},
+		{
+			name: "Inactivity trigger without days",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerInactivity, nil), node("2", models.NodeTypeActionSendMessage, msg)},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "1",
+			errSubstr: "inactiveDays",
+		},
+		{
+			name: "Split edge on unknown branch",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{
+					node("1", models.NodeTypeTriggerDM, nil),
+					node("2", models.NodeTypeLogicSplit, map[string]interface{}{"branches": []interface{}{
+						map[string]interface{}{"handle": "a", "weight": 50.0},
+						map[string]interface{}{"handle": "b", "weight": 50.0},
+					}}),
+					node("3", models.NodeTypeActionSendMessage, msg),
+				},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", "c", "3")},
+			},
+			errNode:   "2",
+			errSubstr: `"c"`,
+		},
+		{
+			name: "Workflow call without a target",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionCallWorkflow, map[string]interface{}{"inputs": map[string]interface{}{"budget": "{{contact.budget}}"}})},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "workflowId is required",
+		},
+		{
+			name: "AI reply temperature out of range",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionAIReply, map[string]interface{}{"prompt": "Help", "temperature": float64(3)})},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "temperature must be between 0 and 2",
+		},
+		{
+			name: "AI reply fallback with an unknown variable",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionAIReply, map[string]interface{}{"prompt": "Help", "fallbackMessage": "Hi {{contact.nme}}"})},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "fallbackMessage",
+		},
+		{
+			name: "Handoff escalation without a team",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionHandoff, map[string]interface{}{"team": "sales", "slaMinutes": float64(15)})},
+				Edges: []models.ReactFlowEdge{edge("e1", "1", "", "2")},
+			},
+			errNode:   "2",
+			errSubstr: "escalateTeam is required",
+		},
+		{
+			name: "Unreachable node is a warning",
+			graph: models.WorkflowGraph{
+				Nodes: []models.ReactFlowNode{node("1", models.NodeTypeTriggerDM, nil), node("2", models.NodeTypeActionSendMessage, msg)},
+			},
+			errNode:  "-",
+			warnings: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := engine.ValidateWorkflowGraph(&tc.graph)
+			if tc.errNode == "-" {
+				if !report.Valid() {
+					t.Fatalf("expected valid graph, got %v", report.Err())
+				}
+			} else {
+				if report.Valid() {
+					t.Fatal("expected validation errors")
+				}
+				first := report.Errors[0]
+				if first.NodeID != tc.errNode || !strings.Contains(first.Message, tc.errSubstr) {
+					t.Errorf("unexpected first error %+v", first)
+				}
+			}
+			if len(report.Warnings) != tc.warnings {
+				t.Errorf("expected %d warnings, got %+v", tc.warnings, report.Warnings)
+			}
+		})
+	}
+}
+
+func TestStepBudget(t *testing.T) {
+	ms := newMemStore()
+	// A delay-free loop that validation would reject but that may already be stored
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			node("1", models.NodeTypeTriggerDM, nil),
+			node("2", models.NodeTypeLogicCondition, map[string]interface{}{"condition": map[string]interface{}{"field": "contact.name", "operator": "exists"}}),
+		},
+		[]models.ReactFlowEdge{edge("e1", "1", "", "2"), edge("e2", "2", engine.HandleTrue, "2")},
+	)
+	gw := engine.NewGraphWalker(ms, nil, nil, nil)
+	gw.MaxSteps = 10
+
+	err := gw.StartWorkflow(context.Background(), 1, 1, map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), "step budget") {
+		t.Fatalf("expected step budget error, got %v", err)
+	}
+	if exec := ms.onlyExecution(t); exec.Status != "failed" {
+		t.Errorf("expected failed execution, got %s", exec.Status)
+	}
+}
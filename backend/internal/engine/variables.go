@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Variables is the data a node can read while it executes: the contact record,
+// the execution's StateData, the inbound message that woke the execution up and,
+// when the tenant has them, the contact's latest visit and the wizard project config.
+type Variables struct {
+	Contact *models.Contact
+	State   map[string]interface{}
+	Message string
+	Visit   *models.Visit
+	Project *models.PropertyVisitConfig
+
+	// Secrets is only populated for action_http_request nodes ({{secret.NAME}})
+	Secrets map[string]string
+
+	// Location is the tenant's timezone, used by the date filter. Nil keeps times as stored.
+	Location *time.Location
+}
+
+// contactFields maps the public variable names to Contact accessors
+var contactFields = map[string]func(c *models.Contact) interface{}{
+	"name":               func(c *models.Contact) interface{} { return c.Name },
+	"phone":              func(c *models.Contact) interface{} { return c.Phone },
+	"email":              func(c *models.Contact) interface{} { return c.Email },
+	"budget":             func(c *models.Contact) interface{} { return c.Budget },
+	"preferred_location": func(c *models.Contact) interface{} { return c.PreferredLocation },
+	"purchase_timeline":  func(c *models.Contact) interface{} { return c.PurchaseTimeline },
+	"is_hot_lead":        func(c *models.Contact) interface{} { return c.IsHotLead },
+	"tags":               func(c *models.Contact) interface{} { return c.Tags },
+	"booking_state":      func(c *models.Contact) interface{} { return c.BookingState },
+	"platform":           func(c *models.Contact) interface{} { return c.Platform },
+}
+
+var visitFields = map[string]func(v *models.Visit) interface{}{
+	"time":    func(v *models.Visit) interface{} { return v.VisitTime },
+	"project": func(v *models.Visit) interface{} { return v.ProjectName },
+	"status":  func(v *models.Visit) interface{} { return v.Status },
+}
+
+var projectFields = map[string]func(p *models.PropertyVisitConfig) interface{}{
+	"name":         func(p *models.PropertyVisitConfig) interface{} { return p.ProjectName },
+	"brochure_url": func(p *models.PropertyVisitConfig) interface{} { return p.BrochureURL },
+	"agent_phone":  func(p *models.PropertyVisitConfig) interface{} { return p.AgentPhone },
+}
+
+// IsKnownVariable reports whether path can ever resolve, independent of runtime data.
+// state.* is open-ended because nodes write arbitrary keys into StateData.
+func IsKnownVariable(path string) bool {
+	scope, key, _ := strings.Cut(path, ".")
+	switch scope {
+	case "contact":
+		_, ok := contactFields[key]
+		return ok
+	case "state":
+		return key != ""
+	case "message":
+		return key == "text"
+	case "visit":
+		_, ok := visitFields[key]
+		return ok
+	case "project":
+		_, ok := projectFields[key]
+		return ok
+	}
+	return false
+}
+
+// Lookup resolves a dotted variable path such as "contact.budget", "state.answer_budget",
+// "state.event.tag", "message.text", "visit.time" or "project.name". The second return value
+// is false when the variable has no value.
+func (v *Variables) Lookup(path string) (interface{}, bool) {
+	scope, key, _ := strings.Cut(path, ".")
+	switch scope {
+	case "contact":
+		getter, ok := contactFields[key]
+		if !ok || v.Contact == nil {
+			return nil, false
+		}
+		return getter(v.Contact), true
+	case "state":
+		if v.State == nil {
+			return nil, false
+		}
+		if val, ok := v.State[key]; ok {
+			return val, true
+		}
+		return lookupNested(v.State, key)
+	case "message":
+		if key != "text" {
+			return nil, false
+		}
+		return v.Message, true
+	case "visit":
+		getter, ok := visitFields[key]
+		if !ok || v.Visit == nil {
+			return nil, false
+		}
+		return getter(v.Visit), true
+	case "project":
+		getter, ok := projectFields[key]
+		if !ok || v.Project == nil {
+			return nil, false
+		}
+		return getter(v.Project), true
+	case "secret":
+		val, ok := v.Secrets[key]
+		return val, ok
+	}
+	return nil, false
+}
+
+// lookupNested walks a dotted path through nested state objects, e.g. "event.tag"
+func lookupNested(state map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = state
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Values of WorkflowExecution.WaitingFor while Status is "waiting"
+const (
+	WaitingForDelay = "delay"
+	WaitingForReply = "reply"
+	WaitingForAgent = "agent" // the contact's bot is paused; see ResumeAgentWaits
+	WaitingForRetry = "retry" // backing off before retrying a failed node
+)
+
+// Source handles of an action_wait_for_reply node
+const (
+	HandleReply   = "reply"
+	HandleTimeout = "timeout"
+)
+
+// ReplyTimeoutPayload is the body of the "workflow:reply_timeout" task
+type ReplyTimeoutPayload struct {
+	ExecutionID int64  `json:"execution_id"`
+	NodeID      string `json:"node_id"`
+	WaitToken   string `json:"wait_token"`
+}
+
+// suspendForReply parks the execution on an action_wait_for_reply node until the contact's next
+// inbound message (ResumeWithReply) or the optional timeout (HandleReplyTimeout).
+func (gw *GraphWalker) suspendForReply(ctx context.Context, exec *models.WorkflowExecution, node *models.ReactFlowNode, stateData map[string]interface{}) error {
+	// The token lets a timeout task recognise that it belongs to an older wait on the same node
+	token := fmt.Sprintf("%s:%d", node.ID, time.Now().UnixNano())
+	stateData["wait_token"] = token
+
+	stateBytes, _ := json.Marshal(stateData)
+	exec.StateData = stateBytes
+	exec.Status = "waiting"
+	exec.WaitingFor = WaitingForReply
+	exec.CurrentNodeID = node.ID
+	if err := gw.Store.UpdateWorkflowExecution(ctx, exec); err != nil {
+		return fmt.Errorf("failed to suspend execution: %w", err)
+	}
+
+	log.Printf("Execution %d waiting for reply at node %s", exec.ID, node.ID)
+
+	timeoutMs := node.DataFloat("timeoutMs", 0)
+	rec := newStepRecord()
+	rec.out("waiting_for", WaitingForReply)
+	rec.out("timeout_ms", timeoutMs)
+	gw.saveStep(ctx, exec, node, rec, nil)
+
+	if timeoutMs <= 0 {
+		return nil
+	}
+
+	payload, _ := json.Marshal(ReplyTimeoutPayload{ExecutionID: exec.ID, NodeID: node.ID, WaitToken: token})
+	task := asynq.NewTask("workflow:reply_timeout", payload)
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+
+	if gw.AsynqClient == nil {
+		log.Printf("WARNING: AsynqClient is nil, reply timeout for execution %d will never fire.", exec.ID)
+		return nil
+	}
+	if _, err := gw.AsynqClient.Enqueue(task, asynq.ProcessIn(timeout)); err != nil {
+		log.Printf("ERROR: Failed to enqueue reply timeout for execution %d: %v", exec.ID, err)
+	}
+	return nil
+}
+
+// ResumeWithReply continues an execution parked on action_wait_for_reply with the contact's reply.
+// The reply is stored in StateData under node.Data["variable"] (default "last_reply").
+func (gw *GraphWalker) ResumeWithReply(ctx context.Context, executionID int64, reply string) error {
+	// Everything that can fail is loaded before the claim, so an error leaves the execution
+	// waiting instead of running with no task to move it on
+	exec, err := gw.Store.GetWorkflowExecutionByID(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	graph, err := gw.loadGraph(ctx, exec)
+	if err != nil {
+		return err
+	}
+
+	claimed, err := gw.Store.ClaimWaitingExecution(ctx, executionID, WaitingForReply)
+	if err != nil {
+		return fmt.Errorf("failed to claim execution: %w", err)
+	}
+	if !claimed {
+		log.Printf("Execution %d is no longer waiting for a reply, skipping", executionID)
+		return nil
+	}
+
+	stateData := decodeState(exec.StateData)
+	delete(stateData, "wait_token")
+
+	node := findNode(graph.Nodes, exec.CurrentNodeID)
+	if node == nil {
+		return gw.continueFrom(ctx, exec, stateData, "")
+	}
+
+	stateData[node.DataString("variable", "last_reply")] = reply
+	stateData["received_message"] = reply
+	log.Printf("Execution %d received reply at node %s", executionID, node.ID)
+
+	rec := newStepRecord()
+	rec.in("reply", reply)
+	rec.branch = HandleReply
+	gw.saveStep(ctx, exec, node, rec, nil)
+
+	return gw.continueFrom(ctx, exec, stateData, findHandleOrDefault(graph.Edges, node.ID, HandleReply))
+}
+
+// HandleReplyTimeout routes a still-waiting execution down the node's "timeout" handle.
+// Stale tasks (the contact already replied, or the execution is waiting on a newer visit
+// to the same node) are ignored.
+func (gw *GraphWalker) HandleReplyTimeout(ctx context.Context, p ReplyTimeoutPayload) error {
+	exec, err := gw.Store.GetWorkflowExecutionByID(ctx, p.ExecutionID)
+	if err != nil {
+		return err
+	}
+	if exec.Status != "waiting" || exec.WaitingFor != WaitingForReply || exec.CurrentNodeID != p.NodeID {
+		return nil
+	}
+	stateData := decodeState(exec.StateData)
+	if token, _ := stateData["wait_token"].(string); token != p.WaitToken {
+		return nil
+	}
+
+	graph, err := gw.loadGraph(ctx, exec)
+	if err != nil {
+		return err
+	}
+	claimed, err := gw.Store.ClaimWaitingExecution(ctx, exec.ID, WaitingForReply)
+	if err != nil || !claimed {
+		return err
+	}
+	delete(stateData, "wait_token")
+	log.Printf("Execution %d timed out waiting for reply at node %s", exec.ID, p.NodeID)
+
+	if node := findNode(graph.Nodes, p.NodeID); node != nil {
+		rec := newStepRecord()
+		rec.branch = HandleTimeout
+		gw.saveStep(ctx, exec, node, rec, nil)
+	}
+
+	// Without an explicit timeout edge the flow simply ends
+	return gw.continueFrom(ctx, exec, stateData, gw.findNextNode(graph.Edges, p.NodeID, HandleTimeout))
+}
+
+// continueFrom saves state and walks on from nextNodeID, or completes the execution if there is none
+func (gw *GraphWalker) continueFrom(ctx context.Context, exec *models.WorkflowExecution, stateData map[string]interface{}, nextNodeID string) error {
+	stateBytes, _ := json.Marshal(stateData)
+	exec.StateData = stateBytes
+	exec.WaitingFor = ""
+
+	if nextNodeID == "" {
+		return gw.finish(ctx, exec, "completed")
+	}
+
+	exec.Status = "running"
+	exec.CurrentNodeID = nextNodeID
+	if err := gw.Store.UpdateWorkflowExecution(ctx, exec); err != nil {
+		return err
+	}
+	return gw.ResumeExecution(ctx, exec.ID)
+}
+
+func decodeState(raw []byte) map[string]interface{} {
+	var stateData map[string]interface{}
+	if err := json.Unmarshal(raw, &stateData); err != nil || stateData == nil {
+		stateData = make(map[string]interface{})
+	}
+	return stateData
+}
@@ -0,0 +1,132 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/social-media-lead/backend/internal/engine"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// waitWorkflow: trigger -> wait (answer_budget) -> reply: condition, timeout: condition
+func waitWorkflow(t *testing.T, ms *memStore) {
+	ms.addWorkflow(t, 1,
+		[]models.ReactFlowNode{
+			{ID: "1", Type: models.NodeTypeTriggerDM},
+			{ID: "2", Type: models.NodeTypeActionWaitForReply, Data: map[string]interface{}{"variable": "answer_budget", "timeoutMs": float64(60000)}},
+			{ID: "3", Type: models.NodeTypeLogicCondition, Data: map[string]interface{}{
+				"condition": map[string]interface{}{"field": "state.answer_budget", "operator": "exists"},
+			}},
+			{ID: "4", Type: models.NodeTypeLogicCondition, Data: map[string]interface{}{
+				"condition": map[string]interface{}{"field": "state.answer_budget", "operator": "not_exists"},
+			}},
+		},
+		[]models.ReactFlowEdge{
+			{ID: "e1", Source: "1", Target: "2"},
+			{ID: "e2", Source: "2", SourceHandle: engine.HandleTimeout, Target: "4"},
+			{ID: "e3", Source: "2", Target: "3"},
+		},
+	)
+}
+
+func TestWaitForReply(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reply resumes and captures variable", func(t *testing.T) {
+		ms := newMemStore()
+		waitWorkflow(t, ms)
+		gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{"received_message": "hi"}); err != nil {
+			t.Fatal(err)
+		}
+		exec := ms.onlyExecution(t)
+		if exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForReply || exec.CurrentNodeID != "2" {
+			t.Fatalf("expected execution parked on node 2, got %+v", exec)
+		}
+
+		if err := gw.ResumeWithReply(ctx, exec.ID, "80 lakhs"); err != nil {
+			t.Fatal(err)
+		}
+		exec = ms.onlyExecution(t)
+		if exec.Status != "completed" || exec.CurrentNodeID != "3" {
+			t.Fatalf("expected completion on reply path (node 3), got %s at %s", exec.Status, exec.CurrentNodeID)
+		}
+		var state map[string]interface{}
+		_ = json.Unmarshal(exec.StateData, &state)
+		if state["answer_budget"] != "80 lakhs" {
+			t.Errorf("expected reply captured in state, got %v", state["answer_budget"])
+		}
+
+		// A second reply must not re-run a finished execution
+		if err := gw.ResumeWithReply(ctx, exec.ID, "again"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Timeout routes to timeout handle", func(t *testing.T) {
+		ms := newMemStore()
+		waitWorkflow(t, ms)
+		gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		exec := ms.onlyExecution(t)
+		var state map[string]interface{}
+		_ = json.Unmarshal(exec.StateData, &state)
+		token, _ := state["wait_token"].(string)
+
+		// Stale token is ignored
+		if err := gw.HandleReplyTimeout(ctx, engine.ReplyTimeoutPayload{ExecutionID: exec.ID, NodeID: "2", WaitToken: "old"}); err != nil {
+			t.Fatal(err)
+		}
+		if ms.onlyExecution(t).Status != "waiting" {
+			t.Fatal("stale timeout should not resume the execution")
+		}
+
+		if err := gw.HandleReplyTimeout(ctx, engine.ReplyTimeoutPayload{ExecutionID: exec.ID, NodeID: "2", WaitToken: token}); err != nil {
+			t.Fatal(err)
+		}
+		exec = ms.onlyExecution(t)
+		if exec.Status != "completed" || exec.CurrentNodeID != "4" {
+			t.Fatalf("expected completion on timeout path (node 4), got %s at %s", exec.Status, exec.CurrentNodeID)
+		}
+	})
+	t.Run("Failed resume leaves the execution waiting", func(t *testing.T) {
+		ms := newMemStore()
+		waitWorkflow(t, ms)
+		gw := engine.NewGraphWalker(ms, nil, nil, nil)
+
+		if err := gw.StartWorkflow(ctx, 1, 1, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		exec := ms.onlyExecution(t)
+		var state map[string]interface{}
+		_ = json.Unmarshal(exec.StateData, &state)
+		token, _ := state["wait_token"].(string)
+
+		// The pinned version cannot be loaded, so neither the reply nor the timeout can run
+		versions := ms.versions
+		ms.versions = nil
+		if err := gw.ResumeWithReply(ctx, exec.ID, "80 lakhs"); err == nil {
+			t.Fatal("expected the reply to fail")
+		}
+		if err := gw.HandleReplyTimeout(ctx, engine.ReplyTimeoutPayload{ExecutionID: exec.ID, NodeID: "2", WaitToken: token}); err == nil {
+			t.Fatal("expected the timeout to fail")
+		}
+		if exec = ms.onlyExecution(t); exec.Status != "waiting" || exec.WaitingFor != engine.WaitingForReply {
+			t.Fatalf("expected the execution to stay waiting, got %s/%s", exec.Status, exec.WaitingFor)
+		}
+
+		// Once the version is back, the retried timeout goes through
+		ms.versions = versions
+		if err := gw.HandleReplyTimeout(ctx, engine.ReplyTimeoutPayload{ExecutionID: exec.ID, NodeID: "2", WaitToken: token}); err != nil {
+			t.Fatal(err)
+		}
+		if exec = ms.onlyExecution(t); exec.Status != "completed" || exec.CurrentNodeID != "4" {
+			t.Fatalf("expected completion on timeout path (node 4), got %s at %s", exec.Status, exec.CurrentNodeID)
+		}
+	})
+}
@@ -3,8 +3,11 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -20,14 +23,37 @@ type GraphWalker struct {
 	LLMClient   ai.LLMClient
 	AsynqClient *asynq.Client
 	MetaClient  *meta.Client
+
+	// HTTPClient and RateLimiter back action_http_request nodes. HTTPClient should come from
+	// NewSafeHTTPClient; a nil RateLimiter disables per-tenant throttling.
+	HTTPClient    *http.Client
+	RateLimiter   RateLimiter
+	HTTPRateLimit int64
+
+	// MaxSteps caps how many nodes a single ResumeExecution may run before the execution is
+	// failed. Save-time validation rejects delay-free cycles; this is the backstop.
+	MaxSteps int
+
+	// LeadExtractionDelay is how long after a contact's last inbound message its lead fields are
+	// extracted from the conversation (see ScheduleLeadExtraction). Zero disables extraction.
+	LeadExtractionDelay time.Duration
+
+	// sim is set while the walker dry-runs a workflow (see Simulate)
+	sim *simulation
 }
 
+// DefaultMaxSteps is the per-run node budget used when GraphWalker.MaxSteps is not set
+const DefaultMaxSteps = 100
+
 func NewGraphWalker(store store.Store, llmClient ai.LLMClient, asynqClient *asynq.Client, metaClient *meta.Client) *GraphWalker {
 	return &GraphWalker{
 		Store:       store,
 		LLMClient:   llmClient,
 		AsynqClient: asynqClient,
 		MetaClient:  metaClient,
+
+		HTTPRateLimit: 60,
+		MaxSteps:      DefaultMaxSteps,
 	}
 }
 
@@ -38,42 +64,84 @@ func (gw *GraphWalker) StartWorkflow(ctx context.Context, workflowID, contactID
 		return fmt.Errorf("failed to get workflow: %w", err)
 	}
 
-	graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+	exec, err := gw.newExecution(ctx, w, contactID, initialState)
 	if err != nil {
-		return fmt.Errorf("failed to parse workflow graph: %w", err)
+		return err
+	}
+
+	// Simulations always start; real runs go through the cooldown and re-entry policy
+	if gw.sim != nil {
+		if err := gw.Store.CreateWorkflowExecution(ctx, exec); err != nil {
+			return fmt.Errorf("failed to create execution: %w", err)
+		}
+	} else {
+		if err := gw.checkCooldown(ctx, w, contactID); err != nil {
+			return err
+		}
+		queued, err := gw.createExecution(ctx, w, exec)
+		if err != nil {
+			if errors.Is(err, ErrEntrySkipped) {
+				return err
+			}
+			return fmt.Errorf("failed to create execution: %w", err)
+		}
+		if queued {
+			return nil
+		}
 	}
 
-	// Find the trigger node
+	// Begin execution loop
+	return gw.ResumeExecution(ctx, exec.ID)
+}
+
+// newExecution builds (but does not save) an execution of w's published version, positioned on
+// its trigger node
+func (gw *GraphWalker) newExecution(ctx context.Context, w *models.Workflow, contactID int64, initialState map[string]interface{}) (*models.WorkflowExecution, error) {
+	// Executions run on the published snapshot, never on the live (editable) graph
+	version, err := gw.publishedVersion(ctx, w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow version: %w", err)
+	}
+
+	graph, err := models.ParseWorkflowGraph(version.Nodes, version.Edges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow graph: %w", err)
+	}
+
+	// Find the trigger node. MatchTriggers tells us which one fired via "trigger_node_id";
+	// otherwise fall back to the first trigger on the canvas.
 	var startNode *models.ReactFlowNode
-	for _, n := range graph.Nodes {
-		if n.Type == models.NodeTypeTriggerDM || n.Type == models.NodeTypeTriggerKeyword {
-			// Re-assign explicitly because implicit memory address of loop var is bad conceptually
-			nCopy := n
-			startNode = &nCopy
-			break
+	if triggerID, ok := initialState["trigger_node_id"].(string); ok && triggerID != "" {
+		startNode = findNode(graph.Nodes, triggerID)
+		if startNode != nil && !startNode.Type.IsTrigger() {
+			return nil, fmt.Errorf("node %s in workflow %d is not a trigger", triggerID, w.ID)
+		}
+	}
+	if startNode == nil {
+		for _, n := range graph.Nodes {
+			if n.Type.IsTrigger() {
+				// Re-assign explicitly because implicit memory address of loop var is bad conceptually
+				nCopy := n
+				startNode = &nCopy
+				break
+			}
 		}
 	}
 
 	if startNode == nil {
-		return fmt.Errorf("no trigger node found in workflow %d", workflowID)
+		return nil, fmt.Errorf("no trigger node found in workflow %d", w.ID)
 	}
 
 	stateBytes, _ := json.Marshal(initialState)
 
-	exec := &models.WorkflowExecution{
-		WorkflowID:    workflowID,
+	return &models.WorkflowExecution{
+		WorkflowID:        w.ID,
+		WorkflowVersionID: &version.ID,
 		ContactID:     contactID,
 		CurrentNodeID: startNode.ID,
 		Status:        "running",
 		StateData:     stateBytes,
-	}
-
-	if err := gw.Store.CreateWorkflowExecution(ctx, exec); err != nil {
-		return fmt.Errorf("failed to create execution: %w", err)
-	}
-
-	// Begin execution loop
-	return gw.ResumeExecution(ctx, exec.ID)
+	}, nil
 }
 
 // ResumeExecution picks up an execution from its CurrentNodeID and walks the DAG
@@ -83,42 +151,152 @@ func (gw *GraphWalker) ResumeExecution(ctx context.Context, executionID int64) e
 		return err
 	}
 
-	w, err := gw.Store.GetWorkflowByID(ctx, exec.WorkflowID)
-	if err != nil {
-		return err
+	// Only running executions and elapsed delays may walk; anything else is a stale task
+	switch {
+	case exec.Status == "running":
+		exec.WaitingFor = ""
+	case exec.Status == "waiting" && (exec.WaitingFor == WaitingForDelay || exec.WaitingFor == WaitingForRetry || exec.WaitingFor == ""):
+		// A resumed pause can leave two tasks for the same delay; only one may claim it
+		claimed, err := gw.Store.ClaimWaitingExecution(ctx, executionID, exec.WaitingFor)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			log.Printf("Execution %d delay already claimed, not resuming", executionID)
+			return nil
+		}
+		exec.Status = "running"
+		exec.WaitingFor = ""
+	default:
+		log.Printf("Execution %d is %s (waiting for %q), not resuming", executionID, exec.Status, exec.WaitingFor)
+		return nil
 	}
 
-	graph, err := models.ParseWorkflowGraph(w.Nodes, w.Edges)
+	graph, err := gw.loadGraph(ctx, exec)
 	if err != nil {
 		return err
 	}
 
-	var stateData map[string]interface{}
-	if err := json.Unmarshal(exec.StateData, &stateData); err != nil {
-		stateData = make(map[string]interface{})
-	}
+	stateData := decodeState(exec.StateData)
+	delete(stateData, "delay_until")
+
+	goals := gw.workflowGoals(ctx, exec.WorkflowID)
 
 	currentNodeID := exec.CurrentNodeID
 
-	for {
+	maxSteps := gw.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	for steps := 0; ; steps++ {
+		if steps >= maxSteps {
+			exec.CurrentNodeID = currentNodeID
+			gw.finish(ctx, exec, "failed")
+			return fmt.Errorf("execution %d exceeded the step budget of %d nodes", executionID, maxSteps)
+		}
+
+		// Someone may have paused, cancelled or ended the execution while the last node ran
+		if steps > 0 {
+			if current, err := gw.Store.GetWorkflowExecutionByID(ctx, executionID); err == nil && current.Status != "running" {
+				log.Printf("Execution %d is %s, stopping", executionID, current.Status)
+				return nil
+			}
+		}
+
 		// Find current node
 		node := findNode(graph.Nodes, currentNodeID)
 		if node == nil {
-			exec.Status = "completed"
-			gw.Store.UpdateWorkflowExecution(ctx, exec)
+			gw.finish(ctx, exec, "completed")
 			log.Printf("Execution %d completed. Node %s not found (end of flow).", executionID, currentNodeID)
 			return nil
 		}
 
+		if goal := gw.reachedGoal(ctx, exec, goals, stateData); goal != nil {
+			gw.endOnGoal(ctx, exec, goal)
+			return nil
+		}
+
+		// Wait-for-reply nodes park the execution until the contact answers
+		if node.Type == models.NodeTypeActionWaitForReply {
+			if gw.sim != nil {
+				if next, park := gw.simulateWait(ctx, exec, node, graph, stateData); !park {
+					if next == "" {
+						exec.StateData, _ = json.Marshal(stateData)
+						return gw.finish(ctx, exec, "completed")
+					}
+					currentNodeID = next
+					exec.CurrentNodeID = next
+					continue
+				}
+			}
+			return gw.suspendForReply(ctx, exec, node, stateData)
+		}
+
+		// An agent has taken over the conversation; hold every message until they hand it back
+		if sendsMessage(node.Type) {
+			if contact, err := gw.Store.GetContactByID(ctx, exec.ContactID); err == nil && contact.BotPaused {
+				return gw.parkForAgent(ctx, exec, node, stateData)
+			}
+		}
+
+		if gw.sim != nil {
+			gw.sim.node = node.ID
+		}
+
 		// Execute node logic
 		log.Printf("Executing Node %s (%s) for Execution %d", node.ID, node.Type, executionID)
 		
-		nextNodeID, err := gw.processNode(ctx, node, graph, exec, stateData)
+		// Invalid policies are rejected on publish; a bad one on an old version just means no retries
+		policy, _ := parseNodePolicy(node)
+		nodeCtx, cancel := policy.context(ctx)
+		rec := newStepRecord()
+		nextNodeID, err := gw.processNode(nodeCtx, node, graph, exec, stateData, rec)
+		if err != nil && errors.Is(nodeCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %v: %w", policy.timeout, err)
+		}
+		cancel()
+
+		if errors.Is(err, errSuspended) {
+			// The node parked the execution itself (see runCallWorkflow)
+			return nil
+		}
 		if err != nil {
-			exec.Status = "failed"
-			gw.Store.UpdateWorkflowExecution(ctx, exec)
-			return fmt.Errorf("node %s failed: %w", node.ID, err)
+			if errors.Is(err, ErrBotPaused) {
+				// The bot was paused while the node ran
+				return gw.parkForAgent(ctx, exec, node, stateData)
+			}
+
+			if retry := retriesDone(stateData, node.ID) + 1; retry <= policy.retries {
+				rec.out("retry", retry)
+				gw.saveStep(ctx, exec, node, rec, err)
+				if gw.sim != nil {
+					// Simulations retry at once
+					stateData["retry_node"] = node.ID
+					stateData["retry_count"] = float64(retry)
+					gw.sim.record(EffectDelay, map[string]interface{}{"retry": retry, "delay_ms": policy.backoffFor(retry).Milliseconds()})
+					continue
+				}
+				return gw.scheduleRetry(ctx, exec, node, stateData, retry, policy.backoffFor(retry))
+			}
+
+			// Retries are used up: take the error edge if there is one
+			nextNodeID = gw.findNextNode(graph.Edges, node.ID, HandleError)
+			if nextNodeID == "" {
+				gw.saveStep(ctx, exec, node, rec, err)
+				gw.finish(ctx, exec, "failed")
+				return fmt.Errorf("node %s failed: %w", node.ID, err)
+			}
+			log.Printf("[GraphWalker] Node %s failed, following error handle: %v", node.ID, err)
+			rec.branch = HandleError
+			stateData["last_error"] = err.Error()
+			stateData["last_error_node"] = node.ID
+		}
+		clearRetry(stateData)
+		if nextNodeID != "" {
+			rec.out("next_node_id", nextNodeID)
 		}
+		gw.saveStep(ctx, exec, node, rec, err)
 
 		// Update state in DB
 		newStateBytes, _ := json.Marshal(stateData)
@@ -126,39 +304,38 @@ func (gw *GraphWalker) ResumeExecution(ctx context.Context, executionID int64) e
 		
 		if nextNodeID == "" {
 			// Flow finished
-			exec.Status = "completed"
 			exec.CurrentNodeID = node.ID // Keep last valid node
-			gw.Store.UpdateWorkflowExecution(ctx, exec)
+			gw.finish(ctx, exec, "completed")
 			log.Printf("Execution %d completed successfully.", executionID)
 			return nil
 		}
 
-		// If it's a delay node, we would pause here and rely on Asynq to resume later
-		if node.Type == models.NodeTypeActionDelay {
-			exec.Status = "waiting"
-			exec.CurrentNodeID = nextNodeID
-			gw.Store.UpdateWorkflowExecution(ctx, exec)
-			
-			// Schedule Asynq worker using the configured delay
-			delayDuration := 1 * time.Minute // default 1 minute
-			if val, ok := node.Data["delayMs"]; ok {
-				if ms, ok := val.(float64); ok {
-					delayDuration = time.Duration(ms) * time.Millisecond
+		// Delay nodes (and time windows waiting for opening hours) set a wake-up time; the
+		// execution parks and Asynq resumes it at the next node
+		if wakeAt, ok := wakeTime(stateData); ok {
+			if gw.sim != nil {
+				// Simulations fast-forward
+				delete(stateData, "delay_until")
+				detail := map[string]interface{}{"until": wakeAt.Format(time.RFC3339)}
+				if node.Type == models.NodeTypeActionDelay {
+					detail = map[string]interface{}{"delay_ms": node.DataFloat("delayMs", float64(time.Minute/time.Millisecond))}
 				}
+				gw.sim.record(EffectDelay, detail)
+				currentNodeID = nextNodeID
+				exec.CurrentNodeID = currentNodeID
+				continue
 			}
 
-			payload, _ := json.Marshal(map[string]int64{"execution_id": executionID})
-			task := asynq.NewTask("workflow:resume", payload)
+			exec.StateData, _ = json.Marshal(stateData)
+			exec.Status = "waiting"
+			exec.WaitingFor = WaitingForDelay
+			exec.CurrentNodeID = nextNodeID
+			gw.Store.UpdateWorkflowExecution(ctx, exec)
 
-			log.Printf("Execution %d paused at Delay node %s. Target resume in %v", executionID, node.ID, delayDuration)
+			log.Printf("Execution %d paused at %s node %s until %s", executionID, node.Type, node.ID, wakeAt.Format(time.RFC3339))
 
-			if gw.AsynqClient != nil {
-				_, err := gw.AsynqClient.Enqueue(task, asynq.ProcessIn(delayDuration))
-				if err != nil {
-					log.Printf("ERROR: Failed to enqueue resume task for execution %d: %v", executionID, err)
-				}
-			} else {
-				log.Printf("WARNING: AsynqClient is nil, execution %d is permanently stalled.", executionID)
+			if err := gw.scheduleResume(ctx, executionID, wakeAt); err != nil {
+				log.Printf("ERROR: Failed to enqueue resume task for execution %d: %v", executionID, err)
 			}
 			return nil
 		}
@@ -170,59 +347,171 @@ func (gw *GraphWalker) ResumeExecution(ctx context.Context, executionID int64) e
 	}
 }
 
-func (gw *GraphWalker) processNode(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}) (string, error) {
+func (gw *GraphWalker) processNode(ctx context.Context, node *models.ReactFlowNode, graph *models.WorkflowGraph, exec *models.WorkflowExecution, stateData map[string]interface{}, rec *stepRecord) (string, error) {
 	// Execute specific behaviors
 	switch node.Type {
-	case models.NodeTypeTriggerDM, models.NodeTypeTriggerKeyword:
+	case models.NodeTypeTriggerDM:
 		// Triggers just pass through, state is already populated by StartWorkflow
 		log.Printf("Processing Trigger: %v", node.Data["label"])
+		rec.in("received_message", stateData["received_message"])
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+
+	case models.NodeTypeTriggerKeyword:
+		// Matching already happened in MatchTriggers; the keyword that fired is kept in state
+		log.Printf("Processing Keyword Trigger: %v (matched %q)", node.Data["label"], stateData["matched_keyword"])
+		rec.in("received_message", stateData["received_message"])
+		rec.out("matched_keyword", stateData["matched_keyword"])
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+
+	case models.NodeTypeTriggerSchedule, models.NodeTypeTriggerContactEvent, models.NodeTypeTriggerInactivity:
+		// Fired by the scheduler or a contact event; what happened is kept in state as "event"
+		log.Printf("Processing %s Trigger: %v", node.Type, node.Data["label"])
+		rec.in("event", stateData["event"])
 		return gw.findNextNode(graph.Edges, node.ID, ""), nil
 
+	case models.NodeTypeTriggerWorkflowCall:
+		// Started by another workflow's action_call_workflow node
+		rec.in("caller", stateData["caller"])
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+
+	case models.NodeTypeActionCallWorkflow:
+		return gw.runCallWorkflow(ctx, node, graph, exec, stateData, rec)
+
+	case models.NodeTypeActionHandoff:
+		return gw.runHandoff(ctx, node, graph, exec, stateData, rec)
+
 	case models.NodeTypeActionSendMessage:
 		// Send a message using Meta API
-		msg := "Hello!"
-		if val, ok := node.Data["message"]; ok {
-			msg = val.(string)
+		vars, err := gw.buildVariables(ctx, exec, stateData)
+		if err != nil {
+			return "", err
+		}
+		msg, err := RenderTemplate(node.DataString("message", "Hello!"), vars)
+		if err != nil {
+			log.Printf("[GraphWalker] Node %s message template error, sending raw text: %v", node.ID, err)
 		}
 		
+		rec.out("message", msg)
 		if err := gw.sendMetaMessage(ctx, exec.ContactID, msg); err != nil {
-			log.Printf("[GraphWalker] Failed to send static message: %v", err)
+			return "", fmt.Errorf("failed to send message: %w", err)
 		}
 		
 		return gw.findNextNode(graph.Edges, node.ID, ""), nil
 		
 	case models.NodeTypeActionAIReply:
-		// Read prompt from UI
-		prompt := "Reply to the user's message."
-		if val, ok := node.Data["prompt"]; ok {
-			prompt = val.(string)
+		// Read prompt from UI and fill in contact/state placeholders
+		vars, err := gw.buildVariables(ctx, exec, stateData)
+		if err != nil {
+			return "", err
 		}
-		
-		// If knowledge base RAG was executed before this, context would be in stateData["kb_context"]
-		userMsg := ""
-		if val, ok := stateData["received_message"]; ok {
-			userMsg = val.(string)
+		prompt, err := RenderTemplate(node.DataString("prompt", "Reply to the user's message."), vars)
+		if err != nil {
+			log.Printf("[GraphWalker] Node %s prompt template error, using raw text: %v", node.ID, err)
 		}
-		
-		fullPrompt := fmt.Sprintf("%s\n\nUser Message: %s", prompt, userMsg)
-		
+
+		// Recent conversation, a summary of older history, what we know about the contact and
+		// the Knowledge Base entries found by an earlier action_rag_search
+		kbContext, _ := stateData["kb_context"].(string)
+		llm := gw.llmFor(vars.Contact.UserID, node)
+		aiCtx := aiContext(ctx, vars.Contact.UserID, exec, node)
+		mem, err := gw.buildMemory(aiCtx, llm, exec.ContactID, vars.Message,
+			int(node.DataFloat("historyMessages", DefaultHistoryMessages)),
+			int(node.DataFloat("maxContextTokens", DefaultContextTokens)))
+		if err != nil {
+			return "", err
+		}
+		req := ai.ChatRequest{
+			System:      replySystemPrompt(prompt, contactFacts(vars.Contact, vars.Visit), mem.Summary, kbContext),
+			Messages:    mem.Messages,
+			Temperature: float32(node.DataFloat("temperature", 0)),
+			MaxTokens:   int(node.DataFloat("maxTokens", 0)),
+		}
+
 		// Call LLM
-		reply, err := gw.LLMClient.GenerateText(ctx, fullPrompt)
+		rec.in("prompt", req.System)
+		rec.in("history_messages", len(mem.Messages))
+		rec.in("history_tokens", mem.Tokens)
+		reply, err := llm.Chat(aiCtx, req)
+		if errors.Is(err, ai.ErrBudgetExceeded) {
+			return gw.budgetFallback(ctx, node, graph, exec, vars, rec, err)
+		}
 		if err != nil {
 			return "", err
 		}
+		rec.out("reply", reply)
 		
 		if err := gw.sendMetaMessage(ctx, exec.ContactID, reply); err != nil {
-			log.Printf("[GraphWalker] Failed to send AI reply: %v", err)
+			return "", fmt.Errorf("failed to send AI reply: %w", err)
 		}
 
 		return gw.findNextNode(graph.Edges, node.ID, ""), nil
 
+	case models.NodeTypeLogicCondition:
+		branches, err := ParseConditionNode(node)
+		if err != nil {
+			return "", fmt.Errorf("invalid condition: %w", err)
+		}
+
+		vars, err := gw.buildVariables(ctx, exec, stateData)
+		if err != nil {
+			return "", err
+		}
+
+		handle := EvaluateBranches(branches, vars)
+		log.Printf("Condition node %s took branch %q", node.ID, handle)
+		rec.branch = handle
+
+		return gw.findNextNode(graph.Edges, node.ID, handle), nil
+
+	case models.NodeTypeLogicSplit:
+		return gw.runSplit(ctx, node, graph, exec, rec)
+
+	case models.NodeTypeActionRAGSearch:
+		return gw.runRAGSearch(ctx, node, graph, exec, stateData, rec)
+
+	case models.NodeTypeLogicAIRouter:
+		return gw.runAIRouter(ctx, node, graph, exec, stateData, rec)
+
+	case models.NodeTypeActionHTTPRequest:
+		handle, err := gw.runHTTPRequest(ctx, node, exec, stateData, rec)
+		if err != nil {
+			// Retries and the error edge are handled like for any other node
+			stateData["http_error"] = err.Error()
+			rec.out("http_error", err.Error())
+			return "", err
+		}
+		rec.branch = handle
+		return findHandleOrDefault(graph.Edges, node.ID, handle), nil
+
+	case models.NodeTypeActionAddTag:
+		tag := strings.TrimSpace(node.DataString("tag", ""))
+		if tag == "" {
+			return "", fmt.Errorf("tag is required")
+		}
+		added, err := gw.Store.AddContactTag(ctx, exec.ContactID, tag)
+		if err != nil {
+			return "", fmt.Errorf("failed to add tag: %w", err)
+		}
+		rec.out("tag", tag)
+		rec.out("added", added)
+		if added {
+			// Tag goals of this and the contact's other executions may be met now
+			gw.CheckGoals(ctx, exec.ContactID)
+			gw.EmitContactEvent(ctx, ContactEvent{Type: ContactEventTagAdded, ContactID: exec.ContactID, Tag: tag})
+		}
+		return gw.findNextNode(graph.Edges, node.ID, ""), nil
+
 	case models.NodeTypeActionDelay:
-		// For delay, we just return the next node to schedule
-		log.Printf("Delay node executed")
+		// The loop parks the execution until delay_until. It is kept in state so a pause during
+		// the delay can resume at the original time.
+		delayMs := node.DataFloat("delayMs", float64(time.Minute/time.Millisecond))
+		stateData["delay_until"] = time.Now().Add(time.Duration(delayMs) * time.Millisecond).Format(time.RFC3339)
+		rec.out("delay_ms", delayMs)
 		return gw.findNextNode(graph.Edges, node.ID, ""), nil
 
+	case models.NodeTypeLogicTimeWindow:
+		return gw.runTimeWindow(ctx, node, graph, exec, stateData, rec)
+
 	default:
 		log.Printf("Unknown node type: %s", node.Type)
 		return gw.findNextNode(graph.Edges, node.ID, ""), nil
@@ -232,6 +521,10 @@ func (gw *GraphWalker) processNode(ctx context.Context, node *models.ReactFlowNo
 func (gw *GraphWalker) findNextNode(edges []models.ReactFlowEdge, sourceNodeID, sourceHandle string) string {
 	for _, edge := range edges {
 		if edge.Source == sourceNodeID {
+			// The error and budget edges are only ever taken explicitly
+			if sourceHandle == "" && (edge.SourceHandle == HandleError || edge.SourceHandle == HandleBudgetExceeded) {
+				continue
+			}
 			// If a specific source handle is requested (e.g. AI intent routing), match it
 			if sourceHandle != "" && edge.SourceHandle != sourceHandle {
 				continue
@@ -242,16 +535,97 @@ func (gw *GraphWalker) findNextNode(edges []models.ReactFlowEdge, sourceNodeID,
 	return ""
 }
 
+// findHandleOrDefault follows the edge leaving sourceHandle, falling back to an edge without a handle.
+// Unlike findNextNode("") it never picks an edge that belongs to a different named handle.
+func findHandleOrDefault(edges []models.ReactFlowEdge, sourceNodeID, sourceHandle string) string {
+	fallback := ""
+	for _, edge := range edges {
+		if edge.Source != sourceNodeID {
+			continue
+		}
+		if edge.SourceHandle == sourceHandle {
+			return edge.Target
+		}
+		if edge.SourceHandle == "" && fallback == "" {
+			fallback = edge.Target
+		}
+	}
+	return fallback
+}
+
+// loadGraph returns the workflow graph an execution runs on
+func (gw *GraphWalker) loadGraph(ctx context.Context, exec *models.WorkflowExecution) (*models.WorkflowGraph, error) {
+	if exec.WorkflowVersionID != nil {
+		v, err := gw.Store.GetWorkflowVersionByID(ctx, *exec.WorkflowVersionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load workflow version %d: %w", *exec.WorkflowVersionID, err)
+		}
+		return models.ParseWorkflowGraph(v.Nodes, v.Edges)
+	}
+
+	// Executions created before versioning follow the live graph
+	w, err := gw.Store.GetWorkflowByID(ctx, exec.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+	return models.ParseWorkflowGraph(w.Nodes, w.Edges)
+}
+
+// publishedVersion returns the snapshot new executions should pin. Workflows published before
+// versioning existed get their current graph snapshotted as version 1 on first use.
+func (gw *GraphWalker) publishedVersion(ctx context.Context, w *models.Workflow) (*models.WorkflowVersion, error) {
+	if v, err := gw.Store.GetLatestWorkflowVersion(ctx, w.ID); err == nil {
+		return v, nil
+	}
+	v := &models.WorkflowVersion{WorkflowID: w.ID, Nodes: w.Nodes, Edges: w.Edges}
+	if err := gw.Store.CreateWorkflowVersion(ctx, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// buildVariables gathers everything templates and conditions may reference for this execution.
+// Visit and project data are optional, so lookup failures there are not errors.
+func (gw *GraphWalker) buildVariables(ctx context.Context, exec *models.WorkflowExecution, stateData map[string]interface{}) (*Variables, error) {
+	contact, err := gw.Store.GetContactByID(ctx, exec.ContactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+
+	vars := &Variables{Contact: contact, State: stateData}
+	vars.Message, _ = stateData["received_message"].(string)
+
+	if visit, err := gw.Store.GetVisitByContact(ctx, contact.ID); err == nil {
+		vars.Visit = visit
+	}
+	if cfg, err := gw.Store.GetPropertyVisitConfig(ctx, contact.UserID); err == nil {
+		vars.Project = cfg
+	}
+	vars.Location = TenantCalendar(ctx, gw.Store, contact.UserID).Location()
+	return vars, nil
+}
+
 func (gw *GraphWalker) sendMetaMessage(ctx context.Context, contactID int64, msg string) error {
+	if gw.sim != nil {
+		gw.sim.record(EffectMessage, map[string]interface{}{"text": msg})
+		return nil
+	}
+
 	contact, err := gw.Store.GetContactByID(ctx, contactID)
 	if err != nil {
 		return fmt.Errorf("failed to get contact: %w", err)
 	}
+	if contact.BotPaused {
+		return ErrBotPaused
+	}
 
 	channel, err := gw.Store.GetChannelByID(ctx, contact.ChannelID)
 	if err != nil {
 		return fmt.Errorf("failed to get channel: %w", err)
 	}
+	if gw.MetaClient == nil {
+		return fmt.Errorf("meta client is not configured")
+	}
 
 	result, err := gw.MetaClient.SendMessage(
 		contact.Platform,
@@ -12,18 +12,60 @@ type NodeType string
 const (
 	NodeTypeTriggerDM       NodeType = "trigger_meta_dm"
 	NodeTypeTriggerKeyword  NodeType = "trigger_keyword"
+	NodeTypeTriggerSchedule     NodeType = "trigger_schedule"      // Cron schedule, fans out to the tenant's contacts
+	NodeTypeTriggerContactEvent NodeType = "trigger_contact_event" // Tag added, field changed, visit booked/updated, bot resumed
+	NodeTypeTriggerInactivity   NodeType = "trigger_inactivity"    // Contact has not replied for a number of days
+	NodeTypeTriggerWorkflowCall NodeType = "trigger_workflow_call" // Entry point of a module started by action_call_workflow
 	
 	// Native Actions
 	NodeTypeActionSendMessage NodeType = "action_send_message"
 	NodeTypeActionDelay       NodeType = "action_delay"
 	NodeTypeActionAddTag      NodeType = "action_add_tag"
+	NodeTypeActionWaitForReply NodeType = "action_wait_for_reply" // Suspends until the contact responds
+	NodeTypeActionHTTPRequest  NodeType = "action_http_request"   // Calls an external API and maps the response into state
+	NodeTypeActionCallWorkflow NodeType = "action_call_workflow"  // Runs another workflow for the same contact
+	NodeTypeActionHandoff      NodeType = "action_handoff"        // Pauses the bot and assigns the conversation to an agent
 	
 	// AI Powered Actions
 	NodeTypeActionAIReply     NodeType = "action_ai_reply" // Generates a response and sends it
 	NodeTypeActionRAGSearch   NodeType = "action_rag_search" // Queries knowledge base
 	NodeTypeLogicAIRouter      NodeType = "logic_ai_router" // Classifies intent to branch path
+
+	// Deterministic Logic
+	NodeTypeLogicCondition NodeType = "logic_condition" // If/else over contact fields and state
+	NodeTypeLogicTimeWindow NodeType = "logic_time_window" // Branches on (or waits for) the tenant's business hours
+	NodeTypeLogicSplit      NodeType = "logic_split"       // A/B test: weighted branches, stable per contact
 )
 
+// IsTrigger reports whether the node type can act as the entry point of a workflow
+func (t NodeType) IsTrigger() bool {
+	switch t {
+	case NodeTypeTriggerDM, NodeTypeTriggerKeyword,
+		NodeTypeTriggerSchedule, NodeTypeTriggerContactEvent, NodeTypeTriggerInactivity, NodeTypeTriggerWorkflowCall:
+		return true
+	}
+	return false
+}
+
+// IsInbound reports whether the trigger fires on an inbound message (see engine.MatchTriggers)
+func (t NodeType) IsInbound() bool {
+	return t == NodeTypeTriggerDM || t == NodeTypeTriggerKeyword
+}
+
+// IsKnown reports whether the engine knows how to execute the node type
+func (t NodeType) IsKnown() bool {
+	switch t {
+	case NodeTypeTriggerDM, NodeTypeTriggerKeyword,
+		NodeTypeTriggerSchedule, NodeTypeTriggerContactEvent, NodeTypeTriggerInactivity, NodeTypeTriggerWorkflowCall,
+		NodeTypeActionSendMessage, NodeTypeActionDelay, NodeTypeActionAddTag, NodeTypeActionWaitForReply, NodeTypeActionHTTPRequest,
+		NodeTypeActionCallWorkflow, NodeTypeActionHandoff,
+		NodeTypeActionAIReply, NodeTypeActionRAGSearch, NodeTypeLogicAIRouter,
+		NodeTypeLogicCondition, NodeTypeLogicTimeWindow, NodeTypeLogicSplit:
+		return true
+	}
+	return false
+}
+
 // ReactFlowNode represents a single block on the visual builder canvas
 type ReactFlowNode struct {
 	ID       string                 `json:"id"`
@@ -32,6 +74,46 @@ type ReactFlowNode struct {
 	Data     map[string]interface{} `json:"data"` // Configuration specific to the node type
 }
 
+// DataString returns a string field from the node's Data, or fallback if missing/wrong type
+func (n *ReactFlowNode) DataString(key, fallback string) string {
+	if val, ok := n.Data[key].(string); ok {
+		return val
+	}
+	return fallback
+}
+
+// DataBool returns a boolean field from the node's Data, or fallback if missing/wrong type
+func (n *ReactFlowNode) DataBool(key string, fallback bool) bool {
+	if val, ok := n.Data[key].(bool); ok {
+		return val
+	}
+	return fallback
+}
+
+// DataFloat returns a numeric field from the node's Data, or fallback if missing/wrong type.
+// JSON numbers always decode as float64 into map[string]interface{}.
+func (n *ReactFlowNode) DataFloat(key string, fallback float64) float64 {
+	if val, ok := n.Data[key].(float64); ok {
+		return val
+	}
+	return fallback
+}
+
+// DataStrings returns a list of strings from the node's Data. Non-string entries are skipped.
+func (n *ReactFlowNode) DataStrings(key string) []string {
+	raw, ok := n.Data[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 // ReactFlowEdge represents a connection between two nodes
 type ReactFlowEdge struct {
 	ID           string `json:"id"`
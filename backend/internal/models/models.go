@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User represents a SaaS customer (builder, agency, marketer).
 type User struct {
@@ -79,6 +82,142 @@ type PropertyVisitConfig struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// Agent is a human member of a tenant's team who can take over conversations from the bot
+type Agent struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"user_id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email,omitempty"`
+	Team           string     `json:"team,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	LastAssignedAt *time.Time `json:"last_assigned_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Handoff is a conversation a workflow's action_handoff node escalated to a human. It stays open
+// until an agent hands the contact back to the bot or resolves it; if no agent replies before
+// SLADueAt it is escalated or re-routed.
+type Handoff struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"`
+	ContactID       int64      `json:"contact_id"`
+	ExecutionID     *int64     `json:"execution_id,omitempty"`
+	NodeID          string     `json:"node_id,omitempty"`
+	AgentID         *int64     `json:"agent_id,omitempty"` // nil while nobody in the team is available
+	Team            string     `json:"team,omitempty"`
+	Strategy        string     `json:"strategy"` // "round_robin" or "least_load"
+	Status          string     `json:"status"`   // "open" or "resolved"
+	Summary         string     `json:"summary,omitempty"`
+	SLAMinutes      int        `json:"sla_minutes"`
+	OnBreach        string     `json:"on_breach"` // "escalate" (to EscalationTeam) or "reroute" (to another agent of Team)
+	EscalationTeam  string     `json:"escalation_team,omitempty"`
+	MaxEscalations  int        `json:"max_escalations"`
+	Escalations     int        `json:"escalations"`
+	SLADueAt        *time.Time `json:"sla_due_at,omitempty"`
+	FirstResponseAt *time.Time `json:"first_response_at,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ContactNote is an internal note on a contact. Notes are shown to agents, never sent to the lead.
+type ContactNote struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	ContactID int64     `json:"contact_id"`
+	Author    string    `json:"author"` // agent name, or "bot" for notes written by workflows
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationSummary is the rolling summary of a contact's conversation up to LastMessageID.
+// Messages after it are given to the AI verbatim.
+type ConversationSummary struct {
+	ContactID     int64     `json:"contact_id"`
+	Summary       string    `json:"summary"`
+	LastMessageID int64     `json:"last_message_id"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Sources of a contact's lead field (ContactFieldSource.Source)
+const (
+	FieldSourceAgent = "agent"
+	FieldSourceAI    = "ai"
+)
+
+// ContactFieldSource records who last set one of a contact's lead qualification fields. AI
+// values carry the confidence of the extraction and the message they were read from.
+type ContactFieldSource struct {
+	ContactID  int64     `json:"contact_id"`
+	Field      string    `json:"field"`  // as in field_changed events: budget, preferred_location, ...
+	Source     string    `json:"source"` // "agent" or "ai"
+	Confidence float64   `json:"confidence"`
+	MessageID  int64     `json:"message_id,omitempty"`
+	Evidence   string    `json:"evidence,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LLMUsage is one metered LLM call. UserID and WorkflowID are 0 for calls made outside a tenant
+// or a workflow.
+type LLMUsage struct {
+	ID               int64     `json:"id"`
+	UserID           int64     `json:"user_id"`
+	WorkflowID       int64     `json:"workflow_id,omitempty"`
+	NodeID           string    `json:"node_id,omitempty"`
+	Feature          string    `json:"feature"`
+	Method           string    `json:"method"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	LatencyMs        int64     `json:"latency_ms"`
+	Success          bool      `json:"success"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// LLMUsageSummary totals a tenant's LLM calls for one group of a usage report
+type LLMUsageSummary struct {
+	Key              string  `json:"key"` // workflow ID, node, model, feature or day, depending on the grouping
+	WorkflowID       int64   `json:"workflow_id,omitempty"`
+	Calls            int     `json:"calls"`
+	Failures         int     `json:"failures"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	AvgLatencyMs     int64   `json:"avg_latency_ms"`
+}
+
+// BusinessCalendar is a tenant's timezone, weekly opening hours and holidays. One row per user;
+// tenants without one are treated as always open in UTC.
+type BusinessCalendar struct {
+	ID          int64                      `json:"id"`
+	UserID      int64                      `json:"user_id"`
+	Timezone    string                     `json:"timezone"`     // IANA name, e.g. "Asia/Kolkata"
+	WeeklyHours map[string][]BusinessHours `json:"weekly_hours"` // keyed "mon" … "sun"; missing days are closed
+	Holidays    []string                   `json:"holidays"`     // closed dates, "2006-01-02" in Timezone
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+}
+
+// BusinessHours is one opening interval of a day, as "15:04" wall-clock times
+type BusinessHours struct {
+	Open  string `json:"open"`
+	Close string `json:"close"` // may be "24:00"
+}
+
+// TenantSecret is a named credential used by workflow HTTP request nodes.
+// The value is write-only over the API.
+type TenantSecret struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Value     string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // Message represents a single chat message.
 type Message struct {
 	ID             int64     `json:"id"`
@@ -105,6 +244,7 @@ type Automation struct {
 	ReplyText   string    `json:"reply_text"`
 	ReplyMedia  string    `json:"reply_media,omitempty"` // URL to file
 	DelayMs     int       `json:"delay_ms"`
+	ActiveHours string    `json:"active_hours"` // "always", "open" or "closed" (business hours)
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -153,18 +293,58 @@ type Workflow struct {
 	// Nodes and Edges are stored as JSONB in DB, we use generic map/interfaces or raw JSON here
 	Nodes       []byte    `json:"nodes"` 
 	Edges       []byte    `json:"edges"`
+	// Goals are exit conditions: a running execution whose contact meets one ends as "goal_reached"
+	Goals       json.RawMessage `json:"goals"`
+	// ReentryPolicy decides what happens when the trigger fires for a contact already in the
+	// workflow: "parallel", "skip" (default), "restart" or "queue"
+	ReentryPolicy   string `json:"reentry_policy"`
+	CooldownMinutes int    `json:"cooldown_minutes"` // Minimum minutes between two runs for the same contact
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// WorkflowVersion is an immutable snapshot of a workflow graph, created on every publish and for
+// every draft the AI editor proposes
+type WorkflowVersion struct {
+	ID         int64     `json:"id"`
+	WorkflowID int64     `json:"workflow_id"`
+	Version    int       `json:"version"` // 1, 2, 3... per workflow
+	Draft      bool      `json:"draft"`   // proposed by the AI editor and not published
+	Nodes      []byte    `json:"nodes,omitempty"`
+	Edges      []byte    `json:"edges,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // WorkflowExecution represents the runtime state of a specific Lead passing through a Workflow
 type WorkflowExecution struct {
 	ID            int64     `json:"id"`
 	WorkflowID    int64     `json:"workflow_id"`
 	ContactID     int64     `json:"contact_id"`
 	CurrentNodeID string    `json:"current_node_id"`
-	Status        string    `json:"status"` // "running", "waiting", "completed", "failed"
+	Status        string    `json:"status"` // "running", "waiting", "queued", "paused", "completed", "failed", "cancelled", "goal_reached"
+	WaitingFor    string    `json:"waiting_for,omitempty"` // "delay" or "reply" while Status is "waiting"
+	WorkflowVersionID *int64 `json:"workflow_version_id,omitempty"` // Graph snapshot the execution runs on (nil for legacy rows)
+	Exclusive     bool      `json:"-"` // Set on insert: counts towards the one-active-run-per-contact rule
 	StateData     []byte    `json:"state_data"` // Context payload (JSONB)
+	SplitBranches map[string]string `json:"split_branches,omitempty"` // logic_split node ID -> branch handle taken
+	ParentExecutionID *int64 `json:"parent_execution_id,omitempty"` // Execution whose action_call_workflow node started this one
+	ParentNodeID  string    `json:"parent_node_id,omitempty"`
+	CallDepth     int       `json:"call_depth,omitempty"` // How many action_call_workflow hops below the top-level execution
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
+
+// WorkflowExecutionStep records one node processed by an execution: what it read, what it did
+// (message sent, LLM prompt/response, request made) and which branch it took.
+type WorkflowExecutionStep struct {
+	ID          int64           `json:"id"`
+	ExecutionID int64           `json:"execution_id"`
+	NodeID      string          `json:"node_id"`
+	NodeType    string          `json:"node_type"`
+	Branch      string          `json:"branch,omitempty"` // Source handle followed, for branching nodes
+	Input       json.RawMessage `json:"input"`
+	Output      json.RawMessage `json:"output"`
+	Error       string          `json:"error,omitempty"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+}
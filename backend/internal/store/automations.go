@@ -10,21 +10,24 @@ import (
 // CreateAutomation inserts a new automation rule.
 func (s *Storage) CreateAutomation(ctx context.Context, a *models.Automation) error {
 	query := `
-		INSERT INTO automations (user_id, name, trigger_type, keywords, reply_text, reply_media, delay_ms, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO automations (user_id, name, trigger_type, keywords, reply_text, reply_media, delay_ms, active_hours, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at`
 
+	if a.ActiveHours == "" {
+		a.ActiveHours = "always"
+	}
 	now := time.Now()
 	return s.DB.QueryRow(ctx, query,
 		a.UserID, a.Name, a.TriggerType, a.Keywords,
-		a.ReplyText, a.ReplyMedia, a.DelayMs, true, now, now,
+		a.ReplyText, a.ReplyMedia, a.DelayMs, a.ActiveHours, true, now, now,
 	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
 }
 
 // GetAutomationsByUser fetches all active automations for a user.
 func (s *Storage) GetAutomationsByUser(ctx context.Context, userID int64) ([]models.Automation, error) {
 	query := `
-		SELECT id, user_id, name, trigger_type, keywords, reply_text, reply_media, delay_ms, is_active, created_at, updated_at
+		SELECT id, user_id, name, trigger_type, keywords, reply_text, reply_media, delay_ms, active_hours, is_active, created_at, updated_at
 		FROM automations
 		WHERE user_id = $1 AND is_active = TRUE
 		ORDER BY created_at DESC`
@@ -40,7 +43,7 @@ func (s *Storage) GetAutomationsByUser(ctx context.Context, userID int64) ([]mod
 		var a models.Automation
 		if err := rows.Scan(
 			&a.ID, &a.UserID, &a.Name, &a.TriggerType, &a.Keywords,
-			&a.ReplyText, &a.ReplyMedia, &a.DelayMs, &a.IsActive,
+			&a.ReplyText, &a.ReplyMedia, &a.DelayMs, &a.ActiveHours, &a.IsActive,
 			&a.CreatedAt, &a.UpdatedAt,
 		); err != nil {
 			return nil, err
@@ -54,13 +57,16 @@ func (s *Storage) GetAutomationsByUser(ctx context.Context, userID int64) ([]mod
 func (s *Storage) UpdateAutomation(ctx context.Context, a *models.Automation) error {
 	query := `
 		UPDATE automations
-		SET name = $2, trigger_type = $3, keywords = $4, reply_text = $5, reply_media = $6, delay_ms = $7, is_active = $8, updated_at = $9
+		SET name = $2, trigger_type = $3, keywords = $4, reply_text = $5, reply_media = $6, delay_ms = $7, is_active = $8, updated_at = $9, active_hours = $11
 		WHERE id = $1 AND user_id = $10`
 
+	if a.ActiveHours == "" {
+		a.ActiveHours = "always"
+	}
 	_, err := s.DB.Exec(ctx, query,
 		a.ID, a.Name, a.TriggerType, a.Keywords,
 		a.ReplyText, a.ReplyMedia, a.DelayMs, a.IsActive,
-		time.Now(), a.UserID,
+		time.Now(), a.UserID, a.ActiveHours,
 	)
 	return err
 }
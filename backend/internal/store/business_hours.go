@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// UpsertBusinessCalendar saves a tenant's timezone, weekly hours and holidays.
+func (s *Storage) UpsertBusinessCalendar(ctx context.Context, cal *models.BusinessCalendar) error {
+	hours, err := json.Marshal(cal.WeeklyHours)
+	if err != nil {
+		return err
+	}
+	holidays := cal.Holidays
+	if holidays == nil {
+		holidays = []string{}
+	}
+
+	query := `
+		INSERT INTO business_calendars (user_id, timezone, weekly_hours, holidays, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (user_id)
+		DO UPDATE SET
+			timezone     = EXCLUDED.timezone,
+			weekly_hours = EXCLUDED.weekly_hours,
+			holidays     = EXCLUDED.holidays,
+			updated_at   = EXCLUDED.updated_at
+		RETURNING id, created_at, updated_at`
+
+	return s.DB.QueryRow(ctx, query, cal.UserID, cal.Timezone, hours, holidays, time.Now()).
+		Scan(&cal.ID, &cal.CreatedAt, &cal.UpdatedAt)
+}
+
+// GetBusinessCalendar returns a tenant's calendar, or nil, nil when they have not set one up.
+func (s *Storage) GetBusinessCalendar(ctx context.Context, userID int64) (*models.BusinessCalendar, error) {
+	query := `
+		SELECT id, user_id, timezone, weekly_hours, holidays, created_at, updated_at
+		FROM business_calendars
+		WHERE user_id = $1`
+
+	var cal models.BusinessCalendar
+	var hours []byte
+	err := s.DB.QueryRow(ctx, query, userID).Scan(
+		&cal.ID, &cal.UserID, &cal.Timezone, &hours, &cal.Holidays, &cal.CreatedAt, &cal.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(hours, &cal.WeeklyHours); err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
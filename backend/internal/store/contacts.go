@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/social-media-lead/backend/internal/models"
@@ -119,3 +121,203 @@ func (s *Storage) UpdateContactState(ctx context.Context, contactID int64, booki
 	_, err := s.DB.Exec(ctx, query, contactID, bookingState, botPaused, time.Now())
 	return err
 }
+
+// AddContactTag appends a tag to a contact unless it is already present.
+// Returns false when the contact already had the tag.
+func (s *Storage) AddContactTag(ctx context.Context, contactID int64, tag string) (bool, error) {
+	query := `
+		UPDATE contacts
+		SET tags = array_append(COALESCE(tags, '{}'), $2), updated_at = $3
+		WHERE id = $1 AND NOT ($2 = ANY(COALESCE(tags, '{}')))`
+
+	tagCmd, err := s.DB.Exec(ctx, query, contactID, tag, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return tagCmd.RowsAffected() > 0, nil
+}
+
+// TriggerContactFilter selects the contacts a scheduled or inactivity trigger fans out to.
+// Contacts are returned in ID order so callers can page with AfterID.
+type TriggerContactFilter struct {
+	Tags []string // contact has any of these tags; empty matches everyone
+
+	// InactiveSince keeps contacts whose last inbound message is older than it. With WorkflowID
+	// set, contacts the workflow already started for since that message are left out, so one
+	// silence fires the trigger once.
+	InactiveSince time.Time
+	WorkflowID    int64
+
+	AfterID int64
+	Limit   int
+}
+
+// ListTriggerContacts returns one batch of a tenant's contacts matching the filter.
+func (s *Storage) ListTriggerContacts(ctx context.Context, userID int64, f TriggerContactFilter) ([]models.Contact, error) {
+	query := `
+		SELECT c.id, c.user_id, c.channel_id, c.platform, c.platform_user_id, c.name, c.phone, c.email,
+		       c.budget, c.preferred_location, c.purchase_timeline, c.tags, c.is_hot_lead, c.booking_state, c.bot_paused, c.created_at, c.updated_at
+		FROM contacts c
+		LEFT JOIN LATERAL (
+			SELECT MAX(m.created_at) AS at
+			FROM messages m
+			WHERE m.contact_id = c.id AND m.direction = 'inbound'
+		) last_in ON TRUE
+		WHERE c.user_id = $1 AND c.id > $2
+		  AND (cardinality($3::text[]) = 0 OR c.tags && $3::text[])
+		  AND ($4::timestamptz IS NULL OR (
+		        last_in.at < $4
+		        AND NOT EXISTS (
+		            SELECT 1 FROM workflow_executions e
+		            WHERE e.workflow_id = $5 AND e.contact_id = c.id AND e.created_at > last_in.at
+		        )))
+		ORDER BY c.id
+		LIMIT $6`
+
+	var inactiveSince *time.Time
+	if !f.InactiveSince.IsZero() {
+		inactiveSince = &f.InactiveSince
+	}
+	tags := f.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	rows, err := s.DB.Query(ctx, query, userID, f.AfterID, tags, inactiveSince, f.WorkflowID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []models.Contact
+	for rows.Next() {
+		var c models.Contact
+		if err := rows.Scan(
+			&c.ID, &c.UserID, &c.ChannelID, &c.Platform, &c.PlatformUserID,
+			&c.Name, &c.Phone, &c.Email, &c.Budget,
+			&c.PreferredLocation, &c.PurchaseTimeline, &c.Tags,
+			&c.IsHotLead, &c.BookingState, &c.BotPaused, &c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+// GetContactFieldSources returns the provenance of a contact's lead fields. Fields set before
+// provenance was recorded have none.
+func (s *Storage) GetContactFieldSources(ctx context.Context, contactID int64) ([]models.ContactFieldSource, error) {
+	query := `
+		SELECT contact_id, field, source, confidence, message_id, evidence, updated_at
+		FROM contact_field_sources
+		WHERE contact_id = $1
+		ORDER BY field`
+
+	rows, err := s.DB.Query(ctx, query, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []models.ContactFieldSource
+	for rows.Next() {
+		var fs models.ContactFieldSource
+		if err := rows.Scan(&fs.ContactID, &fs.Field, &fs.Source, &fs.Confidence, &fs.MessageID, &fs.Evidence, &fs.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sources = append(sources, fs)
+	}
+	return sources, rows.Err()
+}
+
+const upsertFieldSourceQuery = `
+	INSERT INTO contact_field_sources (contact_id, field, source, confidence, message_id, evidence, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (contact_id, field) DO UPDATE
+	SET source = EXCLUDED.source, confidence = EXCLUDED.confidence, message_id = EXCLUDED.message_id,
+	    evidence = EXCLUDED.evidence, updated_at = EXCLUDED.updated_at`
+
+// SaveContactFieldSources creates or replaces the provenance of the given fields.
+func (s *Storage) SaveContactFieldSources(ctx context.Context, sources []models.ContactFieldSource) error {
+	now := time.Now()
+	for i := range sources {
+		fs := &sources[i]
+		fs.UpdatedAt = now
+		if _, err := s.DB.Exec(ctx, upsertFieldSourceQuery, fs.ContactID, fs.Field, fs.Source, fs.Confidence, fs.MessageID, fs.Evidence, fs.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leadFieldColumns are the contact columns SaveContactLeadFields may write, in update order
+var leadFieldColumns = []string{"budget", "preferred_location", "purchase_timeline", "phone", "is_hot_lead"}
+
+// SaveContactLeadFields writes lead fields of a contact together with their provenance, one
+// source per field in values. Under a lock on the contact, fields an agent has set are skipped
+// unless the new source is the agent too, so a concurrent agent edit always wins over
+// extraction. It returns the fields that were written.
+func (s *Storage) SaveContactLeadFields(ctx context.Context, contactID int64, values map[string]interface{}, sources []models.ContactFieldSource) ([]string, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	if err := tx.QueryRow(ctx, `SELECT id FROM contacts WHERE id = $1 FOR UPDATE`, contactID).Scan(&id); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `SELECT field FROM contact_field_sources WHERE contact_id = $1 AND source = $2`, contactID, models.FieldSourceAgent)
+	if err != nil {
+		return nil, err
+	}
+	agentFields := make(map[string]bool)
+	for rows.Next() {
+		var field string
+		if err := rows.Scan(&field); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		agentFields[field] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bySource := make(map[string]models.ContactFieldSource, len(sources))
+	for _, fs := range sources {
+		bySource[fs.Field] = fs
+	}
+	var written, set []string
+	args := []interface{}{contactID}
+	for _, column := range leadFieldColumns {
+		value, ok := values[column]
+		fs, hasSource := bySource[column]
+		if !ok || !hasSource || (agentFields[column] && fs.Source != models.FieldSourceAgent) {
+			continue
+		}
+		args = append(args, value)
+		set = append(set, fmt.Sprintf("%s = $%d", column, len(args)))
+		written = append(written, column)
+	}
+	if len(written) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	args = append(args, now)
+	query := fmt.Sprintf(`UPDATE contacts SET %s, updated_at = $%d WHERE id = $1`, strings.Join(set, ", "), len(args))
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	for _, field := range written {
+		fs := bySource[field]
+		if _, err := tx.Exec(ctx, upsertFieldSourceQuery, contactID, field, fs.Source, fs.Confidence, fs.MessageID, fs.Evidence, now); err != nil {
+			return nil, err
+		}
+	}
+	return written, tx.Commit(ctx)
+}
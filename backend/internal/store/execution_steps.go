@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// ExecutionFilter narrows ListWorkflowExecutions. Zero values mean "any".
+type ExecutionFilter struct {
+	WorkflowID int64
+	ContactID  int64
+	Status     string
+	// ParentExecutionID selects the executions an action_call_workflow node of that execution started
+	ParentExecutionID int64
+	Since             time.Time
+	Until             time.Time
+	Limit             int
+	Offset            int
+}
+
+// CreateWorkflowExecutionStep appends a node record to an execution's timeline.
+func (s *Storage) CreateWorkflowExecutionStep(ctx context.Context, step *models.WorkflowExecutionStep) error {
+	query := `
+		INSERT INTO workflow_execution_steps
+			(execution_id, node_id, node_type, branch, input, output, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	return s.DB.QueryRow(ctx, query,
+		step.ExecutionID, step.NodeID, step.NodeType, step.Branch, step.Input, step.Output,
+		step.Error, step.StartedAt, step.FinishedAt,
+	).Scan(&step.ID)
+}
+
+// GetWorkflowExecutionSteps returns an execution's timeline in processing order.
+func (s *Storage) GetWorkflowExecutionSteps(ctx context.Context, executionID int64) ([]models.WorkflowExecutionStep, error) {
+	query := `
+		SELECT id, execution_id, node_id, node_type, branch, input, output, error, started_at, finished_at
+		FROM workflow_execution_steps
+		WHERE execution_id = $1
+		ORDER BY id ASC
+	`
+	rows, err := s.DB.Query(ctx, query, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []models.WorkflowExecutionStep
+	for rows.Next() {
+		var step models.WorkflowExecutionStep
+		if err := rows.Scan(
+			&step.ID, &step.ExecutionID, &step.NodeID, &step.NodeType, &step.Branch,
+			&step.Input, &step.Output, &step.Error, &step.StartedAt, &step.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, rows.Err()
+}
+
+// ListWorkflowExecutions returns a user's executions matching the filter, newest first.
+// Ownership is enforced through the workflow, so callers can pass IDs straight from the request.
+func (s *Storage) ListWorkflowExecutions(ctx context.Context, userID int64, f ExecutionFilter) ([]models.WorkflowExecution, error) {
+	conds := []string{"w.user_id = $1"}
+	args := []interface{}{userID}
+	add := func(cond string, val interface{}) {
+		args = append(args, val)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.WorkflowID != 0 {
+		add("e.workflow_id = $%d", f.WorkflowID)
+	}
+	if f.ContactID != 0 {
+		add("e.contact_id = $%d", f.ContactID)
+	}
+	if f.Status != "" {
+		add("e.status = $%d", f.Status)
+	}
+	if f.ParentExecutionID != 0 {
+		add("e.parent_execution_id = $%d", f.ParentExecutionID)
+	}
+	if !f.Since.IsZero() {
+		add("e.created_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("e.created_at < $%d", f.Until)
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	args = append(args, limit, f.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT e.id, e.workflow_id, e.workflow_version_id, e.contact_id, e.current_node_id, e.status,
+			e.waiting_for, e.state_data, e.split_branches, e.parent_execution_id, e.parent_node_id, e.call_depth, e.created_at, e.updated_at
+		FROM workflow_executions e
+		JOIN workflows w ON w.id = e.workflow_id
+		WHERE %s
+		ORDER BY e.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conds, " AND "), len(args)-1, len(args))
+
+	rows, err := s.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var execs []models.WorkflowExecution
+	for rows.Next() {
+		var exec models.WorkflowExecution
+		if err := rows.Scan(
+			&exec.ID, &exec.WorkflowID, &exec.WorkflowVersionID, &exec.ContactID, &exec.CurrentNodeID,
+			&exec.Status, &exec.WaitingFor, &exec.StateData, &exec.SplitBranches,
+			&exec.ParentExecutionID, &exec.ParentNodeID, &exec.CallDepth, &exec.CreatedAt, &exec.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		execs = append(execs, exec)
+	}
+	return execs, rows.Err()
+}
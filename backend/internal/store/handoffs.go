@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// CreateAgent adds a human agent to a tenant's team.
+func (s *Storage) CreateAgent(ctx context.Context, a *models.Agent) error {
+	query := `
+		INSERT INTO agents (user_id, name, email, team, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING id, created_at, updated_at`
+
+	return s.DB.QueryRow(ctx, query, a.UserID, a.Name, a.Email, a.Team, a.IsActive, time.Now()).
+		Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// ListAgents returns all agents of a tenant ordered by team and name.
+func (s *Storage) ListAgents(ctx context.Context, userID int64) ([]models.Agent, error) {
+	query := `
+		SELECT id, user_id, name, email, team, is_active, last_assigned_at, created_at, updated_at
+		FROM agents
+		WHERE user_id = $1
+		ORDER BY team ASC, name ASC`
+
+	rows, err := s.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []models.Agent
+	for rows.Next() {
+		var a models.Agent
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Name, &a.Email, &a.Team, &a.IsActive, &a.LastAssignedAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
+// GetAgentByID returns a single agent.
+func (s *Storage) GetAgentByID(ctx context.Context, agentID int64) (*models.Agent, error) {
+	query := `
+		SELECT id, user_id, name, email, team, is_active, last_assigned_at, created_at, updated_at
+		FROM agents
+		WHERE id = $1`
+
+	var a models.Agent
+	err := s.DB.QueryRow(ctx, query, agentID).Scan(
+		&a.ID, &a.UserID, &a.Name, &a.Email, &a.Team, &a.IsActive, &a.LastAssignedAt, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// UpdateAgent saves an agent's name, email, team and availability.
+func (s *Storage) UpdateAgent(ctx context.Context, a *models.Agent) error {
+	query := `
+		UPDATE agents
+		SET name = $1, email = $2, team = $3, is_active = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7
+		RETURNING updated_at`
+
+	return s.DB.QueryRow(ctx, query, a.Name, a.Email, a.Team, a.IsActive, time.Now(), a.ID, a.UserID).
+		Scan(&a.UpdatedAt)
+}
+
+// DeleteAgent removes an agent. Their handoffs stay open without an assignee.
+func (s *Storage) DeleteAgent(ctx context.Context, agentID, userID int64) error {
+	query := `DELETE FROM agents WHERE id = $1 AND user_id = $2`
+	_, err := s.DB.Exec(ctx, query, agentID, userID)
+	return err
+}
+
+// PickAgent assigns the next active agent of a team and stamps their last_assigned_at. Round-robin
+// picks whoever was assigned longest ago; least-load picks whoever has the fewest open handoffs,
+// falling back to round-robin order on ties. An empty team means any agent of the tenant.
+// excludeAgentID (0 for none) skips an agent, e.g. the one a breached handoff is re-routed away
+// from. Returns nil, nil when nobody is available.
+func (s *Storage) PickAgent(ctx context.Context, userID int64, team, strategy string, excludeAgentID int64) (*models.Agent, error) {
+	query := `
+		UPDATE agents
+		SET last_assigned_at = $5
+		WHERE id = (
+			SELECT a.id
+			FROM agents a
+			WHERE a.user_id = $1 AND a.is_active
+			  AND ($2 = '' OR a.team = $2)
+			  AND a.id <> $4
+			ORDER BY
+				CASE WHEN $3 = 'least_load' THEN
+					(SELECT COUNT(*) FROM handoffs h WHERE h.agent_id = a.id AND h.status = 'open')
+				ELSE 0 END ASC,
+				a.last_assigned_at ASC NULLS FIRST,
+				a.id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, name, email, team, is_active, last_assigned_at, created_at, updated_at`
+
+	var a models.Agent
+	err := s.DB.QueryRow(ctx, query, userID, team, strategy, excludeAgentID, time.Now()).Scan(
+		&a.ID, &a.UserID, &a.Name, &a.Email, &a.Team, &a.IsActive, &a.LastAssignedAt, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+const handoffColumns = `id, user_id, contact_id, execution_id, node_id, agent_id, team, strategy, status, summary,
+		sla_minutes, on_breach, escalation_team, max_escalations, escalations,
+		sla_due_at, first_response_at, resolved_at, created_at, updated_at`
+
+func scanHandoff(row pgx.Row) (*models.Handoff, error) {
+	var h models.Handoff
+	err := row.Scan(
+		&h.ID, &h.UserID, &h.ContactID, &h.ExecutionID, &h.NodeID, &h.AgentID, &h.Team, &h.Strategy, &h.Status, &h.Summary,
+		&h.SLAMinutes, &h.OnBreach, &h.EscalationTeam, &h.MaxEscalations, &h.Escalations,
+		&h.SLADueAt, &h.FirstResponseAt, &h.ResolvedAt, &h.CreatedAt, &h.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// CreateHandoff opens a handoff. A contact can only have one open handoff at a time.
+func (s *Storage) CreateHandoff(ctx context.Context, h *models.Handoff) error {
+	query := `
+		INSERT INTO handoffs (user_id, contact_id, execution_id, node_id, agent_id, team, strategy, status, summary,
+			sla_minutes, on_breach, escalation_team, max_escalations, escalations, sla_due_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $16)
+		RETURNING id, created_at, updated_at`
+
+	return s.DB.QueryRow(ctx, query,
+		h.UserID, h.ContactID, h.ExecutionID, h.NodeID, h.AgentID, h.Team, h.Strategy, h.Status, h.Summary,
+		h.SLAMinutes, h.OnBreach, h.EscalationTeam, h.MaxEscalations, h.Escalations, h.SLADueAt, time.Now(),
+	).Scan(&h.ID, &h.CreatedAt, &h.UpdatedAt)
+}
+
+// GetHandoffByID returns a single handoff.
+func (s *Storage) GetHandoffByID(ctx context.Context, handoffID int64) (*models.Handoff, error) {
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE id = $1`
+	return scanHandoff(s.DB.QueryRow(ctx, query, handoffID))
+}
+
+// GetOpenHandoffByContact returns the contact's open handoff, or nil, nil when there is none.
+func (s *Storage) GetOpenHandoffByContact(ctx context.Context, contactID int64) (*models.Handoff, error) {
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE contact_id = $1 AND status = 'open'`
+	h, err := scanHandoff(s.DB.QueryRow(ctx, query, contactID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return h, err
+}
+
+// ListHandoffs returns a tenant's handoffs, newest first. An empty status lists all of them.
+func (s *Storage) ListHandoffs(ctx context.Context, userID int64, status string, limit, offset int) ([]models.Handoff, error) {
+	query := `
+		SELECT ` + handoffColumns + `
+		FROM handoffs
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := s.DB.Query(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var handoffs []models.Handoff
+	for rows.Next() {
+		h, err := scanHandoff(rows)
+		if err != nil {
+			return nil, err
+		}
+		handoffs = append(handoffs, *h)
+	}
+	return handoffs, rows.Err()
+}
+
+// UpdateHandoffAssignment saves a handoff's assignee, team, escalation count and SLA deadline
+// after an escalation or re-route.
+func (s *Storage) UpdateHandoffAssignment(ctx context.Context, h *models.Handoff) error {
+	query := `
+		UPDATE handoffs
+		SET agent_id = $1, team = $2, escalations = $3, sla_due_at = $4, updated_at = $5
+		WHERE id = $6
+		RETURNING updated_at`
+
+	return s.DB.QueryRow(ctx, query, h.AgentID, h.Team, h.Escalations, h.SLADueAt, time.Now(), h.ID).
+		Scan(&h.UpdatedAt)
+}
+
+// MarkHandoffResponded records the first human reply on a contact's open handoff, which stops
+// its SLA timer. Later replies leave the timestamp alone.
+func (s *Storage) MarkHandoffResponded(ctx context.Context, contactID int64) error {
+	query := `
+		UPDATE handoffs
+		SET first_response_at = $1, updated_at = $1
+		WHERE contact_id = $2 AND status = 'open' AND first_response_at IS NULL`
+	_, err := s.DB.Exec(ctx, query, time.Now(), contactID)
+	return err
+}
+
+// ResolveHandoffs closes the contact's open handoff, if any.
+func (s *Storage) ResolveHandoffs(ctx context.Context, contactID int64) error {
+	query := `
+		UPDATE handoffs
+		SET status = 'resolved', resolved_at = $1, updated_at = $1
+		WHERE contact_id = $2 AND status = 'open'`
+	_, err := s.DB.Exec(ctx, query, time.Now(), contactID)
+	return err
+}
+
+// CreateContactNote adds an internal note to a contact.
+func (s *Storage) CreateContactNote(ctx context.Context, n *models.ContactNote) error {
+	query := `
+		INSERT INTO contact_notes (user_id, contact_id, author, body, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return s.DB.QueryRow(ctx, query, n.UserID, n.ContactID, n.Author, n.Body, time.Now()).
+		Scan(&n.ID, &n.CreatedAt)
+}
+
+// GetContactNotes returns a contact's internal notes, newest first.
+func (s *Storage) GetContactNotes(ctx context.Context, contactID int64, limit int) ([]models.ContactNote, error) {
+	query := `
+		SELECT id, user_id, contact_id, author, body, created_at
+		FROM contact_notes
+		WHERE contact_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := s.DB.Query(ctx, query, contactID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.ContactNote
+	for rows.Next() {
+		var n models.ContactNote
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ContactID, &n.Author, &n.Body, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
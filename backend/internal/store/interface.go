@@ -26,7 +26,11 @@ type Store interface {
 	// Messages
 	CreateMessage(ctx context.Context, m *models.Message) error
 	GetMessagesByContact(ctx context.Context, contactID int64, limit, offset int) ([]models.Message, error)
+	GetRecentMessagesByContact(ctx context.Context, contactID int64, limit int) ([]models.Message, error)
+	GetMessagesByContactAfter(ctx context.Context, contactID, afterID int64, limit int) ([]models.Message, error)
 	GetConversations(ctx context.Context, userID int64, limit, offset int) ([]models.Message, error)
+	GetConversationSummary(ctx context.Context, contactID int64) (*models.ConversationSummary, error)
+	SaveConversationSummary(ctx context.Context, cs *models.ConversationSummary) error
 
 	// Contacts
 	CreateContact(ctx context.Context, c *models.Contact) error
@@ -34,12 +38,18 @@ type Store interface {
 	GetContactsByUser(ctx context.Context, userID int64, limit, offset int) ([]models.Contact, error)
 	UpdateContactLead(ctx context.Context, contactID int64, budget, location, timeline, phone string, isHot bool) error
 	UpdateContactState(ctx context.Context, contactID int64, bookingState string, botPaused bool) error
+	AddContactTag(ctx context.Context, contactID int64, tag string) (bool, error)
 	GetContactByID(ctx context.Context, contactID int64) (*models.Contact, error)
+	ListTriggerContacts(ctx context.Context, userID int64, filter TriggerContactFilter) ([]models.Contact, error)
+	GetContactFieldSources(ctx context.Context, contactID int64) ([]models.ContactFieldSource, error)
+	SaveContactFieldSources(ctx context.Context, sources []models.ContactFieldSource) error
+	SaveContactLeadFields(ctx context.Context, contactID int64, values map[string]interface{}, sources []models.ContactFieldSource) ([]string, error)
 
 	// Visits
 	CreateVisit(ctx context.Context, v *models.Visit) error
 	GetVisitsByUser(ctx context.Context, userID int64, limit, offset int) ([]models.Visit, error)
 	GetVisitByContact(ctx context.Context, contactID int64) (*models.Visit, error)
+	GetVisitByID(ctx context.Context, visitID int64) (*models.Visit, error)
 	UpdateVisitStatus(ctx context.Context, visitID int64, status string) error
 
 	// Property Visit Config (Wizard Output)
@@ -59,12 +69,39 @@ type Store interface {
 	GetBroadcastByID(ctx context.Context, broadcastID int64) (*models.Broadcast, error)
 	UpdateBroadcastStatus(ctx context.Context, broadcastID int64, status string, totalSent, totalFailed int) error
 
+	// Business hours
+	GetBusinessCalendar(ctx context.Context, userID int64) (*models.BusinessCalendar, error)
+	UpsertBusinessCalendar(ctx context.Context, cal *models.BusinessCalendar) error
+
+	// Agents & Handoffs
+	CreateAgent(ctx context.Context, a *models.Agent) error
+	ListAgents(ctx context.Context, userID int64) ([]models.Agent, error)
+	GetAgentByID(ctx context.Context, agentID int64) (*models.Agent, error)
+	UpdateAgent(ctx context.Context, a *models.Agent) error
+	DeleteAgent(ctx context.Context, agentID, userID int64) error
+	PickAgent(ctx context.Context, userID int64, team, strategy string, excludeAgentID int64) (*models.Agent, error)
+	CreateHandoff(ctx context.Context, h *models.Handoff) error
+	GetHandoffByID(ctx context.Context, handoffID int64) (*models.Handoff, error)
+	GetOpenHandoffByContact(ctx context.Context, contactID int64) (*models.Handoff, error)
+	ListHandoffs(ctx context.Context, userID int64, status string, limit, offset int) ([]models.Handoff, error)
+	UpdateHandoffAssignment(ctx context.Context, h *models.Handoff) error
+	MarkHandoffResponded(ctx context.Context, contactID int64) error
+	ResolveHandoffs(ctx context.Context, contactID int64) error
+	CreateContactNote(ctx context.Context, n *models.ContactNote) error
+	GetContactNotes(ctx context.Context, contactID int64, limit int) ([]models.ContactNote, error)
+
 	// Automations
 	CreateAutomation(ctx context.Context, a *models.Automation) error
 	GetAutomationsByUser(ctx context.Context, userID int64) ([]models.Automation, error)
 	UpdateAutomation(ctx context.Context, a *models.Automation) error
 	DeleteAutomation(ctx context.Context, automationID, userID int64) error
 
+	// Tenant Secrets (HTTP request nodes)
+	UpsertTenantSecret(ctx context.Context, secret *models.TenantSecret) error
+	GetTenantSecrets(ctx context.Context, userID int64) (map[string]string, error)
+	ListTenantSecrets(ctx context.Context, userID int64) ([]models.TenantSecret, error)
+	DeleteTenantSecret(ctx context.Context, userID int64, name string) error
+
 	// Knowledge Base (RAG)
 	CreateKnowledgeBaseEntry(ctx context.Context, entry *models.KnowledgeBase, embedding []float32) error
 	GetKnowledgeBaseEntriesByUser(ctx context.Context, userID int64) ([]models.KnowledgeBase, error)
@@ -76,13 +113,43 @@ type Store interface {
 	GetWorkflowByID(ctx context.Context, workflowID int64) (*models.Workflow, error)
 	GetWorkflowsByUser(ctx context.Context, userID int64) ([]models.Workflow, error)
 	GetActiveWorkflowsByTrigger(ctx context.Context, userID int64, triggerType string) ([]models.Workflow, error)
+	GetPublishedWorkflowsByTrigger(ctx context.Context, triggerType string) ([]models.Workflow, error)
+	GetTriggerLastRun(ctx context.Context, workflowID int64, nodeID string) (time.Time, error)
+	AdvanceTriggerRun(ctx context.Context, workflowID int64, nodeID string, prev, next time.Time) (bool, error)
 	UpdateWorkflow(ctx context.Context, w *models.Workflow) error
 	DeleteWorkflow(ctx context.Context, workflowID, userID int64) error
 
+	// Workflow Versions
+	CreateWorkflowVersion(ctx context.Context, v *models.WorkflowVersion) error
+	PublishWorkflowVersion(ctx context.Context, w *models.Workflow, v *models.WorkflowVersion) error
+	GetWorkflowVersionByID(ctx context.Context, versionID int64) (*models.WorkflowVersion, error)
+	GetWorkflowVersion(ctx context.Context, workflowID int64, version int) (*models.WorkflowVersion, error)
+	GetLatestWorkflowVersion(ctx context.Context, workflowID int64) (*models.WorkflowVersion, error)
+	ListWorkflowVersions(ctx context.Context, workflowID int64) ([]models.WorkflowVersion, error)
+
 	// Workflow Executions
 	CreateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error
 	GetWorkflowExecutionByID(ctx context.Context, executionID int64) (*models.WorkflowExecution, error)
 	UpdateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error
+	GetExecutionsAwaitingReply(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error)
+	ClaimWaitingExecution(ctx context.Context, executionID int64, waitingFor string) (bool, error)
+	ListWorkflowExecutions(ctx context.Context, userID int64, filter ExecutionFilter) ([]models.WorkflowExecution, error)
+	GetActiveExecutionsByContact(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error)
+	SetWorkflowExecutionStatus(ctx context.Context, executionID int64, from []string, status string) (bool, error)
+	MarkExecutionGoalReached(ctx context.Context, executionID int64, goal string) (bool, error)
+	GetLastExecutionStart(ctx context.Context, workflowID, contactID int64) (time.Time, error)
+	ClaimQueuedExecution(ctx context.Context, workflowID, contactID int64) (int64, error)
+	SetExecutionSplitBranch(ctx context.Context, executionID int64, nodeID, branch string) error
+	GetSplitReport(ctx context.Context, workflowID int64, goal SplitGoal, since time.Time) ([]SplitBranchCount, error)
+
+	// Workflow Execution Steps (timeline)
+	CreateWorkflowExecutionStep(ctx context.Context, step *models.WorkflowExecutionStep) error
+	GetWorkflowExecutionSteps(ctx context.Context, executionID int64) ([]models.WorkflowExecutionStep, error)
+
+	// LLM usage metering
+	CreateLLMUsage(ctx context.Context, u *models.LLMUsage) error
+	GetLLMSpend(ctx context.Context, userID int64, since time.Time) (float64, error)
+	GetLLMUsageReport(ctx context.Context, userID int64, from, to time.Time, groupBy string) ([]models.LLMUsageSummary, error)
 }
 
 // Ensure Storage implements Store at compile time.
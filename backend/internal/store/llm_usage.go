@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Groupings of a usage report (GetLLMUsageReport)
+const (
+	UsageByWorkflow = "workflow"
+	UsageByNode     = "node"
+	UsageByModel    = "model"
+	UsageByFeature  = "feature"
+	UsageByDay      = "day"
+)
+
+// usageGroupKeys maps a grouping to its key expression; the workflow ID is kept for workflow and node
+var usageGroupKeys = map[string]string{
+	UsageByWorkflow: `COALESCE(workflow_id::text, '')`,
+	UsageByNode:     `COALESCE(workflow_id::text, '') || ':' || node_id`,
+	UsageByModel:    `provider || '/' || model`,
+	UsageByFeature:  `feature`,
+	UsageByDay:      `to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')`,
+}
+
+// CreateLLMUsage records one metered LLM call.
+func (s *Storage) CreateLLMUsage(ctx context.Context, u *models.LLMUsage) error {
+	query := `
+		INSERT INTO llm_usage (user_id, workflow_id, node_id, feature, method, provider, model,
+		                       prompt_tokens, completion_tokens, cost_usd, latency_ms, success, created_at)
+		VALUES (NULLIF($1, 0), NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`
+
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	return s.DB.QueryRow(ctx, query,
+		u.UserID, u.WorkflowID, u.NodeID, u.Feature, u.Method, u.Provider, u.Model,
+		u.PromptTokens, u.CompletionTokens, u.CostUSD, u.LatencyMs, u.Success, u.CreatedAt,
+	).Scan(&u.ID)
+}
+
+// GetLLMSpend returns a tenant's LLM cost in USD since the given time.
+func (s *Storage) GetLLMSpend(ctx context.Context, userID int64, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(cost_usd), 0)::float8
+		FROM llm_usage
+		WHERE user_id = $1 AND created_at >= $2`
+
+	var spend float64
+	err := s.DB.QueryRow(ctx, query, userID, since).Scan(&spend)
+	return spend, err
+}
+
+// GetLLMUsageReport totals a tenant's LLM calls in [from, to) by one of the Usage* groupings,
+// most expensive first.
+func (s *Storage) GetLLMUsageReport(ctx context.Context, userID int64, from, to time.Time, groupBy string) ([]models.LLMUsageSummary, error) {
+	key, ok := usageGroupKeys[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage grouping %q", groupBy)
+	}
+	workflowID := `0::bigint`
+	if groupBy == UsageByWorkflow || groupBy == UsageByNode {
+		workflowID = `COALESCE(workflow_id, 0)`
+	}
+	query := fmt.Sprintf(`
+		SELECT %s AS key, %s AS workflow_id,
+		       COUNT(*), COUNT(*) FILTER (WHERE NOT success),
+		       COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+		       COALESCE(SUM(cost_usd), 0)::float8, COALESCE(AVG(latency_ms), 0)::bigint
+		FROM llm_usage
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY 1, 2
+		ORDER BY 7 DESC, 1 ASC`, key, workflowID)
+
+	rows, err := s.DB.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.LLMUsageSummary
+	for rows.Next() {
+		var u models.LLMUsageSummary
+		if err := rows.Scan(&u.Key, &u.WorkflowID, &u.Calls, &u.Failures, &u.PromptTokens, &u.CompletionTokens, &u.CostUSD, &u.AvgLatencyMs); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
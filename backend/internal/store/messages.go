@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/social-media-lead/backend/internal/models"
 )
 
@@ -51,6 +53,50 @@ func (s *Storage) GetMessagesByContact(ctx context.Context, contactID int64, lim
 	return messages, nil
 }
 
+// GetRecentMessagesByContact returns the newest limit messages of a contact, oldest first.
+func (s *Storage) GetRecentMessagesByContact(ctx context.Context, contactID int64, limit int) ([]models.Message, error) {
+	query := `
+		SELECT id, user_id, channel_id, contact_id, platform, direction, content, message_type, platform_msg_id, status, is_automated, created_at
+		FROM messages
+		WHERE contact_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := s.DB.Query(ctx, query, contactID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// GetMessagesByContactAfter returns up to limit messages of a contact with an ID above afterID,
+// oldest first.
+func (s *Storage) GetMessagesByContactAfter(ctx context.Context, contactID, afterID int64, limit int) ([]models.Message, error) {
+	query := `
+		SELECT id, user_id, channel_id, contact_id, platform, direction, content, message_type, platform_msg_id, status, is_automated, created_at
+		FROM messages
+		WHERE contact_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3`
+
+	rows, err := s.DB.Query(ctx, query, contactID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
 // GetConversations returns the latest message per contact for a user (inbox view).
 func (s *Storage) GetConversations(ctx context.Context, userID int64, limit, offset int) ([]models.Message, error) {
 	query := `
@@ -81,3 +127,53 @@ func (s *Storage) GetConversations(ctx context.Context, userID int64, limit, off
 	}
 	return messages, nil
 }
+
+// GetConversationSummary returns the contact's rolling conversation summary, or nil, nil when
+// none has been written yet.
+func (s *Storage) GetConversationSummary(ctx context.Context, contactID int64) (*models.ConversationSummary, error) {
+	query := `
+		SELECT contact_id, summary, last_message_id, updated_at
+		FROM conversation_summaries
+		WHERE contact_id = $1`
+
+	var cs models.ConversationSummary
+	err := s.DB.QueryRow(ctx, query, contactID).Scan(&cs.ContactID, &cs.Summary, &cs.LastMessageID, &cs.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+// SaveConversationSummary creates or replaces the contact's rolling summary. A summary never
+// moves backwards: a concurrent writer that covered newer messages wins.
+func (s *Storage) SaveConversationSummary(ctx context.Context, cs *models.ConversationSummary) error {
+	query := `
+		INSERT INTO conversation_summaries (contact_id, summary, last_message_id, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET summary = EXCLUDED.summary, last_message_id = EXCLUDED.last_message_id, updated_at = EXCLUDED.updated_at
+		WHERE conversation_summaries.last_message_id <= EXCLUDED.last_message_id`
+
+	cs.UpdatedAt = time.Now()
+	_, err := s.DB.Exec(ctx, query, cs.ContactID, cs.Summary, cs.LastMessageID, cs.UpdatedAt)
+	return err
+}
+
+func scanMessages(rows pgx.Rows) ([]models.Message, error) {
+	var messages []models.Message
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(
+			&m.ID, &m.UserID, &m.ChannelID, &m.ContactID, &m.Platform,
+			&m.Direction, &m.Content, &m.MessageType, &m.PlatformMsgID,
+			&m.Status, &m.IsAutomated, &m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
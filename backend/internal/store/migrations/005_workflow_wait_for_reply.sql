@@ -0,0 +1,9 @@
+-- 005_workflow_wait_for_reply.sql
+-- Lets an execution pause until the contact replies (action_wait_for_reply).
+
+-- '' while running, 'delay' for action_delay, 'reply' for action_wait_for_reply
+ALTER TABLE workflow_executions ADD COLUMN IF NOT EXISTS waiting_for VARCHAR(20) NOT NULL DEFAULT '';
+
+-- Inbound messages look up executions parked on a reply for the sending contact
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_awaiting_reply
+    ON workflow_executions(contact_id) WHERE status = 'waiting' AND waiting_for = 'reply';
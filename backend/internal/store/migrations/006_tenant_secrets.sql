@@ -0,0 +1,13 @@
+-- 006_tenant_secrets.sql
+-- Named per-tenant secrets (API keys, bearer tokens) that action_http_request nodes
+-- reference as {{secret.NAME}}. Values are never returned by the API.
+
+CREATE TABLE IF NOT EXISTS tenant_secrets (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name       VARCHAR(100) NOT NULL,
+    value      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
@@ -0,0 +1,17 @@
+-- 007_workflow_versions.sql
+-- Immutable snapshots of a workflow graph, one per publish (or rollback).
+
+CREATE TABLE IF NOT EXISTS workflow_versions (
+    id BIGSERIAL PRIMARY KEY,
+    workflow_id BIGINT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    nodes JSONB NOT NULL DEFAULT '[]',
+    edges JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (workflow_id, version)
+);
+
+-- Executions pin the version they started on so edits never change a running flow.
+-- NULL for executions created before versioning; those resume on the live graph.
+ALTER TABLE workflow_executions
+    ADD COLUMN IF NOT EXISTS workflow_version_id BIGINT REFERENCES workflow_versions(id);
@@ -0,0 +1,21 @@
+-- 008_workflow_execution_steps.sql
+-- One row per node an execution processed, for the execution timeline.
+
+CREATE TABLE IF NOT EXISTS workflow_execution_steps (
+    id BIGSERIAL PRIMARY KEY,
+    execution_id BIGINT NOT NULL REFERENCES workflow_executions(id) ON DELETE CASCADE,
+    node_id VARCHAR(100) NOT NULL,
+    node_type VARCHAR(50) NOT NULL,
+    branch VARCHAR(100) NOT NULL DEFAULT '',
+    input JSONB NOT NULL DEFAULT '{}',
+    output JSONB NOT NULL DEFAULT '{}',
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_steps_execution ON workflow_execution_steps(execution_id, id);
+
+-- Execution listings filter by workflow or contact, newest first
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_workflow ON workflow_executions(workflow_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_contact ON workflow_executions(contact_id, created_at DESC);
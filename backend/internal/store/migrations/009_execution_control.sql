@@ -0,0 +1,10 @@
+-- 009_execution_control.sql
+-- Goal (exit) conditions per workflow. Executions additionally use the statuses
+-- 'paused', 'cancelled' and 'goal_reached', and wait on 'agent' while the bot is paused.
+
+ALTER TABLE workflows
+    ADD COLUMN IF NOT EXISTS goals JSONB NOT NULL DEFAULT '[]';
+
+-- Goal checks and bot resumes look up a contact's unfinished executions
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_contact_active
+    ON workflow_executions(contact_id) WHERE status IN ('running', 'waiting', 'paused');
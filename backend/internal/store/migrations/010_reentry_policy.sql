@@ -0,0 +1,19 @@
+-- 010_reentry_policy.sql
+-- Per-workflow re-entry policy ('parallel', 'skip', 'restart', 'queue') and re-trigger cooldown.
+
+ALTER TABLE workflows
+    ADD COLUMN IF NOT EXISTS reentry_policy VARCHAR(20) NOT NULL DEFAULT 'skip',
+    ADD COLUMN IF NOT EXISTS cooldown_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- Executions started under any policy but 'parallel' are exclusive: at most one of them may be
+-- active per workflow and contact. Queued executions wait outside the index until promoted.
+ALTER TABLE workflow_executions
+    ADD COLUMN IF NOT EXISTS exclusive BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_executions_one_active
+    ON workflow_executions(workflow_id, contact_id)
+    WHERE exclusive AND status IN ('running', 'waiting', 'paused');
+
+-- Cooldown checks and queue promotion look up a contact's runs of one workflow
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_workflow_contact
+    ON workflow_executions(workflow_id, contact_id, created_at);
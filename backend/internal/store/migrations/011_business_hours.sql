@@ -0,0 +1,16 @@
+-- 011_business_hours.sql
+-- Tenant timezone, weekly business hours and holidays. Used by logic_time_window nodes,
+-- automations restricted to open/closed hours and the property visit slot offering.
+
+CREATE TABLE IF NOT EXISTS business_calendars (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    timezone     VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    weekly_hours JSONB NOT NULL DEFAULT '{}',
+    holidays     TEXT[] NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 'always', 'open' (only during business hours) or 'closed' (only outside them)
+ALTER TABLE automations ADD COLUMN IF NOT EXISTS active_hours VARCHAR(10) NOT NULL DEFAULT 'always';
@@ -0,0 +1,14 @@
+-- 012_event_triggers.sql
+-- Lookups behind scheduled, contact-event and inactivity workflow triggers.
+
+-- The scheduler scans published workflows of every tenant by trigger type
+CREATE INDEX IF NOT EXISTS idx_workflows_trigger_published
+    ON workflows(trigger_type)
+    WHERE status = 'published';
+
+-- Schedule fan-outs may be limited to contacts carrying any of a set of tags
+CREATE INDEX IF NOT EXISTS idx_contacts_tags ON contacts USING GIN (tags);
+
+-- Inactivity triggers look up each contact's last inbound message
+CREATE INDEX IF NOT EXISTS idx_messages_contact_direction_created
+    ON messages(contact_id, direction, created_at DESC);
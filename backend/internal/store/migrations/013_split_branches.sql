@@ -0,0 +1,10 @@
+-- 013_split_branches.sql
+-- Branches logic_split (A/B test) nodes sent each execution down: {"<node id>": "<branch handle>"}.
+
+ALTER TABLE workflow_executions
+    ADD COLUMN IF NOT EXISTS split_branches JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Split reports read the executions of one workflow that went through a split
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_split
+    ON workflow_executions(workflow_id)
+    WHERE split_branches <> '{}'::jsonb;
@@ -0,0 +1,11 @@
+-- 014_sub_workflows.sql
+-- Links executions started by an action_call_workflow node to the execution that called them.
+
+ALTER TABLE workflow_executions
+    ADD COLUMN IF NOT EXISTS parent_execution_id BIGINT REFERENCES workflow_executions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS parent_node_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS call_depth INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_parent
+    ON workflow_executions(parent_execution_id)
+    WHERE parent_execution_id IS NOT NULL;
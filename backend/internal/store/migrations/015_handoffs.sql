@@ -0,0 +1,56 @@
+-- 015_handoffs.sql
+-- Human agents of a tenant, conversations workflows hand off to them (action_handoff) with their
+-- SLA state, and internal notes on contacts that only agents see.
+
+CREATE TABLE IF NOT EXISTS agents (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name             VARCHAR(255) NOT NULL,
+    email            VARCHAR(255) NOT NULL DEFAULT '',
+    team             VARCHAR(100) NOT NULL DEFAULT '',
+    is_active        BOOLEAN NOT NULL DEFAULT TRUE,
+    last_assigned_at TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agents_user_team ON agents(user_id, team) WHERE is_active;
+
+CREATE TABLE IF NOT EXISTS handoffs (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    contact_id        BIGINT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    execution_id      BIGINT REFERENCES workflow_executions(id) ON DELETE SET NULL,
+    node_id           VARCHAR(100) NOT NULL DEFAULT '',
+    agent_id          BIGINT REFERENCES agents(id) ON DELETE SET NULL,
+    team              VARCHAR(100) NOT NULL DEFAULT '',
+    strategy          VARCHAR(20) NOT NULL DEFAULT 'round_robin', -- 'round_robin' or 'least_load'
+    status            VARCHAR(20) NOT NULL DEFAULT 'open',        -- 'open' or 'resolved'
+    summary           TEXT NOT NULL DEFAULT '',
+    sla_minutes       INT NOT NULL DEFAULT 0,
+    on_breach         VARCHAR(20) NOT NULL DEFAULT 'escalate',    -- 'escalate' or 'reroute'
+    escalation_team   VARCHAR(100) NOT NULL DEFAULT '',
+    max_escalations   INT NOT NULL DEFAULT 1,
+    escalations       INT NOT NULL DEFAULT 0,
+    sla_due_at        TIMESTAMPTZ,
+    first_response_at TIMESTAMPTZ,
+    resolved_at       TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A contact has at most one open handoff; least-load assignment counts open handoffs per agent
+CREATE UNIQUE INDEX IF NOT EXISTS idx_handoffs_open_contact ON handoffs(contact_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_handoffs_open_agent ON handoffs(agent_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_handoffs_user ON handoffs(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS contact_notes (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    author     VARCHAR(255) NOT NULL DEFAULT '', -- agent name, or 'bot' for notes written by workflows
+    body       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_notes_contact ON contact_notes(contact_id, created_at DESC);
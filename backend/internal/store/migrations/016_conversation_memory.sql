@@ -0,0 +1,10 @@
+-- 016_conversation_memory.sql
+-- Rolling summary of a contact's older conversation, kept by action_ai_reply so replies can
+-- remember more than the recent messages that fit in the model's context.
+
+CREATE TABLE IF NOT EXISTS conversation_summaries (
+    contact_id      BIGINT PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+    summary         TEXT NOT NULL DEFAULT '',
+    last_message_id BIGINT NOT NULL DEFAULT 0, -- newest message the summary covers
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,22 @@
+-- 017_llm_usage.sql
+-- One row per metered LLM call, attributed to the tenant, workflow and node that made it, so
+-- spend can be reported and capped by monthly plan budgets.
+
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           BIGINT REFERENCES users(id) ON DELETE CASCADE, -- NULL for calls outside a tenant
+    workflow_id       BIGINT REFERENCES workflows(id) ON DELETE SET NULL,
+    node_id           VARCHAR(100) NOT NULL DEFAULT '',
+    feature           VARCHAR(50) NOT NULL DEFAULT '', -- node type or API feature, e.g. generate_workflow
+    method            VARCHAR(30) NOT NULL,            -- chat, generate_text, structured_json, embedding
+    provider          VARCHAR(30) NOT NULL DEFAULT '',
+    model             VARCHAR(100) NOT NULL DEFAULT '',
+    prompt_tokens     INT NOT NULL DEFAULT 0,
+    completion_tokens INT NOT NULL DEFAULT 0,
+    cost_usd          NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    latency_ms        INT NOT NULL DEFAULT 0,
+    success           BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_created ON llm_usage(user_id, created_at);
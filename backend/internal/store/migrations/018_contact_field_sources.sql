@@ -0,0 +1,14 @@
+-- Provenance of a contact's lead qualification fields: whether an agent typed the value in or
+-- the AI extracted it from the conversation, and how sure it was. Extraction never overwrites
+-- a field an agent set.
+
+CREATE TABLE IF NOT EXISTS contact_field_sources (
+    contact_id  BIGINT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    field       VARCHAR(50) NOT NULL, -- budget, preferred_location, purchase_timeline, phone, is_hot_lead
+    source      VARCHAR(20) NOT NULL, -- agent, ai
+    confidence  NUMERIC(4,3) NOT NULL DEFAULT 1,
+    message_id  BIGINT NOT NULL DEFAULT 0, -- newest message the AI value was extracted from
+    evidence    TEXT NOT NULL DEFAULT '',  -- what the lead said
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (contact_id, field)
+);
@@ -0,0 +1,7 @@
+-- 019_workflow_drafts.sql
+-- Drafts proposed by the AI workflow editor are kept as versions that were never published.
+-- They number along with the published versions so they can be diffed against them, and
+-- publishing one copies it into a new published version like a rollback does.
+
+ALTER TABLE workflow_versions
+    ADD COLUMN IF NOT EXISTS draft BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+-- 020_trigger_runs.sql
+-- When each schedule and inactivity trigger last fired. The scheduler fires every occurrence
+-- since then, so a tick that runs late, or not at all while workers are down, does not lose a
+-- cron occurrence, and two ticks landing in the same minute cannot both fire it.
+
+CREATE TABLE IF NOT EXISTS workflow_trigger_runs (
+    workflow_id  BIGINT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    node_id      VARCHAR(100) NOT NULL,
+    last_run_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (workflow_id, node_id)
+);
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// Tenant secret values are encrypted with AES-256-GCM under a server key and stored as
+// encryptedSecretPrefix followed by the base64 nonce and ciphertext. Values written before
+// encryption was introduced are plaintext until EncryptTenantSecrets rewrites them.
+const encryptedSecretPrefix = "enc:v1:"
+
+// ErrNoSecretKey is returned when secrets are written or read without a server key.
+var ErrNoSecretKey = errors.New("no secrets encryption key configured")
+
+// SetSecretKey sets the 32-byte server key, hex or base64 encoded, that encrypts tenant secrets.
+func (s *Storage) SetSecretKey(encoded string) error {
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(encoded)
+	}
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("secrets encryption key must be 32 bytes, hex or base64 encoded")
+	}
+	s.secretKey = key
+	return nil
+}
+
+func (s *Storage) secretCipher() (cipher.AEAD, error) {
+	if s.secretKey == nil {
+		return nil, ErrNoSecretKey
+	}
+	block, err := aes.NewCipher(s.secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *Storage) sealSecret(plain string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *Storage) openSecret(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedSecretPrefix)
+	if !ok {
+		return stored, nil // not yet encrypted
+	}
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plain), nil
+}
+
+// EncryptTenantSecrets encrypts the secret values still stored in plaintext and returns how many
+// it rewrote.
+func (s *Storage) EncryptTenantSecrets(ctx context.Context) (int, error) {
+	rows, err := s.DB.Query(ctx, `SELECT id, value FROM tenant_secrets WHERE value NOT LIKE $1`, encryptedSecretPrefix+"%")
+	if err != nil {
+		return 0, err
+	}
+	plain := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plain[id] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, value := range plain {
+		sealed, err := s.sealSecret(value)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := s.DB.Exec(ctx, `UPDATE tenant_secrets SET value = $2 WHERE id = $1`, id, sealed); err != nil {
+			return 0, err
+		}
+	}
+	return len(plain), nil
+}
+
+// UpsertTenantSecret creates or replaces a named secret for a user. The value is stored encrypted.
+func (s *Storage) UpsertTenantSecret(ctx context.Context, secret *models.TenantSecret) error {
+	sealed, err := s.sealSecret(secret.Value)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tenant_secrets (user_id, name, value, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (user_id, name)
+		DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at, updated_at`
+
+	return s.DB.QueryRow(ctx, query, secret.UserID, secret.Name, sealed, time.Now()).
+		Scan(&secret.ID, &secret.CreatedAt, &secret.UpdatedAt)
+}
+
+// GetTenantSecrets returns all secrets of a user (including decrypted values), keyed by name.
+func (s *Storage) GetTenantSecrets(ctx context.Context, userID int64) (map[string]string, error) {
+	query := `SELECT name, value FROM tenant_secrets WHERE user_id = $1`
+
+	rows, err := s.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	secrets := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		if secrets[name], err = s.openSecret(value); err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+	}
+	return secrets, rows.Err()
+}
+
+// ListTenantSecrets returns secret metadata for a user. Values are left empty.
+func (s *Storage) ListTenantSecrets(ctx context.Context, userID int64) ([]models.TenantSecret, error) {
+	query := `
+		SELECT id, user_id, name, created_at, updated_at
+		FROM tenant_secrets
+		WHERE user_id = $1
+		ORDER BY name ASC`
+
+	rows, err := s.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []models.TenantSecret
+	for rows.Next() {
+		var sec models.TenantSecret
+		if err := rows.Scan(&sec.ID, &sec.UserID, &sec.Name, &sec.CreatedAt, &sec.UpdatedAt); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, sec)
+	}
+	return secrets, rows.Err()
+}
+
+// DeleteTenantSecret removes a named secret.
+func (s *Storage) DeleteTenantSecret(ctx context.Context, userID int64, name string) error {
+	query := `DELETE FROM tenant_secrets WHERE user_id = $1 AND name = $2`
+	_, err := s.DB.Exec(ctx, query, userID, name)
+	return err
+}
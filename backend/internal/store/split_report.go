@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Conversion goals a split report can measure (SplitGoal.Kind)
+const (
+	SplitGoalReplied     = "replied"      // contact sent a message after entering the workflow
+	SplitGoalVisitBooked = "visit_booked" // contact booked a visit after entering the workflow
+	SplitGoalTag         = "tag"          // contact carries the tag in Value
+	SplitGoalHotLead     = "hot_lead"     // contact is marked as a hot lead
+	SplitGoalWorkflow    = "goal"         // execution ended on a workflow goal, named Value if set
+)
+
+// SplitGoal is the conversion a split report counts
+type SplitGoal struct {
+	Kind  string
+	Value string
+}
+
+// SplitBranchCount is how many distinct contacts a logic_split node sent down a branch, and how
+// many of them converted
+type SplitBranchCount struct {
+	NodeID      string
+	Branch      string
+	Contacts    int
+	Conversions int
+}
+
+// GetSplitReport counts contacts and conversions per logic_split branch of a workflow. Contacts
+// are counted once per branch however often they entered; a contact converts if any of its
+// executions on the branch did. Executions created before since (if set) are ignored.
+func (s *Storage) GetSplitReport(ctx context.Context, workflowID int64, goal SplitGoal, since time.Time) ([]SplitBranchCount, error) {
+	query := `
+		WITH assigned AS (
+			SELECT b.key AS node_id, b.value AS branch, e.contact_id,
+			       MIN(e.created_at) AS entered_at,
+			       BOOL_OR(e.status = 'goal_reached' AND ($3 = '' OR e.state_data->>'goal_reached' = $3)) AS goal_reached
+			FROM workflow_executions e
+			CROSS JOIN LATERAL jsonb_each_text(e.split_branches) b
+			WHERE e.workflow_id = $1 AND ($4::timestamptz IS NULL OR e.created_at >= $4)
+			GROUP BY b.key, b.value, e.contact_id
+		)
+		SELECT a.node_id, a.branch, COUNT(*),
+		       COUNT(*) FILTER (WHERE CASE $2
+		           WHEN 'replied' THEN EXISTS (
+		               SELECT 1 FROM messages m
+		               WHERE m.contact_id = a.contact_id AND m.direction = 'inbound' AND m.created_at > a.entered_at)
+		           WHEN 'visit_booked' THEN EXISTS (
+		               SELECT 1 FROM visits v WHERE v.contact_id = a.contact_id AND v.created_at > a.entered_at)
+		           WHEN 'tag' THEN EXISTS (
+		               SELECT 1 FROM contacts c WHERE c.id = a.contact_id AND $3 = ANY(COALESCE(c.tags, '{}')))
+		           WHEN 'hot_lead' THEN EXISTS (
+		               SELECT 1 FROM contacts c WHERE c.id = a.contact_id AND c.is_hot_lead)
+		           WHEN 'goal' THEN a.goal_reached
+		           ELSE FALSE
+		       END)
+		FROM assigned a
+		GROUP BY a.node_id, a.branch
+		ORDER BY a.node_id, a.branch
+	`
+	var sinceArg *time.Time
+	if !since.IsZero() {
+		sinceArg = &since
+	}
+
+	rows, err := s.DB.Query(ctx, query, workflowID, goal.Kind, goal.Value, sinceArg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []SplitBranchCount
+	for rows.Next() {
+		var c SplitBranchCount
+		if err := rows.Scan(&c.NodeID, &c.Branch, &c.Contacts, &c.Conversions); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -12,6 +12,8 @@ import (
 // Storage wraps the database connection pool.
 type Storage struct {
 	DB *pgxpool.Pool
+
+	secretKey []byte // encrypts tenant secrets at rest, see SetSecretKey
 }
 
 // New creates a new Storage with a connection pool.
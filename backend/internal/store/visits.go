@@ -71,6 +71,24 @@ func (s *Storage) GetVisitByContact(ctx context.Context, contactID int64) (*mode
 	return &v, nil
 }
 
+func (s *Storage) GetVisitByID(ctx context.Context, visitID int64) (*models.Visit, error) {
+	query := `
+		SELECT id, user_id, contact_id, project_name, visit_time, status, lead_source_channel, created_at, updated_at
+		FROM visits
+		WHERE id = $1
+	`
+	var v models.Visit
+	err := s.DB.QueryRow(ctx, query, visitID).Scan(
+		&v.ID, &v.UserID, &v.ContactID, &v.ProjectName,
+		&v.VisitTime, &v.Status, &v.LeadSourceChannel,
+		&v.CreatedAt, &v.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
 func (s *Storage) UpdateVisitStatus(ctx context.Context, visitID int64, status string) error {
 	query := `
 		UPDATE visits
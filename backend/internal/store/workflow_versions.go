@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+
+	"github.com/social-media-lead/backend/internal/models"
+)
+
+// ============================================
+// Workflow Versions (immutable publish snapshots)
+// ============================================
+
+// CreateWorkflowVersion snapshots a graph as the next version number of its workflow.
+const insertWorkflowVersionQuery = `
+		INSERT INTO workflow_versions (workflow_id, version, nodes, edges, draft)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4
+		FROM workflow_versions WHERE workflow_id = $1
+		RETURNING id, version, created_at
+	`
+
+func (s *Storage) CreateWorkflowVersion(ctx context.Context, v *models.WorkflowVersion) error {
+	return s.DB.QueryRow(ctx, insertWorkflowVersionQuery, v.WorkflowID, v.Nodes, v.Edges, v.Draft).Scan(&v.ID, &v.Version, &v.CreatedAt)
+}
+
+// PublishWorkflowVersion saves w and records v as its newest version in one transaction, so the
+// live graph never changes without a matching snapshot for executions to pin.
+func (s *Storage) PublishWorkflowVersion(ctx context.Context, w *models.Workflow, v *models.WorkflowVersion) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, updateWorkflowQuery,
+		w.Name, w.Status, w.Prompt, w.Nodes, w.Edges, goalsOrEmpty(w.Goals),
+		reentryPolicyOrDefault(w.ReentryPolicy), w.CooldownMinutes, w.TriggerType, w.ID, w.UserID,
+	).Scan(&w.ReentryPolicy, &w.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	v.WorkflowID = w.ID
+	if err := tx.QueryRow(ctx, insertWorkflowVersionQuery, v.WorkflowID, v.Nodes, v.Edges, v.Draft).Scan(&v.ID, &v.Version, &v.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetWorkflowVersionByID loads a snapshot by its primary key (what executions reference).
+func (s *Storage) GetWorkflowVersionByID(ctx context.Context, versionID int64) (*models.WorkflowVersion, error) {
+	query := `
+		SELECT id, workflow_id, version, draft, nodes, edges, created_at
+		FROM workflow_versions WHERE id = $1
+	`
+	var v models.WorkflowVersion
+	err := s.DB.QueryRow(ctx, query, versionID).Scan(&v.ID, &v.WorkflowID, &v.Version, &v.Draft, &v.Nodes, &v.Edges, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetWorkflowVersion loads a snapshot by its per-workflow version number.
+func (s *Storage) GetWorkflowVersion(ctx context.Context, workflowID int64, version int) (*models.WorkflowVersion, error) {
+	query := `
+		SELECT id, workflow_id, version, draft, nodes, edges, created_at
+		FROM workflow_versions WHERE workflow_id = $1 AND version = $2
+	`
+	var v models.WorkflowVersion
+	err := s.DB.QueryRow(ctx, query, workflowID, version).Scan(&v.ID, &v.WorkflowID, &v.Version, &v.Draft, &v.Nodes, &v.Edges, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetLatestWorkflowVersion returns the most recently published snapshot of a workflow. Drafts
+// are skipped.
+func (s *Storage) GetLatestWorkflowVersion(ctx context.Context, workflowID int64) (*models.WorkflowVersion, error) {
+	query := `
+		SELECT id, workflow_id, version, nodes, edges, created_at
+		FROM workflow_versions WHERE workflow_id = $1 AND NOT draft
+		ORDER BY version DESC LIMIT 1
+	`
+	var v models.WorkflowVersion
+	err := s.DB.QueryRow(ctx, query, workflowID).Scan(&v.ID, &v.WorkflowID, &v.Version, &v.Nodes, &v.Edges, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListWorkflowVersions returns version metadata (without the graph), newest first.
+func (s *Storage) ListWorkflowVersions(ctx context.Context, workflowID int64) ([]models.WorkflowVersion, error) {
+	query := `
+		SELECT id, workflow_id, version, draft, created_at
+		FROM workflow_versions WHERE workflow_id = $1
+		ORDER BY version DESC
+	`
+	rows, err := s.DB.Query(ctx, query, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.WorkflowVersion
+	for rows.Next() {
+		var v models.WorkflowVersion
+		if err := rows.Scan(&v.ID, &v.WorkflowID, &v.Version, &v.Draft, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
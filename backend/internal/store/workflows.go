@@ -2,10 +2,23 @@ package store
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/social-media-lead/backend/internal/models"
 )
 
+// ErrActiveExecution is returned when an exclusive execution would run alongside another
+// active execution of the same workflow for the same contact.
+var ErrActiveExecution = errors.New("contact already has an active execution of this workflow")
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 // CreateKnowledgeBaseEntry adds a new RAG document chunk and its embedding to the DB.
 func (s *Storage) CreateKnowledgeBaseEntry(ctx context.Context, entry *models.KnowledgeBase, embedding []float32) error {
 	query := `
@@ -91,24 +104,25 @@ func (s *Storage) DeleteKnowledgeBaseEntry(ctx context.Context, entryID, userID
 
 func (s *Storage) CreateWorkflow(ctx context.Context, w *models.Workflow) error {
 	query := `
-		INSERT INTO workflows (user_id, name, trigger_type, status, prompt, nodes, edges)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at, updated_at
+		INSERT INTO workflows (user_id, name, trigger_type, status, prompt, nodes, edges, goals, reentry_policy, cooldown_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, reentry_policy, created_at, updated_at
 	`
 	return s.DB.QueryRow(ctx, query,
-		w.UserID, w.Name, w.TriggerType, w.Status, w.Prompt, w.Nodes, w.Edges,
-	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+		w.UserID, w.Name, w.TriggerType, w.Status, w.Prompt, w.Nodes, w.Edges, goalsOrEmpty(w.Goals),
+		reentryPolicyOrDefault(w.ReentryPolicy), w.CooldownMinutes,
+	).Scan(&w.ID, &w.ReentryPolicy, &w.CreatedAt, &w.UpdatedAt)
 }
 
 func (s *Storage) GetWorkflowByID(ctx context.Context, workflowID int64) (*models.Workflow, error) {
 	query := `
-		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, created_at, updated_at
+		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, goals, reentry_policy, cooldown_minutes, created_at, updated_at
 		FROM workflows WHERE id = $1
 	`
 	var w models.Workflow
 	err := s.DB.QueryRow(ctx, query, workflowID).Scan(
 		&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.Status, &w.Prompt,
-		&w.Nodes, &w.Edges, &w.CreatedAt, &w.UpdatedAt,
+		&w.Nodes, &w.Edges, &w.Goals, &w.ReentryPolicy, &w.CooldownMinutes, &w.CreatedAt, &w.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -118,7 +132,7 @@ func (s *Storage) GetWorkflowByID(ctx context.Context, workflowID int64) (*model
 
 func (s *Storage) GetWorkflowsByUser(ctx context.Context, userID int64) ([]models.Workflow, error) {
 	query := `
-		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, created_at, updated_at
+		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, goals, reentry_policy, cooldown_minutes, created_at, updated_at
 		FROM workflows WHERE user_id = $1 ORDER BY created_at DESC
 	`
 	rows, err := s.DB.Query(ctx, query, userID)
@@ -132,7 +146,7 @@ func (s *Storage) GetWorkflowsByUser(ctx context.Context, userID int64) ([]model
 		var w models.Workflow
 		if err := rows.Scan(
 			&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.Status, &w.Prompt,
-			&w.Nodes, &w.Edges, &w.CreatedAt, &w.UpdatedAt,
+			&w.Nodes, &w.Edges, &w.Goals, &w.ReentryPolicy, &w.CooldownMinutes, &w.CreatedAt, &w.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -143,7 +157,7 @@ func (s *Storage) GetWorkflowsByUser(ctx context.Context, userID int64) ([]model
 
 func (s *Storage) GetActiveWorkflowsByTrigger(ctx context.Context, userID int64, triggerType string) ([]models.Workflow, error) {
 	query := `
-		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, created_at, updated_at
+		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, goals, reentry_policy, cooldown_minutes, created_at, updated_at
 		FROM workflows 
 		WHERE user_id = $1 AND trigger_type = $2 AND status = 'published'
 	`
@@ -158,7 +172,7 @@ func (s *Storage) GetActiveWorkflowsByTrigger(ctx context.Context, userID int64,
 		var w models.Workflow
 		if err := rows.Scan(
 			&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.Status, &w.Prompt,
-			&w.Nodes, &w.Edges, &w.CreatedAt, &w.UpdatedAt,
+			&w.Nodes, &w.Edges, &w.Goals, &w.ReentryPolicy, &w.CooldownMinutes, &w.CreatedAt, &w.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -167,16 +181,101 @@ func (s *Storage) GetActiveWorkflowsByTrigger(ctx context.Context, userID int64,
 	return flows, nil
 }
 
-func (s *Storage) UpdateWorkflow(ctx context.Context, w *models.Workflow) error {
+// GetPublishedWorkflowsByTrigger returns the published workflows of every tenant with the given
+// trigger type. Used by the scheduler, which is not bound to a single tenant.
+func (s *Storage) GetPublishedWorkflowsByTrigger(ctx context.Context, triggerType string) ([]models.Workflow, error) {
 	query := `
+		SELECT id, user_id, name, trigger_type, status, prompt, nodes, edges, goals, reentry_policy, cooldown_minutes, created_at, updated_at
+		FROM workflows
+		WHERE trigger_type = $1 AND status = 'published'
+		ORDER BY id
+	`
+	rows, err := s.DB.Query(ctx, query, triggerType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flows []models.Workflow
+	for rows.Next() {
+		var w models.Workflow
+		if err := rows.Scan(
+			&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.Status, &w.Prompt,
+			&w.Nodes, &w.Edges, &w.Goals, &w.ReentryPolicy, &w.CooldownMinutes, &w.CreatedAt, &w.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		flows = append(flows, w)
+	}
+	return flows, rows.Err()
+}
+
+// GetTriggerLastRun returns when a schedule or inactivity trigger last fired, or the zero time if
+// it never has.
+func (s *Storage) GetTriggerLastRun(ctx context.Context, workflowID int64, nodeID string) (time.Time, error) {
+	var last time.Time
+	err := s.DB.QueryRow(ctx,
+		`SELECT last_run_at FROM workflow_trigger_runs WHERE workflow_id = $1 AND node_id = $2`,
+		workflowID, nodeID,
+	).Scan(&last)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return last, err
+}
+
+// AdvanceTriggerRun moves a trigger's last run from prev (zero if it never ran) to next. It
+// reports false when another tick advanced it first, so each occurrence fires once.
+func (s *Storage) AdvanceTriggerRun(ctx context.Context, workflowID int64, nodeID string, prev, next time.Time) (bool, error) {
+	var tag pgconn.CommandTag
+	var err error
+	if prev.IsZero() {
+		tag, err = s.DB.Exec(ctx, `
+			INSERT INTO workflow_trigger_runs (workflow_id, node_id, last_run_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (workflow_id, node_id) DO NOTHING`,
+			workflowID, nodeID, next)
+	} else {
+		tag, err = s.DB.Exec(ctx, `
+			UPDATE workflow_trigger_runs SET last_run_at = $4
+			WHERE workflow_id = $1 AND node_id = $2 AND last_run_at = $3`,
+			workflowID, nodeID, prev, next)
+	}
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+const updateWorkflowQuery = `
 		UPDATE workflows 
-		SET name = $1, status = $2, prompt = $3, nodes = $4, edges = $5, updated_at = NOW()
-		WHERE id = $6 AND user_id = $7
-		RETURNING updated_at
+		SET name = $1, status = $2, prompt = $3, nodes = $4, edges = $5, goals = $6,
+			reentry_policy = $7, cooldown_minutes = $8, trigger_type = $9, updated_at = NOW()
+		WHERE id = $10 AND user_id = $11
+		RETURNING reentry_policy, updated_at
 	`
-	return s.DB.QueryRow(ctx, query,
-		w.Name, w.Status, w.Prompt, w.Nodes, w.Edges, w.ID, w.UserID,
-	).Scan(&w.UpdatedAt)
+
+func (s *Storage) UpdateWorkflow(ctx context.Context, w *models.Workflow) error {
+	return s.DB.QueryRow(ctx, updateWorkflowQuery,
+		w.Name, w.Status, w.Prompt, w.Nodes, w.Edges, goalsOrEmpty(w.Goals),
+		reentryPolicyOrDefault(w.ReentryPolicy), w.CooldownMinutes, w.TriggerType, w.ID, w.UserID,
+	).Scan(&w.ReentryPolicy, &w.UpdatedAt)
+}
+
+// reentryPolicyOrDefault stores the column default for workflows saved without a policy
+func reentryPolicyOrDefault(policy string) string {
+	if policy == "" {
+		return "skip"
+	}
+	return policy
+}
+
+// goalsOrEmpty keeps the NOT NULL goals column valid for workflows saved without goals
+func goalsOrEmpty(goals []byte) []byte {
+	if len(goals) == 0 {
+		return []byte("[]")
+	}
+	return goals
 }
 
 func (s *Storage) DeleteWorkflow(ctx context.Context, workflowID, userID int64) error {
@@ -189,26 +288,35 @@ func (s *Storage) DeleteWorkflow(ctx context.Context, workflowID, userID int64)
 // Workflow Executions (Running State)
 // ============================================
 
+// CreateWorkflowExecution inserts an execution. An exclusive execution that would become the
+// contact's second active run of the workflow is rejected with ErrActiveExecution.
 func (s *Storage) CreateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error {
 	query := `
-		INSERT INTO workflow_executions (workflow_id, contact_id, current_node_id, status, state_data)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO workflow_executions (workflow_id, workflow_version_id, contact_id, current_node_id, status, waiting_for, state_data, exclusive,
+			parent_execution_id, parent_node_id, call_depth)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at
 	`
-	return s.DB.QueryRow(ctx, query,
-		exec.WorkflowID, exec.ContactID, exec.CurrentNodeID, exec.Status, exec.StateData,
+	err := s.DB.QueryRow(ctx, query,
+		exec.WorkflowID, exec.WorkflowVersionID, exec.ContactID, exec.CurrentNodeID, exec.Status, exec.WaitingFor, exec.StateData, exec.Exclusive,
+		exec.ParentExecutionID, exec.ParentNodeID, exec.CallDepth,
 	).Scan(&exec.ID, &exec.CreatedAt, &exec.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrActiveExecution
+	}
+	return err
 }
 
 func (s *Storage) GetWorkflowExecutionByID(ctx context.Context, executionID int64) (*models.WorkflowExecution, error) {
 	query := `
-		SELECT id, workflow_id, contact_id, current_node_id, status, state_data, created_at, updated_at
+		SELECT id, workflow_id, workflow_version_id, contact_id, current_node_id, status, waiting_for, state_data, split_branches, parent_execution_id, parent_node_id, call_depth, created_at, updated_at
 		FROM workflow_executions WHERE id = $1
 	`
 	var exec models.WorkflowExecution
 	err := s.DB.QueryRow(ctx, query, executionID).Scan(
-		&exec.ID, &exec.WorkflowID, &exec.ContactID, &exec.CurrentNodeID,
-		&exec.Status, &exec.StateData, &exec.CreatedAt, &exec.UpdatedAt,
+		&exec.ID, &exec.WorkflowID, &exec.WorkflowVersionID, &exec.ContactID, &exec.CurrentNodeID,
+		&exec.Status, &exec.WaitingFor, &exec.StateData, &exec.SplitBranches,
+		&exec.ParentExecutionID, &exec.ParentNodeID, &exec.CallDepth, &exec.CreatedAt, &exec.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -216,14 +324,167 @@ func (s *Storage) GetWorkflowExecutionByID(ctx context.Context, executionID int6
 	return &exec, nil
 }
 
+// UpdateWorkflowExecution saves the walker's progress. Rows an operator or goal has taken out of
+// the walker's hands (paused, cancelled, goal_reached) are left alone and pgx.ErrNoRows is returned.
 func (s *Storage) UpdateWorkflowExecution(ctx context.Context, exec *models.WorkflowExecution) error {
 	query := `
 		UPDATE workflow_executions 
-		SET current_node_id = $1, status = $2, state_data = $3, updated_at = NOW()
-		WHERE id = $4
+		SET current_node_id = $1, status = $2, waiting_for = $3, state_data = $4, updated_at = NOW()
+		WHERE id = $5 AND status NOT IN ('paused', 'cancelled', 'goal_reached')
 		RETURNING updated_at
 	`
 	return s.DB.QueryRow(ctx, query,
-		exec.CurrentNodeID, exec.Status, exec.StateData, exec.ID,
+		exec.CurrentNodeID, exec.Status, exec.WaitingFor, exec.StateData, exec.ID,
 	).Scan(&exec.UpdatedAt)
 }
+
+// SetExecutionSplitBranch records the branch a logic_split node sent the execution down.
+func (s *Storage) SetExecutionSplitBranch(ctx context.Context, executionID int64, nodeID, branch string) error {
+	query := `
+		UPDATE workflow_executions
+		SET split_branches = split_branches || jsonb_build_object($2::text, $3::text)
+		WHERE id = $1
+	`
+	_, err := s.DB.Exec(ctx, query, executionID, nodeID, branch)
+	return err
+}
+
+// GetExecutionsAwaitingReply returns the contact's executions parked on an action_wait_for_reply node, oldest first.
+func (s *Storage) GetExecutionsAwaitingReply(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) {
+	query := `
+		SELECT id, workflow_id, workflow_version_id, contact_id, current_node_id, status, waiting_for, state_data, split_branches, parent_execution_id, parent_node_id, call_depth, created_at, updated_at
+		FROM workflow_executions
+		WHERE contact_id = $1 AND status = 'waiting' AND waiting_for = 'reply'
+		ORDER BY created_at ASC
+	`
+	rows, err := s.DB.Query(ctx, query, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var execs []models.WorkflowExecution
+	for rows.Next() {
+		var exec models.WorkflowExecution
+		if err := rows.Scan(
+			&exec.ID, &exec.WorkflowID, &exec.WorkflowVersionID, &exec.ContactID, &exec.CurrentNodeID,
+			&exec.Status, &exec.WaitingFor, &exec.StateData, &exec.SplitBranches,
+			&exec.ParentExecutionID, &exec.ParentNodeID, &exec.CallDepth, &exec.CreatedAt, &exec.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		execs = append(execs, exec)
+	}
+	return execs, rows.Err()
+}
+
+// ClaimWaitingExecution atomically flips an execution from waiting (on waitingFor) back to running.
+// Returns false if another worker (reply vs. timeout) already claimed it.
+func (s *Storage) ClaimWaitingExecution(ctx context.Context, executionID int64, waitingFor string) (bool, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = 'running', waiting_for = '', updated_at = NOW()
+		WHERE id = $1 AND status = 'waiting' AND waiting_for = $2
+	`
+	tag, err := s.DB.Exec(ctx, query, executionID, waitingFor)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetWorkflowExecutionStatus moves an execution to status if it currently has one of the from
+// statuses, leaving its node and waiting_for untouched. Returns false if it was in another state.
+func (s *Storage) SetWorkflowExecutionStatus(ctx context.Context, executionID int64, from []string, status string) (bool, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = $3, updated_at = NOW()
+		WHERE id = $1 AND status = ANY($2)
+	`
+	tag, err := s.DB.Exec(ctx, query, executionID, from, status)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// MarkExecutionGoalReached ends an unfinished execution as goal_reached and records the goal's
+// name under "goal_reached" in its state. Returns false if the execution had already finished.
+func (s *Storage) MarkExecutionGoalReached(ctx context.Context, executionID int64, goal string) (bool, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = 'goal_reached', waiting_for = '',
+			state_data = state_data || jsonb_build_object('goal_reached', $2::text), updated_at = NOW()
+		WHERE id = $1 AND status IN ('running', 'waiting', 'paused')
+	`
+	tag, err := s.DB.Exec(ctx, query, executionID, goal)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetActiveExecutionsByContact returns the contact's running, waiting and paused executions, oldest first.
+func (s *Storage) GetActiveExecutionsByContact(ctx context.Context, contactID int64) ([]models.WorkflowExecution, error) {
+	query := `
+		SELECT id, workflow_id, workflow_version_id, contact_id, current_node_id, status, waiting_for, state_data, split_branches, parent_execution_id, parent_node_id, call_depth, created_at, updated_at
+		FROM workflow_executions
+		WHERE contact_id = $1 AND status IN ('running', 'waiting', 'paused')
+		ORDER BY created_at ASC
+	`
+	rows, err := s.DB.Query(ctx, query, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var execs []models.WorkflowExecution
+	for rows.Next() {
+		var exec models.WorkflowExecution
+		if err := rows.Scan(
+			&exec.ID, &exec.WorkflowID, &exec.WorkflowVersionID, &exec.ContactID, &exec.CurrentNodeID,
+			&exec.Status, &exec.WaitingFor, &exec.StateData, &exec.SplitBranches,
+			&exec.ParentExecutionID, &exec.ParentNodeID, &exec.CallDepth, &exec.CreatedAt, &exec.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		execs = append(execs, exec)
+	}
+	return execs, rows.Err()
+}
+
+// GetLastExecutionStart returns when the contact last entered the workflow, or the zero time.
+func (s *Storage) GetLastExecutionStart(ctx context.Context, workflowID, contactID int64) (time.Time, error) {
+	query := `SELECT MAX(created_at) FROM workflow_executions WHERE workflow_id = $1 AND contact_id = $2`
+	var last *time.Time
+	if err := s.DB.QueryRow(ctx, query, workflowID, contactID).Scan(&last); err != nil {
+		return time.Time{}, err
+	}
+	if last == nil {
+		return time.Time{}, nil
+	}
+	return *last, nil
+}
+
+// ClaimQueuedExecution promotes the contact's oldest queued execution of the workflow to running
+// and returns its ID, or 0 when nothing is queued or another execution is still active.
+func (s *Storage) ClaimQueuedExecution(ctx context.Context, workflowID, contactID int64) (int64, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = 'running', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM workflow_executions
+			WHERE workflow_id = $1 AND contact_id = $2 AND status = 'queued'
+			ORDER BY created_at ASC, id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+	var id int64
+	err := s.DB.QueryRow(ctx, query, workflowID, contactID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) || isUniqueViolation(err) {
+		return 0, nil
+	}
+	return id, err
+}
@@ -2,6 +2,7 @@ package workers
 
 import (
 	"log"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/social-media-lead/backend/internal/engine"
@@ -26,6 +27,12 @@ func StartServer(redisAddr string, graphWalker *engine.GraphWalker) *asynq.Serve
 	// mux maps a type to a handler
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(TaskResumeWorkflow, HandleResumeWorkflowTask(graphWalker))
+	mux.HandleFunc(TaskReplyTimeout, HandleReplyTimeoutTask(graphWalker))
+	mux.HandleFunc(TaskScheduleTick, HandleScheduleTickTask(graphWalker))
+	mux.HandleFunc(TaskTriggerFanout, HandleTriggerFanoutTask(graphWalker))
+	mux.HandleFunc(TaskContactEvent, HandleContactEventTask(graphWalker))
+	mux.HandleFunc(TaskHandoffSLA, HandleHandoffSLATask(graphWalker))
+	mux.HandleFunc(TaskLeadExtraction, HandleLeadExtractionTask(graphWalker))
 
 	// start the background server process
 	go func() {
@@ -36,3 +43,18 @@ func StartServer(redisAddr string, graphWalker *engine.GraphWalker) *asynq.Serve
 	
 	return srv
 }
+
+// StartScheduler enqueues the schedule tick every minute. Ticks are unique for most of a minute so
+// several API instances running a scheduler do not fire triggers twice.
+func StartScheduler(redisAddr string) *asynq.Scheduler {
+	scheduler := asynq.NewScheduler(asynq.RedisClientOpt{Addr: redisAddr}, nil)
+
+	if _, err := scheduler.Register("* * * * *", asynq.NewTask(TaskScheduleTick, nil), asynq.Unique(50*time.Second)); err != nil {
+		log.Fatalf("could not register schedule tick: %v", err)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		log.Fatalf("could not start asynq scheduler: %v", err)
+	}
+	return scheduler
+}
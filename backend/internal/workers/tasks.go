@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/social-media-lead/backend/internal/engine"
@@ -12,6 +13,12 @@ import (
 
 const (
 	TaskResumeWorkflow = "workflow:resume"
+	TaskReplyTimeout   = "workflow:reply_timeout"
+	TaskScheduleTick   = "workflow:schedule_tick"
+	TaskTriggerFanout  = "workflow:trigger_fanout"
+	TaskContactEvent   = "workflow:contact_event"
+	TaskHandoffSLA     = "workflow:handoff_sla"
+	TaskLeadExtraction = "workflow:lead_extract"
 )
 
 // ResumeWorkflowPayload represents the data sent to the background job
@@ -49,3 +56,102 @@ func HandleResumeWorkflowTask(graphWalker *engine.GraphWalker) func(context.Cont
 		return nil
 	}
 }
+
+// HandleReplyTimeoutTask routes an execution that is still waiting for a reply down its timeout handle
+func HandleReplyTimeoutTask(graphWalker *engine.GraphWalker) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		var p engine.ReplyTimeoutPayload
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+		}
+
+		log.Printf("[Worker] Reply timeout fired for execution %d at node %s", p.ExecutionID, p.NodeID)
+
+		if err := graphWalker.HandleReplyTimeout(ctx, p); err != nil {
+			log.Printf("[Worker] Reply timeout for execution %d failed: %v", p.ExecutionID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// HandleScheduleTickTask fans out the schedule and inactivity triggers that came due since they
+// last ran
+func HandleScheduleTickTask(graphWalker *engine.GraphWalker) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		if err := graphWalker.RunScheduledTriggers(ctx, time.Now()); err != nil {
+			log.Printf("[Worker] Schedule tick failed: %v", err)
+			// The next tick fires whatever this one missed
+			return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+		}
+		return nil
+	}
+}
+
+// HandleTriggerFanoutTask starts a scheduled or inactivity workflow for one batch of contacts
+func HandleTriggerFanoutTask(graphWalker *engine.GraphWalker) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		var p engine.FanoutPayload
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+		}
+
+		if err := graphWalker.RunFanout(ctx, p); err != nil {
+			log.Printf("[Worker] Fan-out of workflow %d after contact %d failed: %v", p.WorkflowID, p.AfterContactID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// HandleContactEventTask starts the workflows listening for a contact event
+func HandleContactEventTask(graphWalker *engine.GraphWalker) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		var evt engine.ContactEvent
+		if err := json.Unmarshal(t.Payload(), &evt); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+		}
+
+		log.Printf("[Worker] Contact event %s for contact %d", evt.Type, evt.ContactID)
+
+		if err := graphWalker.HandleContactEvent(ctx, evt); err != nil {
+			log.Printf("[Worker] Contact event %s for contact %d failed: %v", evt.Type, evt.ContactID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// HandleHandoffSLATask escalates or re-routes a handoff no agent has replied to in time
+func HandleHandoffSLATask(graphWalker *engine.GraphWalker) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		var p engine.HandoffSLAPayload
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+		}
+
+		log.Printf("[Worker] SLA timer fired for handoff %d", p.HandoffID)
+
+		if err := graphWalker.HandleHandoffSLA(ctx, p); err != nil {
+			log.Printf("[Worker] SLA check of handoff %d failed: %v", p.HandoffID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// HandleLeadExtractionTask extracts a contact's lead fields once the contact stopped writing
+func HandleLeadExtractionTask(graphWalker *engine.GraphWalker) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, t *asynq.Task) error {
+		var p engine.LeadExtractionPayload
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+		}
+
+		if err := graphWalker.HandleLeadExtraction(ctx, p); err != nil {
+			log.Printf("[Worker] Lead extraction for contact %d failed: %v", p.ContactID, err)
+			return err
+		}
+		return nil
+	}
+}